@@ -0,0 +1,351 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var (
+	ErrInvalidSource     = errors.New("invalid project source")
+	ErrSourceNotFound    = errors.New("project source not found")
+	ErrUnsafeArchivePath = errors.New("archive entry outside the destination")
+)
+
+// sourceIDPattern matches the content hashes that name uploaded sources.
+var sourceIDPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// agentBuildFlags are the go build flags an agent build may be given. The
+// others, such as -toolexec, -exec and -overlay, run programs or read files
+// the coordinator chooses.
+var agentBuildFlags = []string{"a", "asmflags", "buildvcs", "cover", "covermode", "coverpkg", "gcflags", "p", "trimpath", "v", "x"}
+
+// agentDeniedEnv are the variables an agent build may not be given, as they
+// name programs the go command runs or flags it passes to them. CGO_*FLAGS
+// and CC_FOR_*/CXX_FOR_* are denied by agentEnvAllowed as well.
+var agentDeniedEnv = []string{
+	"GOFLAGS", "GOENV", "GOROOT", "GOTOOLCHAIN", "GOCACHEPROG", "GCCGO",
+	"CC", "CXX", "FC", "AR", "PKG_CONFIG",
+	"PATH", "LD_PRELOAD", "LD_LIBRARY_PATH", "DYLD_INSERT_LIBRARIES",
+}
+
+// agentDeniedLinkerFlags are the -ldflags, and garble flags, that choose the
+// external linker or its arguments.
+var agentDeniedLinkerFlags = []string{"extld", "extldflags"}
+
+func agentEnvAllowed(name string) bool {
+	name = strings.ToUpper(name)
+	if strings.HasPrefix(name, "CGO_") && strings.HasSuffix(name, "FLAGS") {
+		return false
+	}
+
+	if strings.HasPrefix(name, "CC_FOR_") || strings.HasPrefix(name, "CXX_FOR_") {
+		return false
+	}
+
+	return !slices.Contains(agentDeniedEnv, name)
+}
+
+// agentLinkerFlagsAllowed reports whether none of the flags picks the
+// external linker, e.g. -extld=/tmp/cc or -ldflags=-extldflags=-B/tmp.
+func agentLinkerFlagsAllowed(flags []string) bool {
+	for _, arg := range flags {
+		for _, denied := range agentDeniedLinkerFlags {
+			if strings.Contains(arg, "-"+denied) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// agentBuild is the body of POST /builds: one dist of an uploaded source,
+// built with the config of the coordinator. SubArch is sent on its own as
+// GoDist leaves it out of its JSON.
+type agentBuild struct {
+	Source  string              `json:"source"`
+	Config  builder.BuildConfig `json:"config"`
+	Dist    builder.GoDist      `json:"dist"`
+	SubArch string              `json:"sub_arch,omitempty"`
+}
+
+// agentResult is the outcome of an agent build. Artifact is the URL path
+// the binary is downloaded from, set when the build succeeded.
+type agentResult struct {
+	Result   builder.BuildResult `json:"result"`
+	Artifact string              `json:"artifact,omitempty"`
+}
+
+// agentLoad tells a coordinator how busy the agent is.
+type agentLoad struct {
+	Slots   int `json:"slots"`
+	Running int `json:"running"`
+}
+
+func (s *buildServer) sourceDir(id string) string {
+	return filepath.Join(s.dir, "sources", id)
+}
+
+// putSource extracts the tar.gz of a project under its content hash, which
+// the upload has to match. A source that was uploaded before is kept as is.
+func (s *buildServer) putSource(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !sourceIDPattern.MatchString(id) {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("%w: id %q", ErrInvalidSource, id))
+		return
+	}
+
+	dir := s.sourceDir(id)
+	if _, err := os.Stat(dir); err == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// sources are extracted beside their final directory and renamed, so a
+	// failed upload leaves nothing behind
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), ".source-*")
+	if errors.Is(err, os.ErrNotExist) {
+		os.MkdirAll(filepath.Dir(dir), 0o755)
+		tmp, err = os.MkdirTemp(filepath.Dir(dir), ".source-*")
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer os.RemoveAll(tmp)
+
+	hash := sha256.New()
+	body := io.TeeReader(r.Body, hash)
+	if err := extractTarGz(body, tmp); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("%w: %w", ErrInvalidSource, err))
+		return
+	}
+
+	// gzip may stop before the end of the body, which is part of the hash
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("%w: %w", ErrInvalidSource, err))
+		return
+	}
+
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != id {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("%w: content hash %s does not match %s", ErrInvalidSource, sum, id))
+		return
+	}
+
+	if err := os.Rename(tmp, dir); err != nil && !errors.Is(err, os.ErrExist) {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// extractTarGz extracts the directories and regular files of a tar.gz into
+// dir.
+func extractTarGz(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.FromSlash(hdr.Name)
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("%w: %s", ErrUnsafeArchivePath, hdr.Name)
+		}
+		fp := filepath.Join(dir, name)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(fp, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(fp), 0o755); err != nil {
+				return err
+			}
+
+			f, err := os.OpenFile(fp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, hdr.FileInfo().Mode().Perm())
+			if err != nil {
+				return err
+			}
+
+			_, err = io.Copy(f, tr)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// build builds one dist of an uploaded source once a slot is free and
+// responds with the result.
+func (s *buildServer) build(w http.ResponseWriter, r *http.Request) {
+	if !isJSONRequest(r) {
+		writeJSONError(w, http.StatusUnsupportedMediaType, fmt.Errorf("%w: expected Content-Type application/json", ErrInvalidJob))
+		return
+	}
+
+	req := agentBuild{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("%w: %w", ErrInvalidJob, err))
+		return
+	}
+
+	if !sourceIDPattern.MatchString(req.Source) {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("%w: %q", ErrSourceNotFound, req.Source))
+		return
+	}
+
+	projectDir := s.sourceDir(req.Source)
+	if _, err := os.Stat(projectDir); err != nil {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("%w: %s", ErrSourceNotFound, req.Source))
+		return
+	}
+
+	raw := make([]byte, 8)
+	rand.Read(raw)
+	id := hex.EncodeToString(raw)
+
+	config, err := agentConfig(req.Config, projectDir, filepath.Join(s.dir, "builds", id))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	dist := req.Dist
+	dist.SubArch = req.SubArch
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-r.Context().Done():
+		return
+	}
+
+	result, err := builder.Build(r.Context(), config, dist)
+	res := agentResult{Result: result}
+	if err != nil {
+		res.Result.Error = err.Error()
+	} else {
+		res.Artifact = "/builds/" + id + "/" + filepath.Base(result.Path)
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+// agentConfig returns the config of a coordinator to build with on the
+// agent. The config comes from another machine, so its paths are rebased
+// onto the source, caches are left to the agent and it builds locally with
+// the agent's go. Build flags, variables and linker flags that run other
+// programs are rejected, as are names and paths leaving the source.
+func agentConfig(config builder.BuildConfig, projectDir string, outputDir string) (builder.BuildConfig, error) {
+	config.ProjectDir = projectDir
+	config.OutputDir = outputDir
+	config.Go, config.Builder = "", "local"
+	config.GoCache, config.GoCacheProg, config.GoModCache = "", "", ""
+
+	for _, arg := range config.BuildFlags {
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || !slices.Contains(agentBuildFlags, name) {
+			return builder.BuildConfig{}, fmt.Errorf("%w: build flag %s is not allowed", ErrInvalidJob, arg)
+		}
+	}
+
+	for _, v := range config.ExtraEnv {
+		name, _, _ := strings.Cut(v, "=")
+		if !agentEnvAllowed(name) {
+			return builder.BuildConfig{}, fmt.Errorf("%w: variable %s is not allowed", ErrInvalidJob, name)
+		}
+	}
+
+	for target, env := range config.Env {
+		for name := range env {
+			if !agentEnvAllowed(name) {
+				return builder.BuildConfig{}, fmt.Errorf("%w: variable %s of %s is not allowed", ErrInvalidJob, name, target)
+			}
+		}
+	}
+
+	if !agentLinkerFlagsAllowed(strings.Fields(config.Ldflags)) {
+		return builder.BuildConfig{}, fmt.Errorf("%w: ldflags %s are not allowed", ErrInvalidJob, config.Ldflags)
+	}
+
+	if !agentLinkerFlagsAllowed(config.GarbleFlags) {
+		return builder.BuildConfig{}, fmt.Errorf("%w: garble flags %s are not allowed", ErrInvalidJob, config.GarbleFlags)
+	}
+
+	if config.BinaryName == "" || config.BinaryName != filepath.Base(config.BinaryName) || strings.ContainsAny(config.BinaryName, `/\`) {
+		return builder.BuildConfig{}, fmt.Errorf("%w: binary name %q is not a file name", ErrInvalidJob, config.BinaryName)
+	}
+
+	paths := map[string]string{"package": config.Package, "go.work": config.GoWork, "pgo": config.PGO}
+	for target, profile := range config.PGOTargets {
+		paths["pgo of "+target] = profile
+	}
+
+	for name, fp := range paths {
+		if fp != "" && !filepath.IsLocal(fp) {
+			return builder.BuildConfig{}, fmt.Errorf("%w: %s %s is outside the project", ErrInvalidJob, name, fp)
+		}
+	}
+
+	if config.GoWork != "" {
+		config.GoWork = filepath.Join(projectDir, config.GoWork)
+	}
+
+	if err := config.Validate(); err != nil {
+		return builder.BuildConfig{}, fmt.Errorf("%w: %w", ErrInvalidJob, err)
+	}
+
+	return config, nil
+}
+
+// buildArtifact serves a binary built by the agent.
+func (s *buildServer) buildArtifact(w http.ResponseWriter, r *http.Request) {
+	id, name := r.PathValue("id"), r.PathValue("name")
+	if name != filepath.Base(name) || strings.HasPrefix(name, ".") || id != filepath.Base(id) || strings.HasPrefix(id, ".") {
+		writeJSONError(w, http.StatusNotFound, os.ErrNotExist)
+		return
+	}
+
+	fp := filepath.Join(s.dir, "builds", id, name)
+	if info, err := os.Stat(fp); err != nil || !info.Mode().IsRegular() {
+		writeJSONError(w, http.StatusNotFound, os.ErrNotExist)
+		return
+	}
+
+	http.ServeFile(w, r, fp)
+}
+
+func (s *buildServer) load(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, agentLoad{Slots: cap(s.slots), Running: len(s.slots)})
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestExtractTarGz(t *testing.T) {
+	archive := func(names ...string) []byte {
+		buf := bytes.Buffer{}
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		for _, name := range names {
+			tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: 2, Typeflag: tar.TypeReg})
+			tw.Write([]byte("ok"))
+		}
+		tw.Close()
+		gz.Close()
+		return buf.Bytes()
+	}
+
+	testCases := []struct {
+		name     string
+		input    []byte
+		wants    string
+		wantsErr error
+	}{
+		{name: "files", input: archive("go.mod", "cmd/app/main.go"), wants: "cmd/app/main.go"},
+		{name: "parent path", input: archive("../escape"), wantsErr: ErrUnsafeArchivePath},
+		{name: "absolute path", input: archive("/etc/escape"), wantsErr: ErrUnsafeArchivePath},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			err := extractTarGz(bytes.NewReader(tc.input), dir)
+			if !errors.Is(err, tc.wantsErr) {
+				t.Logf("Incorrect error, wanted: %v got: %v\n", tc.wantsErr, err)
+				t.Fail()
+			}
+
+			if tc.wants == "" {
+				return
+			}
+
+			if b, err := os.ReadFile(filepath.Join(dir, tc.wants)); err != nil || string(b) != "ok" {
+				t.Logf("File was not extracted: %v\n", err)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestAgentConfig(t *testing.T) {
+	config := builder.NewConfig()
+	config.Go = "/tmp/evil/go"
+	config.Builder = "docker"
+	config.GoWork = "go.work"
+
+	testCases := []struct {
+		name   string
+		modify func(config *builder.BuildConfig)
+		err    error
+	}{
+		{
+			name:   "defaults",
+			modify: func(config *builder.BuildConfig) {},
+		},
+		{
+			name: "allowed settings",
+			modify: func(config *builder.BuildConfig) {
+				config.BuildFlags = []string{"-gcflags=all=-N -l", "-trimpath", "-x"}
+				config.ExtraEnv = []string{"GOPROXY=https://proxy.golang.org", "CGO_ENABLED=0"}
+				config.Env = map[string]map[string]string{"linux/*": {"GOAMD64": "v3"}}
+				config.Ldflags = "-s -w -X main.version=v1.2.3"
+				config.Package = "./cmd/server"
+				config.PGO = "default.pgo"
+			},
+		},
+		{
+			name:   "toolexec",
+			modify: func(config *builder.BuildConfig) { config.BuildFlags = []string{"-toolexec=/bin/sh"} },
+			err:    ErrInvalidJob,
+		},
+		{
+			name:   "overlay",
+			modify: func(config *builder.BuildConfig) { config.BuildFlags = []string{"-overlay", "overlay.json"} },
+			err:    ErrInvalidJob,
+		},
+		{
+			name:   "positional",
+			modify: func(config *builder.BuildConfig) { config.BuildFlags = []string{"./cmd/other"} },
+			err:    ErrInvalidJob,
+		},
+		{
+			name:   "unknown compiler",
+			modify: func(config *builder.BuildConfig) { config.Compiler = "/bin/sh" },
+			err:    ErrInvalidJob,
+		},
+		{
+			name:   "goflags in extra env",
+			modify: func(config *builder.BuildConfig) { config.ExtraEnv = []string{"GOFLAGS=-toolexec=/bin/touch"} },
+			err:    ErrInvalidJob,
+		},
+		{
+			name:   "cgo flags in extra env",
+			modify: func(config *builder.BuildConfig) { config.ExtraEnv = []string{"CGO_LDFLAGS=-fuse-ld=/tmp/evil"} },
+			err:    ErrInvalidJob,
+		},
+		{
+			name:   "cc in env",
+			modify: func(config *builder.BuildConfig) { config.Env = map[string]map[string]string{"*": {"CC": "/tmp/evil"}} },
+			err:    ErrInvalidJob,
+		},
+		{
+			name: "cxx for target in env",
+			modify: func(config *builder.BuildConfig) {
+				config.Env = map[string]map[string]string{"linux": {"CXX_FOR_linux_arm64": "/tmp/evil"}}
+			},
+			err: ErrInvalidJob,
+		},
+		{
+			name:   "extld in ldflags",
+			modify: func(config *builder.BuildConfig) { config.Ldflags = "-extld=/tmp/evil -linkmode=external" },
+			err:    ErrInvalidJob,
+		},
+		{
+			name:   "extldflags in garble flags",
+			modify: func(config *builder.BuildConfig) { config.GarbleFlags = []string{"-ldflags=-extldflags=-B/tmp/evil"} },
+			err:    ErrInvalidJob,
+		},
+		{
+			name:   "binary name path",
+			modify: func(config *builder.BuildConfig) { config.BinaryName = "../../../../escape" },
+			err:    ErrInvalidJob,
+		},
+		{
+			name:   "empty binary name",
+			modify: func(config *builder.BuildConfig) { config.BinaryName = "" },
+			err:    ErrInvalidJob,
+		},
+		{
+			name:   "package outside source",
+			modify: func(config *builder.BuildConfig) { config.Package = "../../../../etc" },
+			err:    ErrInvalidJob,
+		},
+		{
+			name:   "absolute package",
+			modify: func(config *builder.BuildConfig) { config.Package = "/etc" },
+			err:    ErrInvalidJob,
+		},
+		{
+			name:   "go.work outside source",
+			modify: func(config *builder.BuildConfig) { config.GoWork = "../go.work" },
+			err:    ErrInvalidJob,
+		},
+		{
+			name:   "absolute pgo profile",
+			modify: func(config *builder.BuildConfig) { config.PGOTargets = map[string]string{"linux": "/etc/shadow"} },
+			err:    ErrInvalidJob,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := config
+			tc.modify(&config)
+
+			res, err := agentConfig(config, "/agent/src", "/agent/out")
+			if !errors.Is(err, tc.err) {
+				t.Logf("Incorrect error returned, wanted: %v got: %v\n", tc.err, err)
+				t.Fail()
+			}
+
+			if err != nil {
+				return
+			}
+
+			if res.Go != "" || res.Builder != "local" || res.ProjectDir != "/agent/src" || res.GoWork != filepath.Join("/agent/src", "go.work") {
+				t.Logf("Incorrect config: %+v\n", res)
+				t.Fail()
+			}
+
+			if fp := builder.OutputPath(res, builder.GoDist{GOOS: "linux", GOARCH: "amd64"}); filepath.Dir(fp) != "/agent/out" {
+				t.Logf("Output path outside the output directory: %s\n", fp)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestPutSource(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"go.mod": "module example.com/app\n"})
+
+	source := bytes.Buffer{}
+	id, err := packSource(&source, dir, filepath.Join(dir, "build"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := strings.Repeat("0", 64)
+
+	testCases := []struct {
+		name  string
+		input string
+		wants int
+	}{
+		{name: "matching hash", input: id, wants: http.StatusCreated},
+		{name: "uploaded before", input: id, wants: http.StatusNoContent},
+		{name: "other hash", input: other, wants: http.StatusBadRequest},
+	}
+
+	server := httptest.NewServer(newBuildServer("", t.TempDir(), "", "", 1).Handler())
+	defer server.Close()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPut, server.URL+"/sources/"+tc.input, bytes.NewReader(source.Bytes()))
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tc.wants {
+				t.Logf("Incorrect status, wanted: %d got: %d\n", tc.wants, resp.StatusCode)
+				t.Fail()
+			}
+		})
+	}
+}
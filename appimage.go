@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var ErrMissingAppImageIcon = errors.New("appimage needs an icon")
+
+// AppImageConfig describes the AppImages bundled from the linux binaries.
+// Icon is a PNG or SVG relative to the project directory.
+type AppImageConfig struct {
+	// Name is the application name in the desktop file, defaulting to
+	// the binary name.
+	Name       string   `json:"name"`
+	Comment    string   `json:"comment"`
+	Icon       string   `json:"icon"`
+	Categories []string `json:"categories"`
+	Terminal   bool     `json:"terminal"`
+}
+
+func (a AppImageConfig) IsEmpty() bool {
+	return a.Name == "" && a.Comment == "" && a.Icon == "" && len(a.Categories) == 0
+}
+
+// appImageArch maps the linux architectures to the names appimagetool takes
+// in $ARCH.
+var appImageArch = map[string]string{
+	"amd64": "x86_64",
+	"386":   "i686",
+	"arm64": "aarch64",
+	"arm":   "armhf",
+}
+
+const appRun = `#!/bin/sh
+HERE="$(dirname "$(readlink -f "$0")")"
+exec "$HERE/usr/bin/%s" "$@"
+`
+
+// desktopEntry returns the .desktop file for the binary.
+func desktopEntry(binary string, appImage AppImageConfig) string {
+	name := appImage.Name
+	if name == "" {
+		name = binary
+	}
+
+	categories := appImage.Categories
+	if len(categories) == 0 {
+		categories = []string{"Utility"}
+	}
+
+	entry := strings.Builder{}
+	entry.WriteString("[Desktop Entry]\nType=Application\n")
+	fmt.Fprintf(&entry, "Name=%s\n", name)
+	if appImage.Comment != "" {
+		fmt.Fprintf(&entry, "Comment=%s\n", appImage.Comment)
+	}
+	fmt.Fprintf(&entry, "Exec=%s\nIcon=%s\n", binary, binary)
+	fmt.Fprintf(&entry, "Categories=%s;\nTerminal=%t\n", strings.Join(categories, ";"), appImage.Terminal)
+
+	return entry.String()
+}
+
+// appImageOutputPath is the binary's output path with an .AppImage extension.
+func appImageOutputPath(config builder.BuildConfig, dist builder.GoDist) string {
+	return packageBasePath(config, dist) + ".AppImage"
+}
+
+// buildAppImages bundles every linux binary of an architecture appimagetool
+// supports and returns the AppImages it wrote.
+func buildAppImages(ctx context.Context, config builder.BuildConfig, appImage AppImageConfig, jobs []buildJob) ([]string, error) {
+	if appImage.Icon == "" {
+		return nil, ErrMissingAppImageIcon
+	}
+
+	appImages := []string{}
+
+	for _, job := range jobs {
+		if _, ok := appImageArch[job.Dist.GOARCH]; !ok || job.Dist.GOOS != "linux" || !job.Distributable() {
+			continue
+		}
+
+		fp, err := bundleAppImage(ctx, job.Config, appImage, job.Dist)
+		if err != nil {
+			return appImages, fmt.Errorf("%s: %w", job.Dist, err)
+		}
+
+		appImages = append(appImages, fp)
+	}
+
+	return appImages, nil
+}
+
+// bundleAppImage lays out an AppDir with AppRun, the desktop file, the icon
+// and the binary, and packs it with appimagetool.
+func bundleAppImage(ctx context.Context, config builder.BuildConfig, appImage AppImageConfig, dist builder.GoDist) (string, error) {
+	appDir, err := os.MkdirTemp("", "gobuilder-appdir")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(appDir)
+
+	binary := config.BinaryName
+
+	bin, err := os.ReadFile(builder.OutputPath(config, dist))
+	if err != nil {
+		return "", err
+	}
+
+	icon, err := os.ReadFile(filepath.Join(config.ProjectDir, appImage.Icon))
+	if err != nil {
+		return "", err
+	}
+
+	iconFile := binary + filepath.Ext(appImage.Icon)
+
+	files := []struct {
+		name     string
+		contents []byte
+		mode     os.FileMode
+	}{
+		{"AppRun", []byte(fmt.Sprintf(appRun, binary)), 0o755},
+		{binary + ".desktop", []byte(desktopEntry(binary, appImage)), 0o644},
+		{iconFile, icon, 0o644},
+		{".DirIcon", icon, 0o644},
+		{filepath.Join("usr", "bin", binary), bin, 0o755},
+	}
+
+	for _, file := range files {
+		fp := filepath.Join(appDir, file.name)
+
+		if err := os.MkdirAll(filepath.Dir(fp), 0o755); err != nil {
+			return "", err
+		}
+
+		if err := os.WriteFile(fp, file.contents, file.mode); err != nil {
+			return "", err
+		}
+	}
+
+	fp := appImageOutputPath(config, dist)
+
+	cmd := exec.CommandContext(ctx, "appimagetool", appDir, fp)
+	cmd.Env = append(os.Environ(), "ARCH="+appImageArch[dist.GOARCH])
+
+	if res, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("appimagetool: %w\n%s", err, res)
+	}
+
+	return fp, nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestDesktopEntry(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input AppImageConfig
+		wants string
+	}{
+		{
+			name:  "defaults",
+			input: AppImageConfig{Icon: "icon.png"},
+			wants: "[Desktop Entry]\nType=Application\nName=app\nExec=app\nIcon=app\nCategories=Utility;\nTerminal=false\n",
+		},
+		{
+			name:  "configured",
+			input: AppImageConfig{Name: "My App", Comment: "Does things", Categories: []string{"Development", "IDE"}, Terminal: true},
+			wants: "[Desktop Entry]\nType=Application\nName=My App\nComment=Does things\nExec=app\nIcon=app\nCategories=Development;IDE;\nTerminal=true\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := desktopEntry("app", tc.input)
+
+			if res != tc.wants {
+				t.Logf("Incorrect desktop entry, wanted:\n%s\ngot:\n%s\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var (
+	ErrInvalidArchiveFormat = errors.New("invalid archive format")
+	ErrNoPackageArchives    = errors.New("no archives were built for the package")
+)
+
+// ArchiveConfig describes the archives the binaries are packed into for the
+// package managers. Windows binaries are always zipped.
+type ArchiveConfig struct {
+	// Format is tar.gz (the default) or zip.
+	Format string `json:"format"`
+	// Files are extra files, relative to the project directory, such as
+	// README.md and LICENSE.
+	Files []string `json:"files"`
+}
+
+func (a ArchiveConfig) IsEmpty() bool {
+	return a.Format == "" && len(a.Files) == 0
+}
+
+func (a ArchiveConfig) FormatFor(dist builder.GoDist) string {
+	if dist.GOOS == "windows" {
+		return "zip"
+	}
+
+	if a.Format == "" {
+		return "tar.gz"
+	}
+
+	return a.Format
+}
+
+func (a ArchiveConfig) Validate() error {
+	if a.Format != "" && a.Format != "tar.gz" && a.Format != "zip" {
+		return fmt.Errorf("%w: %s, expected tar.gz or zip", ErrInvalidArchiveFormat, a.Format)
+	}
+
+	return nil
+}
+
+// packageBasePath is the binary's output path without its .exe or .wasm
+// extension. Other dots, as in app-go1.22.3-linux_amd64, are part of the name.
+func packageBasePath(config builder.BuildConfig, dist builder.GoDist) string {
+	fp := builder.OutputPath(config, dist)
+	for _, ext := range []string{".exe", ".wasm"} {
+		fp = strings.TrimSuffix(fp, ext)
+	}
+
+	return fp
+}
+
+// archiveOutputPath is the binary's output path with the archive extension in
+// place of any binary extension.
+func archiveOutputPath(config builder.BuildConfig, dist builder.GoDist, format string) string {
+	return packageBasePath(config, dist) + "." + format
+}
+
+// archivedBinaryName is the name of the binary inside an archive, without
+// the target suffix so the unpacked command has its normal name.
+func archivedBinaryName(config builder.BuildConfig, dist builder.GoDist) string {
+	if dist.GOOS == "windows" {
+		return config.BinaryName + ".exe"
+	}
+
+	return config.BinaryName
+}
+
+// buildArchives packs every plain executable in jobs together with the extra
+// files and returns the archives it wrote along with the first one of each
+// dist, keyed by the dist, for the package managers.
+func buildArchives(config builder.BuildConfig, archive ArchiveConfig, jobs []buildJob) (map[string]string, []string, error) {
+	archives := map[string]string{}
+	written := []string{}
+
+	for _, job := range jobs {
+		if !job.Distributable() {
+			continue
+		}
+
+		files := [][2]string{{archivedBinaryName(job.Config, job.Dist), builder.OutputPath(job.Config, job.Dist)}}
+		for _, fp := range archive.Files {
+			files = append(files, [2]string{filepath.Base(fp), filepath.Join(config.ProjectDir, fp)})
+		}
+
+		format := archive.FormatFor(job.Dist)
+		fp := archiveOutputPath(job.Config, job.Dist, format)
+
+		if err := writeArchive(fp, format, files); err != nil {
+			return archives, written, fmt.Errorf("%s: %w", filepath.Base(fp), err)
+		}
+		written = append(written, fp)
+
+		// builds of several binaries or go versions share the dist
+		if _, ok := archives[job.Dist.String()]; !ok {
+			archives[job.Dist.String()] = fp
+		}
+	}
+
+	return archives, written, nil
+}
+
+// writeArchive writes the files, given as archive name and source path
+// pairs, into a tar.gz or zip at fp.
+func writeArchive(fp string, format string, files [][2]string) error {
+	out, err := os.Create(fp)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	switch format {
+	case "zip":
+		err = writeZip(out, files)
+	case "tar.gz":
+		err = writeTarGz(out, files)
+	default:
+		err = fmt.Errorf("%w: %s", ErrInvalidArchiveFormat, format)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+func writeZip(w io.Writer, files [][2]string) error {
+	zw := zip.NewWriter(w)
+
+	for _, file := range files {
+		info, err := os.Stat(file[1])
+		if err != nil {
+			return err
+		}
+
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = file[0]
+		header.Method = zip.Deflate
+
+		dst, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+
+		if err := copyFile(dst, file[1]); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func writeTarGz(w io.Writer, files [][2]string) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	for _, file := range files {
+		info, err := os.Stat(file[1])
+		if err != nil {
+			return err
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = file[0]
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if err := copyFile(tw, file[1]); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gw.Close()
+}
+
+func copyFile(dst io.Writer, fp string) error {
+	src, err := os.Open(fp)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	_, err = io.Copy(dst, src)
+	return err
+}
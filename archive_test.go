@@ -0,0 +1,104 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestWriteArchive(t *testing.T) {
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "app-linux_amd64")
+	readme := filepath.Join(dir, "README.md")
+	os.WriteFile(bin, []byte("binary"), 0o755)
+	os.WriteFile(readme, []byte("readme"), 0o644)
+
+	files := [][2]string{{"app", bin}, {"README.md", readme}}
+	wants := []string{"app", "README.md"}
+
+	tgz := filepath.Join(dir, "app-linux_amd64.tar.gz")
+	if err := writeArchive(tgz, "tar.gz", files); err != nil {
+		t.Fatal(err)
+	}
+
+	f, _ := os.Open(tgz)
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	tr := tar.NewReader(gr)
+	for header, err := tr.Next(); err == nil; header, err = tr.Next() {
+		got = append(got, header.Name)
+
+		if header.Name == "app" && header.Mode&0o111 == 0 {
+			t.Logf("Binary lost its executable bit: %o\n", header.Mode)
+			t.Fail()
+		}
+	}
+
+	if !slices.Equal(got, wants) {
+		t.Logf("Incorrect tar.gz entries, wanted: %v got: %v\n", wants, got)
+		t.Fail()
+	}
+
+	zipFile := filepath.Join(dir, "app-windows_amd64.zip")
+	if err := writeArchive(zipFile, "zip", files); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.OpenReader(zipFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+
+	got = []string{}
+	for _, file := range zr.File {
+		got = append(got, file.Name)
+	}
+
+	if !slices.Equal(got, wants) {
+		t.Logf("Incorrect zip entries, wanted: %v got: %v\n", wants, got)
+		t.Fail()
+	}
+}
+
+func TestPackageBasePath(t *testing.T) {
+	config := builder.NewConfig()
+	config.OutputDir = "build"
+	config.BinaryName = "app"
+
+	dotted := config
+	dotted.BinaryName = "app.v2"
+
+	testCases := []struct {
+		name   string
+		config builder.BuildConfig
+		input  builder.GoDist
+		wants  string
+	}{
+		{name: "linux", config: config, input: builder.GoDist{GOOS: "linux", GOARCH: "amd64"}, wants: "app-linux_amd64"},
+		{name: "windows", config: config, input: builder.GoDist{GOOS: "windows", GOARCH: "amd64"}, wants: "app-windows_amd64"},
+		{name: "wasm", config: config, input: builder.GoDist{GOOS: "js", GOARCH: "wasm"}, wants: "app-js_wasm"},
+		{name: "dotted name", config: dotted, input: builder.GoDist{GOOS: "linux", GOARCH: "arm64"}, wants: "app.v2-linux_arm64"},
+		{name: "dotted name windows", config: dotted, input: builder.GoDist{GOOS: "windows", GOARCH: "arm64"}, wants: "app.v2-windows_arm64"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if res := packageBasePath(tc.config, tc.input); res != filepath.Join("build", tc.wants) {
+				t.Logf("Incorrect package base path, wanted: %s got: %s\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
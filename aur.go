@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+// AURConfig describes the binary PKGBUILD generated for the linux archives
+// and the AUR repository it is pushed to.
+type AURConfig struct {
+	// Name is the package name, defaulting to the binary name with a -bin
+	// suffix as the AUR expects of prebuilt packages.
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	URL         string   `json:"url"`
+	License     []string `json:"license"`
+	Maintainer  string   `json:"maintainer"`
+	Depends     []string `json:"depends"`
+	// Repo is the AUR git URL, e.g. ssh://aur@aur.archlinux.org/app-bin.git.
+	// The PKGBUILD is only written locally when empty.
+	Repo string `json:"repo"`
+}
+
+func (a AURConfig) IsEmpty() bool {
+	return a.Name == "" && a.Description == "" && a.URL == "" && len(a.License) == 0 &&
+		a.Maintainer == "" && len(a.Depends) == 0 && a.Repo == ""
+}
+
+// aurArch maps the linux architectures to pacman architectures. arm is
+// armv7h as Go defaults to GOARM=7.
+var aurArch = map[string]string{
+	"amd64": "x86_64",
+	"386":   "i686",
+	"arm64": "aarch64",
+	"arm":   "armv7h",
+}
+
+type aurSource struct {
+	Arch   string
+	File   string
+	URL    string
+	SHA256 string
+}
+
+// bashString single-quotes s for bash.
+func bashString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// bashArray quotes values as a bash array literal.
+func bashArray(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = bashString(v)
+	}
+
+	return "(" + strings.Join(quoted, " ") + ")"
+}
+
+// writePKGBUILD writes the PKGBUILD and .SRCINFO for the linux archives,
+// keyed by dist, to the aur directory of the output directory and returns
+// their paths.
+func writePKGBUILD(config builder.BuildConfig, aur AURConfig, archives map[string]string, tag string, downloadURL func(tag, file string) string) ([]string, error) {
+	name := aur.Name
+	if name == "" {
+		name = config.BinaryName + "-bin"
+	}
+
+	version := strings.TrimPrefix(strings.ReplaceAll(tag, "-", "_"), "v")
+
+	sources := []aurSource{}
+	for _, goarch := range []string{"amd64", "386", "arm64", "arm"} {
+		fp, ok := archives["linux/"+goarch]
+		if !ok {
+			continue
+		}
+
+		sum, err := sha256File(fp)
+		if err != nil {
+			return nil, err
+		}
+
+		file := filepath.Base(fp)
+		sources = append(sources, aurSource{Arch: aurArch[goarch], File: file, URL: downloadURL(tag, file), SHA256: sum})
+	}
+
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("%w: expected linux amd64, 386, arm64 or arm", ErrNoPackageArchives)
+	}
+
+	arches := []string{}
+	for _, source := range sources {
+		arches = append(arches, source.Arch)
+	}
+
+	pkgbuild := strings.Builder{}
+	if aur.Maintainer != "" {
+		fmt.Fprintf(&pkgbuild, "# Maintainer: %s\n\n", aur.Maintainer)
+	}
+
+	fmt.Fprintf(&pkgbuild, "pkgname=%s\npkgver=%s\npkgrel=1\n", name, version)
+	fmt.Fprintf(&pkgbuild, "pkgdesc=%s\n", bashString(aur.Description))
+	fmt.Fprintf(&pkgbuild, "arch=%s\n", bashArray(arches))
+	if aur.URL != "" {
+		fmt.Fprintf(&pkgbuild, "url=%s\n", bashString(aur.URL))
+	}
+	fmt.Fprintf(&pkgbuild, "license=%s\n", bashArray(aur.License))
+	if len(aur.Depends) > 0 {
+		fmt.Fprintf(&pkgbuild, "depends=%s\n", bashArray(aur.Depends))
+	}
+	fmt.Fprintf(&pkgbuild, "provides=('%s')\nconflicts=('%s')\n\n", config.BinaryName, config.BinaryName)
+
+	for _, source := range sources {
+		fmt.Fprintf(&pkgbuild, "source_%s=%s\n", source.Arch, bashArray([]string{source.File + "::" + source.URL}))
+		fmt.Fprintf(&pkgbuild, "sha256sums_%s=('%s')\n", source.Arch, source.SHA256)
+	}
+
+	fmt.Fprintf(&pkgbuild, "\npackage() {\n  install -Dm755 \"$srcdir/%s\" \"$pkgdir/usr/bin/%s\"\n}\n", config.BinaryName, config.BinaryName)
+
+	srcinfo := strings.Builder{}
+	fmt.Fprintf(&srcinfo, "pkgbase = %s\n\tpkgdesc = %s\n\tpkgver = %s\n\tpkgrel = 1\n", name, aur.Description, version)
+	if aur.URL != "" {
+		fmt.Fprintf(&srcinfo, "\turl = %s\n", aur.URL)
+	}
+	for _, arch := range arches {
+		fmt.Fprintf(&srcinfo, "\tarch = %s\n", arch)
+	}
+	for _, license := range aur.License {
+		fmt.Fprintf(&srcinfo, "\tlicense = %s\n", license)
+	}
+	for _, depend := range aur.Depends {
+		fmt.Fprintf(&srcinfo, "\tdepends = %s\n", depend)
+	}
+	fmt.Fprintf(&srcinfo, "\tprovides = %s\n\tconflicts = %s\n", config.BinaryName, config.BinaryName)
+	for _, source := range sources {
+		fmt.Fprintf(&srcinfo, "\tsource_%s = %s::%s\n", source.Arch, source.File, source.URL)
+		fmt.Fprintf(&srcinfo, "\tsha256sums_%s = %s\n", source.Arch, source.SHA256)
+	}
+	fmt.Fprintf(&srcinfo, "\npkgname = %s\n", name)
+
+	dir := filepath.Join(config.OutputDir, "aur")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	files := []string{filepath.Join(dir, "PKGBUILD"), filepath.Join(dir, ".SRCINFO")}
+
+	if err := os.WriteFile(files[0], []byte(pkgbuild.String()), 0o644); err != nil {
+		return nil, err
+	}
+
+	return files, os.WriteFile(files[1], []byte(srcinfo.String()), 0o644)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestWritePKGBUILD(t *testing.T) {
+	testCases := []struct {
+		name      string
+		dists     []string
+		dist      string
+		wantsArch string
+		err       error
+	}{
+		{
+			name:      "amd64",
+			dists:     []string{"linux/amd64", "windows/amd64"},
+			dist:      "linux/amd64",
+			wantsArch: "x86_64",
+		},
+		{
+			name:      "386",
+			dists:     []string{"linux/386", "darwin/amd64"},
+			dist:      "linux/386",
+			wantsArch: "i686",
+		},
+		{
+			name:      "arm64",
+			dists:     []string{"linux/arm64"},
+			dist:      "linux/arm64",
+			wantsArch: "aarch64",
+		},
+		{
+			name:      "arm",
+			dists:     []string{"linux/arm", "linux/riscv64"},
+			dist:      "linux/arm",
+			wantsArch: "armv7h",
+		},
+		{
+			name:  "no linux archives",
+			dists: []string{"linux/riscv64", "windows/amd64"},
+			err:   ErrNoPackageArchives,
+		},
+	}
+
+	aur := AURConfig{Description: "It's an app", License: []string{"MIT"}, Maintainer: "Jane <jane@example.com>"}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := builder.NewConfig()
+			config.OutputDir = t.TempDir()
+			config.BinaryName = "app"
+			archives := writeTestArchives(t, config, tc.dists...)
+
+			files, err := writePKGBUILD(config, aur, archives, "v1.2.3-rc1", testDownloadURL)
+
+			if !errors.Is(err, tc.err) {
+				t.Logf("Incorrect error returned, wanted: %v got: %v\n", tc.err, err)
+				t.FailNow()
+			} else if err != nil {
+				return
+			}
+
+			pkgbuild, err := os.ReadFile(files[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srcinfo, err := os.ReadFile(files[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sum, err := sha256File(archives[tc.dist])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			file := filepath.Base(archives[tc.dist])
+			url := testDownloadURL("v1.2.3-rc1", file)
+
+			wantsPKGBUILD := []string{
+				"# Maintainer: Jane <jane@example.com>\n",
+				"pkgname=app-bin\npkgver=1.2.3_rc1\npkgrel=1\n",
+				`pkgdesc='It'\''s an app'`,
+				"arch=('" + tc.wantsArch + "')\n",
+				"\nsource_" + tc.wantsArch + "=('" + file + "::" + url + "')\nsha256sums_" + tc.wantsArch + "=('" + sum + "')\n\n",
+				`install -Dm755 "$srcdir/app" "$pkgdir/usr/bin/app"`,
+			}
+
+			for _, wants := range wantsPKGBUILD {
+				if !strings.Contains(string(pkgbuild), wants) {
+					t.Logf("PKGBUILD is missing %q:\n%s\n", wants, pkgbuild)
+					t.Fail()
+				}
+			}
+
+			wantsSRCINFO := []string{
+				"pkgbase = app-bin\n",
+				"\tarch = " + tc.wantsArch + "\n",
+				"\tsource_" + tc.wantsArch + " = " + file + "::" + url + "\n\tsha256sums_" + tc.wantsArch + " = " + sum + "\n",
+				"\npkgname = app-bin\n",
+			}
+
+			for _, wants := range wantsSRCINFO {
+				if !strings.Contains(string(srcinfo), wants) {
+					t.Logf(".SRCINFO is missing %q:\n%s\n", wants, srcinfo)
+					t.Fail()
+				}
+			}
+
+			if strings.Count(string(pkgbuild), "source_") != 1 || strings.Count(string(srcinfo), "source_") != 1 {
+				t.Logf("Expected only the %s source, got:\n%s\n%s\n", tc.wantsArch, pkgbuild, srcinfo)
+				t.Fail()
+			}
+		})
+	}
+}
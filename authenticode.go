@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var ErrInvalidSignTool = errors.New("invalid authenticode tool")
+
+const defaultTimestampURL = "http://timestamp.digicert.com"
+
+// AuthenticodeConfig describes how windows binaries are signed after they are
+// built. The key is either a PKCS#12 file in Certificate, a PKCS#11 token
+// (PKCS11Module with a pkcs11: URI in Key and the PEM certificate in
+// Certificate), or for signtool a certificate store Thumbprint. A key
+// password is read from AUTHENTICODE_PASSWORD.
+type AuthenticodeConfig struct {
+	// Tool is osslsigncode (the default) or signtool.
+	Tool         string `json:"tool"`
+	Certificate  string `json:"certificate"`
+	PKCS11Module string `json:"pkcs11_module"`
+	Key          string `json:"key"`
+	Thumbprint   string `json:"thumbprint"`
+	TimestampURL string `json:"timestamp_url"`
+	// Description and URL are shown in the UAC prompt.
+	Description string `json:"description"`
+	URL         string `json:"url"`
+}
+
+func (a AuthenticodeConfig) IsEmpty() bool {
+	return a == AuthenticodeConfig{}
+}
+
+func (a AuthenticodeConfig) Validate() error {
+	if a.Tool != "" && a.Tool != "osslsigncode" && a.Tool != "signtool" {
+		return fmt.Errorf("%w: %s, expected osslsigncode or signtool", ErrInvalidSignTool, a.Tool)
+	}
+
+	return nil
+}
+
+// authenticodeCommand returns the command signing in and writing the signed
+// binary to out. signtool signs in place, so in and out are the same file.
+func authenticodeCommand(config builder.BuildConfig, sign AuthenticodeConfig, password string, in string, out string) (string, []string) {
+	timestamp := sign.TimestampURL
+	if timestamp == "" {
+		timestamp = defaultTimestampURL
+	}
+
+	abs := func(fp string) string {
+		if fp == "" || filepath.IsAbs(fp) {
+			return fp
+		}
+		return filepath.Join(config.ProjectDir, fp)
+	}
+
+	if sign.Tool == "signtool" {
+		args := []string{"sign", "/fd", "sha256", "/tr", timestamp, "/td", "sha256"}
+
+		if sign.Thumbprint != "" {
+			args = append(args, "/sha1", sign.Thumbprint)
+		} else {
+			args = append(args, "/f", abs(sign.Certificate))
+			if password != "" {
+				args = append(args, "/p", password)
+			}
+		}
+
+		if sign.Description != "" {
+			args = append(args, "/d", sign.Description)
+		}
+		if sign.URL != "" {
+			args = append(args, "/du", sign.URL)
+		}
+
+		return "signtool", append(args, in)
+	}
+
+	args := []string{"sign", "-h", "sha256", "-ts", timestamp}
+
+	if sign.PKCS11Module != "" {
+		args = append(args, "-pkcs11module", sign.PKCS11Module, "-key", sign.Key, "-certs", abs(sign.Certificate))
+	} else {
+		args = append(args, "-pkcs12", abs(sign.Certificate))
+	}
+
+	if password != "" {
+		args = append(args, "-pass", password)
+	}
+
+	if sign.Description != "" {
+		args = append(args, "-n", sign.Description)
+	}
+	if sign.URL != "" {
+		args = append(args, "-i", sign.URL)
+	}
+
+	return "osslsigncode", append(args, "-in", in, "-out", out)
+}
+
+// authenticodeBinaries signs the windows executables of jobs in place.
+func authenticodeBinaries(ctx context.Context, config builder.BuildConfig, sign AuthenticodeConfig, jobs []buildJob) ([]string, error) {
+	password := os.Getenv("AUTHENTICODE_PASSWORD")
+	signed := []string{}
+
+	for _, job := range jobs {
+		if job.Dist.GOOS != "windows" || !job.Distributable() {
+			continue
+		}
+
+		binary := builder.OutputPath(job.Config, job.Dist)
+		out := binary
+		if sign.Tool != "signtool" {
+			out = binary + ".signed"
+		}
+
+		name, args := authenticodeCommand(config, sign, password, binary, out)
+
+		if res, err := exec.CommandContext(ctx, name, args...).CombinedOutput(); err != nil {
+			os.Remove(binary + ".signed")
+			return signed, fmt.Errorf("%s %s: %w\n%s", name, filepath.Base(binary), err, res)
+		}
+
+		if out != binary {
+			if err := os.Rename(out, binary); err != nil {
+				return signed, err
+			}
+		}
+
+		signed = append(signed, binary)
+	}
+
+	return signed, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestAuthenticodeCommand(t *testing.T) {
+	config := builder.NewConfig()
+	config.ProjectDir = "/src"
+
+	testCases := []struct {
+		name     string
+		input    AuthenticodeConfig
+		password string
+		wants    []string
+	}{
+		{
+			name:     "pkcs12",
+			input:    AuthenticodeConfig{Certificate: "cert.pfx", Description: "App"},
+			password: "secret",
+			wants: []string{"osslsigncode", "sign", "-h", "sha256", "-ts", defaultTimestampURL, "-pkcs12", filepath.Join("/src", "cert.pfx"),
+				"-pass", "secret", "-n", "App", "-in", "app.exe", "-out", "app.exe.signed"},
+		},
+		{
+			name:  "pkcs11",
+			input: AuthenticodeConfig{PKCS11Module: "/usr/lib/opensc-pkcs11.so", Key: "pkcs11:object=sign", Certificate: "/etc/cert.pem", TimestampURL: "http://ts.example.com"},
+			wants: []string{"osslsigncode", "sign", "-h", "sha256", "-ts", "http://ts.example.com", "-pkcs11module", "/usr/lib/opensc-pkcs11.so",
+				"-key", "pkcs11:object=sign", "-certs", "/etc/cert.pem", "-in", "app.exe", "-out", "app.exe.signed"},
+		},
+		{
+			name:  "signtool store",
+			input: AuthenticodeConfig{Tool: "signtool", Thumbprint: "ABCDEF"},
+			wants: []string{"signtool", "sign", "/fd", "sha256", "/tr", defaultTimestampURL, "/td", "sha256", "/sha1", "ABCDEF", "app.exe"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd, args := authenticodeCommand(config, tc.input, tc.password, "app.exe", "app.exe.signed")
+			res := append([]string{cmd}, args...)
+
+			if !slices.Equal(res, tc.wants) {
+				t.Logf("Incorrect command, wanted: %v got: %v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
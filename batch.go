@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var ErrNoProjects = errors.New("no project directories matched")
+
+// projectDirs expands the project arguments. Glob patterns such as
+// services/* keep the matching directories that contain a go.mod.
+func projectDirs(args []string) ([]string, error) {
+	dirs := []string{}
+
+	for _, arg := range args {
+		if !strings.ContainsAny(arg, "*?[") {
+			dirs = append(dirs, arg)
+			continue
+		}
+
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", arg, err)
+		}
+
+		found := false
+		for _, match := range matches {
+			if _, err := os.Stat(filepath.Join(match, "go.mod")); err == nil {
+				dirs = append(dirs, match)
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("%w: %s", ErrNoProjects, arg)
+		}
+	}
+
+	return dirs, nil
+}
+
+type batchResult struct {
+	Dir      string
+	Duration time.Duration
+	Err      error
+}
+
+// runBatch builds each project in turn by running exe with the shared flags,
+// the project directory and the go build flags, so a failing project does
+// not stop the rest.
+func runBatch(ctx context.Context, exe string, flags []string, dirs []string, buildFlags []string, stdout io.Writer, stderr io.Writer) []batchResult {
+	results := []batchResult{}
+
+	for _, dir := range dirs {
+		fmt.Fprintln(stdout, "==>", dir)
+
+		cmd := exec.CommandContext(ctx, exe, withBuildFlags(append(append([]string{}, flags...), dir), buildFlags)...)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+
+		start := time.Now()
+		err := cmd.Run()
+
+		results = append(results, batchResult{Dir: dir, Duration: time.Since(start), Err: err})
+	}
+
+	return results
+}
+
+// writeBatchReport writes a table of the project results.
+func writeBatchReport(w io.Writer, results []batchResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROJECT\tSTATUS\tTIME")
+
+	for _, res := range results {
+		status := "ok"
+		if res.Err != nil {
+			status = "failed: " + res.Err.Error()
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", res.Dir, status, res.Duration.Round(100*time.Millisecond))
+	}
+
+	tw.Flush()
+}
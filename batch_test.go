@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProjectDirs(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"services/api/go.mod":  "module example.com/api\n",
+		"services/web/go.mod":  "module example.com/web\n",
+		"services/docs/README": "",
+	})
+
+	dirs, err := projectDirs([]string{filepath.Join(dir, "services", "*"), "tools"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wants := []string{filepath.Join(dir, "services", "api"), filepath.Join(dir, "services", "web"), "tools"}
+	if !slices.Equal(dirs, wants) {
+		t.Logf("Incorrect dirs, wanted: %v got: %v\n", wants, dirs)
+		t.Fail()
+	}
+
+	if _, err := projectDirs([]string{filepath.Join(dir, "missing", "*")}); !errors.Is(err, ErrNoProjects) {
+		t.Logf("Incorrect error, wanted: %v got: %v\n", ErrNoProjects, err)
+		t.Fail()
+	}
+}
+
+func TestWriteBatchReport(t *testing.T) {
+	out := strings.Builder{}
+	writeBatchReport(&out, []batchResult{
+		{Dir: "services/api", Duration: 1234 * time.Millisecond},
+		{Dir: "services/web", Duration: 320 * time.Millisecond, Err: errors.New("exit status 1")},
+	})
+
+	wants := "PROJECT       STATUS                 TIME\n" +
+		"services/api  ok                     1.2s\n" +
+		"services/web  failed: exit status 1  300ms\n"
+
+	if out.String() != wants {
+		t.Logf("Incorrect report, wanted:\n%s\ngot:\n%s\n", wants, out.String())
+		t.Fail()
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import "slices"
+
+// splitBuildFlags splits the command line at the first --, returning the
+// arguments of go-builder and the flags after it, which every go build is
+// given as they are, e.g. -gcflags=all=-N -x.
+func splitBuildFlags(args []string) ([]string, []string) {
+	i := slices.Index(args, "--")
+	if i < 0 {
+		return args, []string{}
+	}
+
+	return args[:i], slices.Clone(args[i+1:])
+}
+
+// withBuildFlags appends the go build flags to the arguments of a run of
+// go-builder.
+func withBuildFlags(args []string, buildFlags []string) []string {
+	if len(buildFlags) == 0 {
+		return args
+	}
+
+	return append(append(args, "--"), buildFlags...)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitBuildFlags(t *testing.T) {
+	testCases := []struct {
+		name       string
+		input      []string
+		wantsArgs  []string
+		wantsFlags []string
+	}{
+		{
+			name:       "no separator",
+			input:      []string{"-target", "linux", "."},
+			wantsArgs:  []string{"-target", "linux", "."},
+			wantsFlags: []string{},
+		},
+		{
+			name:       "build flags",
+			input:      []string{"-target", "linux", ".", "--", "-gcflags=all=-N -l", "-x"},
+			wantsArgs:  []string{"-target", "linux", "."},
+			wantsFlags: []string{"-gcflags=all=-N -l", "-x"},
+		},
+		{
+			name:       "only build flags",
+			input:      []string{"--", "-v", "--", "-x"},
+			wantsArgs:  []string{},
+			wantsFlags: []string{"-v", "--", "-x"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			args, flags := splitBuildFlags(tc.input)
+
+			if !slices.Equal(args, tc.wantsArgs) || !slices.Equal(flags, tc.wantsFlags) {
+				t.Logf("Incorrect split, wanted: %q %q got: %q %q\n", tc.wantsArgs, tc.wantsFlags, args, flags)
+				t.Fail()
+			}
+
+			if rejoined := withBuildFlags(slices.Clone(args), flags); len(flags) > 0 && !slices.Equal(rejoined, tc.input) {
+				t.Logf("Incorrect rejoined arguments, wanted: %q got: %q\n", tc.input, rejoined)
+				t.Fail()
+			}
+		})
+	}
+}
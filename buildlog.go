@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+// buildLogPath returns where the compiler output for dist is kept, e.g.
+// build/logs/linux_arm_7.log. When several binaries are built per target,
+// each gets its own directory of logs.
+func buildLogPath(config builder.BuildConfig, dist builder.GoDist, perBinary bool) string {
+	name := dist.GOOS + "_" + dist.GOARCH
+	if dist.SubArch != "" {
+		name += "_" + strings.ReplaceAll(dist.SubArch, ",", "_")
+	}
+
+	if config.Race {
+		name += "-race"
+	}
+
+	dir := filepath.Join(config.OutputDir, "logs")
+	if perBinary {
+		dir = filepath.Join(dir, config.BinaryName)
+	}
+
+	return filepath.Join(dir, name+".log")
+}
+
+// writeBuildLog writes the full output of a target's build, hooks included,
+// so a failure can be inspected after the run.
+func writeBuildLog(fp string, result builder.BuildResult, output string) error {
+	if err := os.MkdirAll(filepath.Dir(fp), 0o755); err != nil {
+		return err
+	}
+
+	log := strings.Builder{}
+	fmt.Fprintf(&log, "target: %s\n", resultName(result))
+	fmt.Fprintf(&log, "output: %s\n", result.Path)
+	fmt.Fprintf(&log, "status: %s\n\n", resultStatus(result))
+
+	log.WriteString(output)
+	if output != "" && !strings.HasSuffix(output, "\n") {
+		log.WriteString("\n")
+	}
+
+	if result.Error != "" {
+		fmt.Fprintf(&log, "\nerror: %s\n", result.Error)
+	}
+
+	return os.WriteFile(fp, []byte(log.String()), 0o644)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestBuildLogPath(t *testing.T) {
+	config := builder.NewConfig()
+	config.BinaryName = "app"
+	config.OutputDir = "build"
+
+	raceConfig := config
+	raceConfig.Race = true
+
+	testCases := []struct {
+		name      string
+		config    builder.BuildConfig
+		dist      builder.GoDist
+		perBinary bool
+		wants     string
+	}{
+		{name: "target", config: config, dist: builder.GoDist{GOOS: "linux", GOARCH: "amd64"}, wants: "build/logs/linux_amd64.log"},
+		{name: "sub-architecture", config: config, dist: builder.GoDist{GOOS: "linux", GOARCH: "arm", SubArch: "7"}, wants: "build/logs/linux_arm_7.log"},
+		{name: "race", config: raceConfig, dist: builder.GoDist{GOOS: "linux", GOARCH: "amd64"}, wants: "build/logs/linux_amd64-race.log"},
+		{name: "per binary", config: config, dist: builder.GoDist{GOOS: "windows", GOARCH: "amd64"}, perBinary: true, wants: "build/logs/app/windows_amd64.log"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := buildLogPath(tc.config, tc.dist, tc.perBinary)
+
+			if res != filepath.FromSlash(tc.wants) {
+				t.Logf("Incorrect log path, wanted: %v got: %v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestWriteBuildLog(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "logs", "windows_arm64.log")
+
+	result := builder.BuildResult{Target: "windows/arm64", Path: "build/app-windows_arm64.exe", Error: "exit status 1"}
+	if err := writeBuildLog(fp, result, "./main.go:3:9: undefined: foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := os.ReadFile(fp)
+	wants := "target: windows/arm64\n" +
+		"output: build/app-windows_arm64.exe\n" +
+		"status: failed\n\n" +
+		"./main.go:3:9: undefined: foo\n" +
+		"\nerror: exit status 1\n"
+
+	if string(got) != wants {
+		t.Logf("Incorrect log, wanted:\n%s\ngot:\n%s\n", wants, got)
+		t.Fail()
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var ErrInvalidLintMode = errors.New("unsupported lint mode")
+
+// LintConfig is an external lint Command, e.g. "staticcheck ./...", run
+// before building. Mode applies to it and -vet: fail, the default, or warn
+// to report problems without stopping the run.
+type LintConfig struct {
+	Command string `json:"command"`
+	Mode    string `json:"mode"`
+}
+
+func (l LintConfig) Validate() error {
+	if l.Mode != "" && l.Mode != "fail" && l.Mode != "warn" {
+		return fmt.Errorf("%w: %s", ErrInvalidLintMode, l.Mode)
+	}
+
+	return nil
+}
+
+// goCommand returns a go invocation run on the host in the project
+// directory, outside the target matrix.
+func goCommand(ctx context.Context, config builder.BuildConfig, args ...string) *exec.Cmd {
+	name := "go"
+	if config.Go != "" {
+		name = config.Go
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = config.ProjectDir
+	cmd.Env = append(os.Environ(), config.ExtraEnv...)
+
+	if config.GoWork != "" {
+		cmd.Env = append(cmd.Env, "GOWORK="+config.GoWork)
+	}
+
+	if config.Go != "" {
+		cmd.Env = append(cmd.Env, "GOTOOLCHAIN=local")
+	}
+
+	return cmd
+}
+
+// runGenerate runs go generate over every package of the project.
+func runGenerate(ctx context.Context, config builder.BuildConfig) (string, error) {
+	res, err := goCommand(ctx, config, "generate", "./...").CombinedOutput()
+	if err != nil {
+		return string(res), fmt.Errorf("go generate: %w\n%s", err, res)
+	}
+
+	return string(res), nil
+}
+
+// runTests runs go test with flags over every package of the project on
+// the host.
+func runTests(ctx context.Context, config builder.BuildConfig, flags []string) (string, error) {
+	args := append(append([]string{"test"}, flags...), "./...")
+
+	res, err := goCommand(ctx, config, args...).CombinedOutput()
+	if err != nil {
+		return string(res), fmt.Errorf("go test: %w\n%s", err, res)
+	}
+
+	return string(res), nil
+}
+
+// runVet runs go vet over every package of the project.
+func runVet(ctx context.Context, config builder.BuildConfig) (string, error) {
+	res, err := goCommand(ctx, config, "vet", "./...").CombinedOutput()
+	if err != nil {
+		return string(res), fmt.Errorf("go vet: %w\n%s", err, res)
+	}
+
+	return string(res), nil
+}
+
+// runLint runs the external lint command in the project directory.
+func runLint(ctx context.Context, config builder.BuildConfig, command string) (string, error) {
+	cmd := shellCommand(ctx, command)
+	cmd.Dir = config.ProjectDir
+
+	res, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(res), fmt.Errorf("%s: %w\n%s", command, err, res)
+	}
+
+	return string(res), nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestRunGenerate(t *testing.T) {
+	t.Setenv("GOFLAGS", "")
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":     "module example.com/gen\n\ngo 1.21\n",
+		"main.go":    "package main\n\n//go:generate go run ./gen\n\nfunc main() {}\n",
+		"gen/gen.go": "package main\n\nimport \"os\"\n\nfunc main() {\n\tos.WriteFile(\"generated.txt\", []byte(\"ok\"), 0o644)\n}\n",
+	})
+
+	config := builder.NewConfig()
+	config.ProjectDir = dir
+
+	if _, err := runGenerate(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "generated.txt")); err != nil {
+		t.Logf("Generator did not run: %v\n", err)
+		t.Fail()
+	}
+
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\n//go:generate false\n\nfunc main() {}\n"), 0o644)
+
+	if _, err := runGenerate(context.Background(), config); err == nil {
+		t.Logf("Failing generator did not return an error\n")
+		t.Fail()
+	}
+}
+
+func TestRunTests(t *testing.T) {
+	t.Setenv("GOFLAGS", "")
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":       "module example.com/tested\n\ngo 1.21\n",
+		"main.go":      "package main\n\nfunc main() {}\n",
+		"main_test.go": "package main\n\nimport \"testing\"\n\nfunc TestShort(t *testing.T) {\n\tif !testing.Short() {\n\t\tt.Fatal(\"not short\")\n\t}\n}\n",
+	})
+
+	config := builder.NewConfig()
+	config.ProjectDir = dir
+
+	if _, err := runTests(context.Background(), config, []string{"-short"}); err != nil {
+		t.Logf("Passing tests returned an error: %v\n", err)
+		t.Fail()
+	}
+
+	if _, err := runTests(context.Background(), config, nil); err == nil {
+		t.Logf("Failing tests did not return an error\n")
+		t.Fail()
+	}
+}
+
+func TestLintConfigValidate(t *testing.T) {
+	testCases := []struct {
+		input    string
+		wantsErr bool
+	}{
+		{input: ""},
+		{input: "fail"},
+		{input: "warn"},
+		{input: "ignore", wantsErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			err := LintConfig{Mode: tc.input}.Validate()
+
+			if (err != nil) != tc.wantsErr {
+				t.Logf("Incorrect error returned, wanted error: %v got: %v\n", tc.wantsErr, err)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestRunVet(t *testing.T) {
+	t.Setenv("GOFLAGS", "")
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":  "module example.com/vetted\n\ngo 1.21\n",
+		"main.go": "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Printf(\"%d\\n\", \"text\")\n}\n",
+	})
+
+	config := builder.NewConfig()
+	config.ProjectDir = dir
+
+	if _, err := runVet(context.Background(), config); err == nil {
+		t.Logf("Vet problem did not return an error\n")
+		t.Fail()
+	}
+
+	if _, err := runLint(context.Background(), config, "exit 1"); err == nil {
+		t.Logf("Failing lint command did not return an error\n")
+		t.Fail()
+	}
+}
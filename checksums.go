@@ -0,0 +1,46 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const checksumsFile = "checksums.txt"
+
+func sha256File(fp string) (string, error) {
+	f, err := os.Open(fp)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeChecksums writes a sha256sum compatible checksums.txt for the
+// artifacts into outputDir and returns its path.
+func writeChecksums(outputDir string, artifacts []string) (string, error) {
+	lines := strings.Builder{}
+
+	for _, artifact := range artifacts {
+		sum, err := sha256File(artifact)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(&lines, "%s  %s\n", sum, filepath.Base(artifact))
+	}
+
+	fp := filepath.Join(outputDir, checksumsFile)
+	return fp, os.WriteFile(fp, []byte(lines.String()), 0o644)
+}
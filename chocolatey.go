@@ -0,0 +1,201 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var ErrIncompleteNuspec = errors.New("chocolatey packages need authors and a description")
+
+const defaultChocolateySource = "https://push.chocolatey.org/"
+
+// ChocolateyConfig describes the Chocolatey package generated for the
+// windows archives and the feed it is pushed to. The API key is read from
+// CHOCOLATEY_API_KEY.
+type ChocolateyConfig struct {
+	// ID is the package id, defaulting to the binary name.
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Authors     string   `json:"authors"`
+	Description string   `json:"description"`
+	ProjectURL  string   `json:"project_url"`
+	LicenseURL  string   `json:"license_url"`
+	Tags        []string `json:"tags"`
+	// Source is the NuGet feed pushed to, the community repository by
+	// default.
+	Source string `json:"source"`
+	Push   bool   `json:"push"`
+}
+
+func (c ChocolateyConfig) IsEmpty() bool {
+	return c.ID == "" && c.Authors == "" && c.Description == "" && !c.Push
+}
+
+type nuspecMetadata struct {
+	ID                       string `xml:"id"`
+	Version                  string `xml:"version"`
+	Title                    string `xml:"title"`
+	Authors                  string `xml:"authors"`
+	ProjectURL               string `xml:"projectUrl,omitempty"`
+	LicenseURL               string `xml:"licenseUrl,omitempty"`
+	RequireLicenseAcceptance bool   `xml:"requireLicenseAcceptance"`
+	Description              string `xml:"description"`
+	Tags                     string `xml:"tags,omitempty"`
+}
+
+type nuspec struct {
+	XMLName  xml.Name       `xml:"package"`
+	Xmlns    string         `xml:"xmlns,attr"`
+	Metadata nuspecMetadata `xml:"metadata"`
+}
+
+var chocolateyInstallTemplate = template.Must(template.New("install").Parse(`$ErrorActionPreference = 'Stop'
+$toolsDir = "$(Split-Path -parent $MyInvocation.MyCommand.Definition)"
+
+$packageArgs = @{
+  packageName    = $env:ChocolateyPackageName
+  unzipLocation  = $toolsDir
+{{- with index . "386" }}
+  url            = '{{ .URL }}'
+  checksum       = '{{ .SHA256 }}'
+  checksumType   = 'sha256'
+{{- end }}
+{{- with index . "amd64" }}
+  url64bit       = '{{ .URL }}'
+  checksum64     = '{{ .SHA256 }}'
+  checksumType64 = 'sha256'
+{{- end }}
+}
+
+Install-ChocolateyZipPackage @packageArgs
+`))
+
+const nupkgContentTypes = `<?xml version="1.0" encoding="utf-8"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+  <Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml" />
+  <Default Extension="nuspec" ContentType="application/octet" />
+  <Default Extension="ps1" ContentType="application/octet" />
+</Types>
+`
+
+const nupkgRels = `<?xml version="1.0" encoding="utf-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Type="http://schemas.microsoft.com/packaging/2010/07/manifest" Target="/%s.nuspec" Id="R1" />
+</Relationships>
+`
+
+// writeNupkg writes a Chocolatey package whose install script downloads the
+// windows 386 and amd64 archives, keyed by dist, and returns its path.
+func writeNupkg(config builder.BuildConfig, choco ChocolateyConfig, archives map[string]string, tag string, downloadURL func(tag, file string) string) (string, error) {
+	if choco.Authors == "" || choco.Description == "" {
+		return "", ErrIncompleteNuspec
+	}
+
+	spec := nuspec{Xmlns: "http://schemas.microsoft.com/packaging/2015/06/nuspec.xsd"}
+	spec.Metadata.ID = choco.ID
+	if spec.Metadata.ID == "" {
+		spec.Metadata.ID = strings.ToLower(config.BinaryName)
+	}
+	spec.Metadata.Version = strings.TrimPrefix(tag, "v")
+	spec.Metadata.Title = choco.Title
+	if spec.Metadata.Title == "" {
+		spec.Metadata.Title = config.BinaryName
+	}
+	spec.Metadata.Authors = choco.Authors
+	spec.Metadata.ProjectURL = choco.ProjectURL
+	spec.Metadata.LicenseURL = choco.LicenseURL
+	spec.Metadata.Description = choco.Description
+	spec.Metadata.Tags = strings.Join(choco.Tags, " ")
+
+	installers := map[string]*formulaArchive{}
+	for _, goarch := range []string{"386", "amd64"} {
+		fp, ok := archives["windows/"+goarch]
+		if !ok {
+			continue
+		}
+
+		sum, err := sha256File(fp)
+		if err != nil {
+			return "", err
+		}
+
+		installers[goarch] = &formulaArchive{URL: downloadURL(tag, filepath.Base(fp)), SHA256: sum}
+	}
+
+	if len(installers) == 0 {
+		return "", fmt.Errorf("%w: expected windows 386 or amd64", ErrNoPackageArchives)
+	}
+
+	install := bytes.Buffer{}
+	if err := chocolateyInstallTemplate.Execute(&install, installers); err != nil {
+		return "", err
+	}
+
+	specXML, err := xml.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	id := spec.Metadata.ID
+	files := [][2]string{
+		{"[Content_Types].xml", nupkgContentTypes},
+		{"_rels/.rels", fmt.Sprintf(nupkgRels, id)},
+		{id + ".nuspec", xml.Header + string(specXML) + "\n"},
+		{"tools/chocolateyInstall.ps1", install.String()},
+	}
+
+	pkg := bytes.Buffer{}
+	zw := zip.NewWriter(&pkg)
+
+	for _, file := range files {
+		w, err := zw.Create(file[0])
+		if err != nil {
+			return "", err
+		}
+
+		if _, err := w.Write([]byte(file[1])); err != nil {
+			return "", err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+
+	fp := filepath.Join(config.OutputDir, id+"."+spec.Metadata.Version+".nupkg")
+	return fp, os.WriteFile(fp, pkg.Bytes(), 0o644)
+}
+
+// pushNupkg uploads the package to the NuGet v2 feed the way choco push does.
+func pushNupkg(ctx context.Context, choco ChocolateyConfig, fp string) error {
+	apiKey, err := tokenFromEnv("CHOCOLATEY_API_KEY")
+	if err != nil {
+		return err
+	}
+
+	source := choco.Source
+	if source == "" {
+		source = defaultChocolateySource
+	}
+
+	body, contentType, err := multipartFile("package", fp)
+	if err != nil {
+		return err
+	}
+
+	client := apiClient{headers: map[string]string{"X-NuGet-ApiKey": apiKey}}
+	_, err = client.do(ctx, http.MethodPut, strings.TrimSuffix(source, "/")+"/api/v2/package", contentType, body, nil)
+
+	return err
+}
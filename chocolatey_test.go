@@ -0,0 +1,178 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+// chocolateyInstallLines are the url and checksum lines of the install
+// script for each windows architecture.
+var chocolateyInstallLines = map[string][2]string{
+	"386":   {"url            = '", "checksum       = '"},
+	"amd64": {"url64bit       = '", "checksum64     = '"},
+}
+
+func TestWriteNupkg(t *testing.T) {
+	testCases := []struct {
+		name         string
+		choco        ChocolateyConfig
+		dists        []string
+		wantsFile    string
+		wantsSpec    nuspecMetadata
+		wantsInstall map[string]string
+		err          error
+	}{
+		{
+			name:      "amd64",
+			choco:     ChocolateyConfig{Authors: "Acme", Description: "An app"},
+			dists:     []string{"windows/amd64"},
+			wantsFile: "app.1.2.3.nupkg",
+			wantsSpec: nuspecMetadata{ID: "app", Version: "1.2.3", Title: "App", Authors: "Acme", Description: "An app"},
+			wantsInstall: map[string]string{
+				"amd64": "windows/amd64",
+			},
+		},
+		{
+			name: "configured",
+			choco: ChocolateyConfig{
+				ID:          "acme-app",
+				Title:       "Acme App",
+				Authors:     "Acme",
+				Description: "An app",
+				ProjectURL:  "https://acme.example.com",
+				LicenseURL:  "https://acme.example.com/license",
+				Tags:        []string{"cli", "acme"},
+			},
+			dists:     []string{"windows/386", "windows/amd64", "windows/arm64", "linux/amd64"},
+			wantsFile: "acme-app.1.2.3.nupkg",
+			wantsSpec: nuspecMetadata{
+				ID:          "acme-app",
+				Version:     "1.2.3",
+				Title:       "Acme App",
+				Authors:     "Acme",
+				ProjectURL:  "https://acme.example.com",
+				LicenseURL:  "https://acme.example.com/license",
+				Description: "An app",
+				Tags:        "cli acme",
+			},
+			wantsInstall: map[string]string{
+				"386":   "windows/386",
+				"amd64": "windows/amd64",
+			},
+		},
+		{
+			name:  "no authors",
+			choco: ChocolateyConfig{Description: "An app"},
+			dists: []string{"windows/amd64"},
+			err:   ErrIncompleteNuspec,
+		},
+		{
+			name:  "no windows archives",
+			choco: ChocolateyConfig{Authors: "Acme", Description: "An app"},
+			dists: []string{"windows/arm64", "linux/amd64"},
+			err:   ErrNoPackageArchives,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := builder.NewConfig()
+			config.OutputDir = t.TempDir()
+			config.BinaryName = "App"
+			archives := writeTestArchives(t, config, tc.dists...)
+
+			fp, err := writeNupkg(config, tc.choco, archives, "v1.2.3", testDownloadURL)
+
+			if !errors.Is(err, tc.err) {
+				t.Logf("Incorrect error returned, wanted: %v got: %v\n", tc.err, err)
+				t.FailNow()
+			} else if err != nil {
+				return
+			}
+
+			if filepath.Base(fp) != tc.wantsFile {
+				t.Logf("Incorrect package name, wanted: %s got: %s\n", tc.wantsFile, filepath.Base(fp))
+				t.Fail()
+			}
+
+			contents := readZip(t, fp)
+
+			wantsNames := []string{"[Content_Types].xml", "_rels/.rels", tc.wantsSpec.ID + ".nuspec", "tools/chocolateyInstall.ps1"}
+			if res := slices.Sorted(maps.Keys(contents)); !slices.Equal(res, wantsNames) {
+				t.Logf("Incorrect entries, wanted: %v got: %v\n", wantsNames, res)
+				t.Fail()
+			}
+
+			var spec nuspec
+			if err := xml.Unmarshal([]byte(contents[tc.wantsSpec.ID+".nuspec"]), &spec); err != nil {
+				t.Fatal(err)
+			}
+
+			if spec.Metadata != tc.wantsSpec {
+				t.Logf("Incorrect nuspec, wanted: %+v got: %+v\n", tc.wantsSpec, spec.Metadata)
+				t.Fail()
+			}
+
+			install := contents["tools/chocolateyInstall.ps1"]
+			for goarch, lines := range chocolateyInstallLines {
+				dist, ok := tc.wantsInstall[goarch]
+				if !ok {
+					if strings.Contains(install, lines[0]) {
+						t.Logf("Unexpected %s installer in install script:\n%s\n", goarch, install)
+						t.Fail()
+					}
+					continue
+				}
+
+				sum, err := sha256File(archives[dist])
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				url := testDownloadURL("v1.2.3", filepath.Base(archives[dist]))
+				if !strings.Contains(install, lines[0]+url+"'") || !strings.Contains(install, lines[1]+sum+"'") {
+					t.Logf("Incorrect %s installer, wanted: %s %s got:\n%s\n", goarch, url, sum, install)
+					t.Fail()
+				}
+			}
+		})
+	}
+}
+
+// readZip returns the contents of each file in the zip archive by name.
+func readZip(t *testing.T, fp string) map[string]string {
+	t.Helper()
+
+	zr, err := zip.OpenReader(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+
+	contents := map[string]string{}
+	for _, file := range zr.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		raw, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		contents[file.Name] = string(raw)
+	}
+
+	return contents
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var ErrNoCommands = errors.New("no main packages found under ./cmd")
+
+// discoverCommands lists the main packages under ./cmd of the project and
+// returns a config for each, named after its directory.
+func discoverCommands(ctx context.Context, config builder.BuildConfig) ([]builder.BuildConfig, error) {
+	cmd := exec.CommandContext(ctx, "go", "list", "-f", `{{if eq .Name "main"}}{{.Dir}}{{end}}`, "./cmd/...")
+	cmd.Dir = config.ProjectDir
+
+	res, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("go list: %w\n%s", err, exitErr.Stderr)
+		}
+		return nil, fmt.Errorf("go list: %w", err)
+	}
+
+	projectDir, err := filepath.Abs(config.ProjectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	configs := []builder.BuildConfig{}
+
+	for _, dir := range strings.Fields(string(res)) {
+		rel, err := filepath.Rel(projectDir, dir)
+		if err != nil {
+			return nil, err
+		}
+
+		command := config
+		command.BinaryName = filepath.Base(dir)
+		command.Package = "./" + filepath.ToSlash(rel)
+		configs = append(configs, command)
+	}
+
+	if len(configs) == 0 {
+		return nil, ErrNoCommands
+	}
+
+	return configs, nil
+}
+
+var ErrDuplicateBuildName = errors.New("several builds write the same binary name")
+
+// BuildEntry is one binary of a multi-binary project. Every entry is built
+// for the whole target matrix.
+type BuildEntry struct {
+	// Name is the binary name, defaulting to the package's directory.
+	Name string `json:"name"`
+	// Package is the main package, relative to the project directory.
+	Package string   `json:"package"`
+	Tags    []string `json:"tags"`
+	Ldflags string   `json:"ldflags"`
+}
+
+// entryConfigs returns a config for each build entry, with the entry's
+// package, name, tags and ldflags set on a copy of config.
+func entryConfigs(config builder.BuildConfig, entries []BuildEntry) []builder.BuildConfig {
+	configs := []builder.BuildConfig{}
+
+	for _, entry := range entries {
+		build := config
+		build.Package = entry.Package
+		build.Tags = entry.Tags
+		build.Ldflags = entry.Ldflags
+		build.BinaryName = entry.Name
+		build.Package = packagePath(config.ProjectDir, entry.Package)
+
+		if build.BinaryName == "" {
+			if entry.Package == "" || entry.Package == "." {
+				build.BinaryName = config.BinaryName
+			} else {
+				build.BinaryName = filepath.Base(entry.Package)
+			}
+		}
+
+		configs = append(configs, build)
+	}
+
+	return configs
+}
+
+// packagePath returns pkg as go build should be given it: go build treats
+// paths without a leading dot as import paths, so directories of the
+// project get one.
+func packagePath(projectDir string, pkg string) string {
+	if pkg != "" && !strings.HasPrefix(pkg, ".") && !filepath.IsAbs(pkg) {
+		if info, err := os.Stat(filepath.Join(projectDir, pkg)); err == nil && info.IsDir() {
+			return "./" + filepath.ToSlash(pkg)
+		}
+	}
+
+	return pkg
+}
+
+// uniqueBinaryNames reports builds whose outputs would overwrite each other.
+func uniqueBinaryNames(builds []builder.BuildConfig) error {
+	seen := map[string]bool{}
+	errs := []error{}
+
+	for _, build := range builds {
+		if seen[build.BinaryName] {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrDuplicateBuildName, build.BinaryName))
+		}
+		seen[build.BinaryName] = true
+	}
+
+	return errors.Join(errs...)
+}
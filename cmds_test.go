@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestDiscoverCommands(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"go.mod":                 "module example.com/suite\n\ngo 1.21\n",
+		"cmd/server/main.go":     "package main\n\nfunc main() {}\n",
+		"cmd/tools/lint/main.go": "package main\n\nfunc main() {}\n",
+		"cmd/internal/util.go":   "package internal\n",
+	}
+
+	for name, contents := range files {
+		fp := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(fp), 0o755)
+		os.WriteFile(fp, []byte(contents), 0o644)
+	}
+
+	config := builder.NewConfig()
+	config.ProjectDir = dir
+
+	configs, err := discoverCommands(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, command := range configs {
+		got = append(got, command.BinaryName+" "+command.Package)
+	}
+
+	wants := []string{"server ./cmd/server", "lint ./cmd/tools/lint"}
+	if !slices.Equal(got, wants) {
+		t.Logf("Incorrect commands, wanted: %v got: %v\n", wants, got)
+		t.Fail()
+	}
+}
+
+func TestEntryConfigs(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "cmd", "worker"), 0o755)
+
+	config := builder.NewConfig()
+	config.ProjectDir = dir
+	config.BinaryName = "app"
+
+	builds := entryConfigs(config, []BuildEntry{
+		{Package: "."},
+		{Package: "cmd/worker", Tags: []string{"netgo"}},
+		{Name: "cli", Package: "example.com/app/cli", Ldflags: "-s -w"},
+	})
+
+	got := []string{}
+	for _, build := range builds {
+		got = append(got, build.BinaryName+" "+build.Package+" "+build.Ldflags)
+	}
+
+	wants := []string{"app . ", "worker ./cmd/worker ", "cli example.com/app/cli -s -w"}
+	if !slices.Equal(got, wants) || !slices.Equal(builds[1].Tags, []string{"netgo"}) {
+		t.Logf("Incorrect builds, wanted: %v got: %v\n", wants, got)
+		t.Fail()
+	}
+
+	if err := uniqueBinaryNames(append(builds, builds[0])); !errors.Is(err, ErrDuplicateBuildName) {
+		t.Logf("Expected duplicate name error, got: %v\n", err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+// CodesignConfig describes how darwin binaries are signed after they are
+// built. Identity is a "Developer ID Application" identity in the keychain,
+// or "-" for ad-hoc signing.
+type CodesignConfig struct {
+	Identity string `json:"identity"`
+	// Entitlements is a plist relative to the project directory.
+	Entitlements string `json:"entitlements"`
+}
+
+func (c CodesignConfig) IsEmpty() bool {
+	return c == CodesignConfig{}
+}
+
+// codesignArgs returns the codesign arguments for the binary. Identity
+// signatures use the hardened runtime and a secure timestamp, which
+// notarization requires; ad-hoc signatures can use neither.
+func codesignArgs(config builder.BuildConfig, sign CodesignConfig, binary string) []string {
+	args := []string{"--force", "--sign", sign.Identity}
+
+	if sign.Identity != "-" {
+		args = append(args, "--options", "runtime", "--timestamp")
+	}
+
+	if sign.Entitlements != "" {
+		args = append(args, "--entitlements", filepath.Join(config.ProjectDir, sign.Entitlements))
+	}
+
+	return append(args, binary)
+}
+
+// codesignBinaries signs the darwin executables of jobs in place.
+func codesignBinaries(ctx context.Context, config builder.BuildConfig, sign CodesignConfig, jobs []buildJob) ([]string, error) {
+	signed := []string{}
+
+	for _, job := range jobs {
+		if job.Dist.GOOS != "darwin" || !job.Distributable() {
+			continue
+		}
+
+		binary := builder.OutputPath(job.Config, job.Dist)
+
+		if res, err := exec.CommandContext(ctx, "codesign", codesignArgs(config, sign, binary)...).CombinedOutput(); err != nil {
+			return signed, fmt.Errorf("codesign %s: %w\n%s", filepath.Base(binary), err, res)
+		}
+
+		signed = append(signed, binary)
+	}
+
+	return signed, nil
+}
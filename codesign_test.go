@@ -0,0 +1,43 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestCodesignArgs(t *testing.T) {
+	config := builder.NewConfig()
+	config.ProjectDir = "/src"
+
+	testCases := []struct {
+		name  string
+		input CodesignConfig
+		wants []string
+	}{
+		{
+			name:  "ad-hoc",
+			input: CodesignConfig{Identity: "-"},
+			wants: []string{"--force", "--sign", "-", "app"},
+		},
+		{
+			name:  "developer id",
+			input: CodesignConfig{Identity: "Developer ID Application: Acme", Entitlements: "entitlements.plist"},
+			wants: []string{"--force", "--sign", "Developer ID Application: Acme", "--options", "runtime", "--timestamp",
+				"--entitlements", filepath.Join("/src", "entitlements.plist"), "app"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := codesignArgs(config, tc.input, "app")
+
+			if !slices.Equal(res, tc.wants) {
+				t.Logf("Incorrect args, wanted: %v got: %v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
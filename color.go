@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+)
+
+const (
+	colorReset  = "\x1b[0m"
+	colorBold   = "\x1b[1m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+)
+
+// palette colors terminal output. The zero value leaves text as is.
+type palette struct {
+	enabled bool
+}
+
+// newPalette colors output to f when it is a terminal, unless disabled with
+// -no-color or the NO_COLOR convention (https://no-color.org).
+func newPalette(f *os.File, noColor bool) palette {
+	enabled := !noColor &&
+		os.Getenv("NO_COLOR") == "" &&
+		os.Getenv("TERM") != "dumb" &&
+		isTerminal(f)
+
+	return palette{enabled: enabled}
+}
+
+func (p palette) paint(color string, s string) string {
+	if !p.enabled || s == "" {
+		return s
+	}
+
+	return color + s + colorReset
+}
+
+func (p palette) bold(s string) string {
+	return p.paint(colorBold, s)
+}
+
+// status colors a build status such as "ok" or "failed".
+func (p palette) status(status string) string {
+	switch status {
+	case "ok", "completed", "up to date":
+		return p.paint(colorGreen, status)
+	case "failed":
+		return p.paint(colorRed, status)
+	case "cancelled":
+		return p.paint(colorYellow, status)
+	default:
+		return status
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestNewPalette(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	if newPalette(os.Stdout, true).enabled {
+		t.Logf("Colors enabled with -no-color\n")
+		t.Fail()
+	}
+
+	f, err := os.Create(t.TempDir() + "/out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if newPalette(f, false).enabled {
+		t.Logf("Colors enabled for a file\n")
+		t.Fail()
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if newPalette(os.Stdout, false).enabled {
+		t.Logf("Colors enabled with NO_COLOR set\n")
+		t.Fail()
+	}
+}
+
+func TestPaletteStatus(t *testing.T) {
+	colors := palette{enabled: true}
+
+	testCases := []struct {
+		input string
+		wants string
+	}{
+		{input: "ok", wants: "\x1b[32mok\x1b[0m"},
+		{input: "failed", wants: "\x1b[31mfailed\x1b[0m"},
+		{input: "cancelled", wants: "\x1b[33mcancelled\x1b[0m"},
+		{input: "building", wants: "building"},
+	}
+
+	for _, tc := range testCases {
+		if res := colors.status(tc.input); res != tc.wants {
+			t.Logf("Incorrect status, wanted: %q got: %q\n", tc.wants, res)
+			t.Fail()
+		}
+	}
+
+	if res := (palette{}).status("failed"); res != "failed" {
+		t.Logf("Disabled palette colored: %q\n", res)
+		t.Fail()
+	}
+}
+
+func TestColoredSummaryAlignment(t *testing.T) {
+	results := []builder.BuildResult{
+		{Target: "linux/amd64", Size: 5 << 20, Duration: time.Second, Success: true},
+		{Target: "windows/arm64", Error: "exit status 1", Log: "build/logs/windows_arm64.log"},
+	}
+
+	plain := strings.Builder{}
+	writeBuildSummary(&plain, results, time.Second, palette{})
+
+	colored := strings.Builder{}
+	writeBuildSummary(&colored, results, time.Second, palette{enabled: true})
+
+	stripped := regexp.MustCompile("\x1b\\[[0-9]+m").ReplaceAllString(colored.String(), "")
+	if stripped != plain.String() || colored.String() == plain.String() {
+		t.Logf("Colors changed the summary, wanted:\n%s\ngot:\n%s\n", plain.String(), stripped)
+		t.Fail()
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var (
+	ErrInvalidGrowth      = errors.New("invalid size growth limit")
+	ErrSizeGrowthExceeded = errors.New("size growth exceeded")
+)
+
+// loadBaselineSizes reads the binary sizes of a previous run, keyed by file
+// name, from either its output directory or its JSON report.
+func loadBaselineSizes(fp string) (map[string]int64, error) {
+	info, err := os.Stat(fp)
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := map[string]int64{}
+
+	if info.IsDir() {
+		entries, err := os.ReadDir(fp)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if info, err := entry.Info(); err == nil && info.Mode().IsRegular() {
+				sizes[entry.Name()] = info.Size()
+			}
+		}
+
+		return sizes, nil
+	}
+
+	raw, err := os.ReadFile(fp)
+	if err != nil {
+		return nil, err
+	}
+
+	report := buildReport{}
+	if err := json.Unmarshal(raw, &report); err != nil {
+		return nil, fmt.Errorf("%s: %w", fp, err)
+	}
+
+	for _, result := range report.Results {
+		if result.Success && result.Path != "" {
+			sizes[filepath.Base(result.Path)] = result.Size
+		}
+	}
+
+	return sizes, nil
+}
+
+// growthLimit is the most a binary may grow over its baseline, either in
+// percent (e.g. 5%) or as a size (e.g. 100KiB). The zero value allows any
+// growth.
+type growthLimit struct {
+	Percent float64
+	Bytes   int64
+}
+
+func parseGrowthLimit(s string) (growthLimit, error) {
+	if s == "" {
+		return growthLimit{}, nil
+	}
+
+	if percent, ok := strings.CutSuffix(strings.TrimSpace(s), "%"); ok {
+		n, err := strconv.ParseFloat(strings.TrimSpace(percent), 64)
+		if err != nil || n < 0 {
+			return growthLimit{}, fmt.Errorf("%w: %q", ErrInvalidGrowth, s)
+		}
+
+		return growthLimit{Percent: n}, nil
+	}
+
+	n, err := parseSize(s)
+	if err != nil {
+		return growthLimit{}, fmt.Errorf("%w: %q", ErrInvalidGrowth, s)
+	}
+
+	return growthLimit{Bytes: n}, nil
+}
+
+func (l growthLimit) String() string {
+	if l.Bytes > 0 {
+		return formatSize(l.Bytes)
+	}
+
+	return strconv.FormatFloat(l.Percent, 'f', -1, 64) + "%"
+}
+
+// sizeDelta is the size of a binary in the baseline and in this run. Old is
+// -1 for binaries the baseline does not have.
+type sizeDelta struct {
+	Name string
+	Old  int64
+	New  int64
+}
+
+func (d sizeDelta) Percent() float64 {
+	if d.Old <= 0 {
+		return 0
+	}
+
+	return float64(d.New-d.Old) / float64(d.Old) * 100
+}
+
+// check returns an error when d grew by more than the limit.
+func (l growthLimit) check(d sizeDelta) error {
+	if d.Old < 0 || d.New <= d.Old {
+		return nil
+	}
+
+	if (l.Bytes > 0 && d.New-d.Old > l.Bytes) || (l.Percent > 0 && d.Percent() > l.Percent) {
+		return fmt.Errorf("%w: %s grew by %s (%+.1f%%), more than %s",
+			ErrSizeGrowthExceeded, d.Name, formatSize(d.New-d.Old), d.Percent(), l)
+	}
+
+	return nil
+}
+
+// compareSizes pairs the binaries of the successful results with their
+// baseline sizes, in the order of results. Failed results get a zero delta.
+func compareSizes(baseline map[string]int64, results []builder.BuildResult) []sizeDelta {
+	deltas := make([]sizeDelta, len(results))
+
+	for i, result := range results {
+		if !result.Success {
+			continue
+		}
+
+		name := filepath.Base(result.Path)
+		old, ok := baseline[name]
+		if !ok {
+			old = -1
+		}
+
+		deltas[i] = sizeDelta{Name: name, Old: old, New: result.Size}
+	}
+
+	return deltas
+}
+
+// writeSizeComparison prints a table of each binary's size against the
+// baseline.
+func writeSizeComparison(w io.Writer, deltas []sizeDelta) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BINARY\tBEFORE\tAFTER\tDELTA")
+
+	for _, d := range deltas {
+		switch {
+		case d.Name == "":
+		case d.Old < 0:
+			fmt.Fprintf(tw, "%s\t-\t%s\tnew\n", d.Name, formatSize(d.New))
+		case d.New >= d.Old:
+			fmt.Fprintf(tw, "%s\t%s\t%s\t+%s (%+.1f%%)\n", d.Name, formatSize(d.Old), formatSize(d.New), formatSize(d.New-d.Old), d.Percent())
+		default:
+			fmt.Fprintf(tw, "%s\t%s\t%s\t-%s (%+.1f%%)\n", d.Name, formatSize(d.Old), formatSize(d.New), formatSize(d.Old-d.New), d.Percent())
+		}
+	}
+
+	tw.Flush()
+}
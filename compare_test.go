@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestParseGrowthLimit(t *testing.T) {
+	testCases := []struct {
+		input    string
+		wants    growthLimit
+		wantsErr error
+	}{
+		{input: "", wants: growthLimit{}},
+		{input: "5%", wants: growthLimit{Percent: 5}},
+		{input: "2.5 %", wants: growthLimit{Percent: 2.5}},
+		{input: "100KiB", wants: growthLimit{Bytes: 100 << 10}},
+		{input: "lots", wantsErr: ErrInvalidGrowth},
+		{input: "-3%", wantsErr: ErrInvalidGrowth},
+	}
+
+	for _, tc := range testCases {
+		res, err := parseGrowthLimit(tc.input)
+
+		if !errors.Is(err, tc.wantsErr) {
+			t.Logf("Incorrect error for %q, wanted: %v got: %v\n", tc.input, tc.wantsErr, err)
+			t.Fail()
+		}
+
+		if res != tc.wants {
+			t.Logf("Incorrect limit for %q, wanted: %+v got: %+v\n", tc.input, tc.wants, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestGrowthLimitCheck(t *testing.T) {
+	testCases := []struct {
+		name     string
+		limit    growthLimit
+		input    sizeDelta
+		wantsErr error
+	}{
+		{name: "under percent", limit: growthLimit{Percent: 5}, input: sizeDelta{Name: "app", Old: 1000, New: 1040}},
+		{name: "over percent", limit: growthLimit{Percent: 5}, input: sizeDelta{Name: "app", Old: 1000, New: 1060}, wantsErr: ErrSizeGrowthExceeded},
+		{name: "over bytes", limit: growthLimit{Bytes: 10}, input: sizeDelta{Name: "app", Old: 1000, New: 1011}, wantsErr: ErrSizeGrowthExceeded},
+		{name: "shrunk", limit: growthLimit{Percent: 1}, input: sizeDelta{Name: "app", Old: 1000, New: 500}},
+		{name: "new binary", limit: growthLimit{Percent: 1}, input: sizeDelta{Name: "app", Old: -1, New: 500}},
+		{name: "no limit", limit: growthLimit{}, input: sizeDelta{Name: "app", Old: 1000, New: 5000}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.limit.check(tc.input); !errors.Is(err, tc.wantsErr) {
+				t.Logf("Incorrect error, wanted: %v got: %v\n", tc.wantsErr, err)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestLoadBaselineSizes(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "app-linux_amd64"), make([]byte, 300), 0o644)
+	os.Mkdir(filepath.Join(dir, "logs"), 0o755)
+
+	sizes, err := loadBaselineSizes(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sizes) != 1 || sizes["app-linux_amd64"] != 300 {
+		t.Logf("Incorrect sizes from directory: %v\n", sizes)
+		t.Fail()
+	}
+
+	fp := filepath.Join(dir, "report.json")
+	writeJSONReport(fp, newBuildReport("app", []builder.BuildResult{
+		{Target: "linux/amd64", Path: "build/app-linux_amd64", Size: 200, Success: true},
+		{Target: "windows/amd64", Path: "build/app-windows_amd64.exe", Error: "exit status 1"},
+	}))
+
+	sizes, err = loadBaselineSizes(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sizes) != 1 || sizes["app-linux_amd64"] != 200 {
+		t.Logf("Incorrect sizes from report: %v\n", sizes)
+		t.Fail()
+	}
+}
+
+func TestWriteSizeComparison(t *testing.T) {
+	deltas := compareSizes(map[string]int64{"app-linux_amd64": 2 << 20, "app-darwin_arm64": 4 << 20}, []builder.BuildResult{
+		{Path: "build/app-linux_amd64", Size: 3 << 20, Success: true},
+		{Path: "build/app-darwin_arm64", Size: 3 << 20, Success: true},
+		{Path: "build/app-windows_amd64.exe", Size: 1 << 20, Success: true},
+		{Path: "build/app-linux_arm64", Error: "exit status 1"},
+	})
+
+	out := strings.Builder{}
+	writeSizeComparison(&out, deltas)
+
+	wants := "BINARY                 BEFORE   AFTER    DELTA\n" +
+		"app-linux_amd64        2.0 MiB  3.0 MiB  +1.0 MiB (+50.0%)\n" +
+		"app-darwin_arm64       4.0 MiB  3.0 MiB  -1.0 MiB (-25.0%)\n" +
+		"app-windows_amd64.exe  -        1.0 MiB  new\n"
+
+	if out.String() != wants {
+		t.Logf("Incorrect comparison, wanted:\n%s\ngot:\n%s\n", wants, out.String())
+		t.Fail()
+	}
+}
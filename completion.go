@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var ErrUnsupportedShell = errors.New("unsupported shell")
+
+// completionShells are the shells completion scripts are written for.
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+// subcommands are completed in place of the first argument.
+var subcommands = []string{"completion", "doctor", "remote", "self-update", "serve", "version"}
+
+// completionScripts call go-builder __complete with the words of the command
+// line up to the cursor, the word being completed last, and offer the lines
+// it prints.
+var completionScripts = map[string]string{
+	"bash": `_go_builder() {
+	local cur="${COMP_WORDS[COMP_CWORD]}"
+	mapfile -t COMPREPLY < <(go-builder __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null)
+	if [ ${#COMPREPLY[@]} -eq 0 ] && [ "${cur#-}" = "$cur" ]; then
+		mapfile -t COMPREPLY < <(compgen -f -- "$cur")
+	fi
+}
+complete -F _go_builder go-builder
+`,
+	"zsh": `_go_builder() {
+	local -a candidates
+	candidates=("${(@f)$(go-builder __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+	if [[ -n ${candidates[1]} ]]; then
+		compadd -Q -- "${candidates[@]}"
+	else
+		_files
+	fi
+}
+compdef _go_builder go-builder
+`,
+	"fish": `function __go_builder_complete
+	set -l args (commandline -opc) (commandline -ct)
+	go-builder __complete $args[2..-1] 2>/dev/null
+end
+complete -c go-builder -a '(__go_builder_complete)'
+`,
+	"powershell": `Register-ArgumentCompleter -Native -CommandName go-builder -ScriptBlock {
+	param($wordToComplete, $commandAst, $cursorPosition)
+	$words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+	if ($wordToComplete -eq '') { $words += '' }
+	& go-builder __complete @words 2>$null | ForEach-Object {
+		[System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+	}
+}
+`,
+}
+
+// writeCompletion writes the completion script for shell.
+func writeCompletion(w io.Writer, shell string) error {
+	script, ok := completionScripts[shell]
+	if !ok {
+		return fmt.Errorf("%w: %q, expected one of %s", ErrUnsupportedShell, shell, strings.Join(completionShells, ", "))
+	}
+
+	_, err := io.WriteString(w, script)
+	return err
+}
+
+// targetCompletions are the values offered for -target and -exclude: every
+// GOOS, GOOS/GOARCH and */GOARCH of dists, the target groups and keywords.
+func targetCompletions(dists []builder.GoDist) []string {
+	values := map[string]bool{}
+	for _, dist := range dists {
+		values[dist.GOOS] = true
+		values[dist.GOOS+"/"+dist.GOARCH] = true
+		values["*/"+dist.GOARCH] = true
+	}
+
+	for _, keyword := range append(slices.Collect(maps.Keys(builtinAliases)), "all", "first-class") {
+		values[keyword] = true
+	}
+
+	return slices.Sorted(maps.Keys(values))
+}
+
+// archCompletions are the values offered for -arch.
+func archCompletions(dists []builder.GoDist) []string {
+	values := map[string]bool{}
+	for _, dist := range dists {
+		values[dist.GOARCH] = true
+	}
+
+	return slices.Sorted(maps.Keys(values))
+}
+
+// complete returns the completions of the last of args, the words after the
+// program name. Flag values are only completed when given as a separate
+// word. dists is only called when the supported targets are needed.
+func complete(flags *flag.FlagSet, args []string, dists func() []builder.GoDist) []string {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+
+	cur := args[len(args)-1]
+	candidates := []string{}
+
+	prev := ""
+	if len(args) > 1 {
+		prev = strings.TrimLeft(args[len(args)-2], "-")
+	}
+
+	if args[0] == "completion" {
+		if len(args) == 2 {
+			candidates = completionShells
+		}
+	} else if f := flags.Lookup(prev); f != nil && !isBoolFlag(f) && !strings.HasPrefix(cur, "-") {
+		switch prev {
+		case "target", "exclude":
+			candidates = targetCompletions(dists())
+		case "arch":
+			candidates = archCompletions(dists())
+		case "builder":
+			candidates = append(slices.Clone(builder.Builders), "ssh://")
+		case "mod":
+			candidates = builder.ModModes
+		case "log-level":
+			candidates = []string{"debug", "info", "warn", "error"}
+		case "log-format":
+			candidates = []string{"text", "json"}
+		}
+	} else if strings.HasPrefix(cur, "-") {
+		flags.VisitAll(func(f *flag.Flag) {
+			candidates = append(candidates, "-"+f.Name)
+		})
+	} else if len(args) == 1 {
+		candidates = subcommands
+	}
+
+	return slices.DeleteFunc(slices.Clone(candidates), func(c string) bool {
+		return !strings.HasPrefix(c, cur)
+	})
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
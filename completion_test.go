@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"slices"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestComplete(t *testing.T) {
+	flags := flag.NewFlagSet("go-builder", flag.ContinueOnError)
+	flags.String("target", "", "")
+	flags.String("arch", "", "")
+	flags.String("builder", "", "")
+	flags.Bool("race", false, "")
+	flags.String("o", "", "")
+
+	dists := func() []builder.GoDist {
+		return []builder.GoDist{{GOOS: "linux", GOARCH: "amd64"}, {GOOS: "linux", GOARCH: "arm64"}, {GOOS: "darwin", GOARCH: "arm64"}}
+	}
+
+	testCases := []struct {
+		name  string
+		input []string
+		wants []string
+	}{
+		{name: "subcommand", input: []string{"ser"}, wants: []string{"serve"}},
+		{name: "flag", input: []string{"-ta"}, wants: []string{"-target"}},
+		{name: "target", input: []string{"-target", "lin"}, wants: []string{"linux", "linux/amd64", "linux/arm64"}},
+		{name: "target arch", input: []string{"-race", "--target", "*/arm"}, wants: []string{"*/arm64"}},
+		{name: "target group", input: []string{"-target", "desk"}, wants: []string{"desktop"}},
+		{name: "arch", input: []string{"-arch", ""}, wants: []string{"amd64", "arm64"}},
+		{name: "builder", input: []string{"-builder", "s"}, wants: []string{"ssh://"}},
+		{name: "after bool flag", input: []string{"-race", "v"}, wants: []string{}},
+		{name: "unknown value", input: []string{"-o", ""}, wants: []string{}},
+		{name: "shell", input: []string{"completion", "f"}, wants: []string{"fish"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := complete(flags, tc.input, dists)
+
+			if !slices.Equal(res, tc.wants) {
+				t.Logf("Incorrect completions, wanted: %v got: %v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestWriteCompletion(t *testing.T) {
+	for _, shell := range completionShells {
+		buf := bytes.Buffer{}
+		if err := writeCompletion(&buf, shell); err != nil || !bytes.Contains(buf.Bytes(), []byte("go-builder __complete")) {
+			t.Logf("Incorrect %s script: %v\n", shell, err)
+			t.Fail()
+		}
+	}
+
+	if err := writeCompletion(&bytes.Buffer{}, "tcsh"); !errors.Is(err, ErrUnsupportedShell) {
+		t.Logf("Incorrect error, wanted: %v got: %v\n", ErrUnsupportedShell, err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+// DefaultConfigFile is looked up in the project directory when no -config
+// flag is supplied. A missing default file is not an error.
+const DefaultConfigFile = "gobuilder.json"
+
+// ConfigFile holds the settings that can be read from a project's
+// gobuilder.json.
+type ConfigFile struct {
+	// Builds lists the binaries of a multi-binary project. Without it
+	// the project directory is built as a single binary.
+	Builds []BuildEntry `json:"builds"`
+
+	// Aliases maps a group name to the targets it expands to, e.g.
+	// "desktop": ["windows/amd64", "darwin/arm64", "linux/amd64"].
+	// Entries override the built-in aliases of the same name.
+	Aliases map[string][]string `json:"aliases"`
+
+	Mobile MobileConfig `json:"mobile"`
+
+	WindowsResources WindowsResourceConfig `json:"windows_resources"`
+
+	// BuildMode is passed to go build -buildmode for every target unless
+	// overridden in BuildModes, which is keyed by target pattern.
+	BuildMode  string            `json:"buildmode"`
+	BuildModes map[string]string `json:"buildmodes"`
+
+	// PGO is passed to go build -pgo: a profile path relative to the project
+	// directory, "auto" or "off". PGOTargets overrides it per target.
+	PGO        string            `json:"pgo"`
+	PGOTargets map[string]string `json:"pgo_targets"`
+
+	// Compiler selects the backend for every target (see compilers) unless
+	// overridden in Compilers, which is keyed by target pattern.
+	Compiler    string            `json:"compiler"`
+	Compilers   map[string]string `json:"compilers"`
+	GarbleFlags []string          `json:"garble_flags"`
+	GccgoFlags  string            `json:"gccgo_flags"`
+
+	// TinyGoTargets maps a target pattern to the tinygo -target used when
+	// that target is built with tinygo, e.g. "wasip1/wasm": "wasip1".
+	TinyGoTargets map[string]string `json:"tinygo_targets"`
+	// TinyGoBoards are microcontroller targets built with tinygo in
+	// addition to the dist matrix, mapped to their output format (elf,
+	// hex, bin, uf2, ...), e.g. "pico": "uf2".
+	TinyGoBoards map[string]string `json:"tinygo_boards"`
+
+	// Zig sets CC/CXX to zig for cgo cross-compilation. ZigTriples
+	// overrides the built-in target triples, e.g. "linux/amd64":
+	// "x86_64-linux-musl".
+	Zig        bool              `json:"zig"`
+	ZigTriples map[string]string `json:"zig_triples"`
+
+	// Modules sets GOPROXY, GOPRIVATE and the other module download
+	// settings for every build.
+	Modules ModulesConfig `json:"modules"`
+
+	// Mod is the -mod of go build: mod, readonly or vendor. It defaults
+	// to vendor when the project has a vendor directory.
+	Mod string `json:"mod"`
+
+	// Env sets environment variables for the builds of a target pattern,
+	// e.g. "linux/arm64": {"CC": "aarch64-linux-gnu-gcc"}. The most
+	// specific pattern matching a target applies.
+	Env map[string]map[string]string `json:"env"`
+
+	// Builder is local, docker or ssh://user@host[:port][/dir]. DockerImages
+	// overrides DockerImage, the container used for every target, by
+	// target pattern.
+	Builder      string            `json:"builder"`
+	DockerImage  string            `json:"docker_image"`
+	DockerImages map[string]string `json:"docker_images"`
+
+	Hooks HooksConfig `json:"hooks"`
+
+	// SizeBudgets fail targets whose binary is larger than the size, e.g.
+	// "*": "20MiB" or "linux/arm": "8MB", keyed by target pattern.
+	SizeBudgets map[string]string `json:"size_budgets"`
+
+	// TestFlags are passed to go test when -test is set.
+	TestFlags []string   `json:"test_flags"`
+	Lint      LintConfig `json:"lint"`
+
+	// Go is the go command every target is built with, e.g. a locally
+	// built or patched toolchain, instead of the go on PATH.
+	Go string `json:"go"`
+	// GoVersion pins the go release every target is built with, e.g.
+	// 1.22.3. It is downloaded from go.dev and cached.
+	GoVersion string `json:"go_version"`
+	// GoVersions builds the whole matrix once with each go release, the
+	// version appended to the binary names, e.g. app-go1.22.3-linux_amd64.
+	GoVersions []string `json:"go_versions"`
+
+	// TagSets builds the whole matrix once with each set of build tags, the
+	// tags appended to the binary names, e.g. [[], ["pro"]] builds app and
+	// app-pro.
+	TagSets [][]string `json:"tag_sets"`
+
+	// Profiles are named build settings selected with -profile, e.g.
+	// "release": {"trimpath": true, "ldflags": "-s -w", "upx": true}. They
+	// replace the built-in debug and release profiles of the same name.
+	Profiles map[string]Profile `json:"profiles"`
+
+	// Variants builds the whole matrix once per edition, e.g. oss and
+	// enterprise, the variant name appended to the binary names.
+	Variants []Variant `json:"variants"`
+
+	// GoCache is the GOCACHE for every target, relative to the working
+	// directory. With GoCachePerTarget each target gets a subdirectory.
+	GoCache          string `json:"gocache"`
+	GoCachePerTarget bool   `json:"gocache_per_target"`
+
+	RemoteCache RemoteCacheConfig `json:"remote_cache"`
+
+	Image ImageConfig `json:"image"`
+	NFPM  NFPMConfig  `json:"nfpm"`
+	Snap  SnapConfig  `json:"snap"`
+
+	AppImage     AppImageConfig     `json:"appimage"`
+	MSI          MSIConfig          `json:"msi"`
+	MacPkg       MacPkgConfig       `json:"macos_pkg"`
+	Codesign     CodesignConfig     `json:"codesign"`
+	Authenticode AuthenticodeConfig `json:"authenticode"`
+	OCI          OCIConfig          `json:"oci"`
+
+	// Publish selects the release backend: github, gitlab or gitea.
+	Publish string       `json:"publish"`
+	GitHub  GitHubConfig `json:"github"`
+	GitLab  GitLabConfig `json:"gitlab"`
+	Gitea   GiteaConfig  `json:"gitea"`
+
+	Upload UploadConfig `json:"upload"`
+
+	// Notify lists the webhooks told how the run went when it finishes.
+	Notify []NotifyConfig `json:"notify"`
+
+	// ReleaseURL is where published files are downloaded from, e.g.
+	// https://example.com/dl/{tag}/{file}. Defaults to the -publish
+	// backend's download URL.
+	ReleaseURL string           `json:"release_url"`
+	Archive    ArchiveConfig    `json:"archive"`
+	Homebrew   HomebrewConfig   `json:"homebrew"`
+	Scoop      ScoopConfig      `json:"scoop"`
+	Chocolatey ChocolateyConfig `json:"chocolatey"`
+	Winget     WingetConfig     `json:"winget"`
+	AUR        AURConfig        `json:"aur"`
+	Nix        NixConfig        `json:"nix"`
+
+	// InstallScripts writes install.sh and install.ps1, which download and
+	// verify the binary for the machine they run on, and install-<name>.sh
+	// and .ps1 for other binaries such as variants.
+	InstallScripts bool `json:"install_scripts"`
+}
+
+func NewConfigFile() ConfigFile {
+	return ConfigFile{
+		Aliases:    map[string][]string{},
+		BuildModes: map[string]string{},
+		PGOTargets: map[string]string{},
+		Compilers:  map[string]string{},
+
+		TinyGoTargets: map[string]string{},
+		TinyGoBoards:  map[string]string{},
+		ZigTriples:    map[string]string{},
+		DockerImages:  map[string]string{},
+		Env:           map[string]map[string]string{},
+		Profiles:      map[string]Profile{},
+	}
+}
+
+// apply copies the build settings from the config file into config. Command
+// line flags are applied afterwards and take precedence.
+func (f ConfigFile) apply(config *builder.BuildConfig) {
+	config.BuildMode = f.BuildMode
+	config.BuildModes = f.BuildModes
+	config.PGO = f.PGO
+	config.PGOTargets = f.PGOTargets
+	config.Compiler = f.Compiler
+	config.Compilers = f.Compilers
+	config.GarbleFlags = f.GarbleFlags
+	config.GccgoFlags = f.GccgoFlags
+	config.TinyGoTargets = f.TinyGoTargets
+	config.Zig = f.Zig
+	config.ZigTriples = f.ZigTriples
+	config.Env = f.Env
+	config.ModMode = f.Mod
+	config.ExtraEnv = f.Modules.Env()
+	config.DockerImage = f.DockerImage
+	config.DockerImages = f.DockerImages
+	config.GoCache = f.GoCache
+	config.GoCachePerTarget = f.GoCachePerTarget
+
+	if f.Builder != "" {
+		config.Builder = f.Builder
+	}
+}
+
+// loadConfigFile reads the config at fp. When required is false a missing
+// file yields an empty config instead of an error.
+func loadConfigFile(fp string, required bool) (ConfigFile, error) {
+	config := NewConfigFile()
+
+	raw, err := os.ReadFile(fp)
+
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return config, nil
+	} else if err != nil {
+		return config, err
+	}
+
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return config, fmt.Errorf("%s: %w", filepath.Base(fp), err)
+	}
+
+	return config, nil
+}
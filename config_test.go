@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+
+	validFp := filepath.Join(dir, "valid.json")
+	os.WriteFile(validFp, []byte(`{"aliases": {"desktop": ["linux/amd64"]}}`), 0o644)
+
+	invalidFp := filepath.Join(dir, "invalid.json")
+	os.WriteFile(invalidFp, []byte(`{"aliases": [}`), 0o644)
+
+	missingFp := filepath.Join(dir, "missing.json")
+
+	testCases := []struct {
+		name     string
+		input    string
+		required bool
+		aliases  []string
+		wantsErr bool
+	}{
+		{
+			name:     "valid",
+			input:    validFp,
+			required: true,
+			aliases:  []string{"linux/amd64"},
+		},
+		{
+			name:     "invalid json",
+			input:    invalidFp,
+			required: true,
+			wantsErr: true,
+		},
+		{
+			name:     "missing optional",
+			input:    missingFp,
+			required: false,
+		},
+		{
+			name:     "missing required",
+			input:    missingFp,
+			required: true,
+			wantsErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := loadConfigFile(tc.input, tc.required)
+
+			if (err != nil) != tc.wantsErr {
+				t.Logf("Incorrect error returned, wanted error: %v got: %v\n", tc.wantsErr, err)
+				t.Fail()
+			}
+
+			if !slices.Equal(res.Aliases["desktop"], tc.aliases) {
+				t.Logf("Incorrect aliases loaded, wanted: %v got: %v\n", tc.aliases, res.Aliases["desktop"])
+				t.Fail()
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"go/version"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var ErrDoctorFailed = errors.New("some checks failed")
+
+// doctorCheck is the outcome of one check of the environment. Checks that
+// are not ok say how to fix them.
+type doctorCheck struct {
+	Name   string
+	Status string // ok, warn or fail
+	Detail string
+	Fix    string
+}
+
+// doctor checks the environment with lookPath and output, so tests can
+// stand in for the tools.
+type doctor struct {
+	lookPath func(file string) (string, error)
+	output   func(ctx context.Context, name string, args ...string) (string, error)
+}
+
+func newDoctor() doctor {
+	return doctor{
+		lookPath: exec.LookPath,
+		output: func(ctx context.Context, name string, args ...string) (string, error) {
+			res, err := exec.CommandContext(ctx, name, args...).Output()
+			return strings.TrimSpace(string(res)), err
+		},
+	}
+}
+
+// checkGo finds the go command and the version it reports.
+func (d doctor) checkGo(ctx context.Context) (doctorCheck, string) {
+	fp, err := d.lookPath("go")
+	if err != nil {
+		return doctorCheck{Name: "go", Status: "fail", Detail: "go is not on PATH", Fix: "Install Go from https://go.dev/dl and add its bin directory to PATH."}, ""
+	}
+
+	goVersion, err := d.output(ctx, "go", "env", "GOVERSION")
+	if err != nil {
+		return doctorCheck{Name: "go", Status: "fail", Detail: fmt.Sprintf("%s does not run: %v", fp, err), Fix: "Reinstall Go from https://go.dev/dl."}, ""
+	}
+
+	return doctorCheck{Name: "go", Status: "ok", Detail: fmt.Sprintf("%s (%s)", fp, goVersion)}, goVersion
+}
+
+// checkGoVersion compares the installed go with the one go.mod requires.
+func checkGoVersion(goVersion string, required string) doctorCheck {
+	check := doctorCheck{Name: "go version", Status: "ok", Detail: goVersion}
+
+	if required == "" || !version.IsValid(goVersion) {
+		return check
+	}
+
+	check.Detail += ", go.mod requires go" + required
+	if version.Compare(goVersion, "go"+required) < 0 {
+		check.Status = "warn"
+		check.Fix = fmt.Sprintf("Install go%s or later, or leave GOTOOLCHAIN at auto so go downloads it.", required)
+	}
+
+	return check
+}
+
+// checkWritable checks that the directory in the go env variable name can be
+// written, creating it if needed, as go does.
+func checkWritable(name string, dir string) doctorCheck {
+	check := doctorCheck{Name: name, Status: "ok", Detail: dir}
+
+	if dir == "" || dir == "off" {
+		return check
+	}
+
+	err := os.MkdirAll(dir, 0o755)
+	if err == nil {
+		var f *os.File
+		if f, err = os.CreateTemp(dir, ".go-builder-doctor-*"); err == nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}
+
+	if err != nil {
+		check.Status = "fail"
+		check.Detail = fmt.Sprintf("%s is not writable: %v", dir, errors.Unwrap(err))
+		check.Fix = fmt.Sprintf("Fix the permissions of %s, or set %s to a writable directory with go env -w %s=<dir>.", dir, name, name)
+	}
+
+	return check
+}
+
+// checkCgo finds a C compiler able to build dist with cgo.
+func (d doctor) checkCgo(config builder.BuildConfig, dist builder.GoDist) doctorCheck {
+	check := doctorCheck{Name: "cgo " + dist.GOOS + "/" + dist.GOARCH, Status: "ok"}
+
+	if dist.GOOS == runtime.GOOS && dist.GOARCH == runtime.GOARCH {
+		cc := strings.Fields(os.Getenv("CC") + " cc")[0]
+		if fp, err := d.lookPath(cc); err == nil {
+			check.Detail = fp
+			return check
+		}
+
+		check.Status = "warn"
+		check.Detail = "no C compiler, cgo builds will fail"
+		check.Fix = "Install a C compiler (gcc or clang) or set CC."
+		return check
+	}
+
+	if _, ok := config.ZigTripleFor(dist); ok {
+		if fp, err := d.lookPath("zig"); err == nil {
+			check.Detail = fp + " cc"
+			if !config.Zig {
+				check.Detail += ", with -zig"
+			}
+			return check
+		}
+	}
+
+	check.Status = "warn"
+	check.Detail = "no C cross compiler, cgo builds will fail"
+	check.Fix = "Install zig and build with -zig, or build with -builder docker."
+	return check
+}
+
+// checkDocker checks that the docker daemon answers. It is only a failure
+// when builds run in docker.
+func (d doctor) checkDocker(ctx context.Context, required bool) doctorCheck {
+	check := doctorCheck{Name: "docker", Status: "ok"}
+
+	missing := "warn"
+	if required {
+		missing = "fail"
+	}
+
+	if _, err := d.lookPath("docker"); err != nil {
+		check.Status, check.Detail = missing, "docker is not on PATH"
+		check.Fix = "Install Docker to build with -builder docker or push images."
+		return check
+	}
+
+	serverVersion, err := d.output(ctx, "docker", "info", "--format", "{{.ServerVersion}}")
+	if err != nil {
+		check.Status, check.Detail = missing, "the docker daemon is not reachable"
+		check.Fix = "Start the Docker daemon and check that your user may use it."
+		return check
+	}
+
+	check.Detail = "server " + serverVersion
+	return check
+}
+
+// signingTools are the tools the signing and packaging config needs.
+func signingTools(configFile ConfigFile) []string {
+	tools := []string{}
+
+	if !configFile.Codesign.IsEmpty() {
+		tools = append(tools, "codesign")
+	}
+
+	if !configFile.Authenticode.IsEmpty() {
+		tool := configFile.Authenticode.Tool
+		if tool == "" {
+			tool = "osslsigncode"
+		}
+		tools = append(tools, tool)
+	}
+
+	if !configFile.MacPkg.IsEmpty() {
+		tools = append(tools, "pkgbuild")
+		if !configFile.MacPkg.Notarize.IsEmpty() {
+			tools = append(tools, "xcrun")
+		}
+	}
+
+	return tools
+}
+
+// checkTool checks that a tool the config needs is installed.
+func (d doctor) checkTool(tool string) doctorCheck {
+	if fp, err := d.lookPath(tool); err == nil {
+		return doctorCheck{Name: tool, Status: "ok", Detail: fp}
+	}
+
+	fix := fmt.Sprintf("Install %s, which the config needs.", tool)
+	switch tool {
+	case "codesign", "pkgbuild", "xcrun":
+		fix = fmt.Sprintf("%s ships with the Xcode command line tools on macOS: run xcode-select --install.", tool)
+	case "osslsigncode":
+		fix = "Install osslsigncode, e.g. with apt install osslsigncode or brew install osslsigncode."
+	case "signtool":
+		fix = "signtool ships with the Windows SDK."
+	}
+
+	return doctorCheck{Name: tool, Status: "fail", Detail: tool + " is not on PATH", Fix: fix}
+}
+
+// writeDoctorChecks prints the checks and returns ErrDoctorFailed when any
+// failed.
+func writeDoctorChecks(w io.Writer, checks []doctorCheck) error {
+	failed := 0
+
+	for _, check := range checks {
+		fmt.Fprintf(w, "%-5s %s: %s\n", strings.ToUpper(check.Status), check.Name, check.Detail)
+		if check.Fix != "" {
+			fmt.Fprintf(w, "      fix: %s\n", check.Fix)
+		}
+
+		if check.Status == "fail" {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d", ErrDoctorFailed, failed, len(checks))
+	}
+
+	return nil
+}
+
+// runDoctor runs the doctor subcommand, which checks that the environment
+// can build the project and says how to fix what is missing.
+func runDoctor(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	configPath := flags.String("config", "", "Specify the config file to read. Defaults to "+DefaultConfigFile+" in the project directory if present.")
+	targets := []string{}
+	flags.Func("target", "Check cgo toolchains for the target, e.g. windows/amd64. Repeat it for more targets. Defaults to the host.", func(v string) error {
+		targets = append(targets, v)
+		return nil
+	})
+	useZig := flags.Bool("zig", false, "Check with cgo cross-compilation through zig, as -zig builds do.")
+	buildRunner := flags.String("builder", "", "Specify where builds run: local, docker or ssh://user@host.")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: go-builder doctor [flags] [project]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	projectDir := "."
+	if flags.NArg() > 0 {
+		projectDir = flags.Arg(0)
+	}
+
+	required := *configPath != ""
+	if !required {
+		*configPath = filepath.Join(projectDir, DefaultConfigFile)
+	}
+
+	configFile, err := loadConfigFile(*configPath, required)
+	if err != nil {
+		return err
+	}
+
+	config := builder.NewConfig()
+	config.ProjectDir = projectDir
+	configFile.apply(&config)
+	config.Zig = config.Zig || *useZig
+	if *buildRunner != "" {
+		config.Builder = *buildRunner
+	}
+
+	d := newDoctor()
+	checks := []doctorCheck{}
+
+	goCheck, goVersion := d.checkGo(ctx)
+	checks = append(checks, goCheck)
+
+	if goVersion != "" {
+		directives, _ := readGoModDirectives(projectDir)
+		checks = append(checks, checkGoVersion(goVersion, strings.TrimPrefix(directives.Go, "go")))
+
+		for _, name := range []string{"GOPATH", "GOMODCACHE", "GOCACHE"} {
+			dir, err := d.output(ctx, "go", "env", name)
+			if err == nil {
+				checks = append(checks, checkWritable(name, dir))
+			}
+		}
+
+		dists := []builder.GoDist{{GOOS: runtime.GOOS, GOARCH: runtime.GOARCH}}
+		if len(targets) > 0 {
+			config.Targets = []builder.OSARCH{}
+			for _, v := range expandTargetAliases(targets, configFile.Aliases) {
+				target, err := builder.ParseTarget(v)
+				if err != nil {
+					return fmt.Errorf("%q: %w", v, err)
+				}
+				config.Targets = append(config.Targets, target)
+			}
+
+			if dists, err = builder.SelectDists(ctx, config); err != nil {
+				return err
+			}
+		}
+
+		// sub-architectures share the C toolchain of their GOOS/GOARCH
+		seen := map[string]bool{}
+		for _, dist := range dists {
+			if !seen[dist.GOOS+"/"+dist.GOARCH] {
+				seen[dist.GOOS+"/"+dist.GOARCH] = true
+				checks = append(checks, d.checkCgo(config, dist))
+			}
+		}
+	}
+
+	checks = append(checks, d.checkDocker(ctx, config.Builder == "docker"))
+
+	for _, tool := range signingTools(configFile) {
+		checks = append(checks, d.checkTool(tool))
+	}
+
+	return writeDoctorChecks(os.Stdout, checks)
+}
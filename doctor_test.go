@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+// fakeDoctor finds only the tools in paths and fails to run every command
+// except docker when dockerUp is set.
+func fakeDoctor(dockerUp bool, paths ...string) doctor {
+	return doctor{
+		lookPath: func(file string) (string, error) {
+			if slices.Contains(paths, file) {
+				return "/usr/bin/" + file, nil
+			}
+			return "", errors.New("not found")
+		},
+		output: func(ctx context.Context, name string, args ...string) (string, error) {
+			if name == "docker" && dockerUp {
+				return "27.0.1", nil
+			}
+			return "", errors.New("failed")
+		},
+	}
+}
+
+func TestCheckGoVersion(t *testing.T) {
+	testCases := []struct {
+		name     string
+		version  string
+		required string
+		wants    string
+	}{
+		{name: "newer", version: "go1.24.6", required: "1.21", wants: "ok"},
+		{name: "same", version: "go1.22.0", required: "1.22.0", wants: "ok"},
+		{name: "older", version: "go1.21.0", required: "1.24", wants: "warn"},
+		{name: "no go.mod", version: "go1.21.0", wants: "ok"},
+		{name: "devel", version: "devel go1.25-abc", required: "1.24", wants: "ok"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if res := checkGoVersion(tc.version, tc.required); res.Status != tc.wants {
+				t.Logf("Incorrect status, wanted: %s got: %+v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestCheckWritable(t *testing.T) {
+	dir := t.TempDir()
+
+	if res := checkWritable("GOMODCACHE", filepath.Join(dir, "new")); res.Status != "ok" {
+		t.Logf("Incorrect status for a new directory: %+v\n", res)
+		t.Fail()
+	}
+
+	file := filepath.Join(dir, "file")
+	os.WriteFile(file, nil, 0o644)
+
+	if res := checkWritable("GOMODCACHE", filepath.Join(file, "cache")); res.Status != "fail" || res.Fix == "" {
+		t.Logf("Incorrect status for a directory below a file: %+v\n", res)
+		t.Fail()
+	}
+}
+
+func TestCheckCgo(t *testing.T) {
+	config := builder.NewConfig()
+	host := builder.GoDist{GOOS: runtime.GOOS, GOARCH: runtime.GOARCH}
+	cross := builder.GoDist{GOOS: "windows", GOARCH: "amd64"}
+	if host == cross {
+		cross = builder.GoDist{GOOS: "linux", GOARCH: "arm64"}
+	}
+
+	testCases := []struct {
+		name  string
+		d     doctor
+		dist  builder.GoDist
+		wants string
+	}{
+		{name: "host cc", d: fakeDoctor(false, "cc"), dist: host, wants: "ok"},
+		{name: "host without cc", d: fakeDoctor(false), dist: host, wants: "warn"},
+		{name: "cross with zig", d: fakeDoctor(false, "zig"), dist: cross, wants: "ok"},
+		{name: "cross without zig", d: fakeDoctor(false, "cc"), dist: cross, wants: "warn"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("CC", "")
+
+			if res := tc.d.checkCgo(config, tc.dist); res.Status != tc.wants {
+				t.Logf("Incorrect status, wanted: %s got: %+v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestCheckDocker(t *testing.T) {
+	testCases := []struct {
+		name     string
+		d        doctor
+		required bool
+		wants    string
+	}{
+		{name: "running", d: fakeDoctor(true, "docker"), wants: "ok"},
+		{name: "daemon down", d: fakeDoctor(false, "docker"), wants: "warn"},
+		{name: "missing", d: fakeDoctor(false), wants: "warn"},
+		{name: "missing for docker builds", d: fakeDoctor(false), required: true, wants: "fail"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if res := tc.d.checkDocker(context.Background(), tc.required); res.Status != tc.wants {
+				t.Logf("Incorrect status, wanted: %s got: %+v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestSigningTools(t *testing.T) {
+	configFile := NewConfigFile()
+	configFile.Codesign.Identity = "-"
+	configFile.Authenticode.Certificate = "cert.pem"
+	configFile.MacPkg.Identifier = "com.example.app"
+	configFile.MacPkg.Notarize.KeychainProfile = "notary"
+
+	wants := []string{"codesign", "osslsigncode", "pkgbuild", "xcrun"}
+	if res := signingTools(configFile); !slices.Equal(res, wants) {
+		t.Logf("Incorrect tools, wanted: %v got: %v\n", wants, res)
+		t.Fail()
+	}
+}
+
+func TestWriteDoctorChecks(t *testing.T) {
+	buf := bytes.Buffer{}
+	err := writeDoctorChecks(&buf, []doctorCheck{
+		{Name: "go", Status: "ok", Detail: "/usr/bin/go (go1.24.6)"},
+		{Name: "codesign", Status: "fail", Detail: "codesign is not on PATH", Fix: "Install it."},
+	})
+
+	if !errors.Is(err, ErrDoctorFailed) {
+		t.Logf("Incorrect error, wanted: %v got: %v\n", ErrDoctorFailed, err)
+		t.Fail()
+	}
+
+	wants := "OK    go: /usr/bin/go (go1.24.6)\nFAIL  codesign: codesign is not on PATH\n      fix: Install it.\n"
+	if buf.String() != wants {
+		t.Logf("Incorrect output, wanted: %q got: %q\n", wants, buf.String())
+		t.Fail()
+	}
+}
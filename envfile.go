@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidEnvFile = errors.New("invalid env file line")
+
+// DefaultEnvFile is read from the project directory when no -env-file flag
+// is supplied. A missing default file is not an error.
+const DefaultEnvFile = ".env"
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// parseEnvFile reads NAME=value lines, skipping blank lines and # comments.
+// Lines may start with export, and values may be single quoted, taken as
+// is, or double quoted, with Go escapes such as \n.
+func parseEnvFile(r io.Reader) ([]string, error) {
+	env := []string{}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || !envNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("%w %d: %q", ErrInvalidEnvFile, n, line)
+		}
+
+		switch {
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w %d: %q", ErrInvalidEnvFile, n, line)
+			}
+			value = unquoted
+		default:
+			// unquoted values may end in a comment
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+
+		env = append(env, name+"="+value)
+	}
+
+	return env, scanner.Err()
+}
+
+// loadEnvFile reads the env file at fp. When required is false a missing
+// file yields no variables instead of an error.
+func loadEnvFile(fp string, required bool) ([]string, error) {
+	f, err := os.Open(fp)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseEnvFile(f)
+}
+
+// setEnv sets the variables of env that are not already set, so the
+// environment a run is started with wins over the env file, and returns the
+// ones it set.
+func setEnv(env []string) []string {
+	set := []string{}
+
+	for _, v := range env {
+		name, value, _ := strings.Cut(v, "=")
+		if _, ok := os.LookupEnv(name); ok {
+			continue
+		}
+
+		os.Setenv(name, value)
+		set = append(set, v)
+	}
+
+	return set
+}
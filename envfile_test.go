@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		wants []string
+		err   error
+	}{
+		{
+			name:  "plain",
+			input: "# proxy\nGOPROXY=https://proxy.example.com\n\nGOPRIVATE = example.com/*\n",
+			wants: []string{"GOPROXY=https://proxy.example.com", "GOPRIVATE=example.com/*"},
+		},
+		{
+			name:  "export",
+			input: "export TOKEN=abc # read only\n",
+			wants: []string{"TOKEN=abc"},
+		},
+		{
+			name:  "quoted",
+			input: "A='x # y'\nB=\"line\\nbreak\"\nC=\n",
+			wants: []string{"A=x # y", "B=line\nbreak", "C="},
+		},
+		{
+			name:  "no value",
+			input: "TOKEN\n",
+			err:   ErrInvalidEnvFile,
+		},
+		{
+			name:  "invalid name",
+			input: "MY-TOKEN=abc\n",
+			err:   ErrInvalidEnvFile,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := parseEnvFile(strings.NewReader(tc.input))
+
+			if !errors.Is(err, tc.err) {
+				t.Logf("Incorrect error, wanted: %v got: %v\n", tc.err, err)
+				t.Fail()
+			}
+
+			if err == nil && !slices.Equal(res, tc.wants) {
+				t.Logf("Incorrect env, wanted: %q got: %q\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), DefaultEnvFile)
+
+	if res, err := loadEnvFile(missing, false); err != nil || len(res) != 0 {
+		t.Logf("Missing optional env file, wanted no variables got: %v %v\n", res, err)
+		t.Fail()
+	}
+
+	if _, err := loadEnvFile(missing, true); !errors.Is(err, os.ErrNotExist) {
+		t.Logf("Incorrect error for missing required env file: %v\n", err)
+		t.Fail()
+	}
+}
+
+func TestSetEnv(t *testing.T) {
+	t.Setenv("GO_BUILDER_TEST_SET", "shell")
+	t.Setenv("GO_BUILDER_TEST_UNSET", "")
+	os.Unsetenv("GO_BUILDER_TEST_UNSET")
+
+	res := setEnv([]string{"GO_BUILDER_TEST_SET=file", "GO_BUILDER_TEST_UNSET=file"})
+
+	if !slices.Equal(res, []string{"GO_BUILDER_TEST_UNSET=file"}) {
+		t.Logf("Incorrect variables set: %v\n", res)
+		t.Fail()
+	}
+
+	if v := os.Getenv("GO_BUILDER_TEST_SET"); v != "shell" {
+		t.Logf("Variable of the environment was overwritten: %s\n", v)
+		t.Fail()
+	}
+
+	if v := os.Getenv("GO_BUILDER_TEST_UNSET"); v != "file" {
+		t.Logf("Variable was not set: %s\n", v)
+		t.Fail()
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+// githubActions reports whether the run is a GitHub Actions job, which gets
+// workflow commands, a job summary and step outputs.
+func githubActions() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+var (
+	workflowDataEscaper     = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	workflowPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+func resultName(result builder.BuildResult) string {
+	if result.Race {
+		return result.Target + " (race)"
+	}
+
+	return result.Target
+}
+
+// writeWorkflowCommands folds the compiler output of each target into a log
+// group and annotates every failed target with an error.
+func writeWorkflowCommands(w io.Writer, results []builder.BuildResult) {
+	for _, result := range results {
+		if result.Stderr != "" {
+			fmt.Fprintf(w, "::group::build %s\n%s", resultName(result), result.Stderr)
+			if !strings.HasSuffix(result.Stderr, "\n") {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintln(w, "::endgroup::")
+		}
+
+		if !result.Success && !result.Cancelled {
+			fmt.Fprintf(w, "::error title=%s::%s\n",
+				workflowPropertyEscaper.Replace("build "+resultName(result)),
+				workflowDataEscaper.Replace(result.Error))
+		}
+	}
+}
+
+// writeJobSummary writes a markdown table of the targets for the job summary.
+func writeJobSummary(w io.Writer, report buildReport) {
+	fmt.Fprintf(w, "### %s builds\n\n", report.Project)
+	fmt.Fprintln(w, "| Target | Status | Size | Time |")
+	fmt.Fprintln(w, "| --- | --- | --- | --- |")
+
+	for _, result := range report.Results {
+		status := ":white_check_mark: built"
+		size := formatSize(result.Size)
+		switch {
+		case result.Cancelled:
+			status, size = ":no_entry_sign: cancelled", ""
+		case !result.Success:
+			status, size = ":x: failed", ""
+		case result.UpToDate:
+			status = ":white_check_mark: up to date"
+		}
+
+		fmt.Fprintf(w, "| `%s` | %s | %s | %s |\n", resultName(result), status, size, result.Duration.Round(100*time.Millisecond))
+	}
+
+	fmt.Fprintln(w)
+}
+
+// writeStepOutputs writes outputs in the GITHUB_OUTPUT format. Every value
+// uses the multiline form with a random delimiter so it cannot end early.
+func writeStepOutputs(w io.Writer, outputs map[string]string) error {
+	raw := make([]byte, 8)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	delimiter := "ghadelimiter_" + hex.EncodeToString(raw)
+
+	for _, name := range slices.Sorted(maps.Keys(outputs)) {
+		if _, err := fmt.Fprintf(w, "%s<<%s\n%s\n%s\n", name, delimiter, outputs[name], delimiter); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// appendGitHubFile appends to the file named by envVar, such as
+// GITHUB_STEP_SUMMARY. Nothing is written when the variable is unset.
+func appendGitHubFile(envVar string, write func(io.Writer) error) error {
+	fp := os.Getenv(envVar)
+	if fp == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(fp, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestWriteWorkflowCommands(t *testing.T) {
+	out := strings.Builder{}
+	writeWorkflowCommands(&out, []builder.BuildResult{
+		{Target: "linux/amd64", Success: true},
+		{Target: "windows/arm64", Stderr: "# app\n./main.go:3: undefined: foo\n", Error: "exit status 1"},
+		{Target: "darwin/arm64", Error: "signal: interrupt", Cancelled: true},
+	})
+
+	wants := "::group::build windows/arm64\n# app\n./main.go:3: undefined: foo\n::endgroup::\n" +
+		"::error title=build windows/arm64::exit status 1\n"
+
+	if out.String() != wants {
+		t.Logf("Incorrect commands, wanted:\n%s\ngot:\n%s\n", wants, out.String())
+		t.Fail()
+	}
+}
+
+func TestWriteJobSummary(t *testing.T) {
+	out := strings.Builder{}
+	writeJobSummary(&out, newBuildReport("app", []builder.BuildResult{
+		{Target: "linux/amd64", Size: 5 << 20, Duration: 1240 * time.Millisecond, Success: true},
+		{Target: "linux/amd64", Race: true, Size: 2048, Success: true, UpToDate: true},
+		{Target: "windows/arm64", Duration: 300 * time.Millisecond, Error: "exit status 1"},
+	}))
+
+	wants := "### app builds\n\n" +
+		"| Target | Status | Size | Time |\n" +
+		"| --- | --- | --- | --- |\n" +
+		"| `linux/amd64` | :white_check_mark: built | 5.0 MiB | 1.2s |\n" +
+		"| `linux/amd64 (race)` | :white_check_mark: up to date | 2.0 KiB | 0s |\n" +
+		"| `windows/arm64` | :x: failed |  | 300ms |\n\n"
+
+	if out.String() != wants {
+		t.Logf("Incorrect summary, wanted:\n%s\ngot:\n%s\n", wants, out.String())
+		t.Fail()
+	}
+}
+
+func TestWriteStepOutputs(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "output")
+	t.Setenv("GITHUB_OUTPUT", fp)
+
+	err := appendGitHubFile("GITHUB_OUTPUT", func(w io.Writer) error {
+		return writeStepOutputs(w, map[string]string{
+			"version":   "v1.2.0",
+			"artifacts": "build/app-linux_amd64\nbuild/app-windows_amd64.exe",
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, _ := os.ReadFile(fp)
+	lines := strings.Split(strings.TrimSuffix(string(raw), "\n"), "\n")
+
+	if len(lines) != 7 || !strings.HasPrefix(lines[0], "artifacts<<ghadelimiter_") || lines[4] != "version<<"+strings.TrimPrefix(lines[0], "artifacts<<") || lines[5] != "v1.2.0" {
+		t.Logf("Incorrect outputs:\n%s\n", raw)
+		t.Fail()
+	}
+
+	t.Setenv("GITHUB_OUTPUT", "")
+	if err := appendGitHubFile("GITHUB_OUTPUT", func(w io.Writer) error { return nil }); err != nil {
+		t.Logf("Unset file returned: %v\n", err)
+		t.Fail()
+	}
+}
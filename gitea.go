@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrMissingGiteaURL = errors.New("gitea publishing requires the instance url in the config")
+
+// GiteaConfig selects the Gitea or Forgejo instance and repository releases
+// are published to. The token is read from GITEA_TOKEN or FORGEJO_TOKEN.
+type GiteaConfig struct {
+	URL  string `json:"url"`
+	Repo string `json:"repo"`
+}
+
+type giteaRelease struct {
+	ID      int64  `json:"id"`
+	HTMLURL string `json:"html_url"`
+	Assets  []struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	} `json:"assets"`
+}
+
+type giteaPublisher struct {
+	config GiteaConfig
+}
+
+func (p giteaPublisher) DownloadURL(tag string, file string) string {
+	return fmt.Sprintf("%s/%s/releases/download/%s/%s", strings.TrimSuffix(p.config.URL, "/"), p.config.Repo, url.PathEscape(tag), url.PathEscape(file))
+}
+
+// Publish creates or updates the release for the tag and uploads the files
+// as attachments, replacing any of the same name.
+func (p giteaPublisher) Publish(ctx context.Context, release Release, files []string) (string, error) {
+	if p.config.URL == "" {
+		return "", ErrMissingGiteaURL
+	}
+
+	token, err := tokenFromEnv("GITEA_TOKEN", "FORGEJO_TOKEN")
+	if err != nil {
+		return "", fmt.Errorf("gitea: %w", err)
+	}
+
+	client := apiClient{headers: map[string]string{
+		"Authorization": "token " + token,
+		"Accept":        "application/json",
+	}}
+
+	reposURL := fmt.Sprintf("%s/api/v1/repos/%s/releases", strings.TrimSuffix(p.config.URL, "/"), p.config.Repo)
+	settings := map[string]any{
+		"tag_name":   release.Tag,
+		"name":       release.Name,
+		"draft":      release.Draft,
+		"prerelease": release.Prerelease,
+	}
+
+	var rel giteaRelease
+	status, err := client.do(ctx, http.MethodGet, reposURL+"/tags/"+url.PathEscape(release.Tag), "", nil, &rel)
+
+	if status == http.StatusNotFound {
+		_, err = client.do(ctx, http.MethodPost, reposURL, "application/json", jsonBody(settings), &rel)
+	} else if err == nil {
+		_, err = client.do(ctx, http.MethodPatch, fmt.Sprintf("%s/%d", reposURL, rel.ID), "application/json", jsonBody(settings), &rel)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("gitea release: %w", err)
+	}
+
+	assetsURL := fmt.Sprintf("%s/%d/assets", reposURL, rel.ID)
+
+	for _, fp := range files {
+		name := filepath.Base(fp)
+
+		for _, asset := range rel.Assets {
+			if asset.Name == name {
+				if _, err := client.do(ctx, http.MethodDelete, fmt.Sprintf("%s/%d", assetsURL, asset.ID), "", nil, nil); err != nil {
+					return "", fmt.Errorf("gitea asset: %w", err)
+				}
+			}
+		}
+
+		body, contentType, err := multipartFile("attachment", fp)
+		if err != nil {
+			return "", err
+		}
+
+		if _, err := client.do(ctx, http.MethodPost, assetsURL+"?name="+url.QueryEscape(name), contentType, body, nil); err != nil {
+			return "", fmt.Errorf("gitea upload: %w", err)
+		}
+	}
+
+	return rel.HTMLURL, nil
+}
+
+// multipartFile encodes the file at fp as a single form field.
+func multipartFile(field string, fp string) (io.Reader, string, error) {
+	f, err := os.Open(fp)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	body := bytes.Buffer{}
+	writer := multipart.NewWriter(&body)
+
+	part, err := writer.CreateFormFile(field, filepath.Base(fp))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if _, err := io.Copy(part, f); err != nil {
+		return nil, "", err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return &body, writer.FormDataContentType(), nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPublishGitea(t *testing.T) {
+	requests := []string{}
+	uploaded := map[string]string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		if r.Header.Get("Authorization") != "token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			io.WriteString(w, `{"id": 7, "html_url": "https://example.com/r/7", "assets": [{"id": 3, "name": "app-linux_amd64"}]}`)
+		case http.MethodPatch:
+			io.WriteString(w, `{"id": 7, "html_url": "https://example.com/r/7", "assets": [{"id": 3, "name": "app-linux_amd64"}]}`)
+		case http.MethodPost:
+			file, header, err := r.FormFile("attachment")
+			if err == nil {
+				body, _ := io.ReadAll(file)
+				uploaded[header.Filename] = string(body)
+			}
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("GITEA_TOKEN", "secret")
+
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "app-linux_amd64")
+	os.WriteFile(fp, []byte("binary"), 0o755)
+
+	publisher := giteaPublisher{config: GiteaConfig{URL: server.URL, Repo: "acme/app"}}
+	url, err := publisher.Publish(context.Background(), Release{Tag: "v1.0.0", Name: "v1.0.0"}, []string{fp})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantsRequests := []string{
+		"GET /api/v1/repos/acme/app/releases/tags/v1.0.0",
+		"PATCH /api/v1/repos/acme/app/releases/7",
+		"DELETE /api/v1/repos/acme/app/releases/7/assets/3",
+		"POST /api/v1/repos/acme/app/releases/7/assets",
+	}
+
+	if !slices.Equal(requests, wantsRequests) {
+		t.Logf("Incorrect requests, wanted: %v got: %v\n", wantsRequests, requests)
+		t.Fail()
+	}
+
+	if uploaded["app-linux_amd64"] != "binary" || url != "https://example.com/r/7" {
+		t.Logf("Incorrect upload, got: %v %s\n", uploaded, url)
+		t.Fail()
+	}
+}
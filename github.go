@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrMissingToken = errors.New("no API token found in the environment")
+
+const defaultGitHubAPI = "https://api.github.com"
+
+// GitHubConfig selects the repository releases are published to. Repo
+// defaults to the project's origin remote and the token is read from
+// GITHUB_TOKEN or GH_TOKEN.
+type GitHubConfig struct {
+	Repo   string `json:"repo"`
+	APIURL string `json:"api_url"`
+}
+
+type githubRelease struct {
+	ID        int64  `json:"id"`
+	UploadURL string `json:"upload_url"`
+	HTMLURL   string `json:"html_url"`
+	Assets    []struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	} `json:"assets"`
+}
+
+type githubPublisher struct {
+	config GitHubConfig
+}
+
+func (p githubPublisher) DownloadURL(tag string, file string) string {
+	host := "https://github.com"
+	if p.config.APIURL != "" && p.config.APIURL != defaultGitHubAPI {
+		// GitHub Enterprise serves the API under /api/v3 of the host
+		host = strings.TrimSuffix(strings.TrimSuffix(p.config.APIURL, "/"), "/api/v3")
+	}
+
+	return fmt.Sprintf("%s/%s/releases/download/%s/%s", host, p.config.Repo, url.PathEscape(tag), url.PathEscape(file))
+}
+
+// Publish creates the release for the tag, or updates it if it already
+// exists, and uploads the files as assets, replacing any of the same name.
+func (p githubPublisher) Publish(ctx context.Context, release Release, files []string) (string, error) {
+	token, err := tokenFromEnv("GITHUB_TOKEN", "GH_TOKEN")
+	if err != nil {
+		return "", fmt.Errorf("github: %w", err)
+	}
+
+	apiURL := p.config.APIURL
+	if apiURL == "" {
+		apiURL = defaultGitHubAPI
+	}
+
+	client := apiClient{headers: map[string]string{
+		"Authorization":        "Bearer " + token,
+		"Accept":               "application/vnd.github+json",
+		"X-GitHub-Api-Version": "2022-11-28",
+	}}
+
+	reposURL := fmt.Sprintf("%s/repos/%s/releases", strings.TrimSuffix(apiURL, "/"), p.config.Repo)
+	settings := map[string]any{
+		"tag_name":   release.Tag,
+		"name":       release.Name,
+		"draft":      release.Draft,
+		"prerelease": release.Prerelease,
+	}
+
+	var rel githubRelease
+	status, err := client.do(ctx, http.MethodGet, reposURL+"/tags/"+url.PathEscape(release.Tag), "", nil, &rel)
+
+	if status == http.StatusNotFound {
+		_, err = client.do(ctx, http.MethodPost, reposURL, "application/json", jsonBody(settings), &rel)
+	} else if err == nil {
+		_, err = client.do(ctx, http.MethodPatch, fmt.Sprintf("%s/%d", reposURL, rel.ID), "application/json", jsonBody(settings), &rel)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("github release: %w", err)
+	}
+
+	uploadURL, _, _ := strings.Cut(rel.UploadURL, "{")
+
+	for _, fp := range files {
+		name := filepath.Base(fp)
+
+		for _, asset := range rel.Assets {
+			if asset.Name == name {
+				if _, err := client.do(ctx, http.MethodDelete, fmt.Sprintf("%s/assets/%d", reposURL, asset.ID), "", nil, nil); err != nil {
+					return "", fmt.Errorf("github asset: %w", err)
+				}
+			}
+		}
+
+		contents, err := os.ReadFile(fp)
+		if err != nil {
+			return "", err
+		}
+
+		_, err = client.do(ctx, http.MethodPost, uploadURL+"?name="+url.QueryEscape(name), "application/octet-stream", bytes.NewReader(contents), nil)
+		if err != nil {
+			return "", fmt.Errorf("github upload: %w", err)
+		}
+	}
+
+	return rel.HTMLURL, nil
+}
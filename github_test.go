@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPublishGitHub(t *testing.T) {
+	requests := []string{}
+	uploaded := map[string]string{}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.Method == http.MethodGet:
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/repos/acme/app/releases":
+			io.WriteString(w, `{"id": 1, "upload_url": "`+server.URL+`/uploads/1/assets{?name,label}", "html_url": "https://example.com/r/1"}`)
+		case r.URL.Path == "/uploads/1/assets":
+			body, _ := io.ReadAll(r.Body)
+			uploaded[r.URL.Query().Get("name")] = string(body)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("GITHUB_TOKEN", "secret")
+
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "app-linux_amd64")
+	os.WriteFile(fp, []byte("binary"), 0o755)
+
+	publisher := githubPublisher{config: GitHubConfig{Repo: "acme/app", APIURL: server.URL}}
+	url, err := publisher.Publish(context.Background(), Release{Tag: "v1.0.0", Name: "v1.0.0"}, []string{fp})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantsRequests := []string{
+		"GET /repos/acme/app/releases/tags/v1.0.0",
+		"POST /repos/acme/app/releases",
+		"POST /uploads/1/assets",
+	}
+
+	if !slices.Equal(requests, wantsRequests) {
+		t.Logf("Incorrect requests, wanted: %v got: %v\n", wantsRequests, requests)
+		t.Fail()
+	}
+
+	if uploaded["app-linux_amd64"] != "binary" || url != "https://example.com/r/1" {
+		t.Logf("Incorrect upload, got: %v %s\n", uploaded, url)
+		t.Fail()
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const defaultGitLabURL = "https://gitlab.com"
+
+// GitLabConfig selects the GitLab instance and project releases are
+// published to. Project defaults to the origin remote's path and the token
+// is read from GITLAB_TOKEN or CI_JOB_TOKEN.
+type GitLabConfig struct {
+	URL     string `json:"url"`
+	Project string `json:"project"`
+	// Package is the generic package the files are uploaded to. Defaults
+	// to the binary name.
+	Package string `json:"package"`
+}
+
+type gitlabLink struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type gitlabPublisher struct {
+	config  GitLabConfig
+	pkgName string
+}
+
+func (p gitlabPublisher) baseURL() string {
+	if p.config.URL == "" {
+		return defaultGitLabURL
+	}
+
+	return strings.TrimSuffix(p.config.URL, "/")
+}
+
+// DownloadURL returns the file's URL in the generic package registry.
+func (p gitlabPublisher) DownloadURL(tag string, file string) string {
+	pkgName := p.config.Package
+	if pkgName == "" {
+		pkgName = p.pkgName
+	}
+
+	return fmt.Sprintf("%s/api/v4/projects/%s/packages/generic/%s/%s/%s",
+		p.baseURL(), url.PathEscape(p.config.Project), url.PathEscape(pkgName), url.PathEscape(tag), url.PathEscape(file))
+}
+
+// Publish uploads the files to the project's generic package registry and
+// creates the release for the tag with a link to each of them. GitLab has no
+// draft releases, so Draft and Prerelease are ignored.
+func (p gitlabPublisher) Publish(ctx context.Context, release Release, files []string) (string, error) {
+	token, err := tokenFromEnv("GITLAB_TOKEN", "CI_JOB_TOKEN")
+	if err != nil {
+		return "", fmt.Errorf("gitlab: %w", err)
+	}
+
+	header := "PRIVATE-TOKEN"
+	if os.Getenv("GITLAB_TOKEN") == "" {
+		header = "JOB-TOKEN"
+	}
+
+	client := apiClient{headers: map[string]string{header: token}}
+
+	baseURL := p.baseURL()
+	projectURL := fmt.Sprintf("%s/api/v4/projects/%s", baseURL, url.PathEscape(p.config.Project))
+	releaseURL := fmt.Sprintf("%s/releases/%s", projectURL, url.PathEscape(release.Tag))
+
+	links := []gitlabLink{}
+
+	for _, fp := range files {
+		name := filepath.Base(fp)
+		pkgURL := p.DownloadURL(release.Tag, name)
+
+		contents, err := os.ReadFile(fp)
+		if err != nil {
+			return "", err
+		}
+
+		if _, err := client.do(ctx, http.MethodPut, pkgURL, "application/octet-stream", bytes.NewReader(contents), nil); err != nil {
+			return "", fmt.Errorf("gitlab upload: %w", err)
+		}
+
+		links = append(links, gitlabLink{Name: name, URL: pkgURL})
+	}
+
+	status, err := client.do(ctx, http.MethodGet, releaseURL, "", nil, nil)
+
+	if status == http.StatusNotFound {
+		_, err = client.do(ctx, http.MethodPost, projectURL+"/releases", "application/json", jsonBody(map[string]any{
+			"tag_name": release.Tag,
+			"name":     release.Name,
+			"assets":   map[string]any{"links": links},
+		}), nil)
+
+		if err != nil {
+			return "", fmt.Errorf("gitlab release: %w", err)
+		}
+
+		return fmt.Sprintf("%s/%s/-/releases/%s", baseURL, p.config.Project, url.PathEscape(release.Tag)), nil
+	} else if err != nil {
+		return "", fmt.Errorf("gitlab release: %w", err)
+	}
+
+	existing := []gitlabLink{}
+	if _, err := client.do(ctx, http.MethodGet, releaseURL+"/assets/links", "", nil, &existing); err != nil {
+		return "", fmt.Errorf("gitlab links: %w", err)
+	}
+
+	for _, link := range links {
+		for _, old := range existing {
+			if old.Name == link.Name {
+				if _, err := client.do(ctx, http.MethodDelete, fmt.Sprintf("%s/assets/links/%d", releaseURL, old.ID), "", nil, nil); err != nil {
+					return "", fmt.Errorf("gitlab links: %w", err)
+				}
+			}
+		}
+
+		if _, err := client.do(ctx, http.MethodPost, releaseURL+"/assets/links", "application/json", jsonBody(map[string]any{
+			"name":      link.Name,
+			"url":       link.URL,
+			"link_type": "package",
+		}), nil); err != nil {
+			return "", fmt.Errorf("gitlab links: %w", err)
+		}
+	}
+
+	return fmt.Sprintf("%s/%s/-/releases/%s", baseURL, p.config.Project, url.PathEscape(release.Tag)), nil
+}
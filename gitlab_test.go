@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPublishGitLab(t *testing.T) {
+	requests := []string{}
+	uploaded := map[string]string{}
+	var created map[string]any
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.EscapedPath())
+
+		if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			w.WriteHeader(http.StatusNotFound)
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			uploaded[filepath.Base(r.URL.Path)] = string(body)
+		case http.MethodPost:
+			json.NewDecoder(r.Body).Decode(&created)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("GITLAB_TOKEN", "secret")
+
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "app-linux_amd64")
+	os.WriteFile(fp, []byte("binary"), 0o755)
+
+	publisher := gitlabPublisher{config: GitLabConfig{URL: server.URL, Project: "acme/app"}, pkgName: "app"}
+	url, err := publisher.Publish(context.Background(), Release{Tag: "v1.0.0", Name: "v1.0.0"}, []string{fp})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantsRequests := []string{
+		"PUT /api/v4/projects/acme%2Fapp/packages/generic/app/v1.0.0/app-linux_amd64",
+		"GET /api/v4/projects/acme%2Fapp/releases/v1.0.0",
+		"POST /api/v4/projects/acme%2Fapp/releases",
+	}
+
+	if !slices.Equal(requests, wantsRequests) {
+		t.Logf("Incorrect requests, wanted: %v got: %v\n", wantsRequests, requests)
+		t.Fail()
+	}
+
+	if uploaded["app-linux_amd64"] != "binary" || url != server.URL+"/acme/app/-/releases/v1.0.0" {
+		t.Logf("Incorrect upload, got: %v %s\n", uploaded, url)
+		t.Fail()
+	}
+
+	links, _ := created["assets"].(map[string]any)["links"].([]any)
+	if len(links) != 1 || links[0].(map[string]any)["name"] != "app-linux_amd64" {
+		t.Logf("Incorrect release links, got: %v\n", created)
+		t.Fail()
+	}
+}
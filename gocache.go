@@ -0,0 +1,17 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// defaultGoCacheDir is the base of the per-target caches when no directory
+// is configured.
+func defaultGoCacheDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(cacheDir, "go-builder", "gocache"), nil
+}
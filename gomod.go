@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/version"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var ErrGoTooOld = errors.New("go is older than go.mod requires")
+
+// goModDirectives are the go and toolchain lines of a go.mod, as go
+// versions such as go1.22.0.
+type goModDirectives struct {
+	Go        string
+	Toolchain string
+}
+
+// readGoModDirectives reads the directives of the go.mod in projectDir.
+func readGoModDirectives(projectDir string) (goModDirectives, error) {
+	raw, err := os.ReadFile(filepath.Join(projectDir, "go.mod"))
+	if err != nil {
+		return goModDirectives{}, err
+	}
+
+	d := goModDirectives{}
+	for line := range strings.Lines(string(raw)) {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch fields[0] {
+		case "go":
+			d.Go = "go" + fields[1]
+		case "toolchain":
+			d.Toolchain = fields[1]
+		}
+	}
+
+	return d, nil
+}
+
+// checkGoDirectives fails when goVersion is older than the go directive,
+// which go would only report file by file as the matrix builds. An older
+// toolchain than the toolchain directive still builds, so it is a warning.
+func checkGoDirectives(goVersion string, d goModDirectives) (string, error) {
+	// development builds have no comparable version
+	if !version.IsValid(goVersion) {
+		return "", nil
+	}
+
+	if version.IsValid(d.Go) && version.Compare(goVersion, d.Go) < 0 {
+		return "", fmt.Errorf("%w: go.mod requires %s, %s is installed. Install %s or later, or unset GOTOOLCHAIN=local so go downloads it", ErrGoTooOld, d.Go, goVersion, d.Go)
+	}
+
+	if version.IsValid(d.Toolchain) && version.Compare(goVersion, d.Toolchain) < 0 {
+		return fmt.Sprintf("go.mod prefers %s, building with %s", d.Toolchain, goVersion), nil
+	}
+
+	return "", nil
+}
+
+// checkGoToolchain checks the go that builds the project, which go may have
+// switched to on its own, against its go.mod.
+func checkGoToolchain(ctx context.Context, config builder.BuildConfig) (string, error) {
+	d, err := readGoModDirectives(config.ProjectDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	raw, err := goCommand(ctx, config, "env", "GOVERSION").Output()
+	if err != nil {
+		return "", fmt.Errorf("go env: %w", err)
+	}
+
+	return checkGoDirectives(strings.TrimSpace(string(raw)), d)
+}
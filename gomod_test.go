@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReadGoModDirectives(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.22.0\n\ntoolchain go1.24.6\n\nrequire (\n\tgolang.org/x/mod v0.20.0\n)\n",
+	})
+
+	res, err := readGoModDirectives(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if wants := (goModDirectives{Go: "go1.22.0", Toolchain: "go1.24.6"}); res != wants {
+		t.Logf("Incorrect directives, wanted: %+v got: %+v\n", wants, res)
+		t.Fail()
+	}
+}
+
+func TestCheckGoDirectives(t *testing.T) {
+	testCases := []struct {
+		name      string
+		version   string
+		input     goModDirectives
+		wantsWarn bool
+		wantsErr  error
+	}{
+		{name: "newer", version: "go1.24.6", input: goModDirectives{Go: "go1.21"}},
+		{name: "same", version: "go1.22.0", input: goModDirectives{Go: "go1.22.0"}},
+		{name: "older than go", version: "go1.21.5", input: goModDirectives{Go: "go1.22.0"}, wantsErr: ErrGoTooOld},
+		{name: "language version", version: "go1.22.0", input: goModDirectives{Go: "go1.22"}},
+		{name: "older than toolchain", version: "go1.23.0", input: goModDirectives{Go: "go1.22", Toolchain: "go1.24.6"}, wantsWarn: true},
+		{name: "devel", version: "devel go1.25-abc", input: goModDirectives{Go: "go1.30"}},
+		{name: "no directives", version: "go1.21.0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			warning, err := checkGoDirectives(tc.version, tc.input)
+
+			if !errors.Is(err, tc.wantsErr) {
+				t.Logf("Incorrect error, wanted: %v got: %v\n", tc.wantsErr, err)
+				t.Fail()
+			}
+
+			if (warning != "") != tc.wantsWarn {
+				t.Logf("Incorrect warning, wanted one: %t got: %q\n", tc.wantsWarn, warning)
+				t.Fail()
+			}
+		})
+	}
+}
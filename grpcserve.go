@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/jrstaple/go-builder/pkg/buildpb"
+)
+
+var ErrRemoteBuildFailed = errors.New("remote build failed")
+
+// buildService serves the gRPC BuildService with the jobs of a buildServer,
+// so they can also be polled and downloaded over its REST API.
+type buildService struct {
+	buildpb.UnimplementedBuildServiceServer
+	server *buildServer
+}
+
+func (b buildService) Build(req *buildpb.BuildRequest, stream grpc.ServerStreamingServer[buildpb.BuildEvent]) error {
+	// the JSON log of the run has a line for each target as it finishes
+	job := jobRequest{
+		Project: req.GetProject(),
+		Targets: req.GetTargets(),
+		Flags:   append(slices.Clone(req.GetFlags()), "-log-format", "json", "-log-level", "info"),
+	}
+	if err := job.validate(b.server.projects, b.server.dir); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// the started event goes out before any output of the job
+	events := &eventWriter{stream: stream}
+	events.mu.Lock()
+	started, done := b.server.start(job, events)
+	events.err = stream.Send(&buildpb.BuildEvent{Event: &buildpb.BuildEvent_Started{Started: &buildpb.JobStarted{Id: started.ID}}})
+	events.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-stream.Context().Done():
+		b.server.cancelJob(started.ID)
+		<-done
+		return stream.Context().Err()
+	}
+
+	events.Flush()
+
+	finished, err := b.server.lookup(started.ID)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	b.server.addResults(&finished)
+
+	result := &buildpb.RunResult{Success: finished.Status == "succeeded", Artifacts: finished.Artifacts, Error: finished.Error}
+	if finished.Report != nil {
+		for _, r := range finished.Report.Results {
+			result.Results = append(result.Results, &buildpb.TargetResult{
+				Target:     r.Target,
+				Race:       r.Race,
+				Status:     resultStatus(r),
+				DurationMs: r.Duration.Milliseconds(),
+				Size:       r.Size,
+				Error:      r.Error,
+			})
+		}
+	}
+
+	return stream.Send(&buildpb.BuildEvent{Event: &buildpb.BuildEvent_Finished{Finished: result}})
+}
+
+// buildLogLine is the JSON log line of a finished target.
+type buildLogLine struct {
+	Msg      string        `json:"msg"`
+	Target   string        `json:"target"`
+	Race     bool          `json:"race"`
+	Status   string        `json:"status"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error"`
+}
+
+// eventWriter turns the output of a job into events on a stream: a target
+// event for each target in the JSON log and an output event for each other
+// line.
+type eventWriter struct {
+	mu     sync.Mutex
+	stream grpc.ServerStreamingServer[buildpb.BuildEvent]
+	buf    []byte
+	// err is the first error sending, after which events are dropped
+	err error
+}
+
+func (e *eventWriter) Write(p []byte) (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.buf = append(e.buf, p...)
+	for {
+		i := bytes.IndexByte(e.buf, '\n')
+		if i < 0 {
+			break
+		}
+
+		e.send(string(e.buf[:i]))
+		e.buf = e.buf[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// Flush sends the last line when it has no newline.
+func (e *eventWriter) Flush() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.buf) > 0 {
+		e.send(string(e.buf))
+		e.buf = nil
+	}
+}
+
+func (e *eventWriter) send(line string) {
+	if e.err != nil {
+		return
+	}
+
+	event := &buildpb.BuildEvent{Event: &buildpb.BuildEvent_Output{Output: line}}
+
+	entry := buildLogLine{}
+	if json.Unmarshal([]byte(line), &entry) == nil && entry.Msg == "build" && entry.Target != "" {
+		event.Event = &buildpb.BuildEvent_Target{Target: &buildpb.TargetResult{
+			Target:     entry.Target,
+			Race:       entry.Race,
+			Status:     entry.Status,
+			DurationMs: entry.Duration.Milliseconds(),
+			Error:      entry.Error,
+		}}
+	}
+
+	e.err = e.stream.Send(event)
+}
+
+// tokenInterceptor rejects calls without the bearer token, when one is set.
+func tokenInterceptor(token string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		md, _ := metadata.FromIncomingContext(ss.Context())
+		auth := []byte(nil)
+		if values := md.Get("authorization"); len(values) > 0 {
+			auth = []byte(values[0])
+		}
+
+		if token != "" && subtle.ConstantTimeCompare(auth, []byte("Bearer "+token)) != 1 {
+			return status.Error(codes.Unauthenticated, "unauthorized")
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+func newGRPCServer(server *buildServer) *grpc.Server {
+	s := grpc.NewServer(grpc.StreamInterceptor(tokenInterceptor(server.token)))
+	buildpb.RegisterBuildServiceServer(s, buildService{server: server})
+
+	return s
+}
+
+// remoteBuild builds on the server at addr, writing the output of the build
+// to w as it streams in, and returns the outcome of the run.
+func remoteBuild(ctx context.Context, addr string, token string, req *buildpb.BuildRequest, w io.Writer) (*buildpb.RunResult, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+	}
+
+	stream, err := buildpb.NewBuildServiceClient(conn).Build(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = fmt.Errorf("%w: stream ended without a result", ErrRemoteBuildFailed)
+			}
+			return nil, err
+		}
+
+		switch e := event.Event.(type) {
+		case *buildpb.BuildEvent_Output:
+			fmt.Fprintln(w, e.Output)
+		case *buildpb.BuildEvent_Finished:
+			return e.Finished, nil
+		}
+	}
+}
+
+// remote runs the remote subcommand, which builds a project on a go-builder
+// serve -grpc-addr server.
+func remote(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("remote", flag.ExitOnError)
+	addr := flags.String("server", "localhost:9090", "Specify the address of the gRPC build server.")
+	targets := []string{}
+	flags.Func("target", "Specify a target to build, e.g. linux/amd64. Repeat it for more targets.", func(v string) error {
+		targets = append(targets, v)
+		return nil
+	})
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: go-builder remote [flags] project [-- build flags]")
+		fmt.Fprintln(flags.Output(), "Builds the project directory on the server, which is sent GOBUILDER_SERVE_TOKEN as a bearer token when set.")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	req := &buildpb.BuildRequest{Project: flags.Arg(0), Targets: targets}
+	if rest := flags.Args()[1:]; len(rest) > 0 && rest[0] == "--" {
+		req.Flags = rest[1:]
+	}
+
+	result, err := remoteBuild(ctx, *addr, os.Getenv("GOBUILDER_SERVE_TOKEN"), req, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	for _, artifact := range result.GetArtifacts() {
+		fmt.Println(artifact)
+	}
+
+	if !result.GetSuccess() {
+		return fmt.Errorf("%w: %s", ErrRemoteBuildFailed, result.GetError())
+	}
+
+	return nil
+}
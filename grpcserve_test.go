@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/jrstaple/go-builder/pkg/buildpb"
+)
+
+func TestRemoteBuild(t *testing.T) {
+	dir := t.TempDir()
+
+	// the fake build logs a finished target as go-builder -log-format json
+	// does and writes the artifact and report to the -o directory
+	exe := filepath.Join(dir, "fake-builder")
+	script := "#!/bin/sh\n" +
+		"while [ $# -gt 0 ]; do [ \"$1\" = -o ] && out=$2; shift; done\n" +
+		"mkdir -p \"$out\" && touch \"$out/app-linux_amd64\"\n" +
+		"echo '{\"results\": [{\"target\": \"linux/amd64\", \"size\": 42, \"success\": true}]}' > \"$out/report.json\"\n" +
+		"echo building\n" +
+		"echo '{\"level\":\"INFO\",\"msg\":\"build\",\"target\":\"linux/amd64\",\"race\":false,\"status\":\"ok\",\"duration\":1500000000}' >&2\n"
+	os.WriteFile(exe, []byte(script), 0o755)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := newGRPCServer(newBuildServer(exe, filepath.Join(dir, "jobs"), dir, "secret", 1))
+	go server.Serve(listener)
+	defer server.Stop()
+
+	req := &buildpb.BuildRequest{Project: dir, Targets: []string{"linux/amd64"}}
+
+	if _, err := remoteBuild(context.Background(), listener.Addr().String(), "wrong", req, &strings.Builder{}); status.Code(err) != codes.Unauthenticated {
+		t.Logf("Incorrect error for a wrong token: %v\n", err)
+		t.Fail()
+	}
+
+	out := strings.Builder{}
+	result, err := remoteBuild(context.Background(), listener.Addr().String(), "secret", req, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out.String() != "building\n" {
+		t.Logf("Incorrect output: %q\n", out.String())
+		t.Fail()
+	}
+
+	if !result.GetSuccess() || len(result.GetResults()) != 1 || result.GetResults()[0].GetSize() != 42 || len(result.GetArtifacts()) != 2 {
+		t.Logf("Incorrect result: %v\n", result)
+		t.Fail()
+	}
+
+	req.Project = filepath.Join(dir, "missing")
+	if _, err := remoteBuild(context.Background(), listener.Addr().String(), "secret", req, &out); status.Code(err) != codes.InvalidArgument {
+		t.Logf("Incorrect error for a missing project: %v\n", err)
+		t.Fail()
+	}
+}
+
+func TestEventWriter(t *testing.T) {
+	stream := &fakeEventStream{}
+	events := &eventWriter{stream: stream}
+
+	events.Write([]byte("compiling\n{\"msg\":\"build\",\"target\":\"linux/arm64\",\"status\":\"failed\",\"duration\":2000000000,\"error\":\"exit status 1\"}\npart"))
+	events.Write([]byte("ial"))
+	events.Flush()
+
+	if len(stream.events) != 3 {
+		t.Fatalf("Incorrect events: %v\n", stream.events)
+	}
+
+	if stream.events[0].GetOutput() != "compiling" || stream.events[2].GetOutput() != "partial" {
+		t.Logf("Incorrect output events: %v\n", stream.events)
+		t.Fail()
+	}
+
+	if target := stream.events[1].GetTarget(); target.GetTarget() != "linux/arm64" || target.GetDurationMs() != 2000 || target.GetError() != "exit status 1" {
+		t.Logf("Incorrect target event: %v\n", target)
+		t.Fail()
+	}
+}
+
+type fakeEventStream struct {
+	buildpb.BuildService_BuildServer
+	events []*buildpb.BuildEvent
+}
+
+func (f *fakeEventStream) Send(event *buildpb.BuildEvent) error {
+	f.events = append(f.events, event)
+	return nil
+}
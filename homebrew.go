@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var ErrNoReleaseURL = errors.New("no release_url configured and no -publish backend to derive it from")
+
+// HomebrewConfig describes the formula generated for the darwin and linux
+// archives and the tap it is pushed to.
+type HomebrewConfig struct {
+	// Name is the formula name, defaulting to the binary name.
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Homepage    string `json:"homepage"`
+	License     string `json:"license"`
+	// Tap is the git URL of the tap repository the formula is committed
+	// to under Formula/. The formula is only written locally when empty.
+	Tap string `json:"tap"`
+	// Test is the body of the formula's test block.
+	Test string `json:"test"`
+}
+
+func (h HomebrewConfig) IsEmpty() bool {
+	return h == HomebrewConfig{}
+}
+
+type formulaArchive struct {
+	URL    string
+	SHA256 string
+}
+
+type formulaData struct {
+	Class       string
+	Description string
+	Homepage    string
+	License     string
+	Version     string
+	Binary      string
+	Test        string
+	// Archives maps darwin/linux to on_arm/on_intel to the archive.
+	Archives map[string]map[string]formulaArchive
+}
+
+var formulaTemplate = template.Must(template.New("formula").Parse(`class {{ .Class }} < Formula
+  desc "{{ .Description }}"
+{{- if .Homepage }}
+  homepage "{{ .Homepage }}"
+{{- end }}
+  version "{{ .Version }}"
+{{- if .License }}
+  license "{{ .License }}"
+{{- end }}
+{{ range $os, $block := .Archives }}
+  on_{{ if eq $os "darwin" }}macos{{ else }}linux{{ end }} do
+{{- range $cpu, $archive := $block }}
+    on_{{ $cpu }} do
+      url "{{ $archive.URL }}"
+      sha256 "{{ $archive.SHA256 }}"
+    end
+{{- end }}
+  end
+{{ end }}
+  def install
+    bin.install "{{ .Binary }}"
+  end
+{{- if .Test }}
+
+  test do
+    {{ .Test }}
+  end
+{{- end }}
+end
+`))
+
+// formulaClass converts a formula name to its Ruby class name the way
+// Homebrew does, e.g. go-builder to GoBuilder.
+func formulaClass(name string) string {
+	class := strings.Builder{}
+
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '-' || r == '_' || r == '.' }) {
+		class.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+
+	return class.String()
+}
+
+// homebrewCPU maps the archived dists to the formula's CPU blocks. Only the
+// 64-bit architectures Homebrew supports are included.
+var homebrewCPU = map[string]string{
+	"amd64": "intel",
+	"arm64": "arm",
+}
+
+// writeFormula writes the formula for the darwin and linux archives, keyed by
+// dist, to the output directory and returns its path.
+func writeFormula(config builder.BuildConfig, brew HomebrewConfig, archives map[string]string, tag string, downloadURL func(tag, file string) string) (string, error) {
+	name := brew.Name
+	if name == "" {
+		name = config.BinaryName
+	}
+
+	data := formulaData{
+		Class:       formulaClass(name),
+		Description: brew.Description,
+		Homepage:    brew.Homepage,
+		License:     brew.License,
+		Version:     strings.TrimPrefix(tag, "v"),
+		Binary:      config.BinaryName,
+		Test:        brew.Test,
+		Archives:    map[string]map[string]formulaArchive{},
+	}
+
+	for _, goos := range []string{"darwin", "linux"} {
+		for goarch, cpu := range homebrewCPU {
+			fp, ok := archives[goos+"/"+goarch]
+			if !ok {
+				continue
+			}
+
+			sum, err := sha256File(fp)
+			if err != nil {
+				return "", err
+			}
+
+			if data.Archives[goos] == nil {
+				data.Archives[goos] = map[string]formulaArchive{}
+			}
+
+			data.Archives[goos][cpu] = formulaArchive{URL: downloadURL(tag, filepath.Base(fp)), SHA256: sum}
+		}
+	}
+
+	if len(data.Archives) == 0 {
+		return "", errors.New("no darwin or linux amd64/arm64 archives were built")
+	}
+
+	out := strings.Builder{}
+	if err := formulaTemplate.Execute(&out, data); err != nil {
+		return "", err
+	}
+
+	fp := filepath.Join(config.OutputDir, name+".rb")
+	return fp, os.WriteFile(fp, []byte(out.String()), 0o644)
+}
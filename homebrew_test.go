@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestFormulaClass(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		wants string
+	}{
+		{
+			name:  "lowercase",
+			input: "app",
+			wants: "App",
+		},
+		{
+			name:  "dashes",
+			input: "go-builder",
+			wants: "GoBuilder",
+		},
+		{
+			name:  "underscores and dots",
+			input: "my_tool.cli",
+			wants: "MyToolCli",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := formulaClass(tc.input)
+
+			if res != tc.wants {
+				t.Logf("Incorrect class, wanted: %s got: %s\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestWriteFormula(t *testing.T) {
+	dir := t.TempDir()
+	config := builder.NewConfig()
+	config.OutputDir = dir
+	config.BinaryName = "go-builder"
+
+	archives := map[string]string{}
+	for _, dist := range []string{"darwin/arm64", "linux/amd64", "linux/386"} {
+		fp := filepath.Join(dir, strings.ReplaceAll(dist, "/", "_")+".tar.gz")
+		os.WriteFile(fp, []byte(dist), 0o644)
+		archives[dist] = fp
+	}
+
+	fp, err := writeFormula(config, HomebrewConfig{Description: "Builds Go", License: "MIT"}, archives, "v1.2.3",
+		func(tag string, file string) string {
+			return expandReleaseURL("https://dl.example.com/{tag}/{file}", tag, file)
+		})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, _ := os.ReadFile(fp)
+	formula := string(raw)
+
+	wants := []string{
+		"class GoBuilder < Formula",
+		`version "1.2.3"`,
+		`license "MIT"`,
+		"on_macos do\n    on_arm do\n      url \"https://dl.example.com/v1.2.3/darwin_arm64.tar.gz\"",
+		"on_linux do\n    on_intel do\n      url \"https://dl.example.com/v1.2.3/linux_amd64.tar.gz\"",
+		`bin.install "go-builder"`,
+	}
+
+	for _, want := range wants {
+		if !strings.Contains(formula, want) {
+			t.Logf("Formula is missing %q:\n%s\n", want, formula)
+			t.Fail()
+		}
+	}
+
+	if strings.Contains(formula, "linux_386") || filepath.Base(fp) != "go-builder.rb" {
+		t.Logf("Incorrect formula %s:\n%s\n", fp, formula)
+		t.Fail()
+	}
+}
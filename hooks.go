@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+// HooksConfig lists shell commands run in the project directory around each
+// target build. The target is passed in TARGET_* variables, e.g.
+// TARGET_GOOS. Targets build in parallel, so hooks may run concurrently.
+type HooksConfig struct {
+	Pre  []string `json:"pre"`
+	Post []string `json:"post"`
+}
+
+// hookEnv returns the variables describing the build of dist to its hooks.
+func hookEnv(config builder.BuildConfig, dist builder.GoDist) []string {
+	race := "false"
+	if config.Race {
+		race = "true"
+	}
+
+	return []string{
+		"TARGET_GOOS=" + dist.GOOS,
+		"TARGET_GOARCH=" + dist.GOARCH,
+		"TARGET_VARIANT=" + dist.SubArch,
+		"TARGET_NAME=" + config.BinaryName,
+		"TARGET_OUTPUT=" + builder.OutputPath(config, dist),
+		"TARGET_RACE=" + race,
+	}
+}
+
+// shellWaitDelay bounds how long output is read after a cancelled shell is
+// killed, as commands it started may keep the output pipe open.
+const shellWaitDelay = 5 * time.Second
+
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	cmd.WaitDelay = shellWaitDelay
+	return cmd
+}
+
+// runHooks runs the commands in order for the build of dist and stops at the
+// first that fails.
+func runHooks(ctx context.Context, commands []string, config builder.BuildConfig, dist builder.GoDist) (string, error) {
+	out := ""
+
+	for _, command := range commands {
+		cmd := shellCommand(ctx, command)
+		cmd.Dir = config.ProjectDir
+		cmd.Env = append(os.Environ(), hookEnv(config, dist)...)
+
+		res := bytes.Buffer{}
+		cmd.Stdout = &res
+		if config.Output != nil {
+			cmd.Stdout = io.MultiWriter(&res, config.Output)
+		}
+		cmd.Stderr = cmd.Stdout
+
+		err := cmd.Run()
+		out += res.String()
+
+		if err != nil {
+			return out, fmt.Errorf("hook %q: %w", command, err)
+		}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestRunHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks use sh in this test")
+	}
+
+	dir := t.TempDir()
+
+	config := builder.NewConfig()
+	config.ProjectDir = dir
+	config.OutputDir = "build"
+	config.BinaryName = "app"
+
+	dist := builder.GoDist{GOOS: "linux", GOARCH: "arm", SubArch: "7"}
+
+	_, err := runHooks(context.Background(), []string{
+		"echo $TARGET_GOOS $TARGET_GOARCH $TARGET_VARIANT $TARGET_NAME $TARGET_OUTPUT > hook.txt",
+	}, config, dist)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := os.ReadFile(filepath.Join(dir, "hook.txt"))
+	wants := "linux arm 7 app build/app-linux_arm_7\n"
+
+	if string(got) != wants {
+		t.Logf("Incorrect hook env, wanted: %q got: %q\n", wants, got)
+		t.Fail()
+	}
+
+	out, err := runHooks(context.Background(), []string{"echo first", "exit 3", "echo never"}, config, dist)
+	if err == nil || out != "first\n" {
+		t.Logf("Failing hook did not stop, output: %q error: %v\n", out, err)
+		t.Fail()
+	}
+}
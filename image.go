@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+// ImageConfig describes the container images built from the linux binaries.
+type ImageConfig struct {
+	// Name is the image reference without the per-arch suffix, e.g.
+	// ghcr.io/acme/myapp:v1.2.3. A missing tag defaults to latest.
+	Name string `json:"name"`
+	// Dockerfile is built once per architecture with the binary's file name
+	// in the BINARY build arg. Defaults to defaultImageDockerfile.
+	Dockerfile string `json:"dockerfile"`
+	// Push pushes the per-arch images and a multi-arch manifest list.
+	Push bool `json:"push"`
+}
+
+const defaultImageDockerfile = `FROM gcr.io/distroless/static-debian12
+ARG BINARY
+COPY ${BINARY} /app
+ENTRYPOINT ["/app"]
+`
+
+// splitImageTag separates the repository from the tag in an image reference.
+func splitImageTag(name string) (string, string) {
+	slash := strings.LastIndex(name, "/")
+	colon := strings.LastIndex(name, ":")
+
+	if colon > slash {
+		return name[:colon], name[colon+1:]
+	}
+
+	return name, "latest"
+}
+
+// dockerPlatform returns the --platform value for dist, e.g. linux/arm/v7.
+func dockerPlatform(dist builder.GoDist) string {
+	platform := dist.GOOS + "/" + dist.GOARCH
+
+	switch {
+	case dist.GOARCH == "arm" && dist.SubArch != "":
+		platform += "/v" + strings.Split(dist.SubArch, ",")[0]
+	case dist.GOARCH == "amd64" && dist.SubArch != "":
+		platform += "/" + dist.SubArch
+	}
+
+	return platform
+}
+
+func runDocker(ctx context.Context, dir string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Dir = dir
+
+	if res, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("docker %s: %w\n%s", args[0], err, res)
+	}
+
+	return nil
+}
+
+// buildImages builds one image per linux job from the binaries in the output
+// directory and, when pushing, assembles them into a multi-arch manifest list
+// under the configured name. It returns the image references created.
+func buildImages(ctx context.Context, config builder.BuildConfig, image ImageConfig, jobs []buildJob) ([]string, error) {
+	repo, tag := splitImageTag(image.Name)
+	created := []string{}
+	archImages := []string{}
+
+	dockerfile, err := filepath.Abs(image.Dockerfile)
+	if err != nil {
+		return created, err
+	}
+
+	if image.Dockerfile == "" {
+		dockerfile = filepath.Join(config.OutputDir, "Dockerfile.gobuilder")
+		if err := os.WriteFile(dockerfile, []byte(defaultImageDockerfile), 0o644); err != nil {
+			return created, err
+		}
+		defer os.Remove(dockerfile)
+	}
+
+	for _, job := range jobs {
+		if job.Dist.GOOS != "linux" || job.Config.Race {
+			continue
+		}
+
+		binary := builder.OutputPath(job.Config, job.Dist)
+
+		suffix := strings.NewReplacer("/", "-", ",", "-").Replace(strings.TrimPrefix(job.Dist.String(), "linux/"))
+		archImage := fmt.Sprintf("%s:%s-%s", repo, tag, suffix)
+
+		if err := runDocker(ctx, job.Config.OutputDir, "build",
+			"--platform", dockerPlatform(job.Dist),
+			"--build-arg", "BINARY="+filepath.Base(binary),
+			"-f", dockerfile,
+			"-t", archImage,
+			job.Config.OutputDir); err != nil {
+			return created, err
+		}
+
+		created = append(created, archImage)
+		archImages = append(archImages, archImage)
+
+		if image.Push {
+			if err := runDocker(ctx, "", "push", archImage); err != nil {
+				return created, err
+			}
+		}
+	}
+
+	if !image.Push || len(archImages) == 0 {
+		return created, nil
+	}
+
+	manifest := repo + ":" + tag
+
+	if err := runDocker(ctx, "", append([]string{"manifest", "create", "--amend", manifest}, archImages...)...); err != nil {
+		return created, err
+	}
+
+	if err := runDocker(ctx, "", "manifest", "push", manifest); err != nil {
+		return created, err
+	}
+
+	return append(created, manifest), nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestSplitImageTag(t *testing.T) {
+	testCases := []struct {
+		input     string
+		wantsRepo string
+		wantsTag  string
+	}{
+		{input: "myapp", wantsRepo: "myapp", wantsTag: "latest"},
+		{input: "ghcr.io/acme/myapp:v1.2.3", wantsRepo: "ghcr.io/acme/myapp", wantsTag: "v1.2.3"},
+		{input: "localhost:5000/myapp", wantsRepo: "localhost:5000/myapp", wantsTag: "latest"},
+		{input: "localhost:5000/myapp:dev", wantsRepo: "localhost:5000/myapp", wantsTag: "dev"},
+	}
+
+	for _, tc := range testCases {
+		repo, tag := splitImageTag(tc.input)
+
+		if repo != tc.wantsRepo || tag != tc.wantsTag {
+			t.Logf("Incorrect split of %s, wanted: %s %s got: %s %s\n", tc.input, tc.wantsRepo, tc.wantsTag, repo, tag)
+			t.Fail()
+		}
+	}
+}
+
+func TestDockerPlatform(t *testing.T) {
+	testCases := []struct {
+		input builder.GoDist
+		wants string
+	}{
+		{input: builder.GoDist{GOOS: "linux", GOARCH: "arm64"}, wants: "linux/arm64"},
+		{input: builder.GoDist{GOOS: "linux", GOARCH: "arm", SubArch: "7"}, wants: "linux/arm/v7"},
+		{input: builder.GoDist{GOOS: "linux", GOARCH: "arm", SubArch: "6,softfloat"}, wants: "linux/arm/v6"},
+		{input: builder.GoDist{GOOS: "linux", GOARCH: "amd64", SubArch: "v3"}, wants: "linux/amd64/v3"},
+	}
+
+	for _, tc := range testCases {
+		if res := dockerPlatform(tc.input); res != tc.wants {
+			t.Logf("Incorrect platform for %s, wanted: %s got: %s\n", tc.input, tc.wants, res)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+// listedPackage is the part of go list -json output a fingerprint uses.
+type listedPackage struct {
+	Dir      string
+	Standard bool
+	Module   *struct {
+		Path    string
+		Version string
+		GoMod   string
+		Replace *struct{ Path string }
+	}
+
+	GoFiles, CgoFiles, CFiles, CXXFiles, MFiles, HFiles, SFiles, SysoFiles, EmbedFiles []string
+}
+
+// fingerprintEnv are the host variables that change a build beyond its
+// command line and target.
+func fingerprintEnv() []string {
+	env := []string{}
+
+	for _, v := range os.Environ() {
+		name, _, _ := strings.Cut(v, "=")
+		if name == "GOFLAGS" || name == "CC" || name == "CXX" || strings.HasPrefix(name, "CGO_") || strings.HasPrefix(name, "GOEXPERIMENT") {
+			env = append(env, v)
+		}
+	}
+
+	slices.Sort(env)
+	return env
+}
+
+// buildFingerprint hashes what the build of dist depends on: the go
+// version, the build command and environment, and the files of every
+// package it compiles. Dependencies from the module cache are identified by
+// their version since their files cannot change.
+func buildFingerprint(ctx context.Context, config builder.BuildConfig, dist builder.GoDist) (string, error) {
+	env := config.BuildEnv(dist)
+	h := sha256.New()
+
+	version := goCommand(ctx, config, "env", "GOVERSION")
+	version.Env = append(version.Env, env...)
+
+	res, err := version.Output()
+	if err != nil {
+		return "", fmt.Errorf("go env: %w", err)
+	}
+
+	// the cache does not change what is built
+	settings := slices.DeleteFunc(slices.Clone(env), func(v string) bool {
+		return strings.HasPrefix(v, "GOCACHE=") || strings.HasPrefix(v, "GOCACHEPROG=")
+	})
+
+	name, args := config.BuildCommand(dist, builder.OutputPath(config, dist))
+	fmt.Fprintf(h, "%s\n%s %q\n%q\n%q\n", res, name, args, settings, fingerprintEnv())
+
+	if config.Builder == "docker" {
+		fmt.Fprintf(h, "docker %s\n", config.DockerImageFor(dist, slices.Contains(env, "CGO_ENABLED=1")))
+	} else if config.IsSSH() {
+		fmt.Fprintf(h, "%s\n", config.Builder)
+	}
+
+	if profile := config.PGOFor(dist); profile != "" && profile != "off" {
+		if profile == "auto" {
+			profile = filepath.Join(config.ProjectDir, config.MainPackage(), "default.pgo")
+		}
+		hashFile(h, profile)
+	}
+
+	listArgs := []string{"list", "-deps", "-json=Dir,Standard,Module,GoFiles,CgoFiles,CFiles,CXXFiles,MFiles,HFiles,SFiles,SysoFiles,EmbedFiles"}
+	if len(config.Tags) > 0 {
+		listArgs = append(listArgs, "-tags", strings.Join(config.Tags, ","))
+	}
+	if config.ModMode != "" {
+		listArgs = append(listArgs, "-mod", config.ModMode)
+	}
+
+	list := goCommand(ctx, config, append(listArgs, config.MainPackage())...)
+	list.Env = append(list.Env, env...)
+
+	out, err := list.Output()
+	if err != nil {
+		return "", fmt.Errorf("go list: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		pkg := listedPackage{}
+		if err := dec.Decode(&pkg); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return "", err
+		}
+
+		if pkg.Standard {
+			continue
+		}
+
+		if pkg.Module != nil && pkg.Module.Version != "" && pkg.Module.Replace == nil {
+			fmt.Fprintf(h, "%s@%s\n", pkg.Module.Path, pkg.Module.Version)
+			continue
+		}
+
+		if pkg.Module != nil && pkg.Module.GoMod != "" {
+			hashFile(h, pkg.Module.GoMod)
+		}
+
+		files := slices.Concat(pkg.GoFiles, pkg.CgoFiles, pkg.CFiles, pkg.CXXFiles, pkg.MFiles, pkg.HFiles, pkg.SFiles, pkg.SysoFiles, pkg.EmbedFiles)
+		for _, file := range files {
+			hashFile(h, filepath.Join(pkg.Dir, file))
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashFile writes the path and contents of fp to w. A missing file only
+// contributes its path.
+func hashFile(w io.Writer, fp string) {
+	fmt.Fprintln(w, fp)
+
+	if f, err := os.Open(fp); err == nil {
+		io.Copy(w, f)
+		f.Close()
+	}
+}
+
+// stampPath returns where the fingerprint of the artifact at fp is kept,
+// in the user cache directory so the output directory only holds artifacts.
+func stampPath(fp string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	abs, err := filepath.Abs(fp)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(cacheDir, "go-builder", "stamps", hex.EncodeToString(sum[:16])), nil
+}
+
+// upToDate reports whether the artifact at fp exists unchanged since it was
+// stamped with fingerprint.
+func upToDate(fp string, fingerprint string) bool {
+	stamp, err := stampPath(fp)
+	if err != nil {
+		return false
+	}
+
+	raw, err := os.ReadFile(stamp)
+	if err != nil {
+		return false
+	}
+
+	sum, err := sha256File(fp)
+	if err != nil {
+		return false
+	}
+
+	return string(raw) == fingerprint+" "+sum+"\n"
+}
+
+// writeStamp records that the artifact at fp was built with fingerprint.
+func writeStamp(fp string, fingerprint string) error {
+	stamp, err := stampPath(fp)
+	if err != nil {
+		return err
+	}
+
+	sum, err := sha256File(fp)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(stamp), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(stamp, []byte(fingerprint+" "+sum+"\n"), 0o644)
+}
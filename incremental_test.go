@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestBuildFingerprint(t *testing.T) {
+	t.Setenv("GOFLAGS", "")
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":       "module example.com/inc\n\ngo 1.21\n",
+		"main.go":      "package main\n\nimport _ \"example.com/inc/lib\"\n\nfunc main() {}\n",
+		"lib/lib.go":   "package lib\n",
+		"lib/extra.go": "//go:build extra\n\npackage lib\n",
+		"README.md":    "readme\n",
+	})
+
+	config := builder.NewConfig()
+	config.ProjectDir = dir
+	config.OutputDir = filepath.Join(dir, "build")
+	config.BinaryName = "inc"
+
+	dist := builder.GoDist{GOOS: "linux", GOARCH: "amd64"}
+
+	fingerprint := func(config builder.BuildConfig, dist builder.GoDist) string {
+		t.Helper()
+		fp, err := buildFingerprint(context.Background(), config, dist)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fp
+	}
+
+	base := fingerprint(config, dist)
+
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("changed\n"), 0o644)
+	if fingerprint(config, dist) != base {
+		t.Logf("Unrelated file changed the fingerprint\n")
+		t.Fail()
+	}
+
+	os.WriteFile(filepath.Join(dir, "lib", "extra.go"), []byte("//go:build extra\n\npackage lib\n\nvar X = 1\n"), 0o644)
+	if fingerprint(config, dist) != base {
+		t.Logf("Excluded file changed the fingerprint\n")
+		t.Fail()
+	}
+
+	tagged := config
+	tagged.Tags = []string{"extra"}
+	if fingerprint(tagged, dist) == base {
+		t.Logf("Tags did not change the fingerprint\n")
+		t.Fail()
+	}
+
+	if fingerprint(config, builder.GoDist{GOOS: "linux", GOARCH: "arm64"}) == base {
+		t.Logf("Target did not change the fingerprint\n")
+		t.Fail()
+	}
+
+	os.WriteFile(filepath.Join(dir, "lib", "lib.go"), []byte("package lib\n\nvar Y = 2\n"), 0o644)
+	if fingerprint(config, dist) == base {
+		t.Logf("Dependency source did not change the fingerprint\n")
+		t.Fail()
+	}
+}
+
+func TestUpToDate(t *testing.T) {
+	cache := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cache)
+	t.Setenv("HOME", cache)
+	t.Setenv("LocalAppData", cache)
+
+	fp := filepath.Join(t.TempDir(), "app-linux_amd64")
+	os.WriteFile(fp, []byte("binary"), 0o755)
+
+	if upToDate(fp, "abc") {
+		t.Logf("Unstamped artifact was up to date\n")
+		t.Fail()
+	}
+
+	if err := writeStamp(fp, "abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !upToDate(fp, "abc") {
+		t.Logf("Stamped artifact was not up to date\n")
+		t.Fail()
+	}
+
+	if upToDate(fp, "def") {
+		t.Logf("Artifact with another fingerprint was up to date\n")
+		t.Fail()
+	}
+
+	os.WriteFile(fp, []byte("modified"), 0o755)
+	if upToDate(fp, "abc") {
+		t.Logf("Modified artifact was up to date\n")
+		t.Fail()
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+// installScriptArch maps uname -m values to GOARCH, and
+// PROCESSOR_ARCHITECTURE values for the PowerShell script.
+var installScriptArch = [][2]string{
+	{"x86_64|amd64", "amd64"},
+	{"aarch64|arm64", "arm64"},
+	{"i386|i686", "386"},
+	{"armv6*|armv7*", "arm"},
+	{"ppc64le", "ppc64le"},
+	{"riscv64", "riscv64"},
+	{"s390x", "s390x"},
+}
+
+var installScriptWindowsArch = [][2]string{
+	{"AMD64", "amd64"},
+	{"ARM64", "arm64"},
+	{"x86", "386"},
+}
+
+type installBuild struct {
+	Dist   builder.GoDist
+	URL    string
+	SHA256 string
+}
+
+// installBuilds returns the download URL and checksum of every executable a
+// script can select by OS and architecture alone, i.e. without a
+// sub-architecture, by binary name. Each binary name, e.g. of a variant or
+// another command, gets its own script so every os/arch has one build.
+func installBuilds(jobs []buildJob, tag string, downloadURL func(tag, file string) string) (map[string][]installBuild, error) {
+	builds := map[string][]installBuild{}
+
+	for _, job := range jobs {
+		if !job.Distributable() || job.Dist.SubArch != "" {
+			continue
+		}
+
+		name := job.Config.BinaryName
+		if slices.ContainsFunc(builds[name], func(b installBuild) bool { return b.Dist == job.Dist }) {
+			continue
+		}
+
+		fp := builder.OutputPath(job.Config, job.Dist)
+
+		sum, err := sha256File(fp)
+		if err != nil {
+			return nil, err
+		}
+
+		builds[name] = append(builds[name], installBuild{Dist: job.Dist, URL: downloadURL(tag, filepath.Base(fp)), SHA256: sum})
+	}
+
+	return builds, nil
+}
+
+// installScriptPath returns where the install script of binary is written:
+// install.sh for the project's binary and e.g. install-app-lite.sh for the
+// others.
+func installScriptPath(config builder.BuildConfig, binary string, ext string) string {
+	if binary == config.BinaryName {
+		return filepath.Join(config.OutputDir, "install"+ext)
+	}
+
+	return filepath.Join(config.OutputDir, "install-"+binary+ext)
+}
+
+// installSh returns a POSIX shell script installing the binary for the
+// machine it runs on.
+func installSh(binary string, tag string, builds []installBuild) string {
+	script := strings.Builder{}
+
+	fmt.Fprintf(&script, `#!/bin/sh
+# Installs %[1]s %[2]s. Set BINDIR to change the install directory.
+set -eu
+
+BINDIR="${BINDIR:-/usr/local/bin}"
+
+os=$(uname -s | tr '[:upper:]' '[:lower:]')
+arch=$(uname -m)
+
+case "$os" in
+  mingw*|msys*|cygwin*) os=windows ;;
+esac
+
+case "$arch" in
+`, binary, tag)
+
+	for _, arch := range installScriptArch {
+		fmt.Fprintf(&script, "  %s) arch=%s ;;\n", arch[0], arch[1])
+	}
+
+	script.WriteString("esac\n\ncase \"$os/$arch\" in\n")
+
+	for _, build := range builds {
+		if build.Dist.GOOS == "windows" || build.Dist.GOOS == "js" || build.Dist.GOOS == "wasip1" {
+			continue
+		}
+
+		fmt.Fprintf(&script, "  %s/%s)\n    url=%s\n    sum=%s\n    ;;\n",
+			build.Dist.GOOS, build.Dist.GOARCH, bashString(build.URL), build.SHA256)
+	}
+
+	fmt.Fprintf(&script, `  *)
+    echo "%[1]s: no build for $os/$arch" >&2
+    exit 1
+    ;;
+esac
+
+tmp=$(mktemp -d)
+trap 'rm -rf "$tmp"' EXIT
+
+if command -v curl >/dev/null 2>&1; then
+  curl -fsSL "$url" -o "$tmp/%[1]s"
+else
+  wget -qO "$tmp/%[1]s" "$url"
+fi
+
+if command -v sha256sum >/dev/null 2>&1; then
+  got=$(sha256sum "$tmp/%[1]s" | cut -d ' ' -f 1)
+else
+  got=$(shasum -a 256 "$tmp/%[1]s" | cut -d ' ' -f 1)
+fi
+
+if [ "$got" != "$sum" ]; then
+  echo "%[1]s: checksum mismatch, expected $sum got $got" >&2
+  exit 1
+fi
+
+chmod +x "$tmp/%[1]s"
+
+if [ -w "$BINDIR" ]; then
+  mv "$tmp/%[1]s" "$BINDIR/%[1]s"
+else
+  sudo mv "$tmp/%[1]s" "$BINDIR/%[1]s"
+fi
+
+echo "Installed %[1]s %[2]s to $BINDIR/%[1]s"
+`, binary, tag)
+
+	return script.String()
+}
+
+// installPs1 returns a PowerShell script installing the windows binary into
+// the user's programs directory and adding it to the user PATH.
+func installPs1(binary string, tag string, builds []installBuild) string {
+	script := strings.Builder{}
+
+	fmt.Fprintf(&script, `# Installs %[1]s %[2]s. Set INSTALL_DIR to change the install directory.
+$ErrorActionPreference = 'Stop'
+
+$arch = switch ($env:PROCESSOR_ARCHITECTURE) {
+`, binary, tag)
+
+	for _, arch := range installScriptWindowsArch {
+		fmt.Fprintf(&script, "  '%s' { '%s' }\n", arch[0], arch[1])
+	}
+
+	script.WriteString("}\n\n$builds = @{\n")
+
+	for _, build := range builds {
+		if build.Dist.GOOS != "windows" {
+			continue
+		}
+
+		fmt.Fprintf(&script, "  '%s' = @{ Url = '%s'; Sha256 = '%s' }\n",
+			build.Dist.GOARCH, strings.ReplaceAll(build.URL, "'", "''"), build.SHA256)
+	}
+
+	fmt.Fprintf(&script, `}
+
+$build = $builds[$arch]
+if (-not $build) { throw "%[1]s: no build for windows/$arch" }
+
+$dir = if ($env:INSTALL_DIR) { $env:INSTALL_DIR } else { Join-Path $env:LOCALAPPDATA 'Programs\%[1]s' }
+New-Item -ItemType Directory -Force -Path $dir | Out-Null
+
+$tmp = [IO.Path]::GetTempFileName()
+Invoke-WebRequest -UseBasicParsing -Uri $build.Url -OutFile $tmp
+
+$hash = (Get-FileHash -Algorithm SHA256 $tmp).Hash
+if ($hash -ne $build.Sha256) {
+  Remove-Item $tmp
+  throw "%[1]s: checksum mismatch, expected $($build.Sha256) got $hash"
+}
+
+$dest = Join-Path $dir '%[1]s.exe'
+Move-Item -Force $tmp $dest
+
+$path = [Environment]::GetEnvironmentVariable('Path', 'User')
+if (($path -split ';') -notcontains $dir) {
+  [Environment]::SetEnvironmentVariable('Path', "$path;$dir", 'User')
+}
+
+Write-Host "Installed %[1]s %[2]s to $dest"
+`, binary, tag)
+
+	return script.String()
+}
+
+// writeInstallScripts writes an install.sh, and install.ps1 when windows was
+// built, for every binary name to the output directory and returns their
+// paths.
+func writeInstallScripts(config builder.BuildConfig, jobs []buildJob, tag string, downloadURL func(tag, file string) string) ([]string, error) {
+	builds, err := installBuilds(jobs, tag, downloadURL)
+	if err != nil {
+		return nil, err
+	}
+
+	scripts := []string{}
+
+	for _, binary := range slices.Sorted(maps.Keys(builds)) {
+		sh := installScriptPath(config, binary, ".sh")
+		if err := os.WriteFile(sh, []byte(installSh(binary, tag, builds[binary])), 0o755); err != nil {
+			return scripts, err
+		}
+		scripts = append(scripts, sh)
+
+		if !slices.ContainsFunc(builds[binary], func(b installBuild) bool { return b.Dist.GOOS == "windows" }) {
+			continue
+		}
+
+		ps1 := installScriptPath(config, binary, ".ps1")
+		if err := os.WriteFile(ps1, []byte(installPs1(binary, tag, builds[binary])), 0o644); err != nil {
+			return scripts, err
+		}
+		scripts = append(scripts, ps1)
+	}
+
+	return scripts, nil
+}
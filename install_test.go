@@ -0,0 +1,158 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestWriteInstallScripts(t *testing.T) {
+	dir := t.TempDir()
+	config := builder.NewConfig()
+	config.OutputDir = dir
+	config.BinaryName = "app"
+
+	jobs := []buildJob{
+		{Config: config, Dist: builder.GoDist{GOOS: "linux", GOARCH: "amd64"}},
+		{Config: config, Dist: builder.GoDist{GOOS: "linux", GOARCH: "arm", SubArch: "6"}},
+		{Config: config, Dist: builder.GoDist{GOOS: "windows", GOARCH: "arm64"}},
+	}
+
+	for _, job := range jobs {
+		os.WriteFile(builder.OutputPath(job.Config, job.Dist), []byte(job.Dist.String()), 0o755)
+	}
+
+	scripts, err := writeInstallScripts(config, jobs, "v1.2.3", func(tag string, file string) string {
+		return expandReleaseURL("https://dl.example.com/{tag}/{file}", tag, file)
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(scripts) != 2 {
+		t.Fatalf("Expected install.sh and install.ps1, got: %v\n", scripts)
+	}
+
+	sh, _ := os.ReadFile(scripts[0])
+	ps1, _ := os.ReadFile(scripts[1])
+	linuxSum, _ := sha256File(filepath.Join(dir, "app-linux_amd64"))
+
+	if !strings.Contains(string(sh), "  linux/amd64)\n    url='https://dl.example.com/v1.2.3/app-linux_amd64'\n    sum="+linuxSum+"\n") ||
+		strings.Contains(string(sh), "linux/arm)") || strings.Contains(string(sh), "windows/arm64)") {
+		t.Logf("Incorrect install.sh:\n%s\n", sh)
+		t.Fail()
+	}
+
+	if !strings.Contains(string(ps1), "  'arm64' = @{ Url = 'https://dl.example.com/v1.2.3/app-windows_arm64.exe'; Sha256 = '") {
+		t.Logf("Incorrect install.ps1:\n%s\n", ps1)
+		t.Fail()
+	}
+
+	if shell, err := exec.LookPath("sh"); err == nil {
+		if res, err := exec.Command(shell, "-n", scripts[0]).CombinedOutput(); err != nil {
+			t.Logf("install.sh has a syntax error: %v\n%s\n", err, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestWriteInstallScriptsPerBinary(t *testing.T) {
+	config := builder.NewConfig()
+	config.BinaryName = "app"
+
+	lite := config
+	lite.BinaryName = "app-lite"
+
+	linux := builder.GoDist{GOOS: "linux", GOARCH: "amd64"}
+	windows := builder.GoDist{GOOS: "windows", GOARCH: "amd64"}
+
+	testCases := []struct {
+		name  string
+		jobs  []buildJob
+		wants map[string]string
+	}{
+		{
+			name: "variant",
+			jobs: []buildJob{
+				{Config: config, Dist: linux},
+				{Config: lite, Dist: linux},
+				{Config: lite, Dist: windows},
+			},
+			wants: map[string]string{
+				"install.sh":           "app",
+				"install-app-lite.sh":  "app-lite",
+				"install-app-lite.ps1": "app-lite",
+			},
+		},
+		{
+			name: "same build twice",
+			jobs: []buildJob{
+				{Config: config, Dist: linux},
+				{Config: config, Dist: linux},
+			},
+			wants: map[string]string{"install.sh": "app"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			config := config
+			config.OutputDir = dir
+
+			jobs := []buildJob{}
+			for _, job := range tc.jobs {
+				job.Config.OutputDir = dir
+				if err := os.WriteFile(builder.OutputPath(job.Config, job.Dist), []byte(job.Config.BinaryName), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				jobs = append(jobs, job)
+			}
+
+			scripts, err := writeInstallScripts(config, jobs, "v1.2.3", func(tag string, file string) string {
+				return "https://dl.example.com/" + tag + "/" + file
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res := []string{}
+			for _, fp := range scripts {
+				res = append(res, filepath.Base(fp))
+			}
+			if !slices.Equal(slices.Sorted(slices.Values(res)), slices.Sorted(maps.Keys(tc.wants))) {
+				t.Fatalf("Incorrect scripts, wanted: %v got: %v\n", slices.Sorted(maps.Keys(tc.wants)), res)
+			}
+
+			for name, binary := range tc.wants {
+				script, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if strings.HasSuffix(name, ".sh") {
+					if n := strings.Count(string(script), "  linux/amd64)\n"); n != 1 {
+						t.Logf("Incorrect linux/amd64 arms in %s, wanted: 1 got: %d\n", name, n)
+						t.Fail()
+					}
+
+					wants := "url='https://dl.example.com/v1.2.3/" + binary + "-linux_amd64'"
+					if !strings.Contains(string(script), wants) || !strings.Contains(string(script), `"$BINDIR/`+binary+`"`) {
+						t.Logf("Incorrect %s, wanted %s installed from: %s\n%s\n", name, binary, wants, script)
+						t.Fail()
+					}
+				} else if !strings.Contains(string(script), "Join-Path $dir '"+binary+".exe'") || strings.Count(string(script), "'amd64' = @{") != 1 {
+					t.Logf("Incorrect %s for %s:\n%s\n", name, binary, script)
+					t.Fail()
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// interruptContext returns a context cancelled by the first SIGINT or
+// SIGTERM. Later signals are no longer trapped, so a second Ctrl-C kills the
+// process right away.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	return ctx, stop
+}
+
+// removePartialOutput deletes fp when it was written at or after started,
+// which for a cancelled build means a half-written artifact. An older file
+// is the result of a previous build and is kept.
+func removePartialOutput(fp string, started time.Time) bool {
+	info, err := os.Stat(fp)
+	if err != nil || info.ModTime().Before(started) {
+		return false
+	}
+
+	return os.Remove(fp) == nil
+}
+
+// writeCancelSummary lists which builds finished and which were stopped,
+// after the reason the run was stopped for.
+func writeCancelSummary(w io.Writer, reason string, completed, failed, cancelled []string, colors palette) {
+	fmt.Fprintf(w, "%s: %d completed, %d failed, %d cancelled\n", reason, len(completed), len(failed), len(cancelled))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TARGET\tSTATUS")
+
+	for _, name := range completed {
+		fmt.Fprintf(tw, "%s\t%s\n", name, colors.status("completed"))
+	}
+
+	for _, name := range failed {
+		fmt.Fprintf(tw, "%s\t%s\n", name, colors.status("failed"))
+	}
+
+	for _, name := range cancelled {
+		fmt.Fprintf(tw, "%s\t%s\n", name, colors.status("cancelled"))
+	}
+
+	tw.Flush()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRemovePartialOutput(t *testing.T) {
+	dir := t.TempDir()
+	previous := filepath.Join(dir, "app-linux_amd64")
+	partial := filepath.Join(dir, "app-windows_amd64.exe")
+
+	started := time.Now().Add(-time.Second)
+	writeFiles(t, dir, map[string]string{
+		"app-linux_amd64":       "built earlier",
+		"app-windows_amd64.exe": "half written",
+	})
+
+	os.Chtimes(previous, started.Add(-time.Hour), started.Add(-time.Hour))
+
+	if removePartialOutput(previous, started) {
+		t.Logf("Removed the output of a previous build\n")
+		t.Fail()
+	}
+
+	if !removePartialOutput(partial, started) {
+		t.Logf("Partial output was not removed\n")
+		t.Fail()
+	}
+
+	if _, err := os.Stat(partial); err == nil {
+		t.Logf("Partial output still exists\n")
+		t.Fail()
+	}
+
+	if removePartialOutput(filepath.Join(dir, "missing"), started) {
+		t.Logf("Removed a missing file\n")
+		t.Fail()
+	}
+}
+
+func TestWriteCancelSummary(t *testing.T) {
+	out := strings.Builder{}
+	writeCancelSummary(&out, "Interrupted", []string{"app-linux_amd64"}, []string{"app-js_wasm.wasm"}, []string{"app-windows_amd64.exe"}, palette{})
+
+	wants := "Interrupted: 1 completed, 1 failed, 1 cancelled\n" +
+		"TARGET                 STATUS\n" +
+		"app-linux_amd64        completed\n" +
+		"app-js_wasm.wasm       failed\n" +
+		"app-windows_amd64.exe  cancelled\n"
+
+	if out.String() != wants {
+		t.Logf("Incorrect summary, wanted:\n%s\ngot:\n%s\n", wants, out.String())
+		t.Fail()
+	}
+}
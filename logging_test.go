@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewLogger(t *testing.T) {
+	out := strings.Builder{}
+	logger, err := newLogger(&out, "json", slog.LevelInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Debug("build output", "target", "linux/amd64", "output", "")
+	logger.Info("build", "target", "linux/amd64", "duration", 1500*time.Millisecond)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Incorrect records, wanted 1 got:\n%s\n", out.String())
+	}
+
+	record := map[string]any{}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatal(err)
+	}
+
+	if record["msg"] != "build" || record["target"] != "linux/amd64" || record["duration"] != float64(1500*time.Millisecond) {
+		t.Logf("Incorrect record: %v\n", record)
+		t.Fail()
+	}
+
+	out.Reset()
+	logger, _ = newLogger(&out, "text", slog.LevelDebug)
+	logger.Debug("project", "dir", "/src/app")
+
+	if !strings.Contains(out.String(), "level=DEBUG msg=project dir=/src/app") {
+		t.Logf("Incorrect text record: %s\n", out.String())
+		t.Fail()
+	}
+
+	if _, err := newLogger(&out, "xml", slog.LevelInfo); !errors.Is(err, ErrInvalidLogFormat) {
+		t.Logf("Incorrect error, wanted: %v got: %v\n", ErrInvalidLogFormat, err)
+		t.Fail()
+	}
+}
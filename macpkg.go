@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var (
+	ErrMissingPkgIdentifier = errors.New("macos pkg needs an identifier")
+	ErrNotarization         = errors.New("notarization was not accepted")
+)
+
+// MacPkgConfig describes the installer packages built from the darwin
+// binaries with pkgbuild. Universal binaries are packaged when they were
+// built, otherwise one package is built per architecture.
+type MacPkgConfig struct {
+	// Identifier is the package id, e.g. com.example.app.
+	Identifier string `json:"identifier"`
+	// InstallLocation defaults to /usr/local/bin.
+	InstallLocation string `json:"install_location"`
+	// SignIdentity is a "Developer ID Installer" identity in the keychain.
+	SignIdentity string         `json:"sign_identity"`
+	Notarize     NotarizeConfig `json:"notarize"`
+}
+
+func (m MacPkgConfig) IsEmpty() bool {
+	return m == MacPkgConfig{}
+}
+
+// NotarizeConfig holds the notarytool credentials: either a keychain profile
+// saved with `notarytool store-credentials`, or an Apple ID and team whose
+// app-specific password is read from APPLE_APP_PASSWORD.
+type NotarizeConfig struct {
+	KeychainProfile string `json:"keychain_profile"`
+	AppleID         string `json:"apple_id"`
+	TeamID          string `json:"team_id"`
+}
+
+func (n NotarizeConfig) IsEmpty() bool {
+	return n == NotarizeConfig{}
+}
+
+// notarytoolAuth returns the credential arguments for notarytool.
+func (n NotarizeConfig) notarytoolAuth() ([]string, error) {
+	if n.KeychainProfile != "" {
+		return []string{"--keychain-profile", n.KeychainProfile}, nil
+	}
+
+	password, err := tokenFromEnv("APPLE_APP_PASSWORD")
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{"--apple-id", n.AppleID, "--team-id", n.TeamID, "--password", password}, nil
+}
+
+// pkgbuildArgs returns the pkgbuild arguments packaging the root directory.
+func pkgbuildArgs(pkg MacPkgConfig, root string, version string, out string) []string {
+	location := pkg.InstallLocation
+	if location == "" {
+		location = "/usr/local/bin"
+	}
+
+	args := []string{"--root", root, "--identifier", pkg.Identifier, "--version", strings.TrimPrefix(version, "v"), "--install-location", location}
+	if pkg.SignIdentity != "" {
+		args = append(args, "--sign", pkg.SignIdentity)
+	}
+
+	return append(args, out)
+}
+
+// buildMacPkgs builds, and when configured notarizes and staples, an
+// installer package for the darwin binaries and returns the packages.
+func buildMacPkgs(ctx context.Context, config builder.BuildConfig, pkg MacPkgConfig, jobs []buildJob, universal []string, version string) ([]string, error) {
+	if pkg.Identifier == "" {
+		return nil, ErrMissingPkgIdentifier
+	}
+
+	binaries := universal
+	if len(binaries) == 0 {
+		for _, job := range jobs {
+			if job.Dist.GOOS == "darwin" && job.Distributable() {
+				binaries = append(binaries, builder.OutputPath(job.Config, job.Dist))
+			}
+		}
+	}
+
+	pkgs := []string{}
+
+	for _, binary := range binaries {
+		fp, err := buildMacPkg(ctx, config, pkg, binary, version)
+		if err != nil {
+			return pkgs, fmt.Errorf("%s: %w", filepath.Base(binary), err)
+		}
+
+		pkgs = append(pkgs, fp)
+	}
+
+	return pkgs, nil
+}
+
+func buildMacPkg(ctx context.Context, config builder.BuildConfig, pkg MacPkgConfig, binary string, version string) (string, error) {
+	root, err := os.MkdirTemp("", "gobuilder-pkg")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(root)
+
+	contents, err := os.ReadFile(binary)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(filepath.Join(root, config.BinaryName), contents, 0o755); err != nil {
+		return "", err
+	}
+
+	fp := binary + ".pkg"
+
+	if res, err := exec.CommandContext(ctx, "pkgbuild", pkgbuildArgs(pkg, root, version, fp)...).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("pkgbuild: %w\n%s", err, res)
+	}
+
+	if pkg.Notarize.IsEmpty() {
+		return fp, nil
+	}
+
+	if err := notarize(ctx, pkg.Notarize, fp); err != nil {
+		return "", err
+	}
+
+	if res, err := exec.CommandContext(ctx, "xcrun", "stapler", "staple", fp).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("stapler: %w\n%s", err, res)
+	}
+
+	return fp, nil
+}
+
+// notarize submits the file to Apple's notary service and waits for the
+// result.
+func notarize(ctx context.Context, n NotarizeConfig, fp string) error {
+	auth, err := n.notarytoolAuth()
+	if err != nil {
+		return err
+	}
+
+	args := append([]string{"notarytool", "submit", fp, "--wait", "--output-format", "json"}, auth...)
+
+	res, err := exec.CommandContext(ctx, "xcrun", args...).Output()
+	if err != nil {
+		return fmt.Errorf("notarytool: %w\n%s", err, res)
+	}
+
+	var submission struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+
+	if err := json.Unmarshal(res, &submission); err != nil {
+		return fmt.Errorf("notarytool: %w", err)
+	}
+
+	if submission.Status != "Accepted" {
+		return fmt.Errorf("%w: %s (see xcrun notarytool log %s)", ErrNotarization, submission.Status, submission.ID)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestPkgbuildArgs(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input MacPkgConfig
+		wants []string
+	}{
+		{
+			name:  "unsigned",
+			input: MacPkgConfig{Identifier: "com.example.app"},
+			wants: []string{"--root", "/tmp/root", "--identifier", "com.example.app", "--version", "1.2.3", "--install-location", "/usr/local/bin", "app.pkg"},
+		},
+		{
+			name:  "signed",
+			input: MacPkgConfig{Identifier: "com.example.app", InstallLocation: "/opt/app", SignIdentity: "Developer ID Installer: Acme"},
+			wants: []string{"--root", "/tmp/root", "--identifier", "com.example.app", "--version", "1.2.3", "--install-location", "/opt/app",
+				"--sign", "Developer ID Installer: Acme", "app.pkg"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := pkgbuildArgs(tc.input, "/tmp/root", "v1.2.3", "app.pkg")
+
+			if !slices.Equal(res, tc.wants) {
+				t.Logf("Incorrect args, wanted: %v got: %v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestNotarytoolAuth(t *testing.T) {
+	got, err := NotarizeConfig{KeychainProfile: "release"}.notarytoolAuth()
+	if err != nil || !slices.Equal(got, []string{"--keychain-profile", "release"}) {
+		t.Logf("Incorrect keychain auth, got: %v %v\n", got, err)
+		t.Fail()
+	}
+
+	t.Setenv("APPLE_APP_PASSWORD", "")
+	if _, err := (NotarizeConfig{AppleID: "dev@example.com", TeamID: "ABC123"}).notarytoolAuth(); !errors.Is(err, ErrMissingToken) {
+		t.Logf("Expected missing token error, got: %v\n", err)
+		t.Fail()
+	}
+
+	t.Setenv("APPLE_APP_PASSWORD", "secret")
+	got, err = NotarizeConfig{AppleID: "dev@example.com", TeamID: "ABC123"}.notarytoolAuth()
+	wants := []string{"--apple-id", "dev@example.com", "--team-id", "ABC123", "--password", "secret"}
+	if err != nil || !slices.Equal(got, wants) {
+		t.Logf("Incorrect apple id auth, wanted: %v got: %v %v\n", wants, got, err)
+		t.Fail()
+	}
+}
@@ -2,218 +2,430 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"runtime/debug"
+	"slices"
 	"strings"
 	"sync"
-)
+	"time"
 
-var (
-	ErrInvalidOSARCH           = errors.New("invalid os/arch configuration")
-	ErrUnsupportedTargetOSARCH = errors.New("unable to find go dist to support target os/arch combination(s)")
-	ErrFailedBuildCommand      = errors.New("unable to build target")
+	"github.com/jrstaple/go-builder/pkg/builder"
 )
 
 var VERBOSE bool
 
-type OSARCH struct {
-	OS   string
-	ARCH string
-}
+var (
+	ErrAllWithTargets    = errors.New("-all builds every target, only negated targets such as !windows can be combined with it")
+	ErrNegatedExclude    = errors.New("negation only applies to -target, exclude the target instead")
+	ErrPGOOutsideProject = errors.New("docker, ssh and worker builds only read pgo profiles inside the project directory")
+)
 
-func NewOSARCH() OSARCH {
-	return OSARCH{"", ""}
+// buildJob is a single go build invocation: one config for one dist.
+type buildJob struct {
+	Config builder.BuildConfig
+	Dist   builder.GoDist
 }
 
-type GoDist struct {
-	GOOS         string `json:"GOOS"`
-	GOARCH       string `json:"GOARCH"`
-	CgoSupported bool   `json:"CgoSupported"`
-	FirstClass   bool   `json:"FirstClass"`
+// Distributable reports whether the job builds a plain executable meant for
+// users, as opposed to a race-enabled or library build.
+func (j buildJob) Distributable() bool {
+	mode := j.Config.BuildModeFor(j.Dist)
+	return !j.Config.Race && (mode == "" || mode == "exe" || mode == "pie")
 }
 
-type BuildConfig struct {
-	ProjectDir string
-	OutputDir  string
-	BinaryName string
-	Targets    []OSARCH
+// builtinAliases are the target groups available without any config file.
+var builtinAliases = map[string][]string{
+	"desktop": {"windows/amd64", "darwin/amd64", "darwin/arm64", "linux/amd64"},
+	"server":  {"linux/amd64", "linux/arm64", "freebsd/amd64"},
+	"mobile":  {"android/arm64", "ios/arm64"},
+	"bsd":     {"freebsd", "openbsd", "netbsd", "dragonfly"},
 }
 
-func (d GoDist) GOOSEnv() string {
-	return fmt.Sprintf("GOOS=%s", d.GOOS)
-}
+// expandTargetAliases replaces any group names in rawTargets with the targets
+// they stand for, negated for a negated group. Aliases from the config take
+// precedence over the built-in ones and may refer to other aliases.
+func expandTargetAliases(rawTargets []string, aliases map[string][]string) []string {
+	expanded := []string{}
 
-func (d GoDist) GOARCHEnv() string {
-	return fmt.Sprintf("GOARCH=%s", d.GOARCH)
-}
+	var expand func(v string, seen map[string]bool)
+	expand = func(v string, seen map[string]bool) {
+		name := strings.ToLower(v)
 
-func NewConfig() BuildConfig {
-	return BuildConfig{
-		ProjectDir: "./",
-		OutputDir:  "./build",
-		BinaryName: "build",
-		Targets:    []OSARCH{},
-	}
-}
+		group, ok := aliases[name]
+		if !ok {
+			group, ok = builtinAliases[name]
+		}
 
-func getTargetBuilds(targets []OSARCH, allDists []GoDist) []GoDist {
+		if !ok || seen[name] {
+			expanded = append(expanded, v)
+			return
+		}
 
-	if len(targets) == 0 {
-		return allDists
+		seen[name] = true
+		for _, member := range group {
+			expand(member, seen)
+		}
+		delete(seen, name)
 	}
-	targetDists := []GoDist{}
-
-	for _, target := range targets {
-		for _, dist := range allDists {
-			if target.ARCH == "" {
-				if target.OS == dist.GOOS {
-					targetDists = append(targetDists, dist)
-				}
-			} else {
-				if target.OS == dist.GOOS && target.ARCH == dist.GOARCH {
-					targetDists = append(targetDists, dist)
-				}
 
+	for _, v := range rawTargets {
+		// a negated group negates each of its targets
+		if negated, ok := strings.CutPrefix(v, "!"); ok {
+			start := len(expanded)
+			expand(negated, map[string]bool{})
+			for i := start; i < len(expanded); i++ {
+				expanded[i] = "!" + expanded[i]
 			}
+			continue
 		}
+
+		expand(v, map[string]bool{})
 	}
 
-	return targetDists
+	return expanded
 }
 
-func getBuildOptions(ctx context.Context, targets []OSARCH) ([]GoDist, error) {
-	cmd := exec.CommandContext(ctx, "go", "tool", "dist", "list", "-json")
-
-	rawJson, err := cmd.Output()
-
-	if err != nil {
-		return []GoDist{}, fmt.Errorf("dist: %w", err)
+// defaultTargets returns the host platform when no targets are selected and
+// neither -all nor -interactive picks them, and nil when the selection, e.g.
+// every target but the negated ones, stands as given.
+func defaultTargets(rawTargets []string, all bool, interactive bool) []builder.OSARCH {
+	if len(rawTargets) > 0 || all || interactive {
+		return nil
 	}
 
-	var supportedDists []GoDist
-	if err := json.Unmarshal(rawJson, &supportedDists); err != nil {
-		return nil, fmt.Errorf("json parse: %w", err)
+	return []builder.OSARCH{{OS: runtime.GOOS, ARCH: runtime.GOARCH}}
+}
+
+// projectPGOProfile rebases an absolute pgo profile onto the project for
+// builders that run go build in their own copy of it: the docker mount, the
+// ssh sync or a worker's unpacked source. Relative profiles, auto and off
+// are left as they are.
+func projectPGOProfile(profile string, projectDir string) (string, error) {
+	if !filepath.IsAbs(profile) {
+		return profile, nil
 	}
 
-	if len(targets) == 0 {
-		return supportedDists, nil
+	rel, err := filepath.Rel(projectDir, profile)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s", ErrPGOOutsideProject, profile)
 	}
 
-	targetDists := getTargetBuilds(targets, supportedDists)
+	return filepath.ToSlash(rel), nil
+}
 
-	if len(targetDists) > 0 {
-		return targetDists, nil
-	} else {
-		return []GoDist{}, ErrUnsupportedTargetOSARCH
+// selectsAll reports whether the targets start from every target: they
+// include all or begin with a negation such as !windows.
+func selectsAll(rawTargets []string) bool {
+	if len(rawTargets) > 0 && strings.HasPrefix(rawTargets[0], "!") {
+		return true
 	}
+
+	return slices.ContainsFunc(rawTargets, func(v string) bool { return strings.EqualFold(v, "all") })
 }
 
-func Build(config BuildConfig, dist GoDist) (string, error) {
+func getProjectName(projFp string) (string, error) {
+	var err error = nil
+	if projFp == "." {
+
+		projFp, err = os.Getwd()
 
-	filename := fmt.Sprintf("%s-%s_%s", config.BinaryName, dist.GOOS, dist.GOARCH)
+		if err != nil {
+			return "", err
+		}
 
-	if dist.GOOS == "windows" || dist.GOOS == "nt" {
-		filename += ".exe"
 	}
 
-	fp := filepath.Join(config.OutputDir, filename)
+	return filepath.Base(projFp), err
+}
 
-	cmd := exec.Command("go", "build", "-o", fp, config.ProjectDir)
-	cmd.Dir = config.ProjectDir
-	cmd.Env = append(os.Environ(),
-		dist.GOOSEnv(),
-		dist.GOARCHEnv(),
-	)
+func main() {
+	log.SetFlags(0)
 
-	res, err := cmd.Output()
+	ctx, stop := interruptContext()
+	defer stop()
 
-	if err != nil {
+	// version, self-update, doctor, serve and remote are subcommands, as
+	// none of the build flags apply to them
+	if len(os.Args) > 1 && os.Args[1] == "version" {
+		readVersionInfo(debug.ReadBuildInfo()).write(os.Stdout)
+		return
+	}
 
-		return string(res), err
+	if len(os.Args) > 1 && os.Args[1] == "self-update" {
+		if err := selfUpdate(ctx, os.Args[2:]); err != nil {
+			log.Fatalln("self-update:", err)
+		}
+		return
+	}
 
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		if err := runDoctor(ctx, os.Args[2:]); err != nil {
+			log.Fatalln("doctor:", err)
+		}
+		return
 	}
 
-	return string(res), nil
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		if err := serve(ctx, os.Args[2:]); err != nil {
+			log.Fatalln("serve:", err)
+		}
+		return
+	}
 
-}
+	if len(os.Args) > 1 && os.Args[1] == "remote" {
+		if err := remote(ctx, os.Args[2:]); err != nil {
+			log.Fatalln("remote:", err)
+		}
+		return
+	}
 
-func parseStringToOSARCH(rawStr string) (OSARCH, error) {
+	var targetOSRaw []string
 
-	if rawStr == "" {
-		return OSARCH{}, ErrInvalidOSARCH
+	targetOSARCHFunc := func(v string) error {
+		targetOSRaw = append(targetOSRaw, v)
+		return nil
 	}
 
-	strLower := strings.ToLower(rawStr)
-	splitStr := strings.Split(strLower, "/")
+	flag.Func("target",
+		"Specify what OS to target. Additional specifiers can be supplied with <os>/<arch> or <os>/<arch>/<variant> (e.g. linux/arm/7, linux/amd64/v3). Use */<arch> to target every OS for an architecture, a group name such as desktop, server, mobile or bsd, or all or first-class. Wildcards (e.g. linux/mips*) and regular expressions between slashes (e.g. /amd64$/) select families of targets. A leading ! removes the matching targets selected so far (e.g. -target linux -target '!linux/mips*').",
+		targetOSARCHFunc)
 
-	if len(splitStr) == 1 {
-		return OSARCH{
-			OS:   splitStr[0],
-			ARCH: "",
-		}, nil
-	} else if len(splitStr) == 2 {
-		return OSARCH{
-			OS:   splitStr[0],
-			ARCH: splitStr[1],
-		}, nil
-	} else {
-		return OSARCH{}, ErrInvalidOSARCH
+	targetARCHFunc := func(v string) error {
+
+		if v == "" {
+			return builder.ErrInvalidOSARCH
+		}
+
+		targetOSRaw = append(targetOSRaw, "*/"+v)
+		return nil
 	}
 
-}
+	flag.Func("arch",
+		"Specify an architecture to target for every OS that supports it. Equivalent to -target */<arch>.",
+		targetARCHFunc)
 
-func getProjectName(projFp string) (string, error) {
-	var err error = nil
-	if projFp == "." {
+	var excludeOS []builder.OSARCH
 
-		projFp, err = os.Getwd()
+	excludeOSARCHFunc := func(v string) error {
+
+		osarch, err := builder.ParseTarget(v)
 
 		if err != nil {
-			return "", err
+			return fmt.Errorf("parse exclude: %w", err)
+		}
+
+		if osarch.Negate {
+			return fmt.Errorf("exclude %s: %w", v, ErrNegatedExclude)
 		}
 
+		excludeOS = append(excludeOS, osarch)
+		return nil
 	}
 
-	return filepath.Base(projFp), err
-}
+	flag.Func("exclude",
+		"Specify an OS or <os>/<arch> to skip. Wildcards (e.g. linux/mips*) and regular expressions between slashes (e.g. /^linux/mips/) are supported and the flag may be repeated.",
+		excludeOSARCHFunc)
 
-func main() {
-	log.SetFlags(0)
+	var allTargets bool
+	flag.BoolVar(&allTargets, "all", false, "Build every target the go toolchain supports. Without -target or -all only the host platform is built.")
 
-	ctx := context.Background()
+	var assumeYes bool
+	flag.BoolVar(&assumeYes, "yes", false, "Build every target without asking for confirmation first.")
 
-	var targetOS []OSARCH
-	var targetOSRaw []string
+	var firstClass bool
+	flag.BoolVar(&firstClass, "first-class", false, "Only consider first-class ports when selecting targets, e.g. with -all.")
 
-	targetOSARCHFunc := func(v string) error {
+	var cgoOnly bool
+	flag.BoolVar(&cgoOnly, "cgo-only", false, "Only build targets that support cgo and build them with CGO_ENABLED=1.")
 
-		osarch, err := parseStringToOSARCH(v)
+	var noCgo bool
+	flag.BoolVar(&noCgo, "no-cgo", false, "Build every target with CGO_ENABLED=0.")
 
-		if err == ErrInvalidOSARCH {
-			fmt.Fprintf(os.Stderr, "Unable to parse %s to valid OS/ARCH\n", v)
-			return nil
-		} else if err != nil {
-			return fmt.Errorf("parse osarch: %w", err)
-		}
+	var configPath string
+	flag.StringVar(&configPath, "config", "", "Specify the config file to read. Defaults to "+DefaultConfigFile+" in the project directory if present.")
 
-		targetOSRaw = append(targetOSRaw, v)
+	var modMode string
+	flag.StringVar(&modMode, "mod", "", "Specify the module mode of go build: "+strings.Join(builder.ModModes, ", ")+". Defaults to vendor when the project has a vendor directory.")
+
+	var envFile string
+	flag.StringVar(&envFile, "env-file", "", "Specify a file of NAME=value lines set for every build, e.g. proxy credentials. Defaults to "+DefaultEnvFile+" in the project directory if present. Variables already set are kept.")
+
+	var wasmExec bool
+	flag.BoolVar(&wasmExec, "wasm-exec", false, "Copy wasm_exec.js from GOROOT into the output directory when building js/wasm.")
+
+	var mobileMode string
+	flag.StringVar(&mobileMode, "mobile", "", "Build android and ios targets with gomobile. Either bind (.aar/.xcframework) or build (.apk/.app).")
+
+	var universal bool
+	flag.BoolVar(&universal, "universal", false, "Merge the darwin/amd64 and darwin/arm64 executables into an additional darwin_universal binary. Libraries and race builds are not merged.")
+
+	var buildMode string
+	flag.StringVar(&buildMode, "buildmode", "", "Specify the go build -buildmode for every target ("+strings.Join(builder.BuildModes, ", ")+"). Per-target modes can be set in the config file.")
+
+	var pgoProfile string
+	flag.StringVar(&pgoProfile, "pgo", "", "Specify the profile passed to go build -pgo for every target, or auto/off. Per-target profiles can be set in the config file. Docker, ssh and worker builds need the profile inside the project directory.")
+
+	var race bool
+	flag.BoolVar(&race, "race", false, "Also build a -race instrumented binary for the host platform and every selected target that supports the race detector.")
+
+	var compiler string
+	flag.StringVar(&compiler, "compiler", "", "Specify the compiler backend for every target ("+strings.Join(builder.Compilers, ", ")+"). Per-target backends can be set in the config file.")
+
+	var garbleFlags string
+	flag.StringVar(&garbleFlags, "garble-flags", "", "Specify the flags passed to garble before build, e.g. \"-literals -tiny\".")
+
+	var gccgoFlags string
+	flag.StringVar(&gccgoFlags, "gccgoflags", "", "Specify the -gccgoflags passed to go build for targets built with gccgo.")
+
+	var useZig bool
+	flag.BoolVar(&useZig, "zig", false, "Cross-compile cgo code with zig cc/c++ by setting CC and CXX for each target.")
+
+	var buildRunner string
+	flag.StringVar(&buildRunner, "builder", "", "Specify where builds run: local, docker or ssh://user@host[:port][/dir]. Docker runs each target in a cross-compilation container with the module cache mounted. SSH syncs the source to the machine with rsync, builds there and copies the binaries back.")
+
+	var imageName string
+	flag.StringVar(&imageName, "image", "", "Build a container image per linux target and tag them <image>-<arch>, e.g. ghcr.io/acme/app:v1.0.0.")
+
+	var imageDockerfile string
+	flag.StringVar(&imageDockerfile, "dockerfile", "", "Specify the Dockerfile used for -image. The binary file name is passed as the BINARY build arg.")
+
+	var imagePush bool
+	flag.BoolVar(&imagePush, "push", false, "Push the -image builds and a multi-arch manifest list under the -image name.")
+
+	var ociRef string
+	flag.StringVar(&ociRef, "oci", "", "Push the built binaries, with any SBOMs and signatures next to them, to an OCI registry as an artifact, e.g. ghcr.io/acme/app-bin:v1.0.0.")
+
+	var publish string
+	flag.StringVar(&publish, "publish", "", "Publish the artifacts, checksums and signatures to a release. Supported: github, gitlab, gitea.")
 
-		targetOS = append(targetOS,
-			osarch)
+	var uploadURL string
+	flag.StringVar(&uploadURL, "upload", "", "Upload the output directory to object storage, e.g. s3://bucket/myapp/{tag}/. Supported: s3://, gs://, az://account/container/.")
+
+	var releaseTagName string
+	flag.StringVar(&releaseTagName, "tag", "", "Specify the release tag for -publish. Defaults to the tag pointing at HEAD.")
+
+	var draft bool
+	flag.BoolVar(&draft, "draft", false, "Publish the release as a draft.")
+
+	var prerelease bool
+	flag.BoolVar(&prerelease, "prerelease", false, "Mark the published release as a prerelease.")
+
+	var generate bool
+	flag.BoolVar(&generate, "generate", false, "Run go generate ./... in the project directory once before building.")
+
+	var test bool
+	flag.BoolVar(&test, "test", false, "Run go test ./... on the host before building and stop if it fails.")
+
+	var testFlags string
+	flag.StringVar(&testFlags, "test-flags", "", "Specify the flags passed to go test for -test, e.g. \"-short -race\".")
+
+	var vet bool
+	flag.BoolVar(&vet, "vet", false, "Run go vet ./... before building.")
+
+	var lintCommand string
+	flag.StringVar(&lintCommand, "lint", "", "Specify an external lint command run before building, e.g. \"staticcheck ./...\".")
+
+	var lintMode string
+	flag.StringVar(&lintMode, "lint-mode", "", "Specify whether vet and lint problems fail the run or only warn (fail, warn).")
+
+	var watchMode bool
+	flag.BoolVar(&watchMode, "watch", false, "Rebuild whenever source files change. Only the host target is built unless targets are selected.")
+
+	var force bool
+	flag.BoolVar(&force, "force", false, "Rebuild every target, even those whose artifact is up to date with the sources and settings.")
+
+	var deadline time.Duration
+	flag.DurationVar(&deadline, "deadline", 0, "Specify how long the whole run may take, e.g. 30m. Targets still building when it passes are cancelled and the run fails with a report of what finished. Zero means no limit.")
+
+	var timeoutPerTarget time.Duration
+	flag.DurationVar(&timeoutPerTarget, "timeout-per-target", 0, "Specify how long a single target may take to build, e.g. 10m. A target that takes longer is stopped and reported as failed. Zero means no limit.")
+
+	var reports []reportSpec
+	flag.Func("report", "Write a machine-readable report of the builds as format[=path], e.g. json or junit=ci/junit.xml. By default it is written to the output directory. Formats: json, junit.", func(v string) error {
+		spec, err := parseReport(v)
+		reports = append(reports, spec)
+		return err
+	})
+
+	var interactive bool
+	flag.BoolVar(&interactive, "interactive", false, "Pick the targets from a searchable list of every supported GOOS/GOARCH. Targets given with -target are selected to begin with.")
+
+	var maxSize string
+	flag.StringVar(&maxSize, "max-size", "", "Fail every target whose binary is larger than the size, e.g. 20MiB. Per-target budgets are set with size_budgets in the config.")
+
+	var compare string
+	flag.StringVar(&compare, "compare", "", "Compare the binary sizes with those of a previous run, given as its output directory or JSON report.")
+
+	var maxGrowth string
+	flag.StringVar(&maxGrowth, "max-growth", "", "With -compare, fail every target whose binary grew by more than the limit, in percent (e.g. 5%) or as a size (e.g. 100KiB).")
+
+	var metricsFile string
+	flag.StringVar(&metricsFile, "metrics-file", "", "Write Prometheus metrics of the run (targets built and failed, durations and sizes) to the file, for the node exporter textfile collector.")
+
+	var pushgateway string
+	flag.StringVar(&pushgateway, "pushgateway", "", "Push Prometheus metrics of the run to the Pushgateway at the URL, e.g. http://pushgateway:9091.")
+
+	var workers string
+	flag.StringVar(&workers, "workers", "", "Build the targets on go-builder serve agents, given as comma separated URLs, e.g. http://builder1:8080. They are sent GOBUILDER_SERVE_TOKEN as a bearer token when set.")
+
+	var workersEnv bool
+	flag.BoolVar(&workersEnv, "workers-env", false, "Send the variables of the env file to the -workers agents. They are left out by default, as are the env files in the uploaded source.")
+
+	var failFast bool
+	flag.BoolVar(&failFast, "fail-fast", false, "Cancel every other target as soon as one build fails instead of building everything and reporting the failures.")
+
+	retries := retryPolicy{}
+	flag.IntVar(&retries.Retries, "retries", 0, "Specify how many times a failed target build is retried, for transient failures such as module proxy errors. Upx and the post hooks are not retried.")
+	flag.DurationVar(&retries.Backoff, "retry-backoff", time.Second, "Specify the wait before the first retry. It doubles with each further retry.")
+
+	var goPath string
+	flag.StringVar(&goPath, "go", "", "Build with this go command, e.g. /opt/go-patched/bin/go, instead of the go on PATH.")
+
+	var goVersion string
+	flag.StringVar(&goVersion, "go-version", "", "Build with this go release, e.g. 1.22.3, instead of the go on PATH. It is downloaded from go.dev and cached.")
+
+	tagSets := [][]string{}
+	flag.Func("tag-set", "Build the targets again with these comma separated build tags, e.g. -tag-set '' -tag-set pro. Repeat it for more sets; the binaries are named after the tags.", func(v string) error {
+		tagSets = append(tagSets, parseTagSet(v))
 		return nil
-	}
+	})
 
-	flag.Func("target",
-		"Specify what OS to target. Additional specifier can be supplied with <os>/<arch>.",
-		targetOSARCHFunc)
+	var profileName string
+	flag.StringVar(&profileName, "profile", "", "Build with the settings of a profile: debug, release or one from the config's profiles.")
+
+	variantNames := []string{}
+	flag.Func("variant", "Only build this variant of the config's variants, e.g. enterprise. Repeat it for more variants.", func(v string) error {
+		variantNames = append(variantNames, v)
+		return nil
+	})
+
+	var goCache string
+	flag.StringVar(&goCache, "gocache", "", "Specify the GOCACHE shared by every target, or the directory of the per-target caches with -gocache-per-target.")
+
+	var goCachePerTarget bool
+	flag.BoolVar(&goCachePerTarget, "gocache-per-target", false, "Give each target its own GOCACHE subdirectory so concurrent builds do not contend and caches can be kept per target.")
+
+	var remoteCache string
+	flag.StringVar(&remoteCache, "remote-cache", "", "Share the build cache through an HTTP server that stores PUT bodies and serves them on GET, e.g. https://cache.example.com/go.")
+
+	var cacheProgURL string
+	flag.StringVar(&cacheProgURL, "cacheprog", "", "Run as the GOCACHEPROG program for the HTTP cache at the URL. Set up by -remote-cache.")
+
+	var cacheProgReadOnly bool
+	flag.BoolVar(&cacheProgReadOnly, "cacheprog-readonly", false, "Only read from the -cacheprog HTTP cache.")
+
+	var cmds bool
+	flag.BoolVar(&cmds, "cmds", false, "Discover every main package under ./cmd and build each one for every target, named after its directory.")
 
 	var outputDir string
 	flag.StringVar(&outputDir, "o", "", "Specify the output directory to build in.")
@@ -221,19 +433,94 @@ func main() {
 	var binaryName string
 	flag.StringVar(&binaryName, "n", "", "Specify the name of the binary build file(s)")
 
+	var noColor bool
+	flag.BoolVar(&noColor, "no-color", false, "Disable colored output. Color is also off when NO_COLOR is set or output is not a terminal.")
+
+	var quiet bool
+	flag.BoolVar(&quiet, "q", false, "Only print failures and the exit status, for scripts and Makefiles.")
+	flag.BoolVar(&quiet, "quiet", false, "Same as -q.")
+
+	logLevel := slog.LevelWarn
+	flag.TextVar(&logLevel, "log-level", slog.LevelWarn, "Specify the lowest level logged: debug, info (a line per target), warn or error.")
+
+	var logFormat string
+	flag.StringVar(&logFormat, "log-format", "text", "Specify the log format: text or json.")
+
 	flag.BoolVar(&VERBOSE, "v", false, "Specify whether or not to print additional information during run")
 
 	var numProcesses int
 	flag.IntVar(&numProcesses, "nproc", 5, "Specify the maximum number of co-routines to run during build process. Used to set GOMAXPROCS env variable.")
 
-	flag.Parse()
+	// completion needs the flags above, so it is handled here rather than
+	// with the other subcommands
+	if len(os.Args) > 1 && os.Args[1] == "completion" {
+		if len(os.Args) != 3 {
+			log.Fatalln("Usage: go-builder completion " + strings.Join(completionShells, "|"))
+		}
+
+		if err := writeCompletion(os.Stdout, os.Args[2]); err != nil {
+			log.Fatalln("completion:", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "__complete" {
+		dists := func() []builder.GoDist {
+			dists, _ := builder.SupportedDists(ctx)
+			return dists
+		}
+
+		for _, c := range complete(flag.CommandLine, os.Args[2:], dists) {
+			fmt.Println(c)
+		}
+		return
+	}
+
+	// the arguments after -- are go build flags
+	args, buildFlags := splitBuildFlags(os.Args[1:])
+	flag.CommandLine.Parse(args)
+
+	colors, errColors := newPalette(os.Stdout, noColor), newPalette(os.Stderr, noColor)
+
+	// quiet runs only report failures and the exit status
+	var stdout, warnings io.Writer = os.Stdout, os.Stderr
+	if quiet {
+		stdout, warnings = io.Discard, io.Discard
+	}
+
+	if deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, deadline)
+		defer cancel()
+	}
+
+	// the go command runs this binary as its cache program for -remote-cache
+	if cacheProgURL != "" {
+		dir, err := remoteCacheDir()
+		if err != nil {
+			log.Fatalln("cacheprog:", err)
+		}
 
-	logWriter := io.Discard
+		if err := serveCacheProg(ctx, newHTTPCache(cacheProgURL, dir, cacheProgReadOnly), os.Stdin, os.Stdout); err != nil {
+			log.Fatalln("cacheprog:", err)
+		}
+		return
+	}
+
+	// -v shows the debug records, which is everything logged
 	if VERBOSE {
-		logWriter = os.Stdout
+		logLevel = slog.LevelDebug
+	}
+
+	logger, err := newLogger(os.Stderr, logFormat, logLevel)
+	if err != nil {
+		log.Fatalln("log:", err)
 	}
 
-	verboseLogger := log.New(logWriter, "verbose:", log.LstdFlags)
+	tracer, err := newTracer(os.Getenv)
+	if err != nil {
+		log.Fatalln("tracing:", err)
+	}
 
 	numCores := runtime.NumCPU()
 
@@ -243,21 +530,58 @@ func main() {
 
 	runtime.GOMAXPROCS(numProcesses)
 
-	verboseLogger.Println("max procs:", numProcesses)
+	logger.Debug("max procs", "n", numProcesses)
+
+	dirs, err := projectDirs(flag.Args())
+	if err != nil {
+		log.Fatalln("projects:", err)
+	}
+
+	if watchMode && len(dirs) > 1 {
+		log.Fatalln("watch:", ErrWatchProjects)
+	}
+
+	// several projects are each built by a run of this binary with the
+	// same flags, so they share the target matrix
+	if len(dirs) > 1 {
+		exe, err := os.Executable()
+		if err != nil {
+			log.Fatalln("projects:", err)
+		}
+
+		flags := args[:len(args)-len(flag.Args())]
+		results := runBatch(ctx, exe, flags, dirs, buildFlags, stdout, os.Stderr)
+
+		fmt.Fprintln(stdout)
+		writeBatchReport(stdout, results)
+
+		for _, res := range results {
+			if res.Err != nil {
+				os.Exit(1)
+			}
+		}
+		return
+	}
 
 	projectDir := ""
-	if len(flag.Args()) > 0 {
-		projectDir = flag.Args()[0]
+	if len(dirs) > 0 {
+		projectDir = dirs[0]
 	}
-	var err error = nil
 	if projectDir == "" || projectDir == "." {
 		projectDir, err = os.Getwd()
 		if err != nil {
 			log.Fatalln("get wd:", err)
 		}
+	} else {
+		// the go command runs in the project directory, so relative output
+		// paths would resolve against it
+		projectDir, err = filepath.Abs(projectDir)
+		if err != nil {
+			log.Fatalln("project dir:", err)
+		}
 	}
 
-	verboseLogger.Println(logWriter, "project dir:", projectDir)
+	logger.Debug("project", "dir", projectDir)
 
 	projectName, err := getProjectName(projectDir)
 
@@ -265,45 +589,1407 @@ func main() {
 		log.Fatalln("project name:", err)
 	}
 
-	verboseLogger.Println(logWriter, "project name:", projectName)
+	logger.Debug("project", "name", projectName)
 
 	if outputDir == "" {
 		outputDir = filepath.Join(projectDir, "build")
 	}
 
-	verboseLogger.Println(logWriter, "output directory:", outputDir)
+	logger.Debug("output", "dir", outputDir)
+
+	// each rebuild is a run of this binary without -watch
+	if watchMode {
+		exe, err := os.Executable()
+		if err != nil {
+			log.Fatalln("watch:", err)
+		}
+
+		flags := watchFlags(args[:len(args)-len(flag.Args())], len(targetOSRaw) == 0 && !allTargets)
 
-	buildDists, err := getBuildOptions(ctx, targetOS)
+		err = watch(ctx, projectDir, outputDir, 300*time.Millisecond, func(changed []string) {
+			cmd := exec.CommandContext(ctx, exe, withBuildFlags(append(flags, projectDir), buildFlags)...)
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
 
-	if err == ErrUnsupportedTargetOSARCH {
-		log.Fatalln("Unsupported targets: ", strings.Join(targetOSRaw, "\n"), "\n", err)
-	} else if err != nil {
-		log.Fatalln("build options:", err)
+			start := time.Now()
+			err := cmd.Run()
+
+			fmt.Fprintf(stdout, "[%s] %s\n", time.Now().Format(time.TimeOnly), rebuildSummary(changed, time.Since(start), err))
+		})
+		if err != nil {
+			log.Fatalln("watch:", err)
+		}
+		return
 	}
 
-	config := NewConfig()
-	config.Targets = targetOS
+	configRequired := configPath != ""
+	if !configRequired {
+		configPath = filepath.Join(projectDir, DefaultConfigFile)
+	}
+
+	configFile, err := loadConfigFile(configPath, configRequired)
+
+	if err != nil {
+		log.Fatalln("config:", err)
+	}
+
+	envFileRequired := envFile != ""
+	if !envFileRequired {
+		envFile = filepath.Join(projectDir, DefaultEnvFile)
+	}
+
+	dotEnv, err := loadEnvFile(envFile, envFileRequired)
+	if err != nil {
+		log.Fatalln("env file:", err)
+	}
+
+	// the variables are set for the whole run, so hooks and signing see
+	// them too
+	dotEnv = setEnv(dotEnv)
+	logger.Debug("env file", "path", envFile, "n", len(dotEnv))
+
+	// env files hold secrets, so they are never copied to ssh or worker
+	// builders
+	privateFiles := []string{DefaultEnvFile}
+	if fp, err := filepath.Abs(envFile); err == nil {
+		privateFiles = append(privateFiles, fp)
+	}
+
+	if mobileMode != "" {
+		configFile.Mobile.Mode = mobileMode
+	}
+
+	if imageName != "" {
+		configFile.Image.Name = imageName
+	}
+
+	if imageDockerfile != "" {
+		configFile.Image.Dockerfile = imageDockerfile
+	}
+
+	configFile.Image.Push = configFile.Image.Push || imagePush
+
+	if ociRef != "" {
+		configFile.OCI.Ref = ociRef
+	}
+
+	if publish != "" {
+		configFile.Publish = publish
+	}
+
+	if uploadURL != "" {
+		configFile.Upload.URL = uploadURL
+	}
+
+	if lintCommand != "" {
+		configFile.Lint.Command = lintCommand
+	}
+
+	if lintMode != "" {
+		configFile.Lint.Mode = lintMode
+	}
+
+	if maxSize != "" {
+		if configFile.SizeBudgets == nil {
+			configFile.SizeBudgets = map[string]string{}
+		}
+		configFile.SizeBudgets["*"] = maxSize
+	}
+
+	sizeBudgets, err := parseSizeBudgets(configFile.SizeBudgets)
+	if err != nil {
+		log.Fatalln("size budgets:", err)
+	}
+
+	growth, err := parseGrowthLimit(maxGrowth)
+	if err != nil {
+		log.Fatalln("max growth:", err)
+	}
+
+	// the baseline is read before building, which may overwrite it
+	var baselineSizes map[string]int64
+	if compare != "" {
+		if baselineSizes, err = loadBaselineSizes(compare); err != nil {
+			log.Fatalln("compare:", err)
+		}
+	}
+
+	var targetOS []builder.OSARCH
+	var invalidTargets []error
+
+	for _, v := range expandTargetAliases(targetOSRaw, configFile.Aliases) {
+
+		osarch, err := builder.ParseTarget(v)
+
+		if err != nil {
+			invalidTargets = append(invalidTargets, fmt.Errorf("%q: %w", v, err))
+			continue
+		}
+
+		targetOS = append(targetOS, osarch)
+	}
+
+	if allTargets && slices.ContainsFunc(targetOSRaw, func(v string) bool { return !strings.HasPrefix(v, "!") }) {
+		log.Fatalln("targets:", ErrAllWithTargets)
+	}
+
+	if host := defaultTargets(targetOSRaw, allTargets, interactive); host != nil {
+		targetOS = host
+	}
+
+	config := builder.NewConfig()
 	config.BinaryName = projectName
 	config.OutputDir = outputDir
 	config.ProjectDir = projectDir
+	config.Targets = targetOS
+	config.Excludes = excludeOS
 
-	wg := sync.WaitGroup{}
+	configFile.apply(&config)
+	// the config's module settings win over the env file
+	config.ExtraEnv = append(dotEnv, config.ExtraEnv...)
+	config.BuildFlags = buildFlags
 
-	wg.Add(len(buildDists))
+	if modMode != "" {
+		config.ModMode = modMode
+	}
 
-	for _, dist := range buildDists {
+	config.FirstClass = firstClass
+	config.CgoOnly = cgoOnly
+	config.NoCgo = noCgo
+	config.Zig = config.Zig || useZig
 
-		go func() {
-			defer wg.Done()
-			res, err := Build(config, dist)
+	if buildMode != "" {
+		config.BuildMode = buildMode
+	}
 
-			verboseLogger.Println(logWriter, "build:", dist)
-			verboseLogger.Println(res)
-			verboseLogger.Println("error:", err)
-		}()
+	if compiler != "" {
+		config.Compiler = compiler
+	}
 
+	if garbleFlags != "" {
+		config.GarbleFlags = strings.Fields(garbleFlags)
 	}
 
-	wg.Wait()
+	if gccgoFlags != "" {
+		config.GccgoFlags = gccgoFlags
+	}
+
+	if buildRunner != "" {
+		config.Builder = buildRunner
+	}
+
+	if goCache != "" {
+		config.GoCache = goCache
+	}
+
+	config.GoCachePerTarget = config.GoCachePerTarget || goCachePerTarget
+
+	if config.GoCachePerTarget && config.GoCache == "" {
+		config.GoCache, err = defaultGoCacheDir()
+		if err != nil {
+			log.Fatalln("gocache:", err)
+		}
+	}
+
+	if config.GoCache != "" {
+		config.GoCache, err = filepath.Abs(config.GoCache)
+		if err != nil {
+			log.Fatalln("gocache:", err)
+		}
+	}
+
+	if pgoProfile == "auto" || pgoProfile == "off" {
+		config.PGO = pgoProfile
+	} else if pgoProfile != "" {
+		config.PGO, err = filepath.Abs(pgoProfile)
+		if err != nil {
+			log.Fatalln("pgo:", err)
+		}
+	}
+
+	if config.Builder != "local" || workers != "" {
+		config.PGO, err = projectPGOProfile(config.PGO, config.ProjectDir)
+		if err != nil {
+			log.Fatalln("pgo:", err)
+		}
+
+		for target, profile := range config.PGOTargets {
+			config.PGOTargets[target], err = projectPGOProfile(profile, config.ProjectDir)
+			if err != nil {
+				log.Fatalln("pgo:", err)
+			}
+		}
+	}
+
+	if remoteCache != "" {
+		configFile.RemoteCache.URL = remoteCache
+	}
+
+	if !configFile.RemoteCache.IsEmpty() {
+		exe, err := os.Executable()
+		if err != nil {
+			log.Fatalln("remote cache:", err)
+		}
+
+		config.GoCacheProg = configFile.RemoteCache.goCacheProg(exe)
+	}
+
+	if goVersion != "" {
+		configFile.GoVersion = goVersion
+	}
+
+	if goPath != "" {
+		configFile.Go = goPath
+	}
+
+	if configFile.Go != "" && configFile.GoVersion != "" {
+		log.Fatalln("go:", ErrConflictingGo)
+	}
+
+	// builds run the go command itself, and its GOROOT is put first on
+	// PATH so the other go commands of the run use the same toolchain
+	if configFile.Go != "" && config.Builder == "local" {
+		fp, goroot, err := lookGo(ctx, configFile.Go)
+		if err != nil {
+			log.Fatalln("go:", err)
+		}
+
+		config.Go = fp
+		useToolchain(goroot)
+		logger.Debug("toolchain", "go", fp, "goroot", goroot)
+	}
+
+	// docker and ssh builds use the go of their machine
+	if configFile.GoVersion != "" && config.Builder == "local" {
+		v, err := parseGoVersion(configFile.GoVersion)
+		if err != nil {
+			log.Fatalln("go version:", err)
+		}
+
+		cacheDir, err := toolchainCacheDir()
+		if err != nil {
+			log.Fatalln("go version:", err)
+		}
+
+		goroot, err := installToolchain(ctx, goDownloadURL, cacheDir, v)
+		if err != nil {
+			log.Fatalln("go version:", err)
+		}
+
+		useToolchain(goroot)
+		logger.Debug("toolchain", "version", v, "goroot", goroot)
+	}
+
+	if err := config.Validate(); err != nil {
+		log.Fatalln("config:", err)
+	}
+
+	if config.Builder == "docker" {
+		config.GoModCache, err = builder.ModCacheDir(ctx)
+		if err != nil {
+			log.Fatalln("builder:", err)
+		}
+
+		// docker would create a missing mount point owned by root
+		if err := os.MkdirAll(config.OutputDir, 0o755); err != nil {
+			log.Fatalln("builder:", err)
+		}
+	}
+
+	if config.IsSSH() {
+		remote, err := builder.ParseSSHBuilder(config.Builder, config.ProjectDir)
+		if err == nil {
+			err = builder.SyncSSH(ctx, config, remote, privateFiles)
+		}
+		if err != nil {
+			log.Fatalln("builder:", err)
+		}
+	}
+
+	// the release tag is only looked up when a step needs it, so untagged
+	// builds keep working without git
+	tag := sync.OnceValues(func() (string, error) {
+		if releaseTagName != "" {
+			return releaseTagName, nil
+		}
+		return releaseTag(ctx, config.ProjectDir)
+	})
+
+	if _, err := configFile.NFPM.formats(); err != nil {
+		log.Fatalln("nfpm:", err)
+	}
+
+	if err := configFile.Lint.Validate(); err != nil {
+		log.Fatalln("lint:", err)
+	}
+
+	for _, n := range configFile.Notify {
+		if err := n.validate(); err != nil {
+			log.Fatalln("notify:", err)
+		}
+	}
+
+	if err := configFile.Archive.Validate(); err != nil {
+		log.Fatalln("archive:", err)
+	}
+
+	if err := configFile.MSI.Validate(); err != nil {
+		log.Fatalln("msi:", err)
+	}
+
+	if err := configFile.Authenticode.Validate(); err != nil {
+		log.Fatalln("authenticode:", err)
+	}
+
+	if err := configFile.Mobile.Validate(); err != nil {
+		log.Fatalln("mobile:", err)
+	}
+
+	if configFile.Upload.URL != "" {
+		if _, err := parseBlobURL(configFile.Upload.URL); err != nil {
+			log.Fatalln("upload:", err)
+		}
+	}
+
+	var publisher Publisher
+	if configFile.Publish != "" {
+		publisher, err = newPublisher(ctx, configFile.Publish, configFile, config.ProjectDir, config.BinaryName)
+		if err != nil {
+			log.Fatalln("publish:", err)
+		}
+	}
+
+	// downloadURL is where a published file is fetched from, for the
+	// package manager manifests
+	downloadURL := func(tag string, file string) string {
+		if configFile.ReleaseURL != "" {
+			return expandReleaseURL(configFile.ReleaseURL, tag, file)
+		}
+		return publisher.DownloadURL(tag, file)
+	}
+
+	if configFile.ReleaseURL == "" && publisher == nil {
+		if !configFile.Homebrew.IsEmpty() {
+			log.Fatalln("homebrew:", ErrNoReleaseURL)
+		}
+
+		if !configFile.Scoop.IsEmpty() {
+			log.Fatalln("scoop:", ErrNoReleaseURL)
+		}
+
+		if !configFile.Chocolatey.IsEmpty() {
+			log.Fatalln("chocolatey:", ErrNoReleaseURL)
+		}
+
+		if !configFile.Winget.IsEmpty() {
+			log.Fatalln("winget:", ErrNoReleaseURL)
+		}
+
+		if !configFile.AUR.IsEmpty() {
+			log.Fatalln("aur:", ErrNoReleaseURL)
+		}
+
+		if !configFile.Nix.IsEmpty() {
+			log.Fatalln("nix:", ErrNoReleaseURL)
+		}
+
+		if configFile.InstallScripts {
+			log.Fatalln("install scripts:", ErrNoReleaseURL)
+		}
+	}
+
+	if interactive && len(invalidTargets) == 0 {
+		targets, err := pickConfigTargets(ctx, config)
+		if err != nil {
+			log.Fatalln("interactive:", err)
+		}
+
+		config.Targets = targets
+	}
+
+	buildDists, err := builder.SelectDists(ctx, config)
+
+	if len(invalidTargets) > 0 || errors.Is(err, builder.ErrUnsupportedTargetOSARCH) {
+		log.Fatalln("Invalid or unsupported targets, nothing was built:\n" +
+			errors.Join(append(invalidTargets, err)...).Error())
+	} else if err != nil {
+		log.Fatalln("build options:", err)
+	}
+
+	// building everything takes a while, so a terminal is asked first
+	if (allTargets || selectsAll(targetOSRaw)) && !assumeYes && isTerminal(os.Stdin) {
+		ok, err := confirm(os.Stdin, os.Stdout, fmt.Sprintf("Build all %d targets?", len(buildDists)))
+		if err != nil {
+			log.Fatalln("confirm:", err)
+		}
+
+		if !ok {
+			log.Fatalln("targets:", ErrNotConfirmed)
+		}
+	}
+
+	if err := resolveWorkspace(ctx, &config); err != nil {
+		log.Fatalln("workspace:", err)
+	}
+
+	// a workspace has its own vendor directory, if any
+	if config.ModMode == "" && config.GoWork == "" {
+		config.ModMode = builder.DefaultModMode(projectDir)
+	}
+
+	// docker, ssh and worker builds use the go of their machine
+	if config.Builder == "local" && workers == "" {
+		warning, err := checkGoToolchain(ctx, config)
+		if err != nil {
+			log.Fatalln("go version:", err)
+		}
+
+		if warning != "" {
+			fmt.Fprintln(warnings, "go version:", warning)
+		}
+	}
+
+	if generate {
+		res, err := runGenerate(ctx, config)
+		if err != nil {
+			log.Fatalln("generate:", err)
+		}
+
+		logger.Debug("generate", "output", res)
+	}
+
+	if test {
+		if testFlags != "" {
+			configFile.TestFlags = strings.Fields(testFlags)
+		}
+
+		res, err := runTests(ctx, config, configFile.TestFlags)
+		if err != nil {
+			log.Fatalln("test:", err)
+		}
+
+		logger.Debug("test", "output", res)
+	}
+
+	// lintGate stops the run on a vet or lint problem unless the lint mode
+	// only warns
+	lintGate := func(name string, res string, err error) {
+		if err == nil {
+			logger.Debug(name, "output", res)
+		} else if configFile.Lint.Mode == "warn" {
+			fmt.Fprintln(warnings, "Warning:", name+":", err)
+		} else {
+			log.Fatalln(name+":", err)
+		}
+	}
+
+	if vet {
+		res, err := runVet(ctx, config)
+		lintGate("vet", res, err)
+	}
+
+	if configFile.Lint.Command != "" {
+		res, err := runLint(ctx, config, configFile.Lint.Command)
+		lintGate("lint", res, err)
+	}
+
+	builds := entryConfigs(config, configFile.Builds)
+	if cmds {
+		commands, err := discoverCommands(ctx, config)
+		if err != nil {
+			log.Fatalln("cmds:", err)
+		}
+
+		builds = append(builds, commands...)
+		logger.Debug("cmds", "n", len(commands))
+	}
+
+	if len(builds) == 0 {
+		builds = []builder.BuildConfig{config}
+	}
+
+	if len(configFile.Variants) > 0 || len(variantNames) > 0 {
+		variants, err := selectVariants(configFile.Variants, variantNames)
+		if err != nil {
+			log.Fatalln("variants:", err)
+		}
+
+		builds = variantBuilds(builds, variants)
+	}
+
+	if len(tagSets) > 0 {
+		configFile.TagSets = tagSets
+	}
+
+	if len(configFile.TagSets) > 0 {
+		builds = tagSetBuilds(builds, configFile.TagSets)
+	}
+
+	if len(configFile.GoVersions) > 0 {
+		if config.Builder != "local" || workers != "" {
+			log.Fatalln("go versions:", ErrGoVersionsBuilder)
+		}
+
+		cacheDir, err := toolchainCacheDir()
+		if err != nil {
+			log.Fatalln("go versions:", err)
+		}
+
+		versions := []string{}
+		goroots := map[string]string{}
+		for _, raw := range configFile.GoVersions {
+			v, err := parseGoVersion(raw)
+			if err != nil {
+				log.Fatalln("go versions:", err)
+			}
+
+			goroots[v], err = installToolchain(ctx, goDownloadURL, cacheDir, v)
+			if err != nil {
+				log.Fatalln("go versions:", err)
+			}
+			versions = append(versions, v)
+		}
+
+		builds = goVersionBuilds(builds, versions, goroots)
+	}
+
+	var profile Profile
+	if profileName != "" {
+		if profile, err = lookupProfile(configFile.Profiles, profileName); err != nil {
+			log.Fatalln("profile:", err)
+		}
+
+		builds = profileBuilds(builds, profile)
+	}
+
+	if err := uniqueBinaryNames(builds); err != nil {
+		log.Fatalln("builds:", err)
+	}
+
+	for _, overlap := range builder.OverlappingTargets(config.Targets, buildDists) {
+		fmt.Fprintf(warnings, "Duplicate target selection, building once: %s\n", overlap)
+	}
+
+	var sysoFiles []string
+	if !configFile.WindowsResources.IsEmpty() {
+		sysoFiles, err = writeWindowsResources(ctx, builds, configFile.WindowsResources, buildDists)
+		if err != nil {
+			log.Fatalln("windows resources:", err)
+		}
+
+		logger.Debug("windows resources", "files", sysoFiles)
+
+		// a second interrupt kills the run before it gets to remove them
+		go func() {
+			<-ctx.Done()
+			removeFiles(sysoFiles)
+		}()
+	}
+	defer removeFiles(sysoFiles)
+
+	wg := sync.WaitGroup{}
+
+	// artifacts collects every file produced by the run for the publishing
+	// steps that follow the builds.
+	artifactsMu := sync.Mutex{}
+	artifacts := []string{}
+	addArtifact := func(fp string) {
+		artifactsMu.Lock()
+		defer artifactsMu.Unlock()
+		artifacts = append(artifacts, fp)
+	}
+
+	goDists := buildDists
+	if configFile.Mobile.Mode != "" {
+		var mobileDists map[string][]builder.GoDist
+		mobileDists, goDists = splitMobileDists(buildDists)
+
+		wg.Add(len(mobileDists))
+
+		for goos, dists := range mobileDists {
+
+			go func() {
+				defer wg.Done()
+				res, err := BuildMobile(ctx, config, configFile.Mobile, goos, dists)
+				if err == nil {
+					addArtifact(mobileOutputPath(config, configFile.Mobile, goos))
+				}
+
+				logger.Info("gomobile", "target", goos, "output", res, "error", err)
+			}()
+
+		}
+	}
+
+	wg.Add(len(configFile.TinyGoBoards))
+
+	for board, format := range configFile.TinyGoBoards {
+
+		go func() {
+			defer wg.Done()
+			res, err := builder.BuildBoard(ctx, config, board, format)
+			if err == nil {
+				addArtifact(builder.BoardOutputPath(config, board, format))
+			}
+
+			logger.Info("tinygo", "board", board, "output", res, "error", err)
+		}()
+
+	}
+
+	var pool *workerPool
+	if workers != "" {
+		pool, err = newWorkerPool(ctx, strings.Split(workers, ","), os.Getenv("GOBUILDER_SERVE_TOKEN"), config.ProjectDir, config.OutputDir, privateFiles)
+		if pool == nil {
+			removeFiles(sysoFiles)
+			log.Fatalln("workers:", err)
+		} else if err != nil {
+			fmt.Fprintln(warnings, "workers:", err)
+		}
+
+		if !workersEnv {
+			pool.privateEnv = dotEnv
+		}
+	}
+
+	jobs := []buildJob{}
+	for _, build := range builds {
+		for _, dist := range goDists {
+			jobs = append(jobs, buildJob{Config: build, Dist: dist})
+		}
+
+		if race {
+			jobs = append(jobs, raceJobs(build, goDists)...)
+		}
+	}
+
+	wg.Add(len(jobs))
+
+	ctx, runSpan := tracer.Start(ctx, "run", "project", config.BinaryName, "targets", len(jobs))
+
+	// endTrace ends the run and exports its spans, even when the run was
+	// interrupted
+	endTrace := func(err error) {
+		runSpan.End(err)
+		if err := tracer.Flush(context.WithoutCancel(ctx)); err != nil {
+			logger.Warn("tracing", "error", err)
+		}
+	}
+
+	buildStart := time.Now()
+
+	// verbose output would be drawn over, so it gets plain progress lines
+	jobProgress := newProgress(stdout, isTerminal(os.Stdout) && !VERBOSE && !quiet, colors, len(jobs))
+	go jobProgress.Run()
+	buildErrs := make([]error, len(jobs))
+	fingerprints := make([]string, len(jobs))
+	skipped := make([]bool, len(jobs))
+	started := make([]time.Time, len(jobs))
+	attempts := make([][]buildAttempt, len(jobs))
+	results := make([]builder.BuildResult, len(jobs))
+
+	// with -fail-fast the first failure cancels the other builds
+	buildCtx, cancelBuilds := context.WithCancelCause(ctx)
+	defer cancelBuilds(nil)
+
+	labels := make([]string, len(jobs))
+	for i, job := range jobs {
+		labels[i] = resultName(builder.BuildResult{Target: job.Dist.String(), Race: job.Config.Race})
+		if len(builds) > 1 {
+			labels[i] = job.Config.BinaryName + " " + labels[i]
+		}
+	}
+
+	// debug logging streams the output of every build as it is written,
+	// each line tagged with its target
+	if logLevel <= slog.LevelDebug {
+		for i, w := range newPrefixWriters(os.Stderr, labels, errColors) {
+			jobs[i].Config.Output = w
+		}
+	}
+
+	for i, job := range jobs {
+
+		go func() {
+			defer wg.Done()
+
+			targetCtx, targetSpan := tracer.Start(buildCtx, "target", "target", job.Dist.String(), "race", job.Config.Race, "binary", job.Config.BinaryName)
+
+			fingerprint, err := buildFingerprint(buildCtx, job.Config, job.Dist)
+			if err != nil {
+				logger.Debug("fingerprint", "target", job.Dist.String(), "error", err)
+			}
+			fingerprints[i] = fingerprint
+
+			results[i] = builder.BuildResult{
+				Target: job.Dist.String(),
+				Race:   job.Config.Race,
+				Path:   builder.OutputPath(job.Config, job.Dist),
+			}
+
+			label := labels[i]
+
+			if !force && fingerprint != "" && upToDate(builder.OutputPath(job.Config, job.Dist), fingerprint) {
+				skipped[i] = true
+				results[i].Success = true
+				results[i].UpToDate = true
+				if info, err := os.Stat(results[i].Path); err == nil {
+					results[i].Size = info.Size()
+				}
+				if results[i].SizeBudget, err = checkSizeBudget(sizeBudgets, job.Dist, results[i].Size); err != nil {
+					buildErrs[i] = err
+					results[i].Success = false
+					results[i].Error = err.Error()
+				}
+				jobProgress.Finish(label, resultStatus(results[i]), 0)
+				logger.Info("build", "target", job.Dist.String(), "race", job.Config.Race, "status", resultStatus(results[i]))
+				targetSpan.Set("status", resultStatus(results[i]))
+				targetSpan.End(buildErrs[i])
+				return
+			}
+
+			started[i] = time.Now()
+			jobProgress.Start(label)
+			res := ""
+			attempts[i] = retries.run(targetCtx, func(attempt int) error {
+				if attempt > 1 {
+					fmt.Fprintln(warnings, "build:", job.Dist, "retrying, attempt", attempt, "of", retries.Retries+1)
+					res += fmt.Sprintf("\n--- attempt %d\n", attempt)
+				}
+
+				jobCtx, cancel := targetContext(targetCtx, timeoutPerTarget)
+				defer cancel()
+
+				out, err := runHooks(jobCtx, configFile.Hooks.Pre, job.Config, job.Dist)
+				res += out
+				if err == nil {
+					_, compileSpan := tracer.Start(jobCtx, "compile", "attempt", attempt)
+					var result builder.BuildResult
+					if pool != nil {
+						result, err = pool.Build(jobCtx, job.Config, job.Dist)
+					} else {
+						result, err = builder.Build(jobCtx, job.Config, job.Dist)
+					}
+					results[i].Stderr = result.Stderr
+					res += result.Stderr
+					compileSpan.End(err)
+				}
+
+				err = timeoutError(jobCtx, err)
+				if errors.Is(err, ErrBuildTimeout) {
+					removePartialOutput(builder.OutputPath(job.Config, job.Dist), started[i])
+					fmt.Fprintln(os.Stderr, "build:", job.Dist, err)
+				}
+				return err
+			})
+
+			err = attempts[i][len(attempts[i])-1].Err
+
+			// upx and the post hooks run once on the built binary, their
+			// failures are not the transient ones retries are for
+			if err == nil {
+				jobCtx, cancel := targetContext(targetCtx, timeoutPerTarget)
+
+				if profile.UPX && !upxSupported(job.Config, job.Dist) {
+					fmt.Fprintln(warnings, "upx:", job.Dist, "cannot be packed by upx, leaving it uncompressed")
+				} else if profile.UPX {
+					var out string
+					out, err = compressBinary(jobCtx, job.Config, job.Dist, profile.UPXFlags)
+					res += out
+				}
+				if err == nil {
+					var out string
+					out, err = runHooks(jobCtx, configFile.Hooks.Post, job.Config, job.Dist)
+					res += out
+				}
+
+				err = timeoutError(jobCtx, err)
+				if errors.Is(err, ErrBuildTimeout) {
+					fmt.Fprintln(os.Stderr, "build:", job.Dist, err)
+				}
+				cancel()
+			}
+
+			// hooks may change the binary, so it is measured last
+			if err == nil {
+				if info, err := os.Stat(results[i].Path); err == nil {
+					results[i].Size = info.Size()
+				}
+				results[i].SizeBudget, err = checkSizeBudget(sizeBudgets, job.Dist, results[i].Size)
+			}
+			buildErrs[i] = err
+
+			results[i].Duration = time.Since(started[i])
+			results[i].Attempts = len(attempts[i])
+			results[i].AttemptResults = attemptResults(attempts[i])
+			results[i].Success = err == nil
+			results[i].Cancelled = err != nil && buildCtx.Err() != nil
+			if err != nil {
+				results[i].Error = err.Error()
+			}
+
+			if cause := failFastCause(buildCtx, failFast, job.Dist.String(), err); cause != nil {
+				cancelBuilds(cause)
+			}
+
+			results[i].Log = buildLogPath(job.Config, job.Dist, len(builds) > 1)
+			if err := writeBuildLog(results[i].Log, results[i], res); err != nil {
+				logger.Warn("build log", "target", job.Dist.String(), "error", err)
+				results[i].Log = ""
+			}
+
+			if w, ok := job.Config.Output.(*prefixWriter); ok {
+				w.Flush()
+			}
+
+			jobProgress.Finish(label, resultStatus(results[i]), results[i].Duration)
 
+			for n, attempt := range attempts[i] {
+				logger.Debug("attempt", "target", job.Dist.String(), "attempt", n+1, "duration", attempt.Duration, "error", attempt.Err)
+			}
+			logger.Info("build",
+				"target", job.Dist.String(),
+				"race", job.Config.Race,
+				"pgo", job.Config.PGOFor(job.Dist),
+				"status", resultStatus(results[i]),
+				"duration", results[i].Duration,
+				"error", err)
+			targetSpan.Set("status", resultStatus(results[i]))
+			targetSpan.Set("size", results[i].Size)
+			targetSpan.Set("attempts", results[i].Attempts)
+			targetSpan.End(err)
+		}()
+
+	}
+
+	wg.Wait()
+	jobProgress.Stop()
+	took := time.Since(buildStart)
+
+	removeFiles(sysoFiles)
+
+	var sizeDeltas []sizeDelta
+	if baselineSizes != nil {
+		sizeDeltas = compareSizes(baselineSizes, results)
+		for i, d := range sizeDeltas {
+			if err := growth.check(d); err != nil {
+				buildErrs[i] = err
+				results[i].Success = false
+				results[i].Error = err.Error()
+			}
+		}
+	}
+
+	// reports are written before a cancelled run exits, as CI wants the
+	// partial results too
+	report := newBuildReport(config.BinaryName, results)
+	report.Duration = took
+	reportFiles, err := writeReports(reports, config.OutputDir, report)
+	if err != nil {
+		log.Fatalln("report:", err)
+	}
+
+	logger.Debug("reports", "files", reportFiles)
+
+	// notify tells the configured webhooks how the run went, with links to
+	// the artifacts
+	notify := func(links []string) {
+		if len(configFile.Notify) == 0 {
+			return
+		}
+
+		version, _ := tag()
+		if err := sendNotifications(context.WithoutCancel(ctx), configFile.Notify, newNotification(report, version, links)); err != nil {
+			logger.Warn("notify", "error", err)
+		}
+	}
+
+	if metricsFile != "" {
+		if err := writeMetricsFile(metricsFile, report, time.Now()); err != nil {
+			log.Fatalln("metrics:", err)
+		}
+	}
+
+	if pushgateway != "" {
+		if err := pushMetrics(context.WithoutCancel(ctx), pushgateway, report, time.Now()); err != nil {
+			logger.Warn("metrics", "error", err)
+		}
+	}
+
+	if githubActions() {
+		writeWorkflowCommands(os.Stdout, results)
+
+		err := appendGitHubFile("GITHUB_STEP_SUMMARY", func(w io.Writer) error {
+			writeJobSummary(w, report)
+			return nil
+		})
+		if err != nil {
+			log.Fatalln("github actions:", err)
+		}
+	}
+
+	if buildCtx.Err() != nil {
+		completed, failed, stopped := []string{}, []string{}, []string{}
+		for i, job := range jobs {
+			name := filepath.Base(builder.OutputPath(job.Config, job.Dist))
+			if buildErrs[i] == nil {
+				completed = append(completed, name)
+			} else if results[i].Cancelled {
+				removePartialOutput(builder.OutputPath(job.Config, job.Dist), started[i])
+				stopped = append(stopped, name)
+			} else {
+				failed = append(failed, name)
+			}
+		}
+
+		endTrace(context.Cause(buildCtx))
+		notify(nil)
+
+		if ctx.Err() == nil {
+			writeCancelSummary(os.Stderr, fmt.Sprintf("Stopped because %v", context.Cause(buildCtx)), completed, failed, stopped, errColors)
+			os.Exit(1)
+		}
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			writeCancelSummary(os.Stderr, fmt.Sprintf("Deadline of %s exceeded", deadline), completed, failed, stopped, errColors)
+			os.Exit(1)
+		}
+
+		writeCancelSummary(os.Stderr, "Interrupted", completed, failed, stopped, errColors)
+		os.Exit(130)
+	}
+
+	if len(jobs) > 0 {
+		writeBuildSummary(stdout, results, took, colors)
+		writeTimings(stdout, results, took)
+	}
+
+	if sizeDeltas != nil {
+		fmt.Fprintln(stdout)
+		writeSizeComparison(stdout, sizeDeltas)
+	}
+
+	if quiet {
+		writeFailures(os.Stderr, results, errColors)
+	}
+
+	// fresh are the jobs that were rebuilt rather than up to date
+	built := []buildJob{}
+	fresh := []buildJob{}
+	for i, job := range jobs {
+		if buildErrs[i] == nil {
+			built = append(built, job)
+			addArtifact(builder.OutputPath(job.Config, job.Dist))
+		}
+
+		if buildErrs[i] == nil && !skipped[i] {
+			fresh = append(fresh, job)
+		}
+	}
+
+	_, packageSpan := tracer.Start(ctx, "package", "binaries", len(built))
+
+	// signatures of the thin binaries carry over into the universal binary
+	if !configFile.Codesign.IsEmpty() {
+		signed, err := codesignBinaries(ctx, config, configFile.Codesign, fresh)
+		if err != nil {
+			log.Fatalln("codesign:", err)
+		}
+
+		logger.Debug("codesign", "files", signed)
+	}
+
+	if !configFile.Authenticode.IsEmpty() {
+		signed, err := authenticodeBinaries(ctx, config, configFile.Authenticode, fresh)
+		if err != nil {
+			log.Fatalln("authenticode:", err)
+		}
+
+		logger.Debug("authenticode", "files", signed)
+	}
+
+	// stamps are written after signing, which changes the binaries
+	for i, job := range jobs {
+		if buildErrs[i] == nil && !skipped[i] && fingerprints[i] != "" {
+			if err := writeStamp(builder.OutputPath(job.Config, job.Dist), fingerprints[i]); err != nil {
+				logger.Debug("stamp", "target", job.Dist.String(), "error", err)
+			}
+		}
+	}
+
+	universalBinaries := []string{}
+	if universal {
+		darwinBuilds := universalBuilds(built)
+
+		for _, name := range slices.Sorted(maps.Keys(darwinBuilds)) {
+			amd64, okAmd64 := darwinBuilds[name]["amd64"]
+			arm64, okArm64 := darwinBuilds[name]["arm64"]
+
+			if !okAmd64 || !okArm64 {
+				fmt.Fprintln(warnings, "Skipping universal binary, darwin/amd64 and darwin/arm64 executables were not both built for", name)
+				continue
+			}
+
+			fp := universalOutputPath(amd64.Config)
+			if err := mergeMachO(fp, builder.OutputPath(amd64.Config, amd64.Dist), builder.OutputPath(arm64.Config, arm64.Dist)); err != nil {
+				log.Fatalln("universal:", err)
+			}
+
+			universalBinaries = append(universalBinaries, fp)
+			addArtifact(fp)
+			logger.Debug("universal", "file", fp)
+		}
+
+		if len(darwinBuilds) == 0 {
+			fmt.Fprintln(warnings, "Skipping universal binary, darwin/amd64 and darwin/arm64 executables were not both built")
+		}
+	}
+
+	if wasmExec && slices.ContainsFunc(buildDists, func(d builder.GoDist) bool {
+		return d.GOOS == "js" && d.GOARCH == "wasm"
+	}) {
+		fp, err := copyWasmExec(ctx, config.OutputDir)
+		if err != nil {
+			log.Fatalln("wasm exec:", err)
+		}
+
+		addArtifact(fp)
+		logger.Debug("wasm exec", "file", fp)
+	}
+
+	if !configFile.NFPM.IsEmpty() {
+		version, err := tag()
+		if err != nil {
+			log.Fatalln("nfpm:", err)
+		}
+
+		packages, err := buildPackages(ctx, config, configFile.NFPM, built, version)
+		for _, fp := range packages {
+			addArtifact(fp)
+		}
+
+		if err != nil {
+			log.Fatalln("nfpm:", err)
+		}
+
+		logger.Debug("nfpm", "files", packages)
+	}
+
+	if !configFile.Snap.IsEmpty() {
+		version, err := tag()
+		if err != nil {
+			log.Fatalln("snap:", err)
+		}
+
+		snaps, err := buildSnaps(ctx, config, configFile.Snap, built, version)
+		for _, fp := range snaps {
+			addArtifact(fp)
+		}
+
+		if err != nil {
+			log.Fatalln("snap:", err)
+		}
+
+		logger.Debug("snap", "files", snaps)
+	}
+
+	if !configFile.AppImage.IsEmpty() {
+		appImages, err := buildAppImages(ctx, config, configFile.AppImage, built)
+		for _, fp := range appImages {
+			addArtifact(fp)
+		}
+
+		if err != nil {
+			log.Fatalln("appimage:", err)
+		}
+
+		logger.Debug("appimage", "files", appImages)
+	}
+
+	if !configFile.MSI.IsEmpty() {
+		version, err := tag()
+		if err != nil {
+			log.Fatalln("msi:", err)
+		}
+
+		msis, err := buildMSIs(ctx, config, configFile.MSI, built, version)
+		for _, fp := range msis {
+			addArtifact(fp)
+		}
+
+		if err != nil {
+			log.Fatalln("msi:", err)
+		}
+
+		logger.Debug("msi", "files", msis)
+	}
+
+	if !configFile.MacPkg.IsEmpty() {
+		version, err := tag()
+		if err != nil {
+			log.Fatalln("pkg:", err)
+		}
+
+		pkgs, err := buildMacPkgs(ctx, config, configFile.MacPkg, built, universalBinaries, version)
+		for _, fp := range pkgs {
+			addArtifact(fp)
+		}
+
+		if err != nil {
+			log.Fatalln("pkg:", err)
+		}
+
+		logger.Debug("pkg", "files", pkgs)
+	}
+
+	archives := map[string]string{}
+	if !configFile.Archive.IsEmpty() || !configFile.Homebrew.IsEmpty() || !configFile.Scoop.IsEmpty() ||
+		!configFile.Chocolatey.IsEmpty() || !configFile.Winget.IsEmpty() || !configFile.AUR.IsEmpty() ||
+		!configFile.Nix.IsEmpty() {
+		var written []string
+		archives, written, err = buildArchives(config, configFile.Archive, built)
+		for _, fp := range written {
+			addArtifact(fp)
+		}
+
+		if err != nil {
+			log.Fatalln("archive:", err)
+		}
+	}
+
+	if configFile.InstallScripts {
+		version, err := tag()
+		if err != nil {
+			log.Fatalln("install scripts:", err)
+		}
+
+		scripts, err := writeInstallScripts(config, built, version, downloadURL)
+		for _, fp := range scripts {
+			addArtifact(fp)
+		}
+
+		if err != nil {
+			log.Fatalln("install scripts:", err)
+		}
+	}
+
+	packageSpan.Set("artifacts", len(artifacts))
+	packageSpan.End(nil)
+
+	slices.Sort(artifacts)
+
+	// later steps of the workflow can pick up the artifacts and version
+	if githubActions() {
+		outputs := map[string]string{"artifacts": strings.Join(artifacts, "\n")}
+		if version, err := tag(); err == nil {
+			outputs["version"] = version
+		}
+
+		err := appendGitHubFile("GITHUB_OUTPUT", func(w io.Writer) error {
+			return writeStepOutputs(w, outputs)
+		})
+		if err != nil {
+			log.Fatalln("github actions:", err)
+		}
+	}
+
+	_, uploadSpan := tracer.Start(ctx, "upload")
+
+	if configFile.Image.Name != "" {
+		images, err := buildImages(ctx, config, configFile.Image, built)
+		if err != nil {
+			log.Fatalln("image:", err)
+		}
+
+		logger.Debug("image", "refs", images)
+	}
+
+	if publisher != nil {
+		if len(built) != len(jobs) {
+			log.Fatalf("publish: %d of %d builds failed, not publishing\n", len(jobs)-len(built), len(jobs))
+		}
+
+		release := Release{Draft: draft, Prerelease: prerelease}
+		release.Tag, err = tag()
+		if err != nil {
+			log.Fatalln("publish:", err)
+		}
+		release.Name = release.Tag
+
+		checksums, err := writeChecksums(config.OutputDir, artifacts)
+		if err != nil {
+			log.Fatalln("checksums:", err)
+		}
+
+		files := append(releaseFiles(artifacts), checksums)
+
+		releaseURL, err := publisher.Publish(ctx, release, files)
+		if err != nil {
+			log.Fatalln("publish:", err)
+		}
+
+		fmt.Fprintln(stdout, "Published", release.Tag, "to", releaseURL)
+	}
+
+	if configFile.Upload.URL != "" {
+		if len(built) != len(jobs) {
+			log.Fatalf("upload: %d of %d builds failed, not uploading\n", len(jobs)-len(built), len(jobs))
+		}
+
+		upload := configFile.Upload
+		if strings.Contains(upload.URL, "{tag}") {
+			version, err := tag()
+			if err != nil {
+				log.Fatalln("upload:", err)
+			}
+
+			upload.URL = strings.ReplaceAll(upload.URL, "{tag}", version)
+		}
+
+		keys, err := uploadOutputDir(ctx, upload, config.OutputDir)
+		if err != nil {
+			log.Fatalln("upload:", err)
+		}
+
+		fmt.Fprintln(stdout, "Uploaded", len(keys), "files to", upload.URL)
+	}
+
+	if configFile.OCI.Ref != "" {
+		if err := pushOCIArtifacts(ctx, config, configFile.OCI, artifacts); err != nil {
+			log.Fatalln("oci:", err)
+		}
+
+		logger.Debug("oci", "ref", configFile.OCI.Ref)
+	}
+
+	if !configFile.Homebrew.IsEmpty() {
+		version, err := tag()
+		if err != nil {
+			log.Fatalln("homebrew:", err)
+		}
+
+		fp, err := writeFormula(config, configFile.Homebrew, archives, version, downloadURL)
+		if err != nil {
+			log.Fatalln("homebrew:", err)
+		}
+
+		if configFile.Homebrew.Tap != "" {
+			message := fmt.Sprintf("%s %s", strings.TrimSuffix(filepath.Base(fp), ".rb"), version)
+			if err := pushToRepo(ctx, configFile.Homebrew.Tap, "Formula", message, fp); err != nil {
+				log.Fatalln("homebrew:", err)
+			}
+		}
+
+		logger.Debug("homebrew", "file", fp)
+	}
+
+	if !configFile.Scoop.IsEmpty() {
+		version, err := tag()
+		if err != nil {
+			log.Fatalln("scoop:", err)
+		}
+
+		fp, err := writeScoopManifest(config, configFile.Scoop, archives, version, downloadURL)
+		if err != nil {
+			log.Fatalln("scoop:", err)
+		}
+
+		if configFile.Scoop.Bucket != "" {
+			message := fmt.Sprintf("%s %s", strings.TrimSuffix(filepath.Base(fp), ".json"), version)
+			if err := pushToRepo(ctx, configFile.Scoop.Bucket, "bucket", message, fp); err != nil {
+				log.Fatalln("scoop:", err)
+			}
+		}
+
+		logger.Debug("scoop", "file", fp)
+	}
+
+	if !configFile.Chocolatey.IsEmpty() {
+		version, err := tag()
+		if err != nil {
+			log.Fatalln("chocolatey:", err)
+		}
+
+		fp, err := writeNupkg(config, configFile.Chocolatey, archives, version, downloadURL)
+		if err != nil {
+			log.Fatalln("chocolatey:", err)
+		}
+
+		if configFile.Chocolatey.Push {
+			if err := pushNupkg(ctx, configFile.Chocolatey, fp); err != nil {
+				log.Fatalln("chocolatey:", err)
+			}
+		}
+
+		logger.Debug("chocolatey", "file", fp)
+	}
+
+	if !configFile.Winget.IsEmpty() {
+		version, err := tag()
+		if err != nil {
+			log.Fatalln("winget:", err)
+		}
+
+		dir, err := writeWingetManifests(config, configFile.Winget, archives, version, downloadURL)
+		if err != nil {
+			log.Fatalln("winget:", err)
+		}
+
+		fmt.Fprintln(stdout, "Wrote winget manifests to", dir)
+	}
+
+	if !configFile.AUR.IsEmpty() {
+		version, err := tag()
+		if err != nil {
+			log.Fatalln("aur:", err)
+		}
+
+		files, err := writePKGBUILD(config, configFile.AUR, archives, version, downloadURL)
+		if err != nil {
+			log.Fatalln("aur:", err)
+		}
+
+		if configFile.AUR.Repo != "" {
+			if err := pushToRepo(ctx, configFile.AUR.Repo, ".", "Update to "+version, files...); err != nil {
+				log.Fatalln("aur:", err)
+			}
+		}
+
+		logger.Debug("aur", "files", files)
+	}
+
+	if !configFile.Nix.IsEmpty() {
+		version, err := tag()
+		if err != nil {
+			log.Fatalln("nix:", err)
+		}
+
+		fp, err := writeNixDerivation(config, configFile.Nix, archives, version, downloadURL)
+		if err != nil {
+			log.Fatalln("nix:", err)
+		}
+
+		logger.Debug("nix", "file", fp)
+	}
+
+	uploadSpan.End(nil)
+
+	links := slices.Clone(artifacts)
+	if version, err := tag(); err == nil && (publisher != nil || configFile.ReleaseURL != "") {
+		for i, fp := range links {
+			links[i] = downloadURL(version, filepath.Base(fp))
+		}
+	}
+	notify(links)
+
+	var runErr error
+	if len(built) != len(jobs) {
+		runErr = fmt.Errorf("%d of %d builds failed", len(jobs)-len(built), len(jobs))
+	}
+	endTrace(runErr)
+
+	// a non-zero exit lets scripts and batch runs see failed targets
+	if len(built) != len(jobs) {
+		log.Fatalf("%d of %d builds failed\n", len(jobs)-len(built), len(jobs))
+	}
 }
@@ -1,251 +1,201 @@
 package main
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"runtime"
 	"slices"
 	"strings"
 	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
 )
 
 var currentWD, _ = os.Getwd()
 
-// just example input, not taken from true
-// go tool dist list
-var testingDists = []GoDist{
-	GoDist{
-		GOOS:         "windows",
-		GOARCH:       "x86",
-		CgoSupported: true,
-		FirstClass:   true,
-	},
-	GoDist{
-		GOOS:         "darwin",
-		GOARCH:       "arm64",
-		CgoSupported: true,
-		FirstClass:   true,
-	},
-	GoDist{
-		GOOS:         "linux",
-		GOARCH:       "x86",
-		CgoSupported: true,
-		FirstClass:   true,
-	},
-	GoDist{
-		GOOS:         "linux",
-		GOARCH:       "arm64",
-		CgoSupported: true,
-		FirstClass:   true,
-	},
-	GoDist{
-		GOOS:         "bsd",
-		GOARCH:       "arm64",
-		CgoSupported: true,
-		FirstClass:   false,
-	},
-}
+func TestGetProjectName(t *testing.T) {
+	windowsPath := "C:/Users/username/projects/myproject"
+	unixPath := "/usr/home/username/projects/myproject"
+	if runtime.GOOS == "windows" {
+		windowsPath = strings.ReplaceAll(windowsPath, "/", "\\")
+		unixPath = strings.ReplaceAll(unixPath, "/", "\\")
+	}
 
-func TestGetTargetBuilds(t *testing.T) {
 	testCases := []struct {
-		name    string
-		targets []OSARCH
-		dists   []GoDist
-		wants   []GoDist
+		name  string
+		input string
+		wants string
+		err   error
 	}{
 		{
-			name: "windows only",
-			targets: []OSARCH{
-				OSARCH{
-					OS:   "windows",
-					ARCH: "",
-				},
-			},
-			dists: testingDists,
-			wants: []GoDist{
-				GoDist{
-					GOOS:         "windows",
-					GOARCH:       "x86",
-					CgoSupported: true,
-					FirstClass:   true,
-				},
-			},
-		},
-		{
-			name: "linux only",
-			targets: []OSARCH{
-				OSARCH{
-					OS:   "linux",
-					ARCH: "",
-				},
-			},
-			dists: testingDists,
-			wants: []GoDist{
-				GoDist{
-					GOOS:         "linux",
-					GOARCH:       "x86",
-					CgoSupported: true,
-					FirstClass:   true,
-				},
-				GoDist{
-					GOOS:         "linux",
-					GOARCH:       "arm64",
-					CgoSupported: true,
-					FirstClass:   true,
-				},
-			},
+			name:  "Windows example",
+			input: `C:/Users/username/projects/myproject`,
+			wants: "myproject",
+			err:   nil,
 		},
 		{
-			name: "linux x86",
-			targets: []OSARCH{
-				OSARCH{
-					OS:   "linux",
-					ARCH: "x86",
-				},
-			},
-			dists: testingDists,
-			wants: []GoDist{
-				GoDist{
-					GOOS:         "linux",
-					GOARCH:       "x86",
-					CgoSupported: true,
-					FirstClass:   true,
-				},
-			},
+			name:  "Unix-style example",
+			input: "/usr/home/username/projects/myproject",
+			wants: "myproject",
+			err:   nil,
 		},
 		{
-			name:    "empty targets",
-			targets: []OSARCH{},
-			dists:   testingDists,
-			wants:   testingDists,
+			name:  "current dir (.)",
+			input: ".",
+			wants: filepath.Base(currentWD),
+			err:   nil,
 		},
 	}
 
 	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			res := getTargetBuilds(tc.targets, tc.dists)
-
-			// could be in one line but it will end up wrapping anyway
-			cmp := slices.CompareFunc(res, tc.wants, func(a GoDist, b GoDist) int {
-				if a.GOOS == b.GOOS {
-					if a.GOARCH == b.GOARCH {
-						if a.CgoSupported == b.CgoSupported {
-							if a.FirstClass == b.FirstClass {
-								return 0
-							}
-							return -4
-						}
-						return -3
-					}
-
-					return -2
-
-				}
-
-				return -1
-			})
-
-			if cmp != 0 {
-				t.Logf("Incorrect target dist returned (cmp: %d), wanted:\n%v\ngot:\n%v\n", cmp, tc.wants, res)
-				t.Fail()
-			}
-		})
+		res, err := getProjectName(tc.input)
+
+		if res != tc.wants {
+			t.Logf("Incorrect project name formulated, wanted: %v got: %v\n", tc.wants, res)
+			t.Fail()
+		} else if err != tc.err {
+			t.Logf("Incorrect error returned, wanted: %v got: %v\n", tc.err, err)
+			t.Fail()
+		}
 	}
+
 }
 
-func TestParseStringToOSARCH(t *testing.T) {
+func TestExpandTargetAliases(t *testing.T) {
 	testCases := []struct {
-		name  string
-		input string
-		wants OSARCH
-		err   error
+		name    string
+		input   []string
+		aliases map[string][]string
+		wants   []string
 	}{
 		{
-			name:  "windows/x86",
-			input: "windows/x86",
-			wants: OSARCH{OS: "windows", ARCH: "x86"},
-			err:   nil,
+			name:    "no aliases",
+			input:   []string{"linux", "windows/amd64"},
+			aliases: map[string][]string{},
+			wants:   []string{"linux", "windows/amd64"},
 		},
 		{
-			name:  "WINDOWS/X86",
-			input: "WINDOWS/x86",
-			wants: OSARCH{OS: "windows", ARCH: "x86"},
-			err:   nil,
+			name:    "builtin alias",
+			input:   []string{"Mobile"},
+			aliases: map[string][]string{},
+			wants:   []string{"android/arm64", "ios/arm64"},
 		},
 		{
-			name:  "windows",
-			input: "windows",
-			wants: OSARCH{OS: "windows", ARCH: ""},
-			err:   nil,
+			name:    "config alias overrides builtin",
+			input:   []string{"mobile", "plan9"},
+			aliases: map[string][]string{"mobile": {"android/amd64"}},
+			wants:   []string{"android/amd64", "plan9"},
 		},
 		{
-			name:  "blank",
-			input: "",
-			wants: OSARCH{OS: "", ARCH: ""},
-			err:   ErrInvalidOSARCH,
+			name:  "nested alias",
+			input: []string{"all-mine"},
+			aliases: map[string][]string{
+				"all-mine": {"mobile", "js/wasm"},
+			},
+			wants: []string{"android/arm64", "ios/arm64", "js/wasm"},
+		},
+		{
+			name:    "negated alias",
+			input:   []string{"linux", "!mobile"},
+			aliases: map[string][]string{"mobile": {"android/amd64", "linux/arm64"}},
+			wants:   []string{"linux", "!android/amd64", "!linux/arm64"},
+		},
+		{
+			name:    "self referencing alias",
+			input:   []string{"loop"},
+			aliases: map[string][]string{"loop": {"loop", "linux"}},
+			wants:   []string{"loop", "linux"},
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			res, err := parseStringToOSARCH(tc.input)
+			res := expandTargetAliases(tc.input, tc.aliases)
 
-			if res != tc.wants {
-				t.Logf("Incorrect OSARCH formulated, wanted: %v got: %v\n", tc.wants, res)
-				t.Fail()
-			} else if err != tc.err {
-				t.Logf("Incorrect error returned, wanted: %v got: %v\n", tc.err, err)
+			if !slices.Equal(res, tc.wants) {
+				t.Logf("Incorrect alias expansion, wanted: %v got: %v\n", tc.wants, res)
 				t.Fail()
 			}
 		})
 	}
+}
+
+func TestSelectsAll(t *testing.T) {
+	testCases := []struct {
+		input []string
+		wants bool
+	}{
+		{input: []string{}, wants: false},
+		{input: []string{"linux", "!linux/mips*"}, wants: false},
+		{input: []string{"!windows"}, wants: true},
+		{input: []string{"linux", "ALL"}, wants: true},
+	}
 
+	for _, tc := range testCases {
+		if res := selectsAll(tc.input); res != tc.wants {
+			t.Logf("Incorrect result for %v, wanted: %v got: %v\n", tc.input, tc.wants, res)
+			t.Fail()
+		}
+	}
 }
 
-func TestGetProjectName(t *testing.T) {
-	windowsPath := "C:/Users/username/projects/myproject"
-	unixPath := "/usr/home/username/projects/myproject"
-	if runtime.GOOS == "windows" {
-		windowsPath = strings.ReplaceAll(windowsPath, "/", "\\")
-		unixPath = strings.ReplaceAll(unixPath, "/", "\\")
+func TestDefaultTargets(t *testing.T) {
+	host := []builder.OSARCH{{OS: runtime.GOOS, ARCH: runtime.GOARCH}}
+
+	testCases := []struct {
+		name        string
+		input       []string
+		all         bool
+		interactive bool
+		wants       []builder.OSARCH
+	}{
+		{name: "host only", input: []string{}, wants: host},
+		{name: "targets", input: []string{"linux/amd64"}, wants: nil},
+		{name: "all", input: []string{}, all: true, wants: nil},
+		{name: "all with negation", input: []string{"!windows"}, all: true, wants: nil},
+		{name: "interactive", input: []string{}, interactive: true, wants: nil},
 	}
 
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if res := defaultTargets(tc.input, tc.all, tc.interactive); !slices.Equal(res, tc.wants) {
+				t.Logf("Incorrect default targets, wanted: %v got: %v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestProjectPGOProfile(t *testing.T) {
+	projectDir := filepath.Join(currentWD, "app")
+
 	testCases := []struct {
 		name  string
 		input string
 		wants string
 		err   error
 	}{
-		{
-			name:  "Windows example",
-			input: `C:/Users/username/projects/myproject`,
-			wants: "myproject",
-			err:   nil,
-		},
-		{
-			name:  "Unix-style example",
-			input: "/usr/home/username/projects/myproject",
-			wants: "myproject",
-			err:   nil,
-		},
-		{
-			name:  "current dir (.)",
-			input: ".",
-			wants: filepath.Base(currentWD),
-			err:   nil,
-		},
+		{name: "auto", input: "auto", wants: "auto"},
+		{name: "off", input: "off", wants: "off"},
+		{name: "unset", input: "", wants: ""},
+		{name: "project relative", input: "default.pgo", wants: "default.pgo"},
+		{name: "inside project", input: filepath.Join(projectDir, "profiles", "cpu.pprof"), wants: "profiles/cpu.pprof"},
+		{name: "outside project", input: filepath.Join(currentWD, "cpu.pprof"), err: ErrPGOOutsideProject},
+		{name: "sibling directory", input: filepath.Join(currentWD, "app2", "cpu.pprof"), err: ErrPGOOutsideProject},
 	}
 
 	for _, tc := range testCases {
-		res, err := getProjectName(tc.input)
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := projectPGOProfile(tc.input, projectDir)
 
-		if res != tc.wants {
-			t.Logf("Incorrect project name formulated, wanted: %v got: %v\n", tc.wants, res)
-			t.Fail()
-		} else if err != tc.err {
-			t.Logf("Incorrect error returned, wanted: %v got: %v\n", tc.err, err)
-			t.Fail()
-		}
+			if res != tc.wants {
+				t.Logf("Incorrect profile, wanted: %v got: %v\n", tc.wants, res)
+				t.Fail()
+			} else if !errors.Is(err, tc.err) {
+				t.Logf("Incorrect error returned, wanted: %v got: %v\n", tc.err, err)
+				t.Fail()
+			}
+		})
 	}
-
 }
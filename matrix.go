@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var ErrUnknownVariant = errors.New("no variant with this name")
+
+// Variant is an edition of the project, e.g. oss or enterprise, built with
+// its own tags and ldflags and optionally its own main package.
+type Variant struct {
+	// Name is appended to the binary names. The variant without a name
+	// keeps them.
+	Name string `json:"name"`
+	// Package replaces the main package of the builds when set.
+	Package string   `json:"package"`
+	Tags    []string `json:"tags"`
+	// Ldflags are appended to the ldflags of the builds.
+	Ldflags string `json:"ldflags"`
+}
+
+// selectVariants returns the variants named in names, or all of them when
+// names is empty.
+func selectVariants(variants []Variant, names []string) ([]Variant, error) {
+	if len(names) == 0 {
+		return variants, nil
+	}
+
+	selected := []Variant{}
+	for _, name := range names {
+		i := slices.IndexFunc(variants, func(v Variant) bool { return v.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownVariant, name)
+		}
+		selected = append(selected, variants[i])
+	}
+
+	return selected, nil
+}
+
+// variantBuilds repeats every build with each variant, appending the
+// variant name to the binary names, e.g. app-enterprise.
+func variantBuilds(builds []builder.BuildConfig, variants []Variant) []builder.BuildConfig {
+	matrix := []builder.BuildConfig{}
+
+	for _, build := range builds {
+		for _, v := range variants {
+			variant := build
+			variant.Tags = append(slices.Clone(build.Tags), v.Tags...)
+			variant.Ldflags = strings.TrimSpace(build.Ldflags + " " + v.Ldflags)
+			if v.Package != "" {
+				variant.Package = packagePath(build.ProjectDir, v.Package)
+			}
+			if v.Name != "" {
+				variant.BinaryName += "-" + v.Name
+			}
+			matrix = append(matrix, variant)
+		}
+	}
+
+	return matrix
+}
+
+// tagSetBuilds repeats every build with each tag set added to its tags,
+// appending the tags to the binary names, e.g. app-pro. The empty set builds
+// with the tags of the build alone and keeps its name.
+func tagSetBuilds(builds []builder.BuildConfig, tagSets [][]string) []builder.BuildConfig {
+	matrix := []builder.BuildConfig{}
+
+	for _, build := range builds {
+		for _, tags := range tagSets {
+			variant := build
+			variant.Tags = append(slices.Clone(build.Tags), tags...)
+			if len(tags) > 0 {
+				variant.BinaryName += "-" + strings.Join(tags, "-")
+			}
+			matrix = append(matrix, variant)
+		}
+	}
+
+	return matrix
+}
+
+// parseTagSet parses a comma separated -tag-set value. The empty value is
+// the set without tags.
+func parseTagSet(v string) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(v, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestTagSetBuilds(t *testing.T) {
+	app := builder.NewConfig()
+	app.BinaryName = "app"
+	app.Tags = []string{"netgo"}
+
+	res := tagSetBuilds([]builder.BuildConfig{app}, [][]string{{}, {"pro"}, {"enterprise", "sso"}})
+
+	wants := []struct {
+		name string
+		tags []string
+	}{
+		{name: "app", tags: []string{"netgo"}},
+		{name: "app-pro", tags: []string{"netgo", "pro"}},
+		{name: "app-enterprise-sso", tags: []string{"netgo", "enterprise", "sso"}},
+	}
+
+	if len(res) != len(wants) {
+		t.Fatalf("Incorrect number of builds, wanted: %d got: %d\n", len(wants), len(res))
+	}
+
+	for i, want := range wants {
+		if res[i].BinaryName != want.name || !slices.Equal(res[i].Tags, want.tags) {
+			t.Logf("Incorrect build, wanted: %s %v got: %s %v\n", want.name, want.tags, res[i].BinaryName, res[i].Tags)
+			t.Fail()
+		}
+	}
+
+	if len(app.Tags) != 1 {
+		t.Logf("Tags of the original build were changed: %v\n", app.Tags)
+		t.Fail()
+	}
+}
+
+func TestParseTagSet(t *testing.T) {
+	testCases := []struct {
+		input string
+		wants []string
+	}{
+		{input: "", wants: []string{}},
+		{input: "pro", wants: []string{"pro"}},
+		{input: "enterprise, sso,", wants: []string{"enterprise", "sso"}},
+	}
+
+	for _, tc := range testCases {
+		if res := parseTagSet(tc.input); !slices.Equal(res, tc.wants) {
+			t.Logf("Incorrect tags for %q, wanted: %v got: %v\n", tc.input, tc.wants, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestVariantBuilds(t *testing.T) {
+	app := builder.NewConfig()
+	app.ProjectDir = t.TempDir()
+	app.BinaryName = "app"
+	app.Ldflags = "-s -w"
+	app.Tags = []string{"netgo"}
+
+	if err := os.MkdirAll(filepath.Join(app.ProjectDir, "cmd", "app-ee"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	variants := []Variant{
+		{Name: "oss"},
+		{Name: "enterprise", Package: "cmd/app-ee", Tags: []string{"ee"}, Ldflags: "-X main.edition=enterprise"},
+	}
+
+	res := variantBuilds([]builder.BuildConfig{app}, variants)
+
+	wants := []struct {
+		name    string
+		pkg     string
+		tags    []string
+		ldflags string
+	}{
+		{name: "app-oss", pkg: app.Package, tags: []string{"netgo"}, ldflags: "-s -w"},
+		{name: "app-enterprise", pkg: "./cmd/app-ee", tags: []string{"netgo", "ee"}, ldflags: "-s -w -X main.edition=enterprise"},
+	}
+
+	if len(res) != len(wants) {
+		t.Fatalf("Incorrect number of builds, wanted: %d got: %d\n", len(wants), len(res))
+	}
+
+	for i, want := range wants {
+		if res[i].BinaryName != want.name || res[i].Package != want.pkg || !slices.Equal(res[i].Tags, want.tags) || res[i].Ldflags != want.ldflags {
+			t.Logf("Incorrect build, wanted: %s %s %v %q got: %s %s %v %q\n", want.name, want.pkg, want.tags, want.ldflags, res[i].BinaryName, res[i].Package, res[i].Tags, res[i].Ldflags)
+			t.Fail()
+		}
+	}
+}
+
+func TestSelectVariants(t *testing.T) {
+	variants := []Variant{{Name: "oss"}, {Name: "enterprise"}}
+
+	testCases := []struct {
+		names []string
+		wants []string
+		err   error
+	}{
+		{names: nil, wants: []string{"oss", "enterprise"}},
+		{names: []string{"enterprise"}, wants: []string{"enterprise"}},
+		{names: []string{"pro"}, err: ErrUnknownVariant},
+	}
+
+	for _, tc := range testCases {
+		res, err := selectVariants(variants, tc.names)
+		if !errors.Is(err, tc.err) {
+			t.Logf("Incorrect error for %v, wanted: %v got: %v\n", tc.names, tc.err, err)
+			t.Fail()
+			continue
+		}
+
+		names := []string{}
+		for _, v := range res {
+			names = append(names, v.Name)
+		}
+		if err == nil && !slices.Equal(names, tc.wants) {
+			t.Logf("Incorrect variants for %v, wanted: %v got: %v\n", tc.names, tc.wants, names)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var ErrMetricsPush = errors.New("unable to push metrics")
+
+// metricsJob is the Pushgateway job the metrics of a run are grouped under,
+// along with the project.
+const metricsJob = "go_builder"
+
+var promLabelValue = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// promLabels formats label pairs such as "target", "linux/amd64" as
+// {target="linux/amd64"}, or nothing without pairs.
+func promLabels(pairs ...string) string {
+	labels := []string{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		labels = append(labels, fmt.Sprintf(`%s="%s"`, pairs[i], promLabelValue.Replace(pairs[i+1])))
+	}
+
+	if len(labels) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+// writeMetrics writes the metrics of a run in the Prometheus text format,
+// as read by the node exporter textfile collector and the Pushgateway, with
+// the base labels on every series.
+func writeMetrics(w io.Writer, report buildReport, finished time.Time, base ...string) {
+	counts := map[string]int{"ok": 0, "up to date": 0, "failed": 0, "cancelled": 0}
+	for _, result := range report.Results {
+		counts[resultStatus(result)]++
+	}
+
+	success := 0
+	if report.Success {
+		success = 1
+	}
+
+	fmt.Fprintln(w, "# HELP go_builder_targets Targets of the last run by status.")
+	fmt.Fprintln(w, "# TYPE go_builder_targets gauge")
+	for _, status := range []string{"ok", "up to date", "failed", "cancelled"} {
+		fmt.Fprintf(w, "go_builder_targets%s %d\n", promLabels(append(base, "status", strings.ReplaceAll(status, " ", "_"))...), counts[status])
+	}
+
+	fmt.Fprintln(w, "# HELP go_builder_run_success Whether every target of the last run built.")
+	fmt.Fprintln(w, "# TYPE go_builder_run_success gauge")
+	fmt.Fprintf(w, "go_builder_run_success%s %d\n", promLabels(base...), success)
+
+	fmt.Fprintln(w, "# HELP go_builder_run_duration_seconds Wall-clock time of the last run.")
+	fmt.Fprintln(w, "# TYPE go_builder_run_duration_seconds gauge")
+	fmt.Fprintf(w, "go_builder_run_duration_seconds%s %g\n", promLabels(base...), report.Duration.Seconds())
+
+	fmt.Fprintln(w, "# HELP go_builder_run_timestamp_seconds When the last run finished.")
+	fmt.Fprintln(w, "# TYPE go_builder_run_timestamp_seconds gauge")
+	fmt.Fprintf(w, "go_builder_run_timestamp_seconds%s %d\n", promLabels(base...), finished.Unix())
+
+	targetLabels := func(result builder.BuildResult) string {
+		return promLabels(append(base, "target", result.Target, "race", strconv.FormatBool(result.Race))...)
+	}
+
+	fmt.Fprintln(w, "# HELP go_builder_target_success Whether the target built in the last run.")
+	fmt.Fprintln(w, "# TYPE go_builder_target_success gauge")
+	for _, result := range report.Results {
+		value := 0
+		if result.Success {
+			value = 1
+		}
+		fmt.Fprintf(w, "go_builder_target_success%s %d\n", targetLabels(result), value)
+	}
+
+	fmt.Fprintln(w, "# HELP go_builder_target_duration_seconds Build time of the target in the last run.")
+	fmt.Fprintln(w, "# TYPE go_builder_target_duration_seconds gauge")
+	for _, result := range report.Results {
+		if !result.UpToDate && !result.Cancelled {
+			fmt.Fprintf(w, "go_builder_target_duration_seconds%s %g\n", targetLabels(result), result.Duration.Seconds())
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP go_builder_target_size_bytes Size of the binary of the target.")
+	fmt.Fprintln(w, "# TYPE go_builder_target_size_bytes gauge")
+	for _, result := range report.Results {
+		if result.Size > 0 {
+			fmt.Fprintf(w, "go_builder_target_size_bytes%s %d\n", targetLabels(result), result.Size)
+		}
+	}
+}
+
+// writeMetricsFile writes the metrics to fp through a temporary file, so the
+// textfile collector never reads a partial file.
+func writeMetricsFile(fp string, report buildReport, finished time.Time) error {
+	buf := bytes.Buffer{}
+	writeMetrics(&buf, report, finished, "project", report.Project)
+
+	tmp, err := os.CreateTemp(filepath.Dir(fp), ".metrics-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), fp)
+}
+
+// pushMetrics replaces the metrics of the project on the Pushgateway at
+// gateway, e.g. http://pushgateway:9091.
+func pushMetrics(ctx context.Context, gateway string, report buildReport, finished time.Time) error {
+	// the project is a grouping label, which the gateway adds to every
+	// series
+	body := bytes.Buffer{}
+	writeMetrics(&body, report, finished)
+
+	endpoint := strings.TrimSuffix(gateway, "/") + "/metrics/job/" + metricsJob + "/project/" + url.PathEscape(report.Project)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%w: %s: %s", ErrMetricsPush, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	return nil
+}
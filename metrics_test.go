@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	report := newBuildReport("app", []builder.BuildResult{
+		{Target: "linux/amd64", Size: 2048, Duration: 1500 * time.Millisecond, Success: true},
+		{Target: "linux/amd64", Race: true, Size: 4096, Success: true, UpToDate: true},
+		{Target: "windows/arm64", Duration: 2 * time.Second, Error: "exit status 1"},
+	})
+	report.Duration = 3 * time.Second
+
+	out := strings.Builder{}
+	writeMetrics(&out, report, time.Unix(1700000000, 0), "project", `my "app"`)
+
+	for _, line := range []string{
+		`go_builder_targets{project="my \"app\"",status="ok"} 1`,
+		`go_builder_targets{project="my \"app\"",status="up_to_date"} 1`,
+		`go_builder_targets{project="my \"app\"",status="failed"} 1`,
+		`go_builder_run_success{project="my \"app\""} 0`,
+		`go_builder_run_duration_seconds{project="my \"app\""} 3`,
+		`go_builder_run_timestamp_seconds{project="my \"app\""} 1700000000`,
+		`go_builder_target_success{project="my \"app\"",target="windows/arm64",race="false"} 0`,
+		`go_builder_target_duration_seconds{project="my \"app\"",target="linux/amd64",race="false"} 1.5`,
+		`go_builder_target_size_bytes{project="my \"app\"",target="linux/amd64",race="true"} 4096`,
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Logf("Missing metric %s in:\n%s\n", line, out.String())
+			t.Fail()
+		}
+	}
+
+	if strings.Contains(out.String(), `go_builder_target_duration_seconds{project="my \"app\"",target="linux/amd64",race="true"}`) {
+		t.Log("Up-to-date target has a build duration")
+		t.Fail()
+	}
+}
+
+func TestPushMetrics(t *testing.T) {
+	var method, path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.Path, string(raw)
+	}))
+	defer server.Close()
+
+	report := newBuildReport("app", []builder.BuildResult{{Target: "linux/amd64", Size: 2048, Success: true}})
+
+	if err := pushMetrics(context.Background(), server.URL+"/", report, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if method != http.MethodPut || path != "/metrics/job/go_builder/project/app" {
+		t.Logf("Incorrect request: %s %s\n", method, path)
+		t.Fail()
+	}
+
+	if !strings.Contains(body, "go_builder_run_success 1\n") || strings.Contains(body, "project=") {
+		t.Logf("Incorrect metrics pushed:\n%s\n", body)
+		t.Fail()
+	}
+}
+
+func TestWriteMetricsFile(t *testing.T) {
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "go_builder.prom")
+
+	report := newBuildReport("app", []builder.BuildResult{{Target: "linux/amd64", Success: true}})
+	if err := writeMetricsFile(fp, report, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(fp)
+	if err != nil || !strings.Contains(string(raw), `go_builder_run_success{project="app"} 1`) {
+		t.Logf("Incorrect metrics file, error: %v contents:\n%s\n", err, raw)
+		t.Fail()
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Logf("Temporary file left behind: %v\n", entries)
+		t.Fail()
+	}
+}
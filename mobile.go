@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var ErrInvalidMobileMode = errors.New("mobile mode must be bind or build")
+
+// MobileConfig controls how android and ios targets are handed to gomobile
+// instead of go build.
+type MobileConfig struct {
+	// Mode is "bind" to produce an .aar/.xcframework library or "build" to
+	// produce an .apk/.app. Empty disables gomobile entirely.
+	Mode string `json:"mode"`
+	// Package is the package passed to gomobile, relative to the project
+	// directory. Bind requires a non-main package.
+	Package    string `json:"package"`
+	BundleID   string `json:"bundle_id"`
+	AndroidAPI int    `json:"android_api"`
+}
+
+// Validate reports a mode other than bind or build. An empty mode is valid
+// and leaves android and ios to go build.
+func (m MobileConfig) Validate() error {
+	if m.Mode != "" && m.Mode != "bind" && m.Mode != "build" {
+		return fmt.Errorf("%w: %s", ErrInvalidMobileMode, m.Mode)
+	}
+
+	return nil
+}
+
+// splitMobileDists separates the android and ios dists, keyed by GOOS, from
+// the ones built with the regular toolchain.
+func splitMobileDists(dists []builder.GoDist) (map[string][]builder.GoDist, []builder.GoDist) {
+	mobile := map[string][]builder.GoDist{}
+	rest := []builder.GoDist{}
+
+	for _, dist := range dists {
+		if dist.GOOS == "android" || dist.GOOS == "ios" {
+			mobile[dist.GOOS] = append(mobile[dist.GOOS], dist)
+		} else {
+			rest = append(rest, dist)
+		}
+	}
+
+	return mobile, rest
+}
+
+func mobileOutputPath(config builder.BuildConfig, mobile MobileConfig, goos string) string {
+	ext := map[string]string{
+		"bind/android":  ".aar",
+		"bind/ios":      ".xcframework",
+		"build/android": ".apk",
+		"build/ios":     ".app",
+	}[mobile.Mode+"/"+goos]
+
+	return filepath.Join(config.OutputDir, fmt.Sprintf("%s-%s%s", config.BinaryName, goos, ext))
+}
+
+// BuildMobile runs a single gomobile invocation covering every selected
+// architecture of goos. The ios/amd64 dist maps to the iossimulator target.
+func BuildMobile(ctx context.Context, config builder.BuildConfig, mobile MobileConfig, goos string, dists []builder.GoDist) (string, error) {
+	if mobile.Mode != "bind" && mobile.Mode != "build" {
+		return "", ErrInvalidMobileMode
+	}
+
+	targets := []string{}
+	for _, dist := range dists {
+		if dist.GOOS == "ios" && dist.GOARCH == "amd64" {
+			targets = append(targets, "iossimulator/amd64")
+		} else {
+			targets = append(targets, dist.GOOS+"/"+dist.GOARCH)
+		}
+	}
+
+	args := []string{mobile.Mode,
+		"-target", strings.Join(targets, ","),
+		"-o", mobileOutputPath(config, mobile, goos),
+	}
+
+	if mobile.BundleID != "" {
+		args = append(args, "-bundleid", mobile.BundleID)
+	}
+
+	if mobile.AndroidAPI > 0 && goos == "android" {
+		args = append(args, "-androidapi", fmt.Sprint(mobile.AndroidAPI))
+	}
+
+	pkg := mobile.Package
+	if pkg == "" {
+		pkg = "."
+	}
+
+	cmd := exec.CommandContext(ctx, "gomobile", append(args, pkg)...)
+	cmd.Dir = config.ProjectDir
+
+	res, err := cmd.CombinedOutput()
+
+	return string(res), err
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestMobileConfigValidate(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input MobileConfig
+		err   error
+	}{
+		{
+			name:  "disabled",
+			input: MobileConfig{},
+		},
+		{
+			name:  "bind",
+			input: MobileConfig{Mode: "bind"},
+		},
+		{
+			name:  "build",
+			input: MobileConfig{Mode: "build"},
+		},
+		{
+			name:  "unknown mode",
+			input: MobileConfig{Mode: "install"},
+			err:   ErrInvalidMobileMode,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.input.Validate()
+
+			if !errors.Is(err, tc.err) {
+				t.Logf("Incorrect error returned, wanted: %v got: %v\n", tc.err, err)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestSplitMobileDists(t *testing.T) {
+	androidArm64 := builder.GoDist{GOOS: "android", GOARCH: "arm64"}
+	androidAmd64 := builder.GoDist{GOOS: "android", GOARCH: "amd64"}
+	iosArm64 := builder.GoDist{GOOS: "ios", GOARCH: "arm64"}
+	linuxAmd64 := builder.GoDist{GOOS: "linux", GOARCH: "amd64"}
+	darwinArm64 := builder.GoDist{GOOS: "darwin", GOARCH: "arm64"}
+
+	testCases := []struct {
+		name        string
+		dists       []builder.GoDist
+		wantsMobile map[string][]builder.GoDist
+		wantsRest   []builder.GoDist
+	}{
+		{
+			name:        "no mobile",
+			dists:       []builder.GoDist{linuxAmd64, darwinArm64},
+			wantsMobile: map[string][]builder.GoDist{},
+			wantsRest:   []builder.GoDist{linuxAmd64, darwinArm64},
+		},
+		{
+			name:  "mixed",
+			dists: []builder.GoDist{androidArm64, linuxAmd64, iosArm64, androidAmd64, darwinArm64},
+			wantsMobile: map[string][]builder.GoDist{
+				"android": {androidArm64, androidAmd64},
+				"ios":     {iosArm64},
+			},
+			wantsRest: []builder.GoDist{linuxAmd64, darwinArm64},
+		},
+		{
+			name:  "mobile only",
+			dists: []builder.GoDist{iosArm64},
+			wantsMobile: map[string][]builder.GoDist{
+				"ios": {iosArm64},
+			},
+			wantsRest: []builder.GoDist{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mobile, rest := splitMobileDists(tc.dists)
+
+			if !maps.EqualFunc(mobile, tc.wantsMobile, slices.Equal) {
+				t.Logf("Incorrect mobile dists, wanted: %v got: %v\n", tc.wantsMobile, mobile)
+				t.Fail()
+			}
+
+			if !slices.Equal(rest, tc.wantsRest) {
+				t.Logf("Incorrect remaining dists, wanted: %v got: %v\n", tc.wantsRest, rest)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestMobileOutputPath(t *testing.T) {
+	config := builder.NewConfig()
+	config.OutputDir = "/out"
+	config.BinaryName = "app"
+
+	testCases := []struct {
+		name  string
+		mode  string
+		goos  string
+		wants string
+	}{
+		{
+			name:  "bind android",
+			mode:  "bind",
+			goos:  "android",
+			wants: filepath.Join("/out", "app-android.aar"),
+		},
+		{
+			name:  "bind ios",
+			mode:  "bind",
+			goos:  "ios",
+			wants: filepath.Join("/out", "app-ios.xcframework"),
+		},
+		{
+			name:  "build android",
+			mode:  "build",
+			goos:  "android",
+			wants: filepath.Join("/out", "app-android.apk"),
+		},
+		{
+			name:  "build ios",
+			mode:  "build",
+			goos:  "ios",
+			wants: filepath.Join("/out", "app-ios.app"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := mobileOutputPath(config, MobileConfig{Mode: tc.mode}, tc.goos)
+
+			if res != tc.wants {
+				t.Logf("Incorrect output path, wanted: %s got: %s\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
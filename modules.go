@@ -0,0 +1,33 @@
+package main
+
+// ModulesConfig sets where every build downloads modules from and which
+// modules are private, so a corporate proxy needs no wrapper script
+// exporting GOPROXY. Empty settings leave the environment as it is.
+type ModulesConfig struct {
+	Proxy    string `json:"proxy"`    // GOPROXY
+	Private  string `json:"private"`  // GOPRIVATE
+	NoProxy  string `json:"noproxy"`  // GONOPROXY
+	NoSumDB  string `json:"nosumdb"`  // GONOSUMDB
+	SumDB    string `json:"sumdb"`    // GOSUMDB
+	Insecure string `json:"insecure"` // GOINSECURE
+}
+
+// Env returns the go environment variables of the settings.
+func (m ModulesConfig) Env() []string {
+	env := []string{}
+
+	for _, v := range []struct{ name, value string }{
+		{"GOPROXY", m.Proxy},
+		{"GOPRIVATE", m.Private},
+		{"GONOPROXY", m.NoProxy},
+		{"GONOSUMDB", m.NoSumDB},
+		{"GOSUMDB", m.SumDB},
+		{"GOINSECURE", m.Insecure},
+	} {
+		if v.value != "" {
+			env = append(env, v.name+"="+v.value)
+		}
+	}
+
+	return env
+}
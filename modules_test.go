@@ -0,0 +1,36 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestModulesConfigEnv(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input ModulesConfig
+		wants []string
+	}{
+		{
+			name:  "empty",
+			input: ModulesConfig{},
+			wants: []string{},
+		},
+		{
+			name:  "proxy and private",
+			input: ModulesConfig{Proxy: "https://proxy.corp.example,direct", Private: "git.corp.example/*", SumDB: "off"},
+			wants: []string{"GOPROXY=https://proxy.corp.example,direct", "GOPRIVATE=git.corp.example/*", "GOSUMDB=off"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := tc.input.Env()
+
+			if !slices.Equal(res, tc.wants) {
+				t.Logf("Incorrect env, wanted: %v got: %v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var (
+	ErrIncompleteMSI  = errors.New("msi packages need a manufacturer and an upgrade_code")
+	ErrInvalidMSITool = errors.New("invalid msi tool")
+)
+
+// MSIConfig describes the MSI installers built from the windows binaries.
+// UpgradeCode must stay the same across releases so newer versions replace
+// older ones.
+type MSIConfig struct {
+	// Name is the product name, defaulting to the binary name.
+	Name         string `json:"name"`
+	Manufacturer string `json:"manufacturer"`
+	UpgradeCode  string `json:"upgrade_code"`
+	// Shortcut adds a Start Menu shortcut to the binary.
+	Shortcut bool `json:"shortcut"`
+	// Path adds the install directory to the system PATH.
+	Path bool `json:"path"`
+	// Tool is wixl (msitools, the default) or wix3 (candle and light).
+	Tool string `json:"tool"`
+}
+
+func (m MSIConfig) IsEmpty() bool {
+	return m == MSIConfig{}
+}
+
+func (m MSIConfig) Validate() error {
+	if m.IsEmpty() {
+		return nil
+	}
+
+	if m.Manufacturer == "" || m.UpgradeCode == "" {
+		return ErrIncompleteMSI
+	}
+
+	if m.Tool != "" && m.Tool != "wixl" && m.Tool != "wix3" {
+		return fmt.Errorf("%w: %s, expected wixl or wix3", ErrInvalidMSITool, m.Tool)
+	}
+
+	return nil
+}
+
+// msiArch maps the windows architectures to the installer platform.
+var msiArch = map[string]string{
+	"amd64": "x64",
+	"386":   "x86",
+}
+
+// msiVersion converts a tag to the numeric major.minor.patch an MSI
+// ProductVersion requires, dropping any prerelease or build suffix.
+func msiVersion(tag string) string {
+	version := strings.TrimPrefix(tag, "v")
+	version, _, _ = strings.Cut(version, "-")
+	version, _, _ = strings.Cut(version, "+")
+
+	parts := strings.Split(version, ".")
+	for len(parts) < 3 {
+		parts = append(parts, "0")
+	}
+
+	for i, part := range parts[:3] {
+		if _, err := strconv.Atoi(part); err != nil {
+			parts[i] = "0"
+		}
+	}
+
+	return strings.Join(parts[:3], ".")
+}
+
+// stableGUID derives a GUID from the upgrade code and name, so components keep
+// their identity across releases.
+func stableGUID(upgradeCode string, name string) string {
+	sum := sha1.Sum([]byte(strings.ToUpper(upgradeCode) + "/" + name))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+
+	return strings.ToUpper(fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16]))
+}
+
+type wxsData struct {
+	MSIConfig
+	Version      string
+	Platform     string
+	ProgramFiles string
+	Binary       string
+	Source       string
+	ShortcutGUID string
+}
+
+var wxsTemplate = template.Must(template.New("wxs").Parse(`<?xml version="1.0" encoding="utf-8"?>
+<Wix xmlns="http://schemas.microsoft.com/wix/2006/wi">
+  <Product Id="*" Name="{{ .Name }}" Language="1033" Version="{{ .Version }}" Manufacturer="{{ .Manufacturer }}" UpgradeCode="{{ .UpgradeCode }}">
+    <Package InstallerVersion="500" Compressed="yes" InstallScope="perMachine" Platform="{{ .Platform }}" />
+    <MajorUpgrade DowngradeErrorMessage="A newer version of {{ .Name }} is already installed." />
+    <Media Id="1" Cabinet="product.cab" EmbedCab="yes" />
+
+    <Directory Id="TARGETDIR" Name="SourceDir">
+      <Directory Id="{{ .ProgramFiles }}">
+        <Directory Id="INSTALLDIR" Name="{{ .Name }}">
+          <Component Id="MainExecutable" Guid="*">
+            <File Id="MainExecutableFile" Name="{{ .Binary }}" Source="{{ .Source }}" KeyPath="yes" />
+{{- if .Path }}
+            <Environment Id="PathEntry" Name="PATH" Value="[INSTALLDIR]" Permanent="no" Part="last" Action="set" System="yes" />
+{{- end }}
+          </Component>
+        </Directory>
+      </Directory>
+{{- if .Shortcut }}
+      <Directory Id="ProgramMenuFolder">
+        <Directory Id="ProgramMenuDir" Name="{{ .Name }}">
+          <Component Id="StartMenuShortcut" Guid="{{ .ShortcutGUID }}">
+            <Shortcut Id="MainExecutableShortcut" Name="{{ .Name }}" Target="[INSTALLDIR]{{ .Binary }}" WorkingDirectory="INSTALLDIR" />
+            <RemoveFolder Id="ProgramMenuDir" On="uninstall" />
+            <RegistryValue Root="HKCU" Key="Software\{{ .Manufacturer }}\{{ .Name }}" Name="shortcut" Type="integer" Value="1" KeyPath="yes" />
+          </Component>
+        </Directory>
+      </Directory>
+{{- end }}
+    </Directory>
+
+    <Feature Id="Main" Level="1">
+      <ComponentRef Id="MainExecutable" />
+{{- if .Shortcut }}
+      <ComponentRef Id="StartMenuShortcut" />
+{{- end }}
+    </Feature>
+  </Product>
+</Wix>
+`))
+
+// msiOutputPath is the binary's output path with an .msi extension.
+func msiOutputPath(config builder.BuildConfig, dist builder.GoDist) string {
+	return packageBasePath(config, dist) + ".msi"
+}
+
+// wxsSource renders the WiX source for the dist's binary.
+func wxsSource(config builder.BuildConfig, msi MSIConfig, dist builder.GoDist, tag string) (string, error) {
+	data := wxsData{
+		MSIConfig:    msi,
+		Version:      msiVersion(tag),
+		Platform:     msiArch[dist.GOARCH],
+		ProgramFiles: "ProgramFilesFolder",
+		Binary:       config.BinaryName + ".exe",
+		Source:       builder.OutputPath(config, dist),
+		ShortcutGUID: stableGUID(msi.UpgradeCode, "StartMenuShortcut"),
+	}
+
+	if data.Name == "" {
+		data.Name = config.BinaryName
+	}
+
+	for _, field := range []*string{&data.Name, &data.Manufacturer, &data.Binary, &data.Source} {
+		escaped := strings.Builder{}
+		xml.EscapeText(&escaped, []byte(*field))
+		*field = escaped.String()
+	}
+
+	if dist.GOARCH == "amd64" {
+		data.ProgramFiles = "ProgramFiles64Folder"
+	}
+
+	out := strings.Builder{}
+	err := wxsTemplate.Execute(&out, data)
+	return out.String(), err
+}
+
+// buildMSIs builds an installer for every windows 386 and amd64 binary and
+// returns the installers it wrote.
+func buildMSIs(ctx context.Context, config builder.BuildConfig, msi MSIConfig, jobs []buildJob, tag string) ([]string, error) {
+	msis := []string{}
+
+	work, err := os.MkdirTemp("", "gobuilder-msi")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(work)
+
+	for _, job := range jobs {
+		if _, ok := msiArch[job.Dist.GOARCH]; !ok || job.Dist.GOOS != "windows" || !job.Distributable() {
+			continue
+		}
+
+		wxs, err := wxsSource(job.Config, msi, job.Dist, tag)
+		if err != nil {
+			return msis, err
+		}
+
+		base := strings.TrimSuffix(filepath.Base(msiOutputPath(job.Config, job.Dist)), ".msi")
+		wxsFile := filepath.Join(work, base+".wxs")
+		if err := os.WriteFile(wxsFile, []byte(wxs), 0o644); err != nil {
+			return msis, err
+		}
+
+		fp := msiOutputPath(job.Config, job.Dist)
+		arch := msiArch[job.Dist.GOARCH]
+
+		commands := [][]string{{"wixl", "-a", arch, "-o", fp, wxsFile}}
+		if msi.Tool == "wix3" {
+			wixobj := filepath.Join(work, base+".wixobj")
+			commands = [][]string{
+				{"candle", "-nologo", "-arch", arch, "-out", wixobj, wxsFile},
+				{"light", "-nologo", "-out", fp, wixobj},
+			}
+		}
+
+		for _, args := range commands {
+			cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+			if res, err := cmd.CombinedOutput(); err != nil {
+				return msis, fmt.Errorf("%s %s: %w\n%s", job.Dist, args[0], err, res)
+			}
+		}
+
+		msis = append(msis, fp)
+	}
+
+	return msis, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestMSIVersion(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		wants string
+	}{
+		{
+			name:  "full",
+			input: "v1.2.3",
+			wants: "1.2.3",
+		},
+		{
+			name:  "missing patch",
+			input: "1.2",
+			wants: "1.2.0",
+		},
+		{
+			name:  "prerelease",
+			input: "v2.0.0-rc.1",
+			wants: "2.0.0",
+		},
+		{
+			name:  "build metadata",
+			input: "v1.2.3+build",
+			wants: "1.2.3",
+		},
+		{
+			name:  "extra part",
+			input: "v1.2.3.4",
+			wants: "1.2.3",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := msiVersion(tc.input)
+
+			if res != tc.wants {
+				t.Logf("Incorrect version, wanted: %s got: %s\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestWxsSource(t *testing.T) {
+	config := builder.NewConfig()
+	config.OutputDir = "/out"
+	config.BinaryName = "app"
+
+	msi := MSIConfig{Name: "App & Co", Manufacturer: "Acme", UpgradeCode: "8E2A2A4C-8F3B-4C7E-9D2B-1C0A6C1B7E11", Shortcut: true, Path: true}
+
+	wxs, err := wxsSource(config, msi, builder.GoDist{GOOS: "windows", GOARCH: "amd64"}, "v1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wants := []string{
+		`Name="App &amp; Co" Language="1033" Version="1.2.3" Manufacturer="Acme" UpgradeCode="8E2A2A4C-8F3B-4C7E-9D2B-1C0A6C1B7E11"`,
+		`Platform="x64"`,
+		`<Directory Id="ProgramFiles64Folder">`,
+		`Source="/out/app-windows_amd64.exe"`,
+		`<Environment Id="PathEntry" Name="PATH"`,
+		`<Component Id="StartMenuShortcut" Guid="` + stableGUID(msi.UpgradeCode, "StartMenuShortcut") + `">`,
+	}
+
+	for _, want := range wants {
+		if !strings.Contains(wxs, want) {
+			t.Logf("wxs is missing %q:\n%s\n", want, wxs)
+			t.Fail()
+		}
+	}
+
+	if stableGUID(msi.UpgradeCode, "a") == stableGUID(msi.UpgradeCode, "b") || len(stableGUID(msi.UpgradeCode, "a")) != 36 {
+		t.Logf("Incorrect stable GUID: %s\n", stableGUID(msi.UpgradeCode, "a"))
+		t.Fail()
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var ErrInvalidPackageFormat = errors.New("invalid package format")
+
+// packageFormats maps the nfpm packagers to their file extensions.
+var packageFormats = map[string]string{
+	"deb":       ".deb",
+	"rpm":       ".rpm",
+	"apk":       ".apk",
+	"archlinux": ".pkg.tar.zst",
+}
+
+// NFPMConfig describes the linux packages built from the linux binaries with
+// nfpm. Paths are relative to the project directory.
+type NFPMConfig struct {
+	// Formats are the packagers to run, defaulting to deb, rpm and apk.
+	Formats     []string `json:"formats"`
+	Name        string   `json:"name"`
+	Maintainer  string   `json:"maintainer"`
+	Description string   `json:"description"`
+	Vendor      string   `json:"vendor"`
+	Homepage    string   `json:"homepage"`
+	License     string   `json:"license"`
+	Depends     []string `json:"depends"`
+	// BinDir is where the binary is installed, /usr/bin by default.
+	BinDir string `json:"bin_dir"`
+	// SystemdUnits are installed to /usr/lib/systemd/system.
+	SystemdUnits []string `json:"systemd_units"`
+	// ConfigFiles maps local files to their installed path. They are
+	// marked as config files so upgrades keep local edits.
+	ConfigFiles map[string]string `json:"config_files"`
+	Contents    []NFPMContent     `json:"contents"`
+	Scripts     NFPMScripts       `json:"scripts"`
+}
+
+type NFPMContent struct {
+	Src  string `json:"src"`
+	Dst  string `json:"dst"`
+	Type string `json:"type,omitempty"`
+}
+
+type NFPMScripts struct {
+	PreInstall  string `json:"preinstall,omitempty"`
+	PostInstall string `json:"postinstall,omitempty"`
+	PreRemove   string `json:"preremove,omitempty"`
+	PostRemove  string `json:"postremove,omitempty"`
+}
+
+func (n NFPMConfig) IsEmpty() bool {
+	return len(n.Formats) == 0 && n.Maintainer == "" && n.Description == "" &&
+		len(n.SystemdUnits) == 0 && len(n.ConfigFiles) == 0 && len(n.Contents) == 0
+}
+
+func (n NFPMConfig) formats() ([]string, error) {
+	if len(n.Formats) == 0 {
+		return []string{"deb", "rpm", "apk"}, nil
+	}
+
+	for _, format := range n.Formats {
+		if _, ok := packageFormats[format]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPackageFormat, format)
+		}
+	}
+
+	return n.Formats, nil
+}
+
+// nfpmArch returns the nfpm architecture of a dist. nfpm takes GOARCH names
+// and encodes the arm version in the name, with Go's default of GOARM=7.
+func nfpmArch(dist builder.GoDist) string {
+	if dist.GOARCH != "arm" {
+		return dist.GOARCH
+	}
+
+	version, _, _ := strings.Cut(dist.SubArch, ",")
+	if version == "" {
+		version = "7"
+	}
+
+	return "arm" + version
+}
+
+// packageOutputPath is the binary's output path with the package extension in
+// place of any binary extension.
+func packageOutputPath(config builder.BuildConfig, dist builder.GoDist, format string) string {
+	return packageBasePath(config, dist) + packageFormats[format]
+}
+
+// nfpmSpec returns the nfpm config for the binary, as JSON which nfpm reads as
+// YAML.
+func nfpmSpec(config builder.BuildConfig, n NFPMConfig, dist builder.GoDist, version string) ([]byte, error) {
+	name := n.Name
+	if name == "" {
+		name = config.BinaryName
+	}
+
+	binDir := n.BinDir
+	if binDir == "" {
+		binDir = "/usr/bin"
+	}
+
+	abs := func(fp string) string {
+		if filepath.IsAbs(fp) {
+			return fp
+		}
+		return filepath.Join(config.ProjectDir, fp)
+	}
+
+	contents := []NFPMContent{{Src: builder.OutputPath(config, dist), Dst: path.Join(binDir, config.BinaryName)}}
+
+	for _, unit := range n.SystemdUnits {
+		contents = append(contents, NFPMContent{Src: abs(unit), Dst: path.Join("/usr/lib/systemd/system", filepath.Base(unit))})
+	}
+
+	for _, src := range slices.Sorted(maps.Keys(n.ConfigFiles)) {
+		contents = append(contents, NFPMContent{Src: abs(src), Dst: n.ConfigFiles[src], Type: "config|noreplace"})
+	}
+
+	for _, content := range n.Contents {
+		content.Src = abs(content.Src)
+		contents = append(contents, content)
+	}
+
+	scripts := n.Scripts
+	for _, script := range []*string{&scripts.PreInstall, &scripts.PostInstall, &scripts.PreRemove, &scripts.PostRemove} {
+		if *script != "" {
+			*script = abs(*script)
+		}
+	}
+
+	return json.MarshalIndent(map[string]any{
+		"name":        name,
+		"arch":        nfpmArch(dist),
+		"platform":    "linux",
+		"version":     version,
+		"maintainer":  n.Maintainer,
+		"description": n.Description,
+		"vendor":      n.Vendor,
+		"homepage":    n.Homepage,
+		"license":     n.License,
+		"depends":     n.Depends,
+		"contents":    contents,
+		"scripts":     scripts,
+	}, "", "  ")
+}
+
+// buildPackages runs nfpm for every format for each linux binary and returns
+// the packages it wrote.
+func buildPackages(ctx context.Context, config builder.BuildConfig, n NFPMConfig, jobs []buildJob, version string) ([]string, error) {
+	formats, err := n.formats()
+	if err != nil {
+		return nil, err
+	}
+
+	specDir, err := os.MkdirTemp("", "nfpm")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(specDir)
+
+	packages := []string{}
+
+	for _, job := range jobs {
+		if job.Dist.GOOS != "linux" || !job.Distributable() {
+			continue
+		}
+
+		spec, err := nfpmSpec(job.Config, n, job.Dist, version)
+		if err != nil {
+			return packages, err
+		}
+
+		specFile := filepath.Join(specDir, filepath.Base(builder.OutputPath(job.Config, job.Dist))+".yaml")
+		if err := os.WriteFile(specFile, spec, 0o644); err != nil {
+			return packages, err
+		}
+
+		for _, format := range formats {
+			fp := packageOutputPath(job.Config, job.Dist, format)
+
+			cmd := exec.CommandContext(ctx, "nfpm", "package", "--config", specFile, "--packager", format, "--target", fp)
+			cmd.Dir = config.ProjectDir
+
+			if res, err := cmd.CombinedOutput(); err != nil {
+				return packages, fmt.Errorf("nfpm %s %s: %w\n%s", job.Dist, format, err, res)
+			}
+
+			packages = append(packages, fp)
+		}
+	}
+
+	return packages, nil
+}
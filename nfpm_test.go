@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestNfpmArch(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input builder.GoDist
+		wants string
+	}{
+		{
+			name:  "amd64",
+			input: builder.GoDist{GOOS: "linux", GOARCH: "amd64"},
+			wants: "amd64",
+		},
+		{
+			name:  "amd64 subarch",
+			input: builder.GoDist{GOOS: "linux", GOARCH: "amd64", SubArch: "v3"},
+			wants: "amd64",
+		},
+		{
+			name:  "arm default",
+			input: builder.GoDist{GOOS: "linux", GOARCH: "arm"},
+			wants: "arm7",
+		},
+		{
+			name:  "arm6",
+			input: builder.GoDist{GOOS: "linux", GOARCH: "arm", SubArch: "6"},
+			wants: "arm6",
+		},
+		{
+			name:  "arm5 softfloat",
+			input: builder.GoDist{GOOS: "linux", GOARCH: "arm", SubArch: "5,softfloat"},
+			wants: "arm5",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := nfpmArch(tc.input)
+
+			if res != tc.wants {
+				t.Logf("Incorrect arch, wanted: %s got: %s\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestNfpmSpec(t *testing.T) {
+	config := builder.NewConfig()
+	config.ProjectDir = "/src"
+	config.OutputDir = "/out"
+	config.BinaryName = "app"
+
+	n := NFPMConfig{
+		Maintainer:   "Jane <jane@example.com>",
+		SystemdUnits: []string{"deploy/app.service"},
+		ConfigFiles:  map[string]string{"deploy/app.yaml": "/etc/app/app.yaml"},
+		Scripts:      NFPMScripts{PostInstall: "deploy/postinstall.sh"},
+	}
+
+	raw, err := nfpmSpec(config, n, builder.GoDist{GOOS: "linux", GOARCH: "arm64"}, "v1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var spec struct {
+		Name     string
+		Arch     string
+		Version  string
+		Contents []NFPMContent
+		Scripts  NFPMScripts
+	}
+	json.Unmarshal(raw, &spec)
+
+	wantsContents := []NFPMContent{
+		{Src: filepath.Join("/out", "app-linux_arm64"), Dst: "/usr/bin/app"},
+		{Src: filepath.Join("/src", "deploy/app.service"), Dst: "/usr/lib/systemd/system/app.service"},
+		{Src: filepath.Join("/src", "deploy/app.yaml"), Dst: "/etc/app/app.yaml", Type: "config|noreplace"},
+	}
+
+	if spec.Name != "app" || spec.Arch != "arm64" || spec.Version != "v1.2.3" {
+		t.Logf("Incorrect metadata, got: %s %s %s\n", spec.Name, spec.Arch, spec.Version)
+		t.Fail()
+	}
+
+	if !slices.Equal(spec.Contents, wantsContents) {
+		t.Logf("Incorrect contents, wanted: %v got: %v\n", wantsContents, spec.Contents)
+		t.Fail()
+	}
+
+	if spec.Scripts.PostInstall != filepath.Join("/src", "deploy/postinstall.sh") {
+		t.Logf("Incorrect scripts, got: %+v\n", spec.Scripts)
+		t.Fail()
+	}
+
+	if _, err := (NFPMConfig{Formats: []string{"msi"}}).formats(); !errors.Is(err, ErrInvalidPackageFormat) {
+		t.Logf("Expected invalid format error, got: %v\n", err)
+		t.Fail()
+	}
+}
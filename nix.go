@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+// NixConfig describes the Nix derivation generated for the darwin and linux
+// archives, callable with callPackage from a flake or overlay.
+type NixConfig struct {
+	// Name is the pname, defaulting to the binary name.
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Homepage    string `json:"homepage"`
+	// License is an attribute of lib.licenses, e.g. mit or asl20.
+	License string `json:"license"`
+	// File is where the derivation is written, relative to the project
+	// directory, so a flake that imports it is updated in place. Defaults
+	// to <name>.nix in the output directory.
+	File string `json:"file"`
+}
+
+func (n NixConfig) IsEmpty() bool {
+	return n == NixConfig{}
+}
+
+// nixSystems maps dists to Nix system doubles, in the order they are listed.
+var nixSystems = [][2]string{
+	{"linux/amd64", "x86_64-linux"},
+	{"linux/arm64", "aarch64-linux"},
+	{"linux/386", "i686-linux"},
+	{"linux/arm", "armv7l-linux"},
+	{"darwin/amd64", "x86_64-darwin"},
+	{"darwin/arm64", "aarch64-darwin"},
+}
+
+// sriHash converts a hex sha256 digest to the SRI form Nix prefers.
+func sriHash(hexSum string) (string, error) {
+	raw, err := hex.DecodeString(hexSum)
+	if err != nil {
+		return "", err
+	}
+
+	return "sha256-" + base64.StdEncoding.EncodeToString(raw), nil
+}
+
+// writeNixDerivation writes the derivation for the archives, keyed by dist,
+// and returns its path.
+func writeNixDerivation(config builder.BuildConfig, nix NixConfig, archives map[string]string, tag string, downloadURL func(tag, file string) string) (string, error) {
+	name := nix.Name
+	if name == "" {
+		name = config.BinaryName
+	}
+
+	sources := strings.Builder{}
+	platforms := []string{}
+
+	for _, system := range nixSystems {
+		fp, ok := archives[system[0]]
+		if !ok {
+			continue
+		}
+
+		sum, err := sha256File(fp)
+		if err != nil {
+			return "", err
+		}
+
+		hash, err := sriHash(sum)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(&sources, "    %s = fetchurl {\n      url = %s;\n      hash = %s;\n    };\n",
+			strconv.Quote(system[1]), strconv.Quote(downloadURL(tag, filepath.Base(fp))), strconv.Quote(hash))
+		platforms = append(platforms, strconv.Quote(system[1]))
+	}
+
+	if len(platforms) == 0 {
+		return "", fmt.Errorf("%w: expected darwin or linux", ErrNoPackageArchives)
+	}
+
+	meta := strings.Builder{}
+	if nix.Description != "" {
+		fmt.Fprintf(&meta, "    description = %s;\n", strconv.Quote(nix.Description))
+	}
+	if nix.Homepage != "" {
+		fmt.Fprintf(&meta, "    homepage = %s;\n", strconv.Quote(nix.Homepage))
+	}
+	if nix.License != "" {
+		fmt.Fprintf(&meta, "    license = lib.licenses.%s;\n", nix.License)
+	}
+	fmt.Fprintf(&meta, "    platforms = [ %s ];\n", strings.Join(platforms, " "))
+	fmt.Fprintf(&meta, "    mainProgram = %s;\n", strconv.Quote(config.BinaryName))
+
+	derivation := fmt.Sprintf(`{ lib, stdenvNoCC, fetchurl }:
+
+let
+  sources = {
+%s  };
+  system = stdenvNoCC.hostPlatform.system;
+in
+stdenvNoCC.mkDerivation {
+  pname = %s;
+  version = %s;
+
+  src = sources.${system} or (throw "%s: unsupported system ${system}");
+  sourceRoot = ".";
+
+  installPhase = ''
+    runHook preInstall
+    install -Dm755 %s $out/bin/%s
+    runHook postInstall
+  '';
+
+  meta = {
+%s  };
+}
+`, sources.String(), strconv.Quote(name), strconv.Quote(strings.TrimPrefix(tag, "v")), name,
+		config.BinaryName, config.BinaryName, meta.String())
+
+	fp := filepath.Join(config.OutputDir, name+".nix")
+	if nix.File != "" {
+		fp = filepath.Join(config.ProjectDir, nix.File)
+	}
+
+	return fp, os.WriteFile(fp, []byte(derivation), 0o644)
+}
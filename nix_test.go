@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestSriHash(t *testing.T) {
+	// sha256 of the empty string
+	got, err := sriHash("e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+
+	if err != nil || got != "sha256-47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=" {
+		t.Logf("Incorrect SRI hash, got: %s %v\n", got, err)
+		t.Fail()
+	}
+}
+
+func TestWriteNixDerivation(t *testing.T) {
+	testCases := []struct {
+		name  string
+		nix   NixConfig
+		dists []string
+		// wantsFile is relative to the project and output directory and
+		// wantsSystems lists the sources by Nix system and dist, in order
+		wantsFile    string
+		wantsSystems [][2]string
+		err          error
+	}{
+		{
+			name:         "linux and darwin",
+			nix:          NixConfig{License: "mit", File: "package.nix"},
+			dists:        []string{"darwin/arm64", "linux/amd64", "windows/amd64"},
+			wantsFile:    "package.nix",
+			wantsSystems: [][2]string{{"x86_64-linux", "linux/amd64"}, {"aarch64-darwin", "darwin/arm64"}},
+		},
+		{
+			name:      "all systems",
+			nix:       NixConfig{Name: "my-app"},
+			dists:     []string{"darwin/arm64", "darwin/amd64", "linux/arm", "linux/386", "linux/arm64", "linux/amd64"},
+			wantsFile: "my-app.nix",
+			wantsSystems: [][2]string{
+				{"x86_64-linux", "linux/amd64"},
+				{"aarch64-linux", "linux/arm64"},
+				{"i686-linux", "linux/386"},
+				{"armv7l-linux", "linux/arm"},
+				{"x86_64-darwin", "darwin/amd64"},
+				{"aarch64-darwin", "darwin/arm64"},
+			},
+		},
+		{
+			name:  "no nix system",
+			dists: []string{"windows/amd64", "freebsd/amd64", "linux/riscv64"},
+			err:   ErrNoPackageArchives,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := builder.NewConfig()
+			config.ProjectDir = t.TempDir()
+			config.OutputDir = config.ProjectDir
+			config.BinaryName = "app"
+			archives := writeTestArchives(t, config, tc.dists...)
+
+			fp, err := writeNixDerivation(config, tc.nix, archives, "v1.2.3", testDownloadURL)
+
+			if !errors.Is(err, tc.err) {
+				t.Logf("Incorrect error returned, wanted: %v got: %v\n", tc.err, err)
+				t.FailNow()
+			} else if err != nil {
+				return
+			}
+
+			if wantsFile := filepath.Join(config.ProjectDir, tc.wantsFile); fp != wantsFile {
+				t.Logf("Incorrect derivation path, wanted: %s got: %s\n", wantsFile, fp)
+				t.Fail()
+			}
+
+			raw, err := os.ReadFile(fp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			derivation := string(raw)
+
+			sources := ""
+			platforms := []string{}
+			for _, system := range tc.wantsSystems {
+				sum, err := sha256File(archives[system[1]])
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				hash, err := sriHash(sum)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				sources += "    \"" + system[0] + "\" = fetchurl {\n" +
+					"      url = \"" + testDownloadURL("v1.2.3", filepath.Base(archives[system[1]])) + "\";\n" +
+					"      hash = \"" + hash + "\";\n    };\n"
+				platforms = append(platforms, "\""+system[0]+"\"")
+			}
+
+			wants := []string{
+				"  sources = {\n" + sources + "  };\n",
+				"  version = \"1.2.3\";",
+				"    platforms = [ " + strings.Join(platforms, " ") + " ];",
+			}
+
+			if tc.nix.License != "" {
+				wants = append(wants, "    license = lib.licenses."+tc.nix.License+";")
+			}
+
+			for _, want := range wants {
+				if !strings.Contains(derivation, want) {
+					t.Logf("Derivation is missing %q:\n%s\n", want, derivation)
+					t.Fail()
+				}
+			}
+		})
+	}
+}
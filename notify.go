@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidNotifyKind    = errors.New("unsupported notification kind")
+	ErrInvalidNotifyOutcome = errors.New("unsupported notification outcome")
+	ErrMissingNotifyURL     = errors.New("notification has no url")
+	ErrNotifyFailed         = errors.New("unable to send notification")
+)
+
+// notifyKinds are the services a notification can be posted to.
+var notifyKinds = []string{"webhook", "slack", "discord"}
+
+// notifyOutcomes are the ways a run can end.
+var notifyOutcomes = []string{"success", "failure", "cancelled"}
+
+// NotifyConfig is a notification posted when the run finishes.
+type NotifyConfig struct {
+	// Kind is webhook (the default), which posts the summary as JSON,
+	// slack or discord, which post it as a message to an incoming webhook.
+	Kind string `json:"kind"`
+	// URL is the webhook URL. As such URLs are often secrets, URLEnv can
+	// name the variable holding it instead.
+	URL    string `json:"url"`
+	URLEnv string `json:"url_env"`
+	// On lists the outcomes notified: success, failure and cancelled. It
+	// defaults to every outcome.
+	On []string `json:"on"`
+}
+
+func (n NotifyConfig) validate() error {
+	if n.Kind != "" && !slices.Contains(notifyKinds, n.Kind) {
+		return fmt.Errorf("%w: %s, expected one of %s", ErrInvalidNotifyKind, n.Kind, strings.Join(notifyKinds, ", "))
+	}
+
+	for _, outcome := range n.On {
+		if !slices.Contains(notifyOutcomes, outcome) {
+			return fmt.Errorf("%w: %s, expected one of %s", ErrInvalidNotifyOutcome, outcome, strings.Join(notifyOutcomes, ", "))
+		}
+	}
+
+	if n.URL == "" && n.URLEnv == "" {
+		return ErrMissingNotifyURL
+	}
+
+	return nil
+}
+
+func (n NotifyConfig) url() string {
+	if n.URLEnv != "" {
+		return os.Getenv(n.URLEnv)
+	}
+
+	return n.URL
+}
+
+// notification is the summary of a finished run. It is the body posted to
+// webhooks.
+type notification struct {
+	Project   string        `json:"project"`
+	Version   string        `json:"version,omitempty"`
+	Outcome   string        `json:"outcome"`
+	Built     int           `json:"built"`
+	UpToDate  int           `json:"up_to_date"`
+	Failed    []string      `json:"failed"`
+	Cancelled []string      `json:"cancelled"`
+	Duration  time.Duration `json:"duration_ns"`
+	Artifacts []string      `json:"artifacts"`
+}
+
+// newNotification summarizes report. links are the artifacts of the run,
+// as download URLs once published.
+func newNotification(report buildReport, version string, links []string) notification {
+	n := notification{
+		Project:   report.Project,
+		Version:   version,
+		Outcome:   "success",
+		Failed:    []string{},
+		Cancelled: []string{},
+		Duration:  report.Duration,
+		Artifacts: links,
+	}
+
+	for _, result := range report.Results {
+		switch resultStatus(result) {
+		case "ok":
+			n.Built++
+		case "up to date":
+			n.UpToDate++
+		case "failed":
+			n.Failed = append(n.Failed, resultName(result))
+		case "cancelled":
+			n.Cancelled = append(n.Cancelled, resultName(result))
+		}
+	}
+
+	switch {
+	case len(n.Cancelled) > 0:
+		n.Outcome = "cancelled"
+	case len(n.Failed) > 0:
+		n.Outcome = "failure"
+	}
+
+	return n
+}
+
+// text formats the notification as a chat message.
+func (n notification) text() string {
+	b := strings.Builder{}
+
+	name := n.Project
+	if n.Version != "" {
+		name += " " + n.Version
+	}
+
+	fmt.Fprintf(&b, "%s: %s, %d built, %d up to date, %d failed", name, n.Outcome, n.Built, n.UpToDate, len(n.Failed))
+	if len(n.Cancelled) > 0 {
+		fmt.Fprintf(&b, ", %d cancelled", len(n.Cancelled))
+	}
+	fmt.Fprintf(&b, " in %s\n", n.Duration.Round(time.Second))
+
+	if len(n.Failed) > 0 {
+		fmt.Fprintf(&b, "Failed: %s\n", strings.Join(n.Failed, ", "))
+	}
+
+	for _, link := range n.Artifacts {
+		fmt.Fprintf(&b, "- %s\n", link)
+	}
+
+	return b.String()
+}
+
+// notificationPayload returns the body posted for kind.
+func notificationPayload(kind string, n notification) ([]byte, error) {
+	switch kind {
+	case "slack":
+		return json.Marshal(map[string]string{"text": n.text()})
+	case "discord":
+		// discord rejects messages over 2000 characters
+		text := []rune(n.text())
+		if len(text) > 2000 {
+			text = append(text[:1997], []rune("...")...)
+		}
+		return json.Marshal(map[string]string{"content": string(text)})
+	default:
+		return json.Marshal(n)
+	}
+}
+
+// sendNotifications posts n to every configured notification that wants
+// its outcome.
+func sendNotifications(ctx context.Context, configs []NotifyConfig, n notification) error {
+	client := &http.Client{Timeout: 30 * time.Second}
+	errs := []error{}
+
+	for _, config := range configs {
+		if len(config.On) > 0 && !slices.Contains(config.On, n.Outcome) {
+			continue
+		}
+
+		body, err := notificationPayload(config.Kind, n)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		// errors of the request would include the URL, which is often a
+		// secret
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, config.url(), bytes.NewReader(body))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: invalid url", ErrNotifyFailed))
+			continue
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: %w", ErrNotifyFailed, errors.Unwrap(err)))
+			continue
+		}
+
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+
+		if resp.StatusCode/100 != 2 {
+			errs = append(errs, fmt.Errorf("%w: %s: %s", ErrNotifyFailed, resp.Status, bytes.TrimSpace(msg)))
+		}
+	}
+
+	return errors.Join(errs...)
+}
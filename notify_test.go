@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestNotifyConfigValidate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    NotifyConfig
+		wantsErr error
+	}{
+		{name: "webhook", input: NotifyConfig{URL: "https://example.com/hook"}},
+		{name: "slack from env", input: NotifyConfig{Kind: "slack", URLEnv: "SLACK_WEBHOOK_URL", On: []string{"failure"}}},
+		{name: "unknown kind", input: NotifyConfig{Kind: "teams", URL: "https://example.com"}, wantsErr: ErrInvalidNotifyKind},
+		{name: "unknown outcome", input: NotifyConfig{URL: "https://example.com", On: []string{"failed"}}, wantsErr: ErrInvalidNotifyOutcome},
+		{name: "no url", input: NotifyConfig{Kind: "discord"}, wantsErr: ErrMissingNotifyURL},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.input.validate(); !errors.Is(err, tc.wantsErr) {
+				t.Logf("Incorrect error, wanted: %v got: %v\n", tc.wantsErr, err)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestNewNotification(t *testing.T) {
+	report := newBuildReport("app", []builder.BuildResult{
+		{Target: "linux/amd64", Success: true},
+		{Target: "linux/arm64", Success: true, UpToDate: true},
+		{Target: "windows/amd64", Error: "exit status 1"},
+	})
+	report.Duration = 90 * time.Second
+
+	n := newNotification(report, "v1.2.0", []string{"https://example.com/v1.2.0/app-linux_amd64"})
+
+	if n.Outcome != "failure" || n.Built != 1 || n.UpToDate != 1 || len(n.Failed) != 1 {
+		t.Logf("Incorrect notification: %+v\n", n)
+		t.Fail()
+	}
+
+	wants := "app v1.2.0: failure, 1 built, 1 up to date, 1 failed in 1m30s\n" +
+		"Failed: windows/amd64\n" +
+		"- https://example.com/v1.2.0/app-linux_amd64\n"
+
+	if n.text() != wants {
+		t.Logf("Incorrect text, wanted:\n%s\ngot:\n%s\n", wants, n.text())
+		t.Fail()
+	}
+}
+
+func TestSendNotifications(t *testing.T) {
+	bodies := map[string]map[string]any{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		body := map[string]any{}
+		json.Unmarshal(raw, &body)
+		bodies[r.URL.Path] = body
+
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("TEST_DISCORD_URL", server.URL+"/discord")
+
+	configs := []NotifyConfig{
+		{URL: server.URL + "/webhook"},
+		{Kind: "slack", URL: server.URL + "/slack"},
+		{Kind: "discord", URLEnv: "TEST_DISCORD_URL"},
+		{Kind: "slack", URL: server.URL + "/failures", On: []string{"failure"}},
+	}
+
+	n := newNotification(newBuildReport("app", []builder.BuildResult{{Target: "linux/amd64", Success: true}}), "", nil)
+	if err := sendNotifications(context.Background(), configs, n); err != nil {
+		t.Fatal(err)
+	}
+
+	if bodies["/webhook"]["outcome"] != "success" || !strings.HasPrefix(bodies["/slack"]["text"].(string), "app: success") ||
+		bodies["/discord"]["content"] == nil {
+		t.Logf("Incorrect notifications: %v\n", bodies)
+		t.Fail()
+	}
+
+	if _, ok := bodies["/failures"]; ok {
+		t.Log("Failure notification sent for a successful run")
+		t.Fail()
+	}
+
+	err := sendNotifications(context.Background(), []NotifyConfig{{URL: server.URL + "/broken"}}, n)
+	if !errors.Is(err, ErrNotifyFailed) {
+		t.Logf("Incorrect error, wanted: %v got: %v\n", ErrNotifyFailed, err)
+		t.Fail()
+	}
+}
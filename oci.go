@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+// OCIConfig describes where the raw binaries are pushed as an OCI artifact.
+type OCIConfig struct {
+	// Ref is the artifact reference, e.g. ghcr.io/acme/myapp-bin:v1.2.3.
+	Ref          string `json:"ref"`
+	ArtifactType string `json:"artifact_type"`
+}
+
+const defaultOCIArtifactType = "application/vnd.gobuilder.artifacts.v1"
+
+// ociCompanionSuffixes are files published alongside an artifact when they
+// exist next to it, mapped to their media type.
+var ociCompanionSuffixes = map[string]string{
+	".sbom.json": "application/json",
+	".spdx.json": "application/spdx+json",
+	".cdx.json":  "application/vnd.cyclonedx+json",
+	".sig":       "application/vnd.dev.cosign.signature",
+	".pem":       "application/x-pem-file",
+}
+
+// ociFiles returns the oras file arguments (<path>:<media type>) for the
+// artifacts and any SBOM or signature files found next to them. Paths are
+// relative to outputDir so the layer titles are plain file names.
+func ociFiles(outputDir string, artifacts []string) []string {
+	files := []string{}
+
+	relative := func(fp string) string {
+		if rel, err := filepath.Rel(outputDir, fp); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+		return fp
+	}
+
+	for _, artifact := range artifacts {
+		files = append(files, relative(artifact)+":application/octet-stream")
+
+		for _, suffix := range slices.Sorted(maps.Keys(ociCompanionSuffixes)) {
+			if _, err := os.Stat(artifact + suffix); err == nil {
+				files = append(files, relative(artifact+suffix)+":"+ociCompanionSuffixes[suffix])
+			}
+		}
+	}
+
+	return files
+}
+
+// pushOCIArtifacts pushes the artifacts to a registry with oras.
+func pushOCIArtifacts(ctx context.Context, config builder.BuildConfig, oci OCIConfig, artifacts []string) error {
+	artifactType := oci.ArtifactType
+	if artifactType == "" {
+		artifactType = defaultOCIArtifactType
+	}
+
+	args := []string{"push", oci.Ref, "--artifact-type", artifactType}
+
+	cmd := exec.CommandContext(ctx, "oras", append(args, ociFiles(config.OutputDir, artifacts)...)...)
+	cmd.Dir = config.OutputDir
+
+	if res, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("oras push: %w\n%s", err, res)
+	}
+
+	return nil
+}
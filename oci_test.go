@@ -0,0 +1,88 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestOCIFiles(t *testing.T) {
+	testCases := []struct {
+		name      string
+		files     map[string]string
+		artifacts []string
+		wants     []string
+	}{
+		{
+			name:      "binary only",
+			files:     map[string]string{"app-linux_amd64": "bin"},
+			artifacts: []string{"app-linux_amd64"},
+			wants:     []string{"app-linux_amd64:application/octet-stream"},
+		},
+		{
+			name: "sbom and signature",
+			files: map[string]string{
+				"app-linux_amd64":           "bin",
+				"app-linux_amd64.spdx.json": "{}",
+				"app-linux_amd64.sig":       "sig",
+				"app-linux_amd64.pem":       "cert",
+			},
+			artifacts: []string{"app-linux_amd64"},
+			wants: []string{
+				"app-linux_amd64:application/octet-stream",
+				"app-linux_amd64.pem:application/x-pem-file",
+				"app-linux_amd64.sig:application/vnd.dev.cosign.signature",
+				"app-linux_amd64.spdx.json:application/spdx+json",
+			},
+		},
+		{
+			name: "companions per artifact",
+			files: map[string]string{
+				"app-linux_amd64":                "bin",
+				"app-linux_amd64.sbom.json":      "{}",
+				"app-windows_amd64.exe":          "bin",
+				"app-windows_amd64.exe.cdx.json": "{}",
+			},
+			artifacts: []string{"app-linux_amd64", "app-windows_amd64.exe"},
+			wants: []string{
+				"app-linux_amd64:application/octet-stream",
+				"app-linux_amd64.sbom.json:application/json",
+				"app-windows_amd64.exe:application/octet-stream",
+				"app-windows_amd64.exe.cdx.json:application/vnd.cyclonedx+json",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tc.files)
+
+			artifacts := []string{}
+			for _, artifact := range tc.artifacts {
+				artifacts = append(artifacts, filepath.Join(dir, artifact))
+			}
+
+			res := ociFiles(dir, artifacts)
+
+			if !slices.Equal(res, tc.wants) {
+				t.Logf("Incorrect oras files, wanted: %v got: %v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestOCIFilesOutsideOutputDir(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"app.tar.gz": "archive"})
+
+	artifact := filepath.Join(dir, "app.tar.gz")
+	res := ociFiles(filepath.Join(dir, "build"), []string{artifact})
+	wants := []string{artifact + ":application/octet-stream"}
+
+	if !slices.Equal(res, wants) {
+		t.Logf("Incorrect oras files, wanted: %v got: %v\n", wants, res)
+		t.Fail()
+	}
+}
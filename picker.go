@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var (
+	ErrNoTargetsPicked = errors.New("no targets picked")
+	ErrPickerAborted   = errors.New("target picker aborted")
+	ErrNotConfirmed    = errors.New("not confirmed, nothing was built")
+)
+
+const pickerHelp = `Type to filter the targets (e.g. "lin arm") or "*" to show them all, numbers
+or ranges such as "1 3 5-7" to toggle them, "a" to toggle every shown target,
+enter to build the selection or "q" to quit.`
+
+// fuzzyMatch reports whether every word of query appears in s in order,
+// though not necessarily contiguously, so "wina64" matches windows/arm64.
+func fuzzyMatch(query string, s string) bool {
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		rest := s
+		for _, r := range word {
+			i := strings.IndexRune(rest, r)
+			if i < 0 {
+				return false
+			}
+			rest = rest[i+1:]
+		}
+	}
+
+	return true
+}
+
+// parseSelection parses numbers and ranges such as "1 3 5-7" into indexes
+// of a list of n entries. ok is false when input is not a selection.
+func parseSelection(input string, n int) (indexes []int, ok bool) {
+	for _, field := range strings.FieldsFunc(input, func(r rune) bool { return r == ' ' || r == ',' }) {
+		lo, hi, isRange := strings.Cut(field, "-")
+		if !isRange {
+			hi = lo
+		}
+
+		from, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, false
+		}
+		to, err := strconv.Atoi(hi)
+		if err != nil {
+			return nil, false
+		}
+
+		for i := from; i <= to; i++ {
+			if i >= 1 && i <= n {
+				indexes = append(indexes, i-1)
+			}
+		}
+	}
+
+	return indexes, len(indexes) > 0
+}
+
+// confirm asks question and reports whether the answer is yes. Anything
+// else, the end of input included, is no.
+func confirm(r io.Reader, w io.Writer, question string) (bool, error) {
+	fmt.Fprintf(w, "%s [y/N] ", question)
+
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() {
+		fmt.Fprintln(w)
+		return false, scanner.Err()
+	}
+
+	answer := strings.ToLower(strings.TrimSpace(scanner.Text()))
+	return answer == "y" || answer == "yes", nil
+}
+
+// pickTargets lets the user choose dists from a filterable list, with the
+// selected dists picked to begin with.
+func pickTargets(r io.Reader, w io.Writer, dists []builder.GoDist, selected []builder.GoDist) ([]builder.GoDist, error) {
+	picked := map[builder.GoDist]bool{}
+	for _, dist := range selected {
+		picked[dist] = true
+	}
+
+	fmt.Fprintln(w, pickerHelp)
+
+	query := ""
+	scanner := bufio.NewScanner(r)
+
+	for {
+		shown := []builder.GoDist{}
+		for _, dist := range dists {
+			if fuzzyMatch(query, dist.String()) {
+				shown = append(shown, dist)
+			}
+		}
+
+		fmt.Fprintln(w)
+		for i, dist := range shown {
+			mark := " "
+			if picked[dist] {
+				mark = "x"
+			}
+
+			note := ""
+			if dist.FirstClass {
+				note = " (first class)"
+			}
+
+			fmt.Fprintf(w, "[%s] %2d %s%s\n", mark, i+1, dist, note)
+		}
+
+		filter := ""
+		if query != "" {
+			filter = fmt.Sprintf(", filter %q", query)
+		}
+		fmt.Fprintf(w, "%d selected%s> ", len(picked), filter)
+
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return nil, err
+			}
+			return nil, ErrPickerAborted
+		}
+		input := strings.TrimSpace(scanner.Text())
+
+		switch input {
+		case "":
+			if len(picked) == 0 {
+				return nil, ErrNoTargetsPicked
+			}
+
+			// keep the order of the dist list
+			return slices.DeleteFunc(slices.Clone(dists), func(d builder.GoDist) bool {
+				return !picked[d]
+			}), nil
+		case "q":
+			return nil, ErrPickerAborted
+		case "*":
+			query = ""
+			continue
+		case "a":
+			allPicked := !slices.ContainsFunc(shown, func(d builder.GoDist) bool { return !picked[d] })
+			for _, dist := range shown {
+				if allPicked {
+					delete(picked, dist)
+				} else {
+					picked[dist] = true
+				}
+			}
+			continue
+		}
+
+		if indexes, ok := parseSelection(input, len(shown)); ok {
+			for _, i := range indexes {
+				if picked[shown[i]] {
+					delete(picked, shown[i])
+				} else {
+					picked[shown[i]] = true
+				}
+			}
+			continue
+		}
+
+		query = input
+	}
+}
+
+// pickConfigTargets asks on the terminal which of the dists config can build
+// to target, starting from those its targets select, and prints the
+// equivalent flags.
+func pickConfigTargets(ctx context.Context, config builder.BuildConfig) ([]builder.OSARCH, error) {
+	selected := []builder.GoDist{}
+	if len(config.Targets) > 0 {
+		var err error
+		if selected, err = builder.SelectDists(ctx, config); err != nil {
+			return nil, err
+		}
+	}
+
+	config.Targets = nil
+	dists, err := builder.SelectDists(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	picked, err := pickTargets(os.Stdin, os.Stdout, dists, selected)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := []builder.OSARCH{}
+	flags := []string{}
+	for _, dist := range picked {
+		targets = append(targets, builder.OSARCH{OS: dist.GOOS, ARCH: dist.GOARCH, SubArch: dist.SubArch})
+		flags = append(flags, "-target "+dist.String())
+	}
+
+	fmt.Println("Building", strings.Join(flags, " "))
+
+	return targets, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	testCases := []struct {
+		query string
+		input string
+		wants bool
+	}{
+		{query: "", input: "linux/amd64", wants: true},
+		{query: "wina64", input: "windows/arm64", wants: true},
+		{query: "lin arm", input: "linux/arm64", wants: true},
+		{query: "Linux", input: "linux/386", wants: true},
+		{query: "arm lin", input: "linux/arm64", wants: true},
+		{query: "darwin", input: "linux/arm64", wants: false},
+		{query: "a64w", input: "windows/arm64", wants: false},
+	}
+
+	for _, tc := range testCases {
+		if res := fuzzyMatch(tc.query, tc.input); res != tc.wants {
+			t.Logf("Incorrect match of %q against %q, wanted: %v got: %v\n", tc.query, tc.input, tc.wants, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestParseSelection(t *testing.T) {
+	testCases := []struct {
+		input   string
+		wants   []int
+		wantsOk bool
+	}{
+		{input: "1 3", wants: []int{0, 2}, wantsOk: true},
+		{input: "2-4,6", wants: []int{1, 2, 3, 5}, wantsOk: true},
+		{input: "9", wantsOk: false},
+		{input: "arm", wantsOk: false},
+	}
+
+	for _, tc := range testCases {
+		res, ok := parseSelection(tc.input, 6)
+		if ok != tc.wantsOk || !slices.Equal(res, tc.wants) {
+			t.Logf("Incorrect selection of %q, wanted: %v %v got: %v %v\n", tc.input, tc.wants, tc.wantsOk, res, ok)
+			t.Fail()
+		}
+	}
+}
+
+func TestPickTargets(t *testing.T) {
+	dists := []builder.GoDist{
+		{GOOS: "darwin", GOARCH: "arm64", FirstClass: true},
+		{GOOS: "linux", GOARCH: "amd64", FirstClass: true},
+		{GOOS: "linux", GOARCH: "arm64", FirstClass: true},
+		{GOOS: "windows", GOARCH: "amd64", FirstClass: true},
+		{GOOS: "windows", GOARCH: "arm64"},
+	}
+
+	testCases := []struct {
+		name     string
+		input    string
+		selected []builder.GoDist
+		wants    []builder.GoDist
+		wantsErr error
+	}{
+		{
+			name:  "filter and toggle",
+			input: "wind\n1-2\n*\n3\n\n",
+			wants: []builder.GoDist{dists[2], dists[3], dists[4]},
+		},
+		{
+			name:     "preselected",
+			input:    "1\n\n",
+			selected: []builder.GoDist{dists[1]},
+			wants:    []builder.GoDist{dists[0], dists[1]},
+		},
+		{
+			name:  "toggle shown",
+			input: "arm64\na\n\n",
+			wants: []builder.GoDist{dists[0], dists[2], dists[4]},
+		},
+		{
+			name:     "nothing picked",
+			input:    "\n",
+			wantsErr: ErrNoTargetsPicked,
+		},
+		{
+			name:     "quit",
+			input:    "1\nq\n",
+			wantsErr: ErrPickerAborted,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := pickTargets(strings.NewReader(tc.input), io.Discard, dists, tc.selected)
+
+			if !errors.Is(err, tc.wantsErr) {
+				t.Logf("Incorrect error, wanted: %v got: %v\n", tc.wantsErr, err)
+				t.Fail()
+			}
+
+			if !slices.Equal(res, tc.wants) {
+				t.Logf("Incorrect targets, wanted: %v got: %v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	testCases := []struct {
+		input string
+		wants bool
+	}{
+		{input: "y\n", wants: true},
+		{input: " Yes\n", wants: true},
+		{input: "\n", wants: false},
+		{input: "no\n", wants: false},
+		{input: "", wants: false},
+	}
+
+	for _, tc := range testCases {
+		out := strings.Builder{}
+		res, err := confirm(strings.NewReader(tc.input), &out, "Build all 45 targets?")
+
+		if err != nil || res != tc.wants {
+			t.Logf("Incorrect answer for %q, wanted: %v got: %v %v\n", tc.input, tc.wants, res, err)
+			t.Fail()
+		}
+
+		if !strings.HasPrefix(out.String(), "Build all 45 targets? [y/N] ") {
+			t.Logf("Incorrect prompt: %q\n", out.String())
+			t.Fail()
+		}
+	}
+}
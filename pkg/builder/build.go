@@ -0,0 +1,237 @@
+package builder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// OutputPath returns where the binary for dist is written, e.g.
+// build/myapp-linux_arm_7 or build/myapp-js_wasm.wasm.
+func OutputPath(config BuildConfig, dist GoDist) string {
+	filename := fmt.Sprintf("%s-%s_%s", config.BinaryName, dist.GOOS, dist.GOARCH)
+
+	if dist.SubArch != "" {
+		filename += "_" + strings.ReplaceAll(dist.SubArch, ",", "_")
+	}
+
+	if config.Race {
+		filename += "-race"
+	}
+
+	switch config.BuildModeFor(dist) {
+	case "c-shared":
+		if dist.GOOS == "windows" {
+			filename += ".dll"
+		} else if dist.GOOS == "darwin" || dist.GOOS == "ios" {
+			filename += ".dylib"
+		} else {
+			filename += ".so"
+		}
+	case "c-archive":
+		filename += ".a"
+	case "plugin":
+		filename += ".so"
+	default:
+		if dist.GOARCH == "wasm" {
+			filename += ".wasm"
+		} else if dist.GOOS == "windows" || dist.GOOS == "nt" {
+			filename += ".exe"
+		}
+	}
+
+	return filepath.Join(config.OutputDir, filename)
+}
+
+// BuildEnv returns the variables a build of dist sets on top of the host
+// environment.
+func (config BuildConfig) BuildEnv(dist GoDist) []string {
+	// the extra variables come first, so they cannot change the target
+	env := append(slices.Clone(config.ExtraEnv),
+		dist.GOOSEnv(),
+		dist.GOARCHEnv(),
+	)
+
+	if subArchEnv := dist.SubArchEnv(); subArchEnv != "" {
+		env = append(env, subArchEnv)
+	}
+
+	if config.GoWork != "" {
+		env = append(env, "GOWORK="+config.GoWork)
+	}
+
+	// a go.mod toolchain line must not switch away from the chosen go
+	if config.Go != "" {
+		env = append(env, "GOTOOLCHAIN=local")
+	}
+
+	if goCache := config.GoCacheFor(dist); goCache != "" {
+		env = append(env, "GOCACHE="+goCache)
+	}
+
+	if config.GoCacheProg != "" {
+		env = append(env, "GOCACHEPROG="+config.GoCacheProg)
+	}
+
+	if config.CgoOnly || config.Race {
+		env = append(env, "CGO_ENABLED=1")
+	} else if config.NoCgo {
+		env = append(env, "CGO_ENABLED=0")
+	}
+
+	env = append(env, config.zigEnv(dist)...)
+
+	// the target's variables come last, so they win over the ones above
+	return append(env, config.EnvFor(dist)...)
+}
+
+// EnvFor returns the variables the config sets for dist, sorted by name. The
+// variables of every matching pattern are merged, those of the more specific
+// patterns winning.
+func (config BuildConfig) EnvFor(dist GoDist) []string {
+	vars := map[string]string{}
+	for _, v := range TargetSettings(config.Env, dist) {
+		maps.Copy(vars, v)
+	}
+
+	env := []string{}
+	for _, name := range slices.Sorted(maps.Keys(vars)) {
+		env = append(env, name+"="+vars[name])
+	}
+
+	return env
+}
+
+// cancelWaitDelay is how long a cancelled build may take to exit after the
+// interrupt before it is killed.
+const cancelWaitDelay = 10 * time.Second
+
+// BuildResult describes the build of one target.
+type BuildResult struct {
+	Target   string        `json:"target"`
+	Race     bool          `json:"race,omitempty"`
+	PGO      string        `json:"pgo,omitempty"`
+	Path     string        `json:"path"`
+	Size     int64         `json:"size"`
+	Duration time.Duration `json:"duration_ns"`
+	Success  bool          `json:"success"`
+	Stderr   string        `json:"stderr,omitempty"`
+	Error    string        `json:"error,omitempty"`
+
+	// The fields below are set by callers that skip, retry, cancel, log or
+	// budget builds.
+	UpToDate   bool   `json:"up_to_date,omitempty"`
+	Attempts   int    `json:"attempts,omitempty"`
+	Cancelled  bool   `json:"cancelled,omitempty"`
+	Log        string `json:"log,omitempty"`
+	SizeBudget int64  `json:"size_budget,omitempty"`
+	// AttemptResults describes every attempt when a build was retried.
+	AttemptResults []Attempt `json:"attempt_results,omitempty"`
+}
+
+// Attempt describes one try at building a target.
+type Attempt struct {
+	Duration time.Duration `json:"duration_ns"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Build builds dist into OutputPath(config, dist) and describes the build,
+// including the compiler's stderr, in the result. Cancelling ctx stops the
+// build.
+func Build(ctx context.Context, config BuildConfig, dist GoDist) (BuildResult, error) {
+	result := BuildResult{
+		Target: dist.String(),
+		Race:   config.Race,
+		Path:   OutputPath(config, dist),
+	}
+
+	// tinygo has no -pgo
+	if config.CompilerFor(dist) != "tinygo" {
+		result.PGO = config.PGOFor(dist)
+	}
+
+	env := config.BuildEnv(dist)
+
+	var cmd *exec.Cmd
+	var remote *SSHBuilder
+
+	if config.IsSSH() {
+		s, err := ParseSSHBuilder(config.Builder, config.ProjectDir)
+		if err != nil {
+			result.Error = err.Error()
+			return result, err
+		}
+		remote = &s
+
+		name, args, input := config.SSHCommand(s, dist, env)
+
+		cmd = exec.CommandContext(ctx, name, args...)
+		cmd.Env = os.Environ()
+		cmd.Stdin = strings.NewReader(input)
+	} else if config.Builder == "docker" {
+		// docker would create a missing mount point owned by root
+		if goCache := config.GoCacheFor(dist); goCache != "" {
+			if err := os.MkdirAll(goCache, 0o755); err != nil {
+				result.Error = err.Error()
+				return result, err
+			}
+		}
+
+		name, args := config.DockerCommand(dist, env)
+
+		cmd = exec.CommandContext(ctx, name, args...)
+		cmd.Env = append(os.Environ(), env...)
+	} else {
+		name, args := config.BuildCommand(dist, result.Path)
+
+		cmd = exec.CommandContext(ctx, name, args...)
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	cmd.Dir = config.ProjectDir
+
+	stderr := bytes.Buffer{}
+	cmd.Stderr = &stderr
+	if config.Output != nil {
+		cmd.Stderr = io.MultiWriter(&stderr, config.Output)
+	}
+
+	// an interrupt lets go build stop its compiler processes and docker
+	// stop the container, which killing the client would leave running
+	if runtime.GOOS != "windows" {
+		cmd.Cancel = func() error {
+			return cmd.Process.Signal(os.Interrupt)
+		}
+		cmd.WaitDelay = cancelWaitDelay
+	}
+
+	start := time.Now()
+	err := cmd.Run()
+	result.Duration = time.Since(start)
+	result.Stderr = stderr.String()
+
+	if err == nil && remote != nil {
+		err = fetchSSH(ctx, config, *remote, dist)
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+
+	result.Success = true
+	if info, err := os.Stat(result.Path); err == nil {
+		result.Size = info.Size()
+	}
+
+	return result, nil
+}
@@ -0,0 +1,209 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestOutputPath(t *testing.T) {
+	config := NewConfig()
+	config.BinaryName = "app"
+	config.OutputDir = "build"
+
+	testCases := []struct {
+		name  string
+		input GoDist
+		wants string
+	}{
+		{
+			name:  "linux",
+			input: GoDist{GOOS: "linux", GOARCH: "amd64"},
+			wants: "app-linux_amd64",
+		},
+		{
+			name:  "windows",
+			input: GoDist{GOOS: "windows", GOARCH: "arm64"},
+			wants: "app-windows_arm64.exe",
+		},
+		{
+			name:  "sub-architecture",
+			input: GoDist{GOOS: "linux", GOARCH: "arm", SubArch: "7"},
+			wants: "app-linux_arm_7",
+		},
+		{
+			name:  "js/wasm",
+			input: GoDist{GOOS: "js", GOARCH: "wasm"},
+			wants: "app-js_wasm.wasm",
+		},
+		{
+			name:  "wasip1/wasm",
+			input: GoDist{GOOS: "wasip1", GOARCH: "wasm"},
+			wants: "app-wasip1_wasm.wasm",
+		},
+	}
+
+	sharedConfig := config
+	sharedConfig.BuildModes = map[string]string{"*": "c-shared"}
+
+	sharedCases := []struct {
+		name  string
+		input GoDist
+		wants string
+	}{
+		{name: "c-shared linux", input: GoDist{GOOS: "linux", GOARCH: "amd64"}, wants: "app-linux_amd64.so"},
+		{name: "c-shared windows", input: GoDist{GOOS: "windows", GOARCH: "amd64"}, wants: "app-windows_amd64.dll"},
+		{name: "c-shared darwin", input: GoDist{GOOS: "darwin", GOARCH: "arm64"}, wants: "app-darwin_arm64.dylib"},
+	}
+
+	for _, tc := range sharedCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := OutputPath(sharedConfig, tc.input)
+
+			if res != filepath.Join("build", tc.wants) {
+				t.Logf("Incorrect output path, wanted: %v got: %v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := OutputPath(config, tc.input)
+
+			if res != filepath.Join("build", tc.wants) {
+				t.Logf("Incorrect output path, wanted: %v got: %v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestEnvFor(t *testing.T) {
+	config := NewConfig()
+	config.Env = map[string]map[string]string{
+		"*/*":         {"CGO_CFLAGS": "-O1", "GOAMD64": "v2"},
+		"linux/*":     {"CGO_CFLAGS": "-O2"},
+		"linux/arm64": {"CC": "aarch64-linux-gnu-gcc", "CGO_ENABLED": "1"},
+	}
+
+	testCases := []struct {
+		name  string
+		input GoDist
+		wants []string
+	}{
+		{name: "exact", input: GoDist{GOOS: "linux", GOARCH: "arm64"}, wants: []string{"CC=aarch64-linux-gnu-gcc", "CGO_CFLAGS=-O2", "CGO_ENABLED=1", "GOAMD64=v2"}},
+		{name: "pattern", input: GoDist{GOOS: "linux", GOARCH: "amd64"}, wants: []string{"CGO_CFLAGS=-O2", "GOAMD64=v2"}},
+		{name: "wildcard only", input: GoDist{GOOS: "windows", GOARCH: "amd64"}, wants: []string{"CGO_CFLAGS=-O1", "GOAMD64=v2"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := config.EnvFor(tc.input)
+
+			if !slices.Equal(res, tc.wants) {
+				t.Logf("Incorrect env, wanted: %v got: %v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+
+	// the target's variables override the ones go-builder sets
+	config.NoCgo = true
+	env := config.BuildEnv(GoDist{GOOS: "linux", GOARCH: "arm64"})
+	if slices.Index(env, "CGO_ENABLED=1") < slices.Index(env, "CGO_ENABLED=0") {
+		t.Logf("Target env does not come last: %v\n", env)
+		t.Fail()
+	}
+}
+
+func TestBuildCancelled(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/app\n\ngo 1.21\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644)
+
+	config := NewConfig()
+	config.ProjectDir = dir
+	config.OutputDir = filepath.Join(dir, "build")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	dist := GoDist{GOOS: "linux", GOARCH: "amd64"}
+	if _, err := Build(ctx, config, dist); !errors.Is(err, context.Canceled) {
+		t.Logf("Incorrect error, wanted: %v got: %v\n", context.Canceled, err)
+		t.Fail()
+	}
+
+	if _, err := os.Stat(OutputPath(config, dist)); err == nil {
+		t.Logf("Cancelled build wrote %s\n", OutputPath(config, dist))
+		t.Fail()
+	}
+}
+
+func TestBuild(t *testing.T) {
+	t.Setenv("GOFLAGS", "")
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/app\n\ngo 1.21\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "broken.go"), []byte("package main\n\nvar _ = undefinedName\n"), 0o644)
+
+	config := NewConfig()
+	config.ProjectDir = dir
+	config.OutputDir = filepath.Join(dir, "build")
+	config.BinaryName = "app"
+
+	dist := GoDist{GOOS: runtime.GOOS, GOARCH: runtime.GOARCH}
+
+	result, err := Build(context.Background(), config, dist)
+	if err == nil || result.Success || !strings.Contains(result.Stderr, "undefinedName") {
+		t.Logf("Incorrect failed build, error: %v result: %+v\n", err, result)
+		t.Fail()
+	}
+
+	os.Remove(filepath.Join(dir, "broken.go"))
+
+	config.PGO = "off"
+	result, err = Build(context.Background(), config, dist)
+	if err != nil {
+		t.Fatal(err, result.Stderr)
+	}
+
+	if !result.Success || result.Target != dist.String() || result.Path != OutputPath(config, dist) || result.Size == 0 || result.PGO != "off" {
+		t.Logf("Incorrect build result: %+v\n", result)
+		t.Fail()
+	}
+}
+
+func TestPGOFor(t *testing.T) {
+	config := NewConfig()
+	config.PGO = "default.pgo"
+	config.PGOTargets = map[string]string{"linux/*": "linux.pgo", "linux/arm64": "off"}
+
+	testCases := []struct {
+		name   string
+		config BuildConfig
+		input  GoDist
+		wants  string
+	}{
+		{name: "global", config: config, input: GoDist{GOOS: "darwin", GOARCH: "arm64"}, wants: "default.pgo"},
+		{name: "pattern", config: config, input: GoDist{GOOS: "linux", GOARCH: "amd64"}, wants: "linux.pgo"},
+		{name: "most specific", config: config, input: GoDist{GOOS: "linux", GOARCH: "arm64"}, wants: "off"},
+		{name: "unset", config: NewConfig(), input: GoDist{GOOS: "linux", GOARCH: "amd64"}, wants: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if res := tc.config.PGOFor(tc.input); res != tc.wants {
+				t.Logf("Incorrect pgo, wanted: %s got: %s\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
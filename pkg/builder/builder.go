@@ -0,0 +1,228 @@
+// Package builder cross-compiles a Go project for a matrix of GOOS/GOARCH
+// targets. It resolves target patterns against the toolchain's dist list and
+// runs go build, or an alternative compiler, for each selected dist:
+//
+//	config := builder.NewConfig()
+//	config.ProjectDir = "."
+//	config.Targets = []builder.OSARCH{{OS: "linux"}, {OS: "darwin", ARCH: "arm64"}}
+//
+//	dists, err := builder.SelectDists(ctx, config)
+//	...
+//	for _, dist := range dists {
+//		result, err := builder.Build(ctx, config, dist)
+//		...
+//	}
+//
+// Each binary is written to OutputPath(config, dist).
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+)
+
+var (
+	ErrInvalidOSARCH           = errors.New("invalid os/arch configuration")
+	ErrUnsupportedTargetOSARCH = errors.New("unable to find go dist to support target os/arch combination(s)")
+	ErrFailedBuildCommand      = errors.New("unable to build target")
+	ErrNoTargetsSelected       = errors.New("no targets left to build after the filters, negations and exclusions")
+	ErrConflictingCgoOptions   = errors.New("cgo-only and no-cgo cannot be used together")
+	ErrInvalidBuildMode        = errors.New("unsupported build mode")
+	ErrInvalidTargetPattern    = errors.New("invalid target pattern")
+)
+
+// OSARCH is a target pattern as given on the command line: an OS, an
+// optional ARCH, both of which may contain wildcards, and an optional
+// sub-architecture such as v3 or 7. FirstClass only matches first-class
+// ports. A Negate target, written !linux/mips*, removes the dists it
+// matches from those selected before it. Regexp, written /amd64$/, matches
+// GOOS/GOARCH in place of OS and ARCH.
+type OSARCH struct {
+	OS         string
+	ARCH       string
+	SubArch    string
+	FirstClass bool
+	Negate     bool
+	Regexp     *regexp.Regexp
+}
+
+func NewOSARCH() OSARCH {
+	return OSARCH{}
+}
+
+// GoDist is a GOOS/GOARCH pair the toolchain can build, as listed by go tool
+// dist list.
+type GoDist struct {
+	GOOS         string `json:"GOOS"`
+	GOARCH       string `json:"GOARCH"`
+	CgoSupported bool   `json:"CgoSupported"`
+	FirstClass   bool   `json:"FirstClass"`
+
+	// SubArch is not part of the dist list; it is set from the target
+	// (e.g. linux/arm/7) and selects GOARM, GOAMD64 and friends.
+	SubArch string `json:"-"`
+}
+
+// BuildConfig holds the settings of a build. NewConfig returns the defaults;
+// the per-target maps are keyed by target pattern (see TargetSetting).
+type BuildConfig struct {
+	ProjectDir string
+	OutputDir  string
+	BinaryName string
+	// Package is the main package built, relative to ProjectDir, e.g.
+	// ./cmd/server. Empty builds ProjectDir itself.
+	Package string
+	Tags    []string
+	Ldflags string
+	// ModMode is the -mod of go build: mod, readonly or vendor.
+	ModMode string
+	// BuildFlags are passed to go build as they are, e.g. -gcflags=all=-N.
+	BuildFlags []string
+	GoWork     string // go.work file set as GOWORK
+	Targets    []OSARCH
+	Excludes   []OSARCH
+	FirstClass bool
+	CgoOnly    bool
+	NoCgo      bool
+	BuildMode  string
+	BuildModes map[string]string
+	PGO        string
+	PGOTargets map[string]string
+	Race       bool
+
+	Compiler    string
+	Compilers   map[string]string
+	GarbleFlags []string
+	GccgoFlags  string
+
+	TinyGoTargets map[string]string
+
+	Zig        bool
+	ZigTriples map[string]string
+
+	// ExtraEnv holds NAME=value variables set for every build, e.g. from
+	// an env file. The variables go-builder sets and Env take precedence.
+	ExtraEnv []string
+
+	// Env holds variables set for the builds of a target pattern, e.g. CC
+	// for linux/arm64, overriding those set by go-builder.
+	Env map[string]map[string]string
+
+	// Go is the go command local builds run, the go on PATH when empty.
+	Go string
+
+	Builder      string
+	DockerImage  string
+	DockerImages map[string]string
+	GoModCache   string
+
+	// GoCache is the GOCACHE for every build, or the directory holding one
+	// cache per target when GoCachePerTarget is set.
+	GoCache          string
+	GoCachePerTarget bool
+	// GoCacheProg is set as GOCACHEPROG for local builds.
+	GoCacheProg string
+
+	// Output, when set, receives the compiler output as it is written, in
+	// addition to BuildResult.Stderr.
+	Output io.Writer `json:"-"`
+}
+
+func (d GoDist) String() string {
+	if d.SubArch == "" {
+		return d.GOOS + "/" + d.GOARCH
+	}
+
+	return d.GOOS + "/" + d.GOARCH + "/" + d.SubArch
+}
+
+func (d GoDist) GOOSEnv() string {
+	return fmt.Sprintf("GOOS=%s", d.GOOS)
+}
+
+func (d GoDist) GOARCHEnv() string {
+	return fmt.Sprintf("GOARCH=%s", d.GOARCH)
+}
+
+func NewConfig() BuildConfig {
+	return BuildConfig{
+		ProjectDir: "./",
+		OutputDir:  "./build",
+		BinaryName: "build",
+		Targets:    []OSARCH{},
+		Excludes:   []OSARCH{},
+		BuildModes: map[string]string{},
+		PGOTargets: map[string]string{},
+		Compilers:  map[string]string{},
+
+		TinyGoTargets: map[string]string{},
+		ZigTriples:    map[string]string{},
+		Env:           map[string]map[string]string{},
+		Builder:       "local",
+		DockerImages:  map[string]string{},
+	}
+}
+
+// PGOFor returns the -pgo value for dist, preferring a per-target profile
+// over the global one. An empty result leaves the go tool default (auto).
+func (config BuildConfig) PGOFor(dist GoDist) string {
+	if profile, ok := TargetSetting(config.PGOTargets, dist); ok {
+		return profile
+	}
+
+	return config.PGO
+}
+
+// Validate reports settings that cannot be combined or are not supported.
+func (config BuildConfig) Validate() error {
+	if config.CgoOnly && config.NoCgo {
+		return ErrConflictingCgoOptions
+	}
+
+	if config.Zig && config.NoCgo {
+		return ErrZigWithoutCgo
+	}
+
+	for _, mode := range append(slices.Collect(maps.Values(config.BuildModes)), config.BuildMode) {
+		if mode != "" && !slices.Contains(BuildModes, mode) {
+			return fmt.Errorf("%w: %s", ErrInvalidBuildMode, mode)
+		}
+	}
+
+	for _, c := range append(slices.Collect(maps.Values(config.Compilers)), config.Compiler) {
+		if c != "" && !slices.Contains(Compilers, c) {
+			return fmt.Errorf("%w: %s", ErrInvalidCompiler, c)
+		}
+	}
+
+	if config.ModMode != "" && !slices.Contains(ModModes, config.ModMode) {
+		return fmt.Errorf("%w: %s", ErrInvalidModMode, config.ModMode)
+	}
+
+	if config.IsSSH() {
+		if _, err := ParseSSHBuilder(config.Builder, config.ProjectDir); err != nil {
+			return err
+		}
+	} else if !slices.Contains(Builders, config.Builder) {
+		return fmt.Errorf("%w: %s", ErrInvalidBuilder, config.Builder)
+	}
+
+	return nil
+}
+
+// BuildModes lists the -buildmode values supported across the target matrix.
+var BuildModes = []string{"default", "exe", "pie", "c-shared", "c-archive", "plugin"}
+
+// BuildModeFor returns the build mode for dist, preferring a per-target
+// setting over the global one.
+func (config BuildConfig) BuildModeFor(dist GoDist) string {
+	if mode, ok := TargetSetting(config.BuildModes, dist); ok {
+		return mode
+	}
+
+	return config.BuildMode
+}
@@ -0,0 +1,127 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var ErrInvalidCompiler = errors.New("unsupported compiler")
+
+// Compilers lists the backends a target can be built with. gc is the
+// regular go build, gccgo is go build -compiler gccgo, garble wraps go build
+// to obfuscate the binary and tinygo targets wasm and microcontrollers with
+// much smaller output.
+var Compilers = []string{"gc", "gccgo", "garble", "tinygo"}
+
+// CompilerFor returns the compiler backend for dist, preferring a per-target
+// setting over the global one and defaulting to gc.
+func (config BuildConfig) CompilerFor(dist GoDist) string {
+	if compiler, ok := TargetSetting(config.Compilers, dist); ok {
+		return compiler
+	}
+
+	if config.Compiler == "" {
+		return "gc"
+	}
+
+	return config.Compiler
+}
+
+// MainPackage returns the package argument for go build: the configured
+// package, relative to the project directory, or the project directory.
+func (config BuildConfig) MainPackage() string {
+	if config.Package != "" {
+		return config.Package
+	}
+
+	return config.ProjectDir
+}
+
+// BuildCommand returns the program and arguments that build dist into out
+// with the dist's compiler backend.
+func (config BuildConfig) BuildCommand(dist GoDist, out string) (string, []string) {
+	args := []string{"build", "-o", out}
+
+	if len(config.Tags) > 0 {
+		args = append(args, "-tags", strings.Join(config.Tags, ","))
+	}
+
+	if config.Ldflags != "" {
+		args = append(args, "-ldflags", config.Ldflags)
+	}
+
+	if config.CompilerFor(dist) == "tinygo" {
+		if target, ok := TargetSetting(config.TinyGoTargets, dist); ok {
+			args = append(args, "-target", target)
+		}
+
+		return "tinygo", append(args, config.MainPackage())
+	}
+
+	if config.ModMode != "" {
+		args = append(args, "-mod", config.ModMode)
+	}
+
+	if mode := config.BuildModeFor(dist); mode != "" {
+		args = append(args, "-buildmode", mode)
+	}
+
+	if profile := config.PGOFor(dist); profile != "" {
+		args = append(args, "-pgo", profile)
+	}
+
+	if config.Race {
+		args = append(args, "-race")
+	}
+
+	if config.CompilerFor(dist) == "gccgo" {
+		args = append(args, "-compiler", "gccgo")
+
+		if config.GccgoFlags != "" {
+			args = append(args, "-gccgoflags", config.GccgoFlags)
+		}
+	}
+
+	args = append(args, config.BuildFlags...)
+	args = append(args, config.MainPackage())
+
+	if config.CompilerFor(dist) == "garble" {
+		return "garble", append(append([]string{}, config.GarbleFlags...), args...)
+	}
+
+	if config.Go != "" {
+		return config.Go, args
+	}
+
+	return "go", args
+}
+
+// BoardOutputPath returns where a tinygo microcontroller build is written.
+// The format (elf, hex, bin, uf2, ...) decides what tinygo emits.
+func BoardOutputPath(config BuildConfig, board string, format string) string {
+	if format == "" {
+		format = "elf"
+	}
+
+	return filepath.Join(config.OutputDir, fmt.Sprintf("%s-%s.%s", config.BinaryName, board, format))
+}
+
+// BuildBoard builds the project with tinygo for a microcontroller board such
+// as pico or arduino-nano33, which has no GOOS/GOARCH of its own.
+func BuildBoard(ctx context.Context, config BuildConfig, board string, format string) (string, error) {
+	cmd := exec.CommandContext(ctx, "tinygo", "build",
+		"-target", board,
+		"-o", BoardOutputPath(config, board, format),
+		config.MainPackage())
+	cmd.Dir = config.ProjectDir
+	cmd.Env = os.Environ()
+
+	res, err := cmd.CombinedOutput()
+
+	return string(res), err
+}
@@ -0,0 +1,86 @@
+package builder
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBuildCommand(t *testing.T) {
+	config := NewConfig()
+	config.GarbleFlags = []string{"-literals", "-tiny"}
+	config.ProjectDir = "."
+	config.Compilers = map[string]string{"windows": "garble", "*/wasm": "tinygo", "linux/ppc64": "gccgo"}
+	config.GccgoFlags = "-O2 -march=native"
+	config.TinyGoTargets = map[string]string{"wasip1/wasm": "wasip1"}
+	config.BuildModes = map[string]string{"*": "pie"}
+
+	testCases := []struct {
+		name      string
+		input     GoDist
+		wantsName string
+		wantsArgs []string
+	}{
+		{
+			name:      "default gc",
+			input:     GoDist{GOOS: "linux", GOARCH: "amd64"},
+			wantsName: "go",
+			wantsArgs: []string{"build", "-o", "out", "-buildmode", "pie", "."},
+		},
+		{
+			name:      "per-target garble",
+			input:     GoDist{GOOS: "windows", GOARCH: "amd64"},
+			wantsName: "garble",
+			wantsArgs: []string{"-literals", "-tiny", "build", "-o", "out", "-buildmode", "pie", "."},
+		},
+		{
+			name:      "per-target gccgo",
+			input:     GoDist{GOOS: "linux", GOARCH: "ppc64"},
+			wantsName: "go",
+			wantsArgs: []string{"build", "-o", "out", "-buildmode", "pie", "-compiler", "gccgo", "-gccgoflags", "-O2 -march=native", "."},
+		},
+		{
+			name:      "tinygo with target",
+			input:     GoDist{GOOS: "wasip1", GOARCH: "wasm"},
+			wantsName: "tinygo",
+			wantsArgs: []string{"build", "-o", "out", "-target", "wasip1", "."},
+		},
+		{
+			name:      "tinygo without target",
+			input:     GoDist{GOOS: "js", GOARCH: "wasm"},
+			wantsName: "tinygo",
+			wantsArgs: []string{"build", "-o", "out", "."},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, args := config.BuildCommand(tc.input, "out")
+
+			if name != tc.wantsName || !slices.Equal(args, tc.wantsArgs) {
+				t.Logf("Incorrect command, wanted: %s %v got: %s %v\n", tc.wantsName, tc.wantsArgs, name, args)
+				t.Fail()
+			}
+		})
+	}
+
+	tagged := NewConfig()
+	tagged.ProjectDir = "."
+	tagged.Package = "./cmd/server"
+	tagged.Tags = []string{"netgo", "osusergo"}
+	tagged.Ldflags = "-s -w"
+	tagged.ModMode = "vendor"
+	tagged.BuildFlags = []string{"-gcflags=all=-N -l", "-x"}
+
+	name, args := tagged.BuildCommand(GoDist{GOOS: "linux", GOARCH: "amd64"}, "out")
+	wantsArgs := []string{"build", "-o", "out", "-tags", "netgo,osusergo", "-ldflags", "-s -w", "-mod", "vendor", "-gcflags=all=-N -l", "-x", "./cmd/server"}
+
+	if name != "go" || !slices.Equal(args, wantsArgs) {
+		t.Logf("Incorrect tagged command, wanted: go %v got: %s %v\n", wantsArgs, name, args)
+		t.Fail()
+	}
+
+	if !slices.Equal(config.GarbleFlags, []string{"-literals", "-tiny"}) {
+		t.Logf("Garble flags were modified: %v\n", config.GarbleFlags)
+		t.Fail()
+	}
+}
@@ -0,0 +1,151 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+var ErrInvalidBuilder = errors.New("unsupported builder")
+
+// Builders lists where a build can run.
+var Builders = []string{"local", "docker"}
+
+const (
+	defaultDockerImage = "golang:latest"
+	// crossDockerImage ships C cross compilers for the common cgo targets.
+	crossDockerImage = "ghcr.io/goreleaser/goreleaser-cross:latest"
+
+	containerProjectDir = "/src"
+	containerOutputDir  = "/out"
+	containerModCache   = "/go/pkg/mod"
+)
+
+// crossCompilers are the CC values for targets in crossDockerImage.
+var crossCompilers = map[string]string{
+	"darwin/amd64":  "o64-clang",
+	"darwin/arm64":  "oa64-clang",
+	"linux/amd64":   "x86_64-linux-gnu-gcc",
+	"linux/arm64":   "aarch64-linux-gnu-gcc",
+	"linux/arm":     "arm-linux-gnueabihf-gcc",
+	"linux/ppc64le": "powerpc64le-linux-gnu-gcc",
+	"linux/riscv64": "riscv64-linux-gnu-gcc",
+	"linux/s390x":   "s390x-linux-gnu-gcc",
+	"windows/386":   "i686-w64-mingw32-gcc",
+	"windows/amd64": "x86_64-w64-mingw32-gcc",
+}
+
+// ModCacheDir returns the host module cache, which docker builds mount.
+func ModCacheDir(ctx context.Context) (string, error) {
+	raw, err := exec.CommandContext(ctx, "go", "env", "GOMODCACHE").Output()
+	if err != nil {
+		return "", fmt.Errorf("gomodcache: %w", err)
+	}
+
+	return strings.TrimSpace(string(raw)), nil
+}
+
+// DockerImageFor returns the container image a docker build of dist runs in.
+// Without any configured image, cgo builds use the cross-compilation image
+// and everything else the official golang image.
+func (config BuildConfig) DockerImageFor(dist GoDist, cgo bool) string {
+	if image, ok := TargetSetting(config.DockerImages, dist); ok {
+		return image
+	}
+
+	if config.DockerImage != "" {
+		return config.DockerImage
+	}
+
+	if cgo {
+		return crossDockerImage
+	}
+
+	return defaultDockerImage
+}
+
+// DockerCommand returns a docker run invocation that builds dist inside a
+// container. The project, output directory and module cache are mounted so
+// artifacts land in the usual place and downloads are shared with the host.
+// The variables of env are passed by name, so docker has to be run with env
+// set; their values, which may be secrets, do not show in the process list.
+func (config BuildConfig) DockerCommand(dist GoDist, env []string) (string, []string) {
+	container := config
+	container.Go = ""
+	container.ProjectDir = containerProjectDir
+	container.OutputDir = containerOutputDir
+
+	name, buildArgs := container.BuildCommand(dist, OutputPath(container, dist))
+
+	cgo := false
+	hasCC := false
+	for _, v := range env {
+		cgo = cgo || v == "CGO_ENABLED=1"
+		hasCC = hasCC || strings.HasPrefix(v, "CC=")
+	}
+
+	image := config.DockerImageFor(dist, cgo)
+
+	cc, ok := crossCompilers[dist.GOOS+"/"+dist.GOARCH]
+	if !ok || !cgo || hasCC || image != crossDockerImage {
+		cc = ""
+	}
+
+	outputDir, _ := filepath.Abs(config.OutputDir)
+	projectDir, _ := filepath.Abs(config.ProjectDir)
+
+	args := []string{"run", "--rm",
+		"-v", projectDir + ":" + containerProjectDir,
+		"-v", outputDir + ":" + containerOutputDir,
+		"-w", containerProjectDir,
+		"-e", "GOCACHE=" + containerGoCache,
+		"-e", "HOME=/tmp",
+	}
+
+	if goCache := config.GoCacheFor(dist); goCache != "" {
+		args = append(args, "-v", goCache+":"+containerGoCache)
+	}
+
+	if config.GoModCache != "" {
+		args = append(args, "-v", config.GoModCache+":"+containerModCache, "-e", "GOMODCACHE="+containerModCache)
+	}
+
+	if runtime.GOOS != "windows" {
+		args = append(args, "--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()))
+	}
+
+	for _, v := range env {
+		// the cache is mounted instead and the cache program is a host command
+		if strings.HasPrefix(v, "GOCACHE=") || strings.HasPrefix(v, "GOCACHEPROG=") {
+			continue
+		}
+
+		if gowork, ok := strings.CutPrefix(v, "GOWORK="); ok {
+			// The workspace file is only visible in the container when it
+			// lives inside the mounted project.
+			rel, err := filepath.Rel(projectDir, gowork)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				continue
+			}
+			args = append(args, "-e", "GOWORK="+path.Join(containerProjectDir, filepath.ToSlash(rel)))
+			continue
+		}
+
+		envName, _, _ := strings.Cut(v, "=")
+		args = append(args, "-e", envName)
+	}
+
+	if cc != "" {
+		args = append(args, "-e", "CC="+cc)
+	}
+
+	args = append(args, "--entrypoint", name, image)
+
+	return "docker", append(args, buildArgs...)
+}
@@ -0,0 +1,89 @@
+package builder
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDockerCommand(t *testing.T) {
+	config := NewConfig()
+	config.Builder = "docker"
+	config.BinaryName = "app"
+	config.ProjectDir = "/home/user/app"
+	config.OutputDir = "/home/user/app/build"
+	config.GoModCache = "/home/user/go/pkg/mod"
+	config.DockerImages = map[string]string{"linux/riscv64": "example.com/riscv:latest"}
+
+	testCases := []struct {
+		name       string
+		dist       GoDist
+		env        []string
+		wantsImage string
+		wantsEnv   []string
+	}{
+		{
+			name:       "pure go",
+			dist:       GoDist{GOOS: "linux", GOARCH: "amd64"},
+			env:        []string{"GOOS=linux", "GOARCH=amd64", "API_TOKEN=hunter2"},
+			wantsImage: defaultDockerImage,
+			wantsEnv:   []string{"GOOS", "GOARCH", "API_TOKEN"},
+		},
+		{
+			name:       "cgo cross",
+			dist:       GoDist{GOOS: "windows", GOARCH: "amd64"},
+			env:        []string{"GOOS=windows", "GOARCH=amd64", "CGO_ENABLED=1"},
+			wantsImage: crossDockerImage,
+			wantsEnv:   []string{"GOOS", "GOARCH", "CGO_ENABLED", "CC=x86_64-w64-mingw32-gcc"},
+		},
+		{
+			name:       "configured image",
+			dist:       GoDist{GOOS: "linux", GOARCH: "riscv64"},
+			env:        []string{"GOOS=linux", "GOARCH=riscv64", "CGO_ENABLED=1"},
+			wantsImage: "example.com/riscv:latest",
+			wantsEnv:   []string{"GOOS", "GOARCH", "CGO_ENABLED"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, args := config.DockerCommand(tc.dist, tc.env)
+
+			if name != "docker" {
+				t.Logf("Incorrect command, wanted: docker got: %s\n", name)
+				t.Fail()
+			}
+
+			image := args[slices.Index(args, "--entrypoint")+2]
+			if image != tc.wantsImage {
+				t.Logf("Incorrect image, wanted: %s got: %s\n", tc.wantsImage, image)
+				t.Fail()
+			}
+
+			for _, env := range tc.wantsEnv {
+				if !slices.Contains(args, env) {
+					t.Logf("Missing env %s in: %v\n", env, args)
+					t.Fail()
+				}
+			}
+
+			joined := strings.Join(args, " ")
+			if strings.Contains(joined, "hunter2") {
+				t.Logf("Variable value is on the command line: %s\n", joined)
+				t.Fail()
+			}
+
+			for _, want := range []string{
+				"/home/user/app:/src",
+				"/home/user/app/build:/out",
+				"/home/user/go/pkg/mod:/go/pkg/mod",
+				"-o /out/app-" + tc.dist.GOOS + "_" + tc.dist.GOARCH,
+			} {
+				if !strings.Contains(joined, want) {
+					t.Logf("Missing %q in: %s\n", want, joined)
+					t.Fail()
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package builder
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+const containerGoCache = "/tmp/go-cache"
+
+// GoCacheFor returns the GOCACHE for the build of dist: GoCache itself, or
+// a subdirectory named after the target when GoCachePerTarget is set. An
+// empty result leaves the go command's default cache.
+func (config BuildConfig) GoCacheFor(dist GoDist) string {
+	if config.GoCache == "" || !config.GoCachePerTarget {
+		return config.GoCache
+	}
+
+	name := strings.NewReplacer("/", "_", ",", "_").Replace(dist.String())
+	if config.Race {
+		name += "-race"
+	}
+
+	return filepath.Join(config.GoCache, name)
+}
@@ -0,0 +1,79 @@
+package builder
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGoCacheFor(t *testing.T) {
+	testCases := []struct {
+		name      string
+		goCache   string
+		perTarget bool
+		race      bool
+		dist      GoDist
+		wants     string
+	}{
+		{
+			name: "default",
+			dist: GoDist{GOOS: "linux", GOARCH: "amd64"},
+		},
+		{
+			name:    "shared",
+			goCache: "/cache",
+			dist:    GoDist{GOOS: "linux", GOARCH: "amd64"},
+			wants:   "/cache",
+		},
+		{
+			name:      "per target",
+			goCache:   "/cache",
+			perTarget: true,
+			dist:      GoDist{GOOS: "linux", GOARCH: "amd64", SubArch: "v3"},
+			wants:     filepath.Join("/cache", "linux_amd64_v3"),
+		},
+		{
+			name:      "per target race",
+			goCache:   "/cache",
+			perTarget: true,
+			race:      true,
+			dist:      GoDist{GOOS: "darwin", GOARCH: "arm64"},
+			wants:     filepath.Join("/cache", "darwin_arm64-race"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := NewConfig()
+			config.GoCache = tc.goCache
+			config.GoCachePerTarget = tc.perTarget
+			config.Race = tc.race
+
+			if got := config.GoCacheFor(tc.dist); got != tc.wants {
+				t.Logf("Incorrect cache, wanted: %q got: %q\n", tc.wants, got)
+				t.Fail()
+			}
+
+			env := config.BuildEnv(tc.dist)
+			if tc.wants != "" && !slices.Contains(env, "GOCACHE="+tc.wants) {
+				t.Logf("Missing GOCACHE in env: %v\n", env)
+				t.Fail()
+			}
+		})
+	}
+
+	config := NewConfig()
+	config.Builder = "docker"
+	config.GoCache = "/cache"
+	config.GoCachePerTarget = true
+
+	dist := GoDist{GOOS: "linux", GOARCH: "arm64"}
+	_, args := config.DockerCommand(dist, config.BuildEnv(dist))
+	joined := strings.Join(args, " ")
+
+	if !strings.Contains(joined, filepath.Join("/cache", "linux_arm64")+":"+containerGoCache) || strings.Contains(joined, "GOCACHE=/cache") {
+		t.Logf("Incorrect docker cache mount: %s\n", joined)
+		t.Fail()
+	}
+}
@@ -0,0 +1,23 @@
+package builder
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+var ErrInvalidModMode = errors.New("unsupported module mode")
+
+// ModModes lists the -mod values of go build.
+var ModModes = []string{"mod", "readonly", "vendor"}
+
+// DefaultModMode returns vendor for projects with a vendor directory, so
+// they build offline whatever GOFLAGS says, and otherwise leaves the go
+// tool default.
+func DefaultModMode(projectDir string) string {
+	if _, err := os.Stat(filepath.Join(projectDir, "vendor", "modules.txt")); err == nil {
+		return "vendor"
+	}
+
+	return ""
+}
@@ -0,0 +1,42 @@
+package builder
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDefaultModMode(t *testing.T) {
+	plain := t.TempDir()
+
+	vendored := t.TempDir()
+	os.MkdirAll(filepath.Join(vendored, "vendor"), 0o755)
+	os.WriteFile(filepath.Join(vendored, "vendor", "modules.txt"), []byte("# example.com/dep v1.0.0\n"), 0o644)
+
+	if res := DefaultModMode(plain); res != "" {
+		t.Logf("Incorrect mode without vendor, wanted: \"\" got: %q\n", res)
+		t.Fail()
+	}
+
+	if res := DefaultModMode(vendored); res != "vendor" {
+		t.Logf("Incorrect mode with vendor, wanted: vendor got: %q\n", res)
+		t.Fail()
+	}
+}
+
+func TestValidateModMode(t *testing.T) {
+	config := NewConfig()
+
+	config.ModMode = "readonly"
+	if err := config.Validate(); err != nil {
+		t.Logf("Unexpected error for readonly: %v\n", err)
+		t.Fail()
+	}
+
+	config.ModMode = "offline"
+	if err := config.Validate(); !errors.Is(err, ErrInvalidModMode) {
+		t.Logf("Incorrect error, wanted: %v got: %v\n", ErrInvalidModMode, err)
+		t.Fail()
+	}
+}
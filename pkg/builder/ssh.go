@@ -0,0 +1,184 @@
+package builder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// SSHBuilder is a machine builds run on over ssh, given as
+// ssh://user@host[:port][/dir].
+type SSHBuilder struct {
+	User string
+	Host string
+	Port string
+	// Dir holds the synced source in src and the binaries in out. Relative
+	// directories are in the home directory of the user.
+	Dir string
+}
+
+// ParseSSHBuilder parses an ssh:// builder. Without a directory the builds
+// of a project go to .cache/go-builder/<project directory name>.
+func ParseSSHBuilder(builder string, projectDir string) (SSHBuilder, error) {
+	u, err := url.Parse(builder)
+	if err != nil || u.Scheme != "ssh" || u.Hostname() == "" || u.RawQuery != "" || u.Fragment != "" {
+		return SSHBuilder{}, fmt.Errorf("%w: %s, expected ssh://user@host[:port][/dir]", ErrInvalidBuilder, builder)
+	}
+
+	s := SSHBuilder{
+		User: u.User.Username(),
+		Host: u.Hostname(),
+		Port: u.Port(),
+		Dir:  path.Clean(u.Path),
+	}
+
+	if u.Path == "" || u.Path == "/" {
+		projectDir, _ = filepath.Abs(projectDir)
+		s.Dir = path.Join(".cache", "go-builder", filepath.Base(projectDir))
+	}
+
+	return s, nil
+}
+
+// IsSSH reports whether builds run on a remote machine over ssh.
+func (config BuildConfig) IsSSH() bool {
+	return strings.HasPrefix(config.Builder, "ssh://")
+}
+
+// Destination is the user@host argument of ssh.
+func (s SSHBuilder) Destination() string {
+	if s.User == "" {
+		return s.Host
+	}
+
+	return s.User + "@" + s.Host
+}
+
+func (s SSHBuilder) sshArgs() []string {
+	// ssh must not prompt, as builds run in parallel
+	args := []string{"-o", "BatchMode=yes"}
+	if s.Port != "" {
+		args = append(args, "-p", s.Port)
+	}
+
+	return args
+}
+
+// rsyncShell is the remote shell of rsync.
+func (s SSHBuilder) rsyncShell() string {
+	return "ssh " + strings.Join(s.sshArgs(), " ")
+}
+
+// shellQuote quotes s for the remote POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// SSHCommand returns the ssh invocation that builds dist in the synced
+// source on the remote machine, along with the input it is given. The
+// variables of env are fed on stdin, rather than the command line, so their
+// values, which may be secrets, do not show in the process list.
+func (config BuildConfig) SSHCommand(s SSHBuilder, dist GoDist, env []string) (string, []string, string) {
+	// the build runs in the source, so the package is the current directory
+	// and the binaries go beside it
+	remote := config
+	remote.Go = ""
+	remote.ProjectDir = "."
+	remote.OutputDir = "../out"
+
+	name, buildArgs := remote.BuildCommand(dist, filepath.ToSlash(OutputPath(remote, dist)))
+
+	script := []string{"cd", shellQuote(path.Join(s.Dir, "src")), "&&", "mkdir", "-p", shellQuote(remote.OutputDir), "&&", `eval "$(cat)"`, "&&"}
+	input := strings.Builder{}
+
+	for _, v := range env {
+		// the caches are host paths and the remote keeps its own
+		if strings.HasPrefix(v, "GOCACHE=") || strings.HasPrefix(v, "GOCACHEPROG=") {
+			continue
+		}
+
+		if gowork, ok := strings.CutPrefix(v, "GOWORK="); ok {
+			// the workspace file is only synced when it lives in the project
+			projectDir, _ := filepath.Abs(config.ProjectDir)
+			rel, err := filepath.Rel(projectDir, gowork)
+			if err != nil || !filepath.IsLocal(rel) {
+				continue
+			}
+			v = "GOWORK=" + filepath.ToSlash(rel)
+		}
+
+		input.WriteString("export " + shellQuote(v) + "\n")
+	}
+
+	script = append(script, name)
+	for _, arg := range buildArgs {
+		script = append(script, shellQuote(arg))
+	}
+
+	args := append(s.sshArgs(), s.Destination(), strings.Join(script, " "))
+
+	return "ssh", args, input.String()
+}
+
+// SyncSSH copies the project source, without version control metadata, the
+// output directory and the private files, to the remote machine. A private
+// file given by name, such as .env, is left out of every directory, one
+// given by path only where it is. It runs once before the builds, which
+// build the synced copy.
+func SyncSSH(ctx context.Context, config BuildConfig, s SSHBuilder, private []string) error {
+	src := path.Join(s.Dir, "src")
+
+	mkdir := exec.CommandContext(ctx, "ssh", append(s.sshArgs(), s.Destination(), "mkdir -p "+shellQuote(src))...)
+	if out, err := mkdir.CombinedOutput(); err != nil {
+		return fmt.Errorf("ssh: %w: %s", err, bytes.TrimSpace(out))
+	}
+
+	args := []string{"-az", "--delete", "-e", s.rsyncShell(), "--exclude", "/.git"}
+
+	projectDir, _ := filepath.Abs(config.ProjectDir)
+	outputDir, _ := filepath.Abs(config.OutputDir)
+	if rel, err := filepath.Rel(projectDir, outputDir); err == nil && filepath.IsLocal(rel) {
+		args = append(args, "--exclude", "/"+filepath.ToSlash(rel))
+	}
+
+	for _, name := range private {
+		if !filepath.IsAbs(name) {
+			args = append(args, "--exclude", name)
+		} else if rel, err := filepath.Rel(projectDir, name); err == nil && filepath.IsLocal(rel) {
+			args = append(args, "--exclude", "/"+filepath.ToSlash(rel))
+		}
+	}
+
+	args = append(args, projectDir+string(os.PathSeparator), s.Destination()+":"+src+"/")
+
+	if out, err := exec.CommandContext(ctx, "rsync", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("rsync: %w: %s", err, bytes.TrimSpace(out))
+	}
+
+	return nil
+}
+
+// fetchSSH copies the binary of dist from the remote machine to
+// OutputPath(config, dist).
+func fetchSSH(ctx context.Context, config BuildConfig, s SSHBuilder, dist GoDist) error {
+	remote := config
+	remote.OutputDir = path.Join(s.Dir, "out")
+
+	local := OutputPath(config, dist)
+	if err := os.MkdirAll(filepath.Dir(local), 0o755); err != nil {
+		return err
+	}
+
+	src := s.Destination() + ":" + filepath.ToSlash(OutputPath(remote, dist))
+	if out, err := exec.CommandContext(ctx, "rsync", "-a", "-e", s.rsyncShell(), src, local).CombinedOutput(); err != nil {
+		return fmt.Errorf("rsync: %w: %s", err, bytes.TrimSpace(out))
+	}
+
+	return nil
+}
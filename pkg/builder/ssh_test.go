@@ -0,0 +1,82 @@
+package builder
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseSSHBuilder(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		wants    SSHBuilder
+		wantsErr error
+	}{
+		{name: "user and host", input: "ssh://ci@mac.local", wants: SSHBuilder{User: "ci", Host: "mac.local", Dir: ".cache/go-builder/app"}},
+		{name: "port and dir", input: "ssh://ci@mac.local:2222/srv/builds/", wants: SSHBuilder{User: "ci", Host: "mac.local", Port: "2222", Dir: "/srv/builds"}},
+		{name: "host only", input: "ssh://mac.local", wants: SSHBuilder{Host: "mac.local", Dir: ".cache/go-builder/app"}},
+		{name: "missing host", input: "ssh:///srv", wantsErr: ErrInvalidBuilder},
+		{name: "other scheme", input: "sftp://mac.local", wantsErr: ErrInvalidBuilder},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := ParseSSHBuilder(tc.input, "/home/user/app")
+
+			if !errors.Is(err, tc.wantsErr) {
+				t.Logf("Incorrect error, wanted: %v got: %v\n", tc.wantsErr, err)
+				t.Fail()
+			}
+
+			if res != tc.wants {
+				t.Logf("Incorrect builder, wanted: %+v got: %+v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestSSHCommand(t *testing.T) {
+	config := NewConfig()
+	config.Builder = "ssh://ci@mac.local:2222"
+	config.BinaryName = "app"
+	config.ProjectDir = "/home/user/app"
+	config.OutputDir = "/home/user/app/build"
+	config.Ldflags = "-X main.version=it's"
+
+	s, _ := ParseSSHBuilder(config.Builder, config.ProjectDir)
+	dist := GoDist{GOOS: "darwin", GOARCH: "arm64"}
+	env := []string{"GOOS=darwin", "GOARCH=arm64", "CGO_ENABLED=1", "GOCACHE=/home/user/.cache/go", "GOWORK=/home/user/app/go.work", "API_TOKEN=it's\nsecret"}
+
+	name, args, input := config.SSHCommand(s, dist, env)
+
+	if name != "ssh" || !slices.Equal(args[:len(args)-1], []string{"-o", "BatchMode=yes", "-p", "2222", "ci@mac.local"}) {
+		t.Logf("Incorrect ssh invocation: %s %v\n", name, args)
+		t.Fail()
+	}
+
+	script := args[len(args)-1]
+	for _, want := range []string{
+		"cd '.cache/go-builder/app/src'",
+		`eval "$(cat)" && go 'build' '-o' '../out/app-darwin_arm64'`,
+		`'-X main.version=it'\''s' '.'`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Logf("Missing %q in: %s\n", want, script)
+			t.Fail()
+		}
+	}
+
+	wantsInput := "export 'GOOS=darwin'\nexport 'GOARCH=arm64'\nexport 'CGO_ENABLED=1'\nexport 'GOWORK=go.work'\nexport 'API_TOKEN=it'\\''s\nsecret'\n"
+	if input != wantsInput {
+		t.Logf("Incorrect input, wanted: %q got: %q\n", wantsInput, input)
+		t.Fail()
+	}
+
+	if strings.Contains(script, "GOCACHE") || strings.Contains(input, "GOCACHE") || strings.Contains(script, "secret") {
+		t.Logf("Host cache or a variable value was sent on the command line: %s\n", script)
+		t.Fail()
+	}
+}
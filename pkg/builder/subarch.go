@@ -0,0 +1,60 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var ErrInvalidSubArch = errors.New("invalid sub-architecture for target")
+
+// subArchSetting names the environment variable that selects a
+// sub-architecture for a GOARCH and the values the go tool accepts for it.
+// Values may carry comma separated options (e.g. GOARM=7,softfloat); only
+// the part before the first comma is checked.
+type subArchSetting struct {
+	Env    string
+	Values []string
+}
+
+var subArchSettings = map[string]subArchSetting{
+	"386":      {Env: "GO386", Values: []string{"sse2", "softfloat"}},
+	"amd64":    {Env: "GOAMD64", Values: []string{"v1", "v2", "v3", "v4"}},
+	"arm":      {Env: "GOARM", Values: []string{"5", "6", "7"}},
+	"arm64":    {Env: "GOARM64", Values: []string{"v8.0", "v8.1", "v8.2", "v8.3", "v8.4", "v8.5", "v8.6", "v8.7", "v8.8", "v8.9", "v9.0", "v9.1", "v9.2", "v9.3", "v9.4", "v9.5"}},
+	"mips":     {Env: "GOMIPS", Values: []string{"hardfloat", "softfloat"}},
+	"mipsle":   {Env: "GOMIPS", Values: []string{"hardfloat", "softfloat"}},
+	"mips64":   {Env: "GOMIPS64", Values: []string{"hardfloat", "softfloat"}},
+	"mips64le": {Env: "GOMIPS64", Values: []string{"hardfloat", "softfloat"}},
+	"ppc64":    {Env: "GOPPC64", Values: []string{"power8", "power9", "power10"}},
+	"ppc64le":  {Env: "GOPPC64", Values: []string{"power8", "power9", "power10"}},
+	"riscv64":  {Env: "GORISCV64", Values: []string{"rva20u64", "rva22u64", "rva23u64"}},
+}
+
+// validateSubArch checks that subArch is a value the go tool accepts for arch.
+func validateSubArch(arch string, subArch string) error {
+	setting, ok := subArchSettings[arch]
+	if !ok {
+		return fmt.Errorf("%w: %s has no sub-architectures", ErrInvalidSubArch, arch)
+	}
+
+	base, _, _ := strings.Cut(subArch, ",")
+	if !slices.Contains(setting.Values, base) {
+		return fmt.Errorf("%w: %s=%s, expected one of %s",
+			ErrInvalidSubArch, setting.Env, subArch, strings.Join(setting.Values, ", "))
+	}
+
+	return nil
+}
+
+// SubArchEnv returns the environment assignment selecting the dist's
+// sub-architecture, or an empty string when none was requested.
+func (d GoDist) SubArchEnv() string {
+	setting, ok := subArchSettings[d.GOARCH]
+	if d.SubArch == "" || !ok {
+		return ""
+	}
+
+	return fmt.Sprintf("%s=%s", setting.Env, d.SubArch)
+}
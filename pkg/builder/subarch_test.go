@@ -0,0 +1,73 @@
+package builder
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateSubArch(t *testing.T) {
+	testCases := []struct {
+		name    string
+		arch    string
+		subArch string
+		err     error
+	}{
+		{name: "arm 7", arch: "arm", subArch: "7", err: nil},
+		{name: "arm 7 softfloat", arch: "arm", subArch: "7,softfloat", err: nil},
+		{name: "arm 8", arch: "arm", subArch: "8", err: ErrInvalidSubArch},
+		{name: "amd64 v3", arch: "amd64", subArch: "v3", err: nil},
+		{name: "amd64 v5", arch: "amd64", subArch: "v5", err: ErrInvalidSubArch},
+		{name: "mipsle softfloat", arch: "mipsle", subArch: "softfloat", err: nil},
+		{name: "s390x has none", arch: "s390x", subArch: "z13", err: ErrInvalidSubArch},
+		{name: "wildcard arch", arch: "arm*", subArch: "7", err: ErrInvalidSubArch},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateSubArch(tc.arch, tc.subArch)
+
+			if !errors.Is(err, tc.err) {
+				t.Logf("Incorrect error returned, wanted: %v got: %v\n", tc.err, err)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestSubArchEnv(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input GoDist
+		wants string
+	}{
+		{
+			name:  "no sub-architecture",
+			input: GoDist{GOOS: "linux", GOARCH: "arm"},
+			wants: "",
+		},
+		{
+			name:  "arm",
+			input: GoDist{GOOS: "linux", GOARCH: "arm", SubArch: "6"},
+			wants: "GOARM=6",
+		},
+		{
+			name:  "amd64",
+			input: GoDist{GOOS: "windows", GOARCH: "amd64", SubArch: "v2"},
+			wants: "GOAMD64=v2",
+		},
+		{
+			name:  "mips64le",
+			input: GoDist{GOOS: "linux", GOARCH: "mips64le", SubArch: "softfloat"},
+			wants: "GOMIPS64=softfloat",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if res := tc.input.SubArchEnv(); res != tc.wants {
+				t.Logf("Incorrect env returned, wanted: %q got: %q\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
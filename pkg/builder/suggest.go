@@ -0,0 +1,147 @@
+package builder
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// UnsupportedTargetError describes a requested target that did not match any
+// dist, along with the closest valid target if one is near enough.
+type UnsupportedTargetError struct {
+	Target     OSARCH
+	Suggestion string
+}
+
+func (e UnsupportedTargetError) Error() string {
+	if e.Suggestion == "" {
+		return fmt.Sprintf("%s does not match any supported target", e.Target)
+	}
+
+	return fmt.Sprintf("%s does not match any supported target, did you mean %s?", e.Target, e.Suggestion)
+}
+
+func (e UnsupportedTargetError) Unwrap() error {
+	return ErrUnsupportedTargetOSARCH
+}
+
+func (t OSARCH) String() string {
+	if t.Negate {
+		positive := t
+		positive.Negate = false
+		return "!" + positive.String()
+	}
+
+	if t.Regexp != nil {
+		return "/" + t.Regexp.String() + "/"
+	}
+
+	if t.FirstClass && t.OS == "*" && t.ARCH == "" {
+		return "first-class"
+	}
+
+	if t.ARCH == "" {
+		return t.OS
+	}
+
+	if t.SubArch == "" {
+		return t.OS + "/" + t.ARCH
+	}
+
+	return t.OS + "/" + t.ARCH + "/" + t.SubArch
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+// closestMatch returns the candidate nearest to v, or an empty string when
+// nothing is close enough to be a plausible typo.
+func closestMatch(v string, candidates []string) string {
+	best := ""
+	bestDistance := max(2, len(v)/3) + 1
+
+	for _, candidate := range candidates {
+		if d := editDistance(v, candidate); d < bestDistance {
+			best = candidate
+			bestDistance = d
+		}
+	}
+
+	return best
+}
+
+// suggestTarget proposes a valid target for one that matched no dists. The OS
+// is corrected first; the ARCH is then corrected against the arches available
+// for that OS.
+func suggestTarget(target OSARCH, dists []GoDist) string {
+	if target.Regexp != nil {
+		return ""
+	}
+
+	isPattern := func(v string) bool {
+		return strings.ContainsAny(v, "*?[")
+	}
+
+	osNames := []string{}
+	for _, dist := range dists {
+		if !slices.Contains(osNames, dist.GOOS) {
+			osNames = append(osNames, dist.GOOS)
+		}
+	}
+
+	suggestedOS := target.OS
+	if !isPattern(target.OS) && !slices.Contains(osNames, target.OS) {
+		suggestedOS = closestMatch(target.OS, osNames)
+		if suggestedOS == "" {
+			return ""
+		}
+	}
+
+	if target.ARCH == "" || isPattern(target.ARCH) {
+		if suggestedOS == target.OS {
+			return ""
+		}
+		return OSARCH{OS: suggestedOS, ARCH: target.ARCH, SubArch: target.SubArch}.String()
+	}
+
+	archNames := []string{}
+	for _, dist := range dists {
+		if ok, _ := path.Match(suggestedOS, dist.GOOS); ok {
+			archNames = append(archNames, dist.GOARCH)
+		}
+	}
+
+	suggestedARCH := closestMatch(target.ARCH, archNames)
+	if suggestedARCH == "" {
+		return ""
+	}
+
+	suggestion := OSARCH{OS: suggestedOS, ARCH: suggestedARCH, SubArch: target.SubArch}
+	if suggestion == target {
+		return ""
+	}
+
+	return suggestion.String()
+}
@@ -0,0 +1,69 @@
+package builder
+
+import "testing"
+
+func TestEditDistance(t *testing.T) {
+	testCases := []struct {
+		a, b  string
+		wants int
+	}{
+		{a: "", b: "", wants: 0},
+		{a: "linux", b: "linux", wants: 0},
+		{a: "widows", b: "windows", wants: 1},
+		{a: "amd63", b: "amd64", wants: 1},
+		{a: "darwin", b: "", wants: 6},
+		{a: "plan9", b: "plan", wants: 1},
+	}
+
+	for _, tc := range testCases {
+		if res := editDistance(tc.a, tc.b); res != tc.wants {
+			t.Logf("Incorrect distance between %q and %q, wanted: %d got: %d\n", tc.a, tc.b, tc.wants, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestSuggestTarget(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input OSARCH
+		wants string
+	}{
+		{
+			name:  "misspelled os",
+			input: OSARCH{OS: "widows", ARCH: ""},
+			wants: "windows",
+		},
+		{
+			name:  "misspelled arch",
+			input: OSARCH{OS: "linux", ARCH: "arm65"},
+			wants: "linux/arm64",
+		},
+		{
+			name:  "misspelled os and arch",
+			input: OSARCH{OS: "darwn", ARCH: "arm46"},
+			wants: "darwin/arm64",
+		},
+		{
+			name:  "wildcard os",
+			input: OSARCH{OS: "*", ARCH: "x68"},
+			wants: "*/x86",
+		},
+		{
+			name:  "nothing close",
+			input: OSARCH{OS: "solaris", ARCH: ""},
+			wants: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := suggestTarget(tc.input, testingDists)
+
+			if res != tc.wants {
+				t.Logf("Incorrect suggestion, wanted: %q got: %q\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
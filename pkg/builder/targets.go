@@ -0,0 +1,371 @@
+package builder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Matches reports whether the dist is selected by the OS/ARCH pattern. Both
+// parts may contain shell-style wildcards (see path.Match) and an empty ARCH
+// matches every architecture of the OS. A Regexp target matches GOOS/GOARCH
+// instead. Negate is left to the caller.
+func (t OSARCH) Matches(dist GoDist) bool {
+	if t.FirstClass && !dist.FirstClass {
+		return false
+	}
+
+	if t.Regexp != nil {
+		return t.Regexp.MatchString(dist.GOOS + "/" + dist.GOARCH)
+	}
+
+	if ok, _ := path.Match(t.OS, dist.GOOS); !ok {
+		return false
+	}
+
+	if t.ARCH == "" {
+		return true
+	}
+
+	ok, _ := path.Match(t.ARCH, dist.GOARCH)
+	return ok
+}
+
+// Validate reports an OS or ARCH pattern that is not a valid glob, which
+// would otherwise match nothing.
+func (t OSARCH) Validate() error {
+	for _, part := range []string{t.OS, t.ARCH} {
+		if _, err := path.Match(part, ""); err != nil {
+			return fmt.Errorf("%w %s: %v, e.g. linux/mips*, */arm64, linux/arm[67] or a regular expression such as /amd64$/", ErrInvalidTargetPattern, t, err)
+		}
+	}
+
+	return nil
+}
+
+func filterDists(dists []GoDist, keep func(GoDist) bool) []GoDist {
+	filtered := []GoDist{}
+
+	for _, dist := range dists {
+		if keep(dist) {
+			filtered = append(filtered, dist)
+		}
+	}
+
+	return filtered
+}
+
+// filterSupportedDists keeps the dists allowed by the config's first-class
+// and cgo-only filters, before any target is matched against them.
+func filterSupportedDists(config BuildConfig, dists []GoDist) []GoDist {
+	if config.FirstClass {
+		dists = filterDists(dists, func(d GoDist) bool {
+			return d.FirstClass
+		})
+	}
+
+	if config.CgoOnly {
+		dists = filterDists(dists, func(d GoDist) bool {
+			return d.CgoSupported
+		})
+	}
+
+	return dists
+}
+
+func excludeTargetBuilds(excludes []OSARCH, dists []GoDist) []GoDist {
+	if len(excludes) == 0 {
+		return dists
+	}
+
+	return filterDists(dists, func(d GoDist) bool {
+		for _, exclude := range excludes {
+			if exclude.Matches(d) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func getTargetBuilds(targets []OSARCH, allDists []GoDist) []GoDist {
+
+	if len(targets) == 0 {
+		return allDists
+	}
+	targetDists := []GoDist{}
+
+	// a leading negation removes from every dist
+	if targets[0].Negate {
+		targetDists = slices.Clone(allDists)
+	}
+
+	for _, target := range targets {
+		if target.Negate {
+			targetDists = slices.DeleteFunc(targetDists, func(d GoDist) bool {
+				return target.Matches(d) && (target.SubArch == "" || target.SubArch == d.SubArch)
+			})
+			continue
+		}
+
+		for _, dist := range allDists {
+			dist.SubArch = target.SubArch
+			if target.Matches(dist) && !slices.Contains(targetDists, dist) {
+				targetDists = append(targetDists, dist)
+			}
+		}
+	}
+
+	return targetDists
+}
+
+// OverlappingTargets describes every dist that is selected by more than one
+// of the targets, e.g. "linux/amd64 is selected by linux, linux/amd64".
+func OverlappingTargets(targets []OSARCH, dists []GoDist) []string {
+	overlaps := []string{}
+
+	for _, dist := range dists {
+		selectedBy := []string{}
+		for _, target := range targets {
+			if !target.Negate && target.Matches(dist) && target.SubArch == dist.SubArch {
+				selectedBy = append(selectedBy, target.String())
+			}
+		}
+
+		if len(selectedBy) > 1 {
+			overlaps = append(overlaps,
+				fmt.Sprintf("%s is selected by %s", dist, strings.Join(selectedBy, ", ")))
+		}
+	}
+
+	return overlaps
+}
+
+// validateTargets checks that every target selects at least one dist so a
+// typo is reported before any build starts rather than silently skipped.
+func validateTargets(targets []OSARCH, dists []GoDist) error {
+	unsupported := []error{}
+
+	for _, target := range targets {
+		if err := target.Validate(); err != nil {
+			unsupported = append(unsupported, err)
+			continue
+		}
+
+		// a negation is checked for the dists it would remove
+		positive := target
+		positive.Negate = false
+
+		if len(getTargetBuilds([]OSARCH{positive}, dists)) == 0 {
+			suggestion := suggestTarget(positive, dists)
+			if suggestion != "" && target.Negate {
+				suggestion = "!" + suggestion
+			}
+
+			unsupported = append(unsupported, UnsupportedTargetError{
+				Target:     target,
+				Suggestion: suggestion,
+			})
+		}
+	}
+
+	return errors.Join(unsupported...)
+}
+
+// SupportedDists lists the dists the go toolchain supports.
+func SupportedDists(ctx context.Context) ([]GoDist, error) {
+	cmd := exec.CommandContext(ctx, "go", "tool", "dist", "list", "-json")
+
+	rawJson, err := cmd.Output()
+
+	if err != nil {
+		return []GoDist{}, fmt.Errorf("dist: %w", err)
+	}
+
+	var supportedDists []GoDist
+	if err := json.Unmarshal(rawJson, &supportedDists); err != nil {
+		return nil, fmt.Errorf("json parse: %w", err)
+	}
+
+	return supportedDists, nil
+}
+
+// SelectDists lists the dists the go toolchain supports and returns those
+// selected by the config's targets, filters and excludes. Targets that
+// select nothing are reported as UnsupportedTargetError.
+func SelectDists(ctx context.Context, config BuildConfig) ([]GoDist, error) {
+	supportedDists, err := SupportedDists(ctx)
+	if err != nil {
+		return []GoDist{}, err
+	}
+
+	supportedDists = filterSupportedDists(config, supportedDists)
+
+	if err := validateTargets(config.Targets, supportedDists); err != nil {
+		return []GoDist{}, err
+	}
+
+	targetDists := getTargetBuilds(config.Targets, supportedDists)
+
+	// negations may remove every dist the other targets select
+	if len(targetDists) == 0 && slices.ContainsFunc(config.Targets, func(t OSARCH) bool { return t.Negate }) {
+		return []GoDist{}, ErrNoTargetsSelected
+	} else if len(targetDists) == 0 {
+		return []GoDist{}, ErrUnsupportedTargetOSARCH
+	}
+
+	targetDists = excludeTargetBuilds(config.Excludes, targetDists)
+
+	if len(targetDists) == 0 {
+		return []GoDist{}, ErrNoTargetsSelected
+	}
+
+	return targetDists, nil
+}
+
+// ParseTarget parses a target such as linux, linux/arm64, */wasm or
+// linux/arm/7, a regular expression between slashes such as /amd64$/, or
+// one of the keywords all and first-class. A leading ! negates the target.
+func ParseTarget(rawStr string) (OSARCH, error) {
+
+	if negated, ok := strings.CutPrefix(rawStr, "!"); ok {
+		target, err := ParseTarget(negated)
+		if err != nil {
+			return OSARCH{}, err
+		} else if target.Negate {
+			return OSARCH{}, ErrInvalidOSARCH
+		}
+
+		target.Negate = true
+		return target, nil
+	}
+
+	if rawStr == "" {
+		return OSARCH{}, ErrInvalidOSARCH
+	}
+
+	if len(rawStr) > 2 && strings.HasPrefix(rawStr, "/") && strings.HasSuffix(rawStr, "/") {
+		re, err := regexp.Compile(rawStr[1 : len(rawStr)-1])
+		if err != nil {
+			return OSARCH{}, fmt.Errorf("%w %s: %v, e.g. /amd64$/ or /^(linux|darwin)/arm64$/", ErrInvalidTargetPattern, rawStr, err)
+		}
+
+		return OSARCH{Regexp: re}, nil
+	}
+
+	strLower := strings.ToLower(rawStr)
+
+	osPattern, archPattern, _ := strings.Cut(strLower, "/")
+	archPattern, _, _ = strings.Cut(archPattern, "/")
+	if err := (OSARCH{OS: osPattern, ARCH: archPattern}).Validate(); err != nil {
+		return OSARCH{}, err
+	}
+
+	switch strLower {
+	case "all":
+		return OSARCH{OS: "*"}, nil
+	case "first-class":
+		return OSARCH{OS: "*", FirstClass: true}, nil
+	}
+
+	splitStr := strings.Split(strLower, "/")
+
+	if len(splitStr) == 1 {
+		return OSARCH{
+			OS:   splitStr[0],
+			ARCH: "",
+		}, nil
+	} else if len(splitStr) == 2 {
+		return OSARCH{
+			OS:   splitStr[0],
+			ARCH: splitStr[1],
+		}, nil
+	} else if len(splitStr) == 3 {
+		if err := validateSubArch(splitStr[1], splitStr[2]); err != nil {
+			return OSARCH{}, err
+		}
+
+		return OSARCH{
+			OS:      splitStr[0],
+			ARCH:    splitStr[1],
+			SubArch: splitStr[2],
+		}, nil
+	} else {
+		return OSARCH{}, ErrInvalidOSARCH
+	}
+
+}
+
+// TargetSetting returns the value configured for dist in settings, whose keys
+// are targets such as "linux/arm64" or patterns such as "windows/*". When
+// several keys match, the most specific one wins: a matching sub-architecture
+// beats an exact ARCH, which beats an exact OS, which beats a wildcard.
+func TargetSetting[T any](settings map[string]T, dist GoDist) (T, bool) {
+	matches := TargetSettings(settings, dist)
+	if len(matches) == 0 {
+		var value T
+		return value, false
+	}
+
+	return matches[len(matches)-1], true
+}
+
+// TargetSettings returns every value configured for dist in settings, from
+// the least to the most specific key, so values that are merged in order
+// let the more specific ones win. Keys of the same specificity are ordered
+// so the smaller one comes last.
+func TargetSettings[T any](settings map[string]T, dist GoDist) []T {
+	type match struct {
+		key   string
+		score int
+	}
+
+	isExact := func(v string) bool {
+		return v != "" && !strings.ContainsAny(v, "*?[")
+	}
+
+	matches := []match{}
+	for key := range settings {
+		target, err := ParseTarget(key)
+		if err != nil || target.Negate || !target.Matches(dist) {
+			continue
+		}
+
+		if target.SubArch != "" && target.SubArch != dist.SubArch {
+			continue
+		}
+
+		score := 0
+		if target.SubArch != "" {
+			score += 4
+		}
+		if isExact(target.ARCH) {
+			score += 2
+		}
+		if isExact(target.OS) {
+			score += 1
+		}
+
+		matches = append(matches, match{key: key, score: score})
+	}
+
+	slices.SortFunc(matches, func(a, b match) int {
+		if a.score != b.score {
+			return a.score - b.score
+		}
+		return strings.Compare(b.key, a.key)
+	})
+
+	values := []T{}
+	for _, m := range matches {
+		values = append(values, settings[m.key])
+	}
+
+	return values
+}
@@ -0,0 +1,676 @@
+package builder
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+// just example input, not taken from true
+// go tool dist list
+var testingDists = []GoDist{
+	GoDist{
+		GOOS:         "windows",
+		GOARCH:       "x86",
+		CgoSupported: true,
+		FirstClass:   true,
+	},
+	GoDist{
+		GOOS:         "darwin",
+		GOARCH:       "arm64",
+		CgoSupported: true,
+		FirstClass:   true,
+	},
+	GoDist{
+		GOOS:         "linux",
+		GOARCH:       "x86",
+		CgoSupported: true,
+		FirstClass:   true,
+	},
+	GoDist{
+		GOOS:         "linux",
+		GOARCH:       "arm64",
+		CgoSupported: true,
+		FirstClass:   true,
+	},
+	GoDist{
+		GOOS:         "bsd",
+		GOARCH:       "arm64",
+		CgoSupported: true,
+		FirstClass:   false,
+	},
+}
+
+func TestGetTargetBuilds(t *testing.T) {
+	testCases := []struct {
+		name    string
+		targets []OSARCH
+		dists   []GoDist
+		wants   []GoDist
+	}{
+		{
+			name: "windows only",
+			targets: []OSARCH{
+				OSARCH{
+					OS:   "windows",
+					ARCH: "",
+				},
+			},
+			dists: testingDists,
+			wants: []GoDist{
+				GoDist{
+					GOOS:         "windows",
+					GOARCH:       "x86",
+					CgoSupported: true,
+					FirstClass:   true,
+				},
+			},
+		},
+		{
+			name: "linux only",
+			targets: []OSARCH{
+				OSARCH{
+					OS:   "linux",
+					ARCH: "",
+				},
+			},
+			dists: testingDists,
+			wants: []GoDist{
+				GoDist{
+					GOOS:         "linux",
+					GOARCH:       "x86",
+					CgoSupported: true,
+					FirstClass:   true,
+				},
+				GoDist{
+					GOOS:         "linux",
+					GOARCH:       "arm64",
+					CgoSupported: true,
+					FirstClass:   true,
+				},
+			},
+		},
+		{
+			name: "linux x86",
+			targets: []OSARCH{
+				OSARCH{
+					OS:   "linux",
+					ARCH: "x86",
+				},
+			},
+			dists: testingDists,
+			wants: []GoDist{
+				GoDist{
+					GOOS:         "linux",
+					GOARCH:       "x86",
+					CgoSupported: true,
+					FirstClass:   true,
+				},
+			},
+		},
+		{
+			name: "arm64 only",
+			targets: []OSARCH{
+				OSARCH{
+					OS:   "*",
+					ARCH: "arm64",
+				},
+			},
+			dists: testingDists,
+			wants: []GoDist{
+				GoDist{
+					GOOS:         "darwin",
+					GOARCH:       "arm64",
+					CgoSupported: true,
+					FirstClass:   true,
+				},
+				GoDist{
+					GOOS:         "linux",
+					GOARCH:       "arm64",
+					CgoSupported: true,
+					FirstClass:   true,
+				},
+				GoDist{
+					GOOS:         "bsd",
+					GOARCH:       "arm64",
+					CgoSupported: true,
+					FirstClass:   false,
+				},
+			},
+		},
+		{
+			name: "overlapping targets",
+			targets: []OSARCH{
+				OSARCH{
+					OS:   "linux",
+					ARCH: "",
+				},
+				OSARCH{
+					OS:   "linux",
+					ARCH: "arm64",
+				},
+			},
+			dists: testingDists,
+			wants: []GoDist{
+				GoDist{
+					GOOS:         "linux",
+					GOARCH:       "x86",
+					CgoSupported: true,
+					FirstClass:   true,
+				},
+				GoDist{
+					GOOS:         "linux",
+					GOARCH:       "arm64",
+					CgoSupported: true,
+					FirstClass:   true,
+				},
+			},
+		},
+		{
+			name: "first-class",
+			targets: []OSARCH{
+				OSARCH{
+					OS:         "*",
+					FirstClass: true,
+				},
+			},
+			dists: testingDists,
+			wants: testingDists[:4],
+		},
+		{
+			name: "negation",
+			targets: []OSARCH{
+				OSARCH{OS: "linux"},
+				OSARCH{OS: "linux", ARCH: "arm*", Negate: true},
+			},
+			dists: testingDists,
+			wants: []GoDist{
+				GoDist{
+					GOOS:         "linux",
+					GOARCH:       "x86",
+					CgoSupported: true,
+					FirstClass:   true,
+				},
+			},
+		},
+		{
+			name: "leading negation",
+			targets: []OSARCH{
+				OSARCH{OS: "*", ARCH: "arm64", Negate: true},
+			},
+			dists: testingDists,
+			wants: []GoDist{testingDists[0], testingDists[2]},
+		},
+		{
+			name: "negation then selection",
+			targets: []OSARCH{
+				OSARCH{OS: "linux", Negate: true},
+				OSARCH{OS: "linux", ARCH: "arm64"},
+			},
+			dists: testingDists,
+			wants: []GoDist{testingDists[0], testingDists[1], testingDists[4], testingDists[3]},
+		},
+		{
+			name: "regexp",
+			targets: []OSARCH{
+				OSARCH{Regexp: regexp.MustCompile("^(linux|darwin)/arm64$")},
+			},
+			dists: testingDists,
+			wants: []GoDist{testingDists[1], testingDists[3]},
+		},
+		{
+			name: "first-class keyword",
+			targets: []OSARCH{
+				OSARCH{OS: "*", FirstClass: true},
+			},
+			dists: testingDists,
+			wants: testingDists[:4],
+		},
+		{
+			name:    "empty targets",
+			targets: []OSARCH{},
+			dists:   testingDists,
+			wants:   testingDists,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := getTargetBuilds(tc.targets, tc.dists)
+
+			// could be in one line but it will end up wrapping anyway
+			cmp := slices.CompareFunc(res, tc.wants, func(a GoDist, b GoDist) int {
+				if a.GOOS == b.GOOS {
+					if a.GOARCH == b.GOARCH {
+						if a.CgoSupported == b.CgoSupported {
+							if a.FirstClass == b.FirstClass {
+								return 0
+							}
+							return -4
+						}
+						return -3
+					}
+
+					return -2
+
+				}
+
+				return -1
+			})
+
+			if cmp != 0 {
+				t.Logf("Incorrect target dist returned (cmp: %d), wanted:\n%v\ngot:\n%v\n", cmp, tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestParseStringToOSARCH(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		wants OSARCH
+		err   error
+	}{
+		{
+			name:  "windows/x86",
+			input: "windows/x86",
+			wants: OSARCH{OS: "windows", ARCH: "x86"},
+			err:   nil,
+		},
+		{
+			name:  "WINDOWS/X86",
+			input: "WINDOWS/x86",
+			wants: OSARCH{OS: "windows", ARCH: "x86"},
+			err:   nil,
+		},
+		{
+			name:  "windows",
+			input: "windows",
+			wants: OSARCH{OS: "windows", ARCH: ""},
+			err:   nil,
+		},
+		{
+			name:  "*/arm64",
+			input: "*/ARM64",
+			wants: OSARCH{OS: "*", ARCH: "arm64"},
+			err:   nil,
+		},
+		{
+			name:  "linux/arm/7",
+			input: "linux/arm/7",
+			wants: OSARCH{OS: "linux", ARCH: "arm", SubArch: "7"},
+			err:   nil,
+		},
+		{
+			name:  "linux/amd64/v3",
+			input: "linux/amd64/V3",
+			wants: OSARCH{OS: "linux", ARCH: "amd64", SubArch: "v3"},
+			err:   nil,
+		},
+		{
+			name:  "all",
+			input: "all",
+			wants: OSARCH{OS: "*"},
+			err:   nil,
+		},
+		{
+			name:  "first-class",
+			input: "First-Class",
+			wants: OSARCH{OS: "*", FirstClass: true},
+			err:   nil,
+		},
+		{
+			name:  "negated",
+			input: "!linux/MIPS*",
+			wants: OSARCH{OS: "linux", ARCH: "mips*", Negate: true},
+			err:   nil,
+		},
+		{
+			name:  "double negation",
+			input: "!!linux",
+			wants: OSARCH{},
+			err:   ErrInvalidOSARCH,
+		},
+		{
+			name:  "bare negation",
+			input: "!",
+			wants: OSARCH{},
+			err:   ErrInvalidOSARCH,
+		},
+		{
+			name:  "glob",
+			input: "linux/MIPS*",
+			wants: OSARCH{OS: "linux", ARCH: "mips*"},
+			err:   nil,
+		},
+		{
+			name:  "regexp",
+			input: "/^(linux|darwin)/arm64$/",
+			wants: OSARCH{Regexp: regexp.MustCompile("^(linux|darwin)/arm64$")},
+			err:   nil,
+		},
+		{
+			name:  "negated regexp",
+			input: "!/amd64$/",
+			wants: OSARCH{Regexp: regexp.MustCompile("amd64$"), Negate: true},
+			err:   nil,
+		},
+		{
+			name:  "invalid glob",
+			input: "linux/arm[",
+			wants: OSARCH{},
+			err:   ErrInvalidTargetPattern,
+		},
+		{
+			name:  "invalid regexp",
+			input: "/(amd64/",
+			wants: OSARCH{},
+			err:   ErrInvalidTargetPattern,
+		},
+		{
+			name:  "blank",
+			input: "",
+			wants: OSARCH{OS: "", ARCH: ""},
+			err:   ErrInvalidOSARCH,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := ParseTarget(tc.input)
+
+			if !sameTarget(res, tc.wants) {
+				t.Logf("Incorrect OSARCH formulated, wanted: %v got: %v\n", tc.wants, res)
+				t.Fail()
+			} else if !errors.Is(err, tc.err) {
+				t.Logf("Incorrect error returned, wanted: %v got: %v\n", tc.err, err)
+				t.Fail()
+			}
+		})
+	}
+
+}
+
+// sameTarget compares targets, with their regular expressions compared by
+// their source.
+func sameTarget(a, b OSARCH) bool {
+	if (a.Regexp == nil) != (b.Regexp == nil) || (a.Regexp != nil && a.Regexp.String() != b.Regexp.String()) {
+		return false
+	}
+
+	a.Regexp, b.Regexp = nil, nil
+	return a == b
+}
+
+func TestExcludeTargetBuilds(t *testing.T) {
+	testCases := []struct {
+		name     string
+		excludes []OSARCH
+		dists    []GoDist
+		wants    []GoDist
+	}{
+		{
+			name:     "no excludes",
+			excludes: []OSARCH{},
+			dists:    testingDists,
+			wants:    testingDists,
+		},
+		{
+			name: "exclude os",
+			excludes: []OSARCH{
+				OSARCH{OS: "linux", ARCH: ""},
+				OSARCH{OS: "bsd", ARCH: ""},
+			},
+			dists: testingDists,
+			wants: testingDists[:2],
+		},
+		{
+			name: "exclude wildcard arch",
+			excludes: []OSARCH{
+				OSARCH{OS: "*", ARCH: "arm*"},
+			},
+			dists: testingDists,
+			wants: []GoDist{testingDists[0], testingDists[2]},
+		},
+		{
+			name: "exclude os/arch",
+			excludes: []OSARCH{
+				OSARCH{OS: "linux", ARCH: "x86"},
+			},
+			dists: testingDists,
+			wants: []GoDist{testingDists[0], testingDists[1], testingDists[3], testingDists[4]},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := excludeTargetBuilds(tc.excludes, tc.dists)
+
+			if !slices.Equal(res, tc.wants) {
+				t.Logf("Incorrect dists remaining, wanted:\n%v\ngot:\n%v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestFilterSupportedDists(t *testing.T) {
+	wasmDist := GoDist{GOOS: "js", GOARCH: "wasm", CgoSupported: false, FirstClass: false}
+	dists := append(slices.Clone(testingDists), wasmDist)
+
+	testCases := []struct {
+		name   string
+		config BuildConfig
+		dists  []GoDist
+		wants  []GoDist
+	}{
+		{
+			name:   "no filters",
+			config: BuildConfig{},
+			dists:  testingDists,
+			wants:  testingDists,
+		},
+		{
+			name:   "first-class",
+			config: BuildConfig{FirstClass: true},
+			dists:  testingDists,
+			wants:  testingDists[:4],
+		},
+		{
+			name:   "cgo-only",
+			config: BuildConfig{CgoOnly: true},
+			dists:  dists,
+			wants:  testingDists,
+		},
+		{
+			name:   "no-cgo keeps every dist",
+			config: BuildConfig{NoCgo: true},
+			dists:  dists,
+			wants:  dists,
+		},
+		{
+			name:   "first-class and cgo-only",
+			config: BuildConfig{FirstClass: true, CgoOnly: true},
+			dists:  dists,
+			wants:  testingDists[:4],
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := filterSupportedDists(tc.config, tc.dists)
+
+			if !slices.Equal(res, tc.wants) {
+				t.Logf("Incorrect dists remaining, wanted:\n%v\ngot:\n%v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestValidateTargets(t *testing.T) {
+	testCases := []struct {
+		name    string
+		targets []OSARCH
+		wants   int
+	}{
+		{
+			name:    "all supported",
+			targets: []OSARCH{{OS: "linux", ARCH: ""}, {OS: "darwin", ARCH: "arm64"}},
+			wants:   0,
+		},
+		{
+			name:    "one unsupported",
+			targets: []OSARCH{{OS: "linux", ARCH: ""}, {OS: "linux", ARCH: "mips"}},
+			wants:   1,
+		},
+		{
+			name:    "negation",
+			targets: []OSARCH{{OS: "linux", ARCH: ""}, {OS: "linux", ARCH: "arm*", Negate: true}},
+			wants:   0,
+		},
+		{
+			name:    "unsupported negation",
+			targets: []OSARCH{{OS: "linux", ARCH: ""}, {OS: "linux", ARCH: "mips*", Negate: true}},
+			wants:   1,
+		},
+		{
+			name:    "all unsupported",
+			targets: []OSARCH{{OS: "plan9", ARCH: ""}, {OS: "widows", ARCH: "x86"}},
+			wants:   2,
+		},
+		{
+			name:    "invalid pattern",
+			targets: []OSARCH{{OS: "linux", ARCH: "arm["}},
+			wants:   1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateTargets(tc.targets, testingDists)
+
+			count := 0
+			if joined, ok := err.(interface{ Unwrap() []error }); ok {
+				for _, e := range joined.Unwrap() {
+					if errors.Is(e, ErrUnsupportedTargetOSARCH) || errors.Is(e, ErrInvalidTargetPattern) {
+						count++
+					}
+				}
+			}
+
+			if count != tc.wants {
+				t.Logf("Incorrect number of unsupported targets, wanted: %d got: %d (%v)\n", tc.wants, count, err)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestOverlappingTargets(t *testing.T) {
+	testCases := []struct {
+		name    string
+		targets []OSARCH
+		wants   []string
+	}{
+		{
+			name:    "no overlap",
+			targets: []OSARCH{{OS: "linux", ARCH: ""}, {OS: "windows", ARCH: ""}},
+			wants:   []string{},
+		},
+		{
+			name:    "os and os/arch",
+			targets: []OSARCH{{OS: "linux", ARCH: ""}, {OS: "linux", ARCH: "arm64"}},
+			wants:   []string{"linux/arm64 is selected by linux, linux/arm64"},
+		},
+		{
+			name:    "arch and os",
+			targets: []OSARCH{{OS: "*", ARCH: "arm64"}, {OS: "darwin", ARCH: ""}},
+			wants:   []string{"darwin/arm64 is selected by */arm64, darwin"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := OverlappingTargets(tc.targets, testingDists)
+
+			if !slices.Equal(res, tc.wants) {
+				t.Logf("Incorrect overlaps reported, wanted: %v got: %v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestTargetSetting(t *testing.T) {
+	settings := map[string]string{
+		"*":             "any",
+		"linux":         "linux",
+		"*/arm64":       "arm64",
+		"linux/arm64":   "linux-arm64",
+		"linux/arm/7":   "armv7",
+		"windows/amd64": "windows-amd64",
+	}
+
+	testCases := []struct {
+		name  string
+		input GoDist
+		wants string
+	}{
+		{name: "wildcard only", input: GoDist{GOOS: "plan9", GOARCH: "386"}, wants: "any"},
+		{name: "os beats wildcard", input: GoDist{GOOS: "linux", GOARCH: "amd64"}, wants: "linux"},
+		{name: "arch beats os", input: GoDist{GOOS: "darwin", GOARCH: "arm64"}, wants: "arm64"},
+		{name: "exact", input: GoDist{GOOS: "linux", GOARCH: "arm64"}, wants: "linux-arm64"},
+		{name: "sub-architecture", input: GoDist{GOOS: "linux", GOARCH: "arm", SubArch: "7"}, wants: "armv7"},
+		{name: "other sub-architecture", input: GoDist{GOOS: "linux", GOARCH: "arm", SubArch: "6"}, wants: "linux"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, ok := TargetSetting(settings, tc.input)
+
+			if !ok || res != tc.wants {
+				t.Logf("Incorrect setting returned, wanted: %v got: %v (%v)\n", tc.wants, res, ok)
+				t.Fail()
+			}
+		})
+	}
+
+	if _, ok := TargetSetting(map[string]string{"linux": "x"}, GoDist{GOOS: "darwin", GOARCH: "arm64"}); ok {
+		t.Log("Expected no setting for an unmatched target")
+		t.Fail()
+	}
+}
+
+func TestTargetSettings(t *testing.T) {
+	settings := map[string]string{
+		"*":           "any",
+		"linux":       "linux",
+		"*/arm64":     "arm64",
+		"linux/arm64": "linux-arm64",
+		"windows":     "windows",
+	}
+
+	testCases := []struct {
+		name     string
+		settings map[string]string
+		input    GoDist
+		wants    []string
+	}{
+		{name: "least to most specific", settings: settings, input: GoDist{GOOS: "linux", GOARCH: "arm64"}, wants: []string{"any", "linux", "arm64", "linux-arm64"}},
+		{name: "wildcard only", settings: settings, input: GoDist{GOOS: "plan9", GOARCH: "386"}, wants: []string{"any"}},
+		{name: "no match", settings: map[string]string{"linux": "x"}, input: GoDist{GOOS: "plan9", GOARCH: "386"}, wants: []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if res := TargetSettings(tc.settings, tc.input); !slices.Equal(res, tc.wants) {
+				t.Logf("Incorrect settings, wanted: %v got: %v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
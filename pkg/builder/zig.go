@@ -0,0 +1,52 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrZigWithoutCgo = errors.New("zig cross-compiles cgo code, it cannot be used with no-cgo")
+
+// zigTriples maps a GOOS/GOARCH to the target triple zig cc uses to
+// cross-compile C code for it.
+var zigTriples = map[string]string{
+	"darwin/amd64":  "x86_64-macos",
+	"darwin/arm64":  "aarch64-macos",
+	"linux/386":     "x86-linux-gnu",
+	"linux/amd64":   "x86_64-linux-gnu",
+	"linux/arm":     "arm-linux-gnueabihf",
+	"linux/arm64":   "aarch64-linux-gnu",
+	"linux/loong64": "loongarch64-linux-gnu",
+	"linux/ppc64le": "powerpc64le-linux-gnu",
+	"linux/riscv64": "riscv64-linux-gnu",
+	"linux/s390x":   "s390x-linux-gnu",
+	"windows/386":   "x86-windows-gnu",
+	"windows/amd64": "x86_64-windows-gnu",
+	"windows/arm64": "aarch64-windows-gnu",
+}
+
+// ZigTripleFor returns the zig target triple for dist, preferring one from
+// the config (e.g. to pick musl over glibc), and whether one is known.
+func (config BuildConfig) ZigTripleFor(dist GoDist) (string, bool) {
+	if triple, ok := TargetSetting(config.ZigTriples, dist); ok {
+		return triple, true
+	}
+
+	triple, ok := zigTriples[dist.GOOS+"/"+dist.GOARCH]
+	return triple, ok
+}
+
+// zigEnv returns the CC/CXX overrides that make cgo cross-compile with zig.
+// Targets zig has no triple for are left to the default toolchain.
+func (config BuildConfig) zigEnv(dist GoDist) []string {
+	triple, ok := config.ZigTripleFor(dist)
+	if !config.Zig || !ok {
+		return []string{}
+	}
+
+	return []string{
+		"CGO_ENABLED=1",
+		fmt.Sprintf("CC=zig cc -target %s", triple),
+		fmt.Sprintf("CXX=zig c++ -target %s", triple),
+	}
+}
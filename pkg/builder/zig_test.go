@@ -0,0 +1,69 @@
+package builder
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestZigTripleFor(t *testing.T) {
+	config := NewConfig()
+	config.ZigTriples = map[string]string{"linux/amd64": "x86_64-linux-musl"}
+
+	testCases := []struct {
+		name    string
+		input   GoDist
+		wants   string
+		wantsOk bool
+	}{
+		{name: "builtin", input: GoDist{GOOS: "linux", GOARCH: "arm64"}, wants: "aarch64-linux-gnu", wantsOk: true},
+		{name: "configured", input: GoDist{GOOS: "linux", GOARCH: "amd64"}, wants: "x86_64-linux-musl", wantsOk: true},
+		{name: "unknown", input: GoDist{GOOS: "plan9", GOARCH: "amd64"}, wants: "", wantsOk: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, ok := config.ZigTripleFor(tc.input)
+			if res != tc.wants || ok != tc.wantsOk {
+				t.Logf("Incorrect triple, wanted: %s %t got: %s %t\n", tc.wants, tc.wantsOk, res, ok)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestZigEnv(t *testing.T) {
+	zig := NewConfig()
+	zig.Zig = true
+
+	testCases := []struct {
+		name   string
+		config BuildConfig
+		input  GoDist
+		wants  []string
+	}{
+		{
+			name:   "zig",
+			config: zig,
+			input:  GoDist{GOOS: "linux", GOARCH: "arm64"},
+			wants:  []string{"CGO_ENABLED=1", "CC=zig cc -target aarch64-linux-gnu", "CXX=zig c++ -target aarch64-linux-gnu"},
+		},
+		{name: "unknown target", config: zig, input: GoDist{GOOS: "plan9", GOARCH: "amd64"}, wants: []string{}},
+		{name: "without zig", config: NewConfig(), input: GoDist{GOOS: "linux", GOARCH: "arm64"}, wants: []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if res := tc.config.zigEnv(tc.input); !slices.Equal(res, tc.wants) {
+				t.Logf("Incorrect env, wanted: %v got: %v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+
+	zig.NoCgo = true
+	if err := zig.Validate(); !errors.Is(err, ErrZigWithoutCgo) {
+		t.Logf("Incorrect error, wanted: %v got: %v\n", ErrZigWithoutCgo, err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,503 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: build.proto
+
+// The build service of go-builder serve -grpc-addr. A build runs the
+// go-builder command line on the server and streams its progress.
+
+package buildpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type BuildRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Project is the project directory on the server.
+	Project string `protobuf:"bytes,1,opt,name=project,proto3" json:"project,omitempty"`
+	// Targets are target patterns such as linux/amd64 or windows/*.
+	Targets []string `protobuf:"bytes,2,rep,name=targets,proto3" json:"targets,omitempty"`
+	// Flags are more command line flags, e.g. ["-ldflags", "-s -w"].
+	Flags         []string `protobuf:"bytes,3,rep,name=flags,proto3" json:"flags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BuildRequest) Reset() {
+	*x = BuildRequest{}
+	mi := &file_build_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BuildRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuildRequest) ProtoMessage() {}
+
+func (x *BuildRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_build_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuildRequest.ProtoReflect.Descriptor instead.
+func (*BuildRequest) Descriptor() ([]byte, []int) {
+	return file_build_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *BuildRequest) GetProject() string {
+	if x != nil {
+		return x.Project
+	}
+	return ""
+}
+
+func (x *BuildRequest) GetTargets() []string {
+	if x != nil {
+		return x.Targets
+	}
+	return nil
+}
+
+func (x *BuildRequest) GetFlags() []string {
+	if x != nil {
+		return x.Flags
+	}
+	return nil
+}
+
+type BuildEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*BuildEvent_Started
+	//	*BuildEvent_Output
+	//	*BuildEvent_Target
+	//	*BuildEvent_Finished
+	Event         isBuildEvent_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BuildEvent) Reset() {
+	*x = BuildEvent{}
+	mi := &file_build_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BuildEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuildEvent) ProtoMessage() {}
+
+func (x *BuildEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_build_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuildEvent.ProtoReflect.Descriptor instead.
+func (*BuildEvent) Descriptor() ([]byte, []int) {
+	return file_build_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *BuildEvent) GetEvent() isBuildEvent_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *BuildEvent) GetStarted() *JobStarted {
+	if x != nil {
+		if x, ok := x.Event.(*BuildEvent_Started); ok {
+			return x.Started
+		}
+	}
+	return nil
+}
+
+func (x *BuildEvent) GetOutput() string {
+	if x != nil {
+		if x, ok := x.Event.(*BuildEvent_Output); ok {
+			return x.Output
+		}
+	}
+	return ""
+}
+
+func (x *BuildEvent) GetTarget() *TargetResult {
+	if x != nil {
+		if x, ok := x.Event.(*BuildEvent_Target); ok {
+			return x.Target
+		}
+	}
+	return nil
+}
+
+func (x *BuildEvent) GetFinished() *RunResult {
+	if x != nil {
+		if x, ok := x.Event.(*BuildEvent_Finished); ok {
+			return x.Finished
+		}
+	}
+	return nil
+}
+
+type isBuildEvent_Event interface {
+	isBuildEvent_Event()
+}
+
+type BuildEvent_Started struct {
+	// Started is sent first, with the id of the job on the server.
+	Started *JobStarted `protobuf:"bytes,1,opt,name=started,proto3,oneof"`
+}
+
+type BuildEvent_Output struct {
+	// Output is a line of the build output.
+	Output string `protobuf:"bytes,2,opt,name=output,proto3,oneof"`
+}
+
+type BuildEvent_Target struct {
+	Target *TargetResult `protobuf:"bytes,3,opt,name=target,proto3,oneof"`
+}
+
+type BuildEvent_Finished struct {
+	// Finished is sent last.
+	Finished *RunResult `protobuf:"bytes,4,opt,name=finished,proto3,oneof"`
+}
+
+func (*BuildEvent_Started) isBuildEvent_Event() {}
+
+func (*BuildEvent_Output) isBuildEvent_Event() {}
+
+func (*BuildEvent_Target) isBuildEvent_Event() {}
+
+func (*BuildEvent_Finished) isBuildEvent_Event() {}
+
+type JobStarted struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobStarted) Reset() {
+	*x = JobStarted{}
+	mi := &file_build_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobStarted) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobStarted) ProtoMessage() {}
+
+func (x *JobStarted) ProtoReflect() protoreflect.Message {
+	mi := &file_build_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobStarted.ProtoReflect.Descriptor instead.
+func (*JobStarted) Descriptor() ([]byte, []int) {
+	return file_build_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *JobStarted) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type TargetResult struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Target string                 `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	Race   bool                   `protobuf:"varint,2,opt,name=race,proto3" json:"race,omitempty"`
+	// Status is ok, up to date, failed or cancelled.
+	Status        string `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	DurationMs    int64  `protobuf:"varint,4,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	Size          int64  `protobuf:"varint,5,opt,name=size,proto3" json:"size,omitempty"`
+	Error         string `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TargetResult) Reset() {
+	*x = TargetResult{}
+	mi := &file_build_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TargetResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TargetResult) ProtoMessage() {}
+
+func (x *TargetResult) ProtoReflect() protoreflect.Message {
+	mi := &file_build_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TargetResult.ProtoReflect.Descriptor instead.
+func (*TargetResult) Descriptor() ([]byte, []int) {
+	return file_build_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *TargetResult) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *TargetResult) GetRace() bool {
+	if x != nil {
+		return x.Race
+	}
+	return false
+}
+
+func (x *TargetResult) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *TargetResult) GetDurationMs() int64 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+func (x *TargetResult) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *TargetResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type RunResult struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Success bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Results []*TargetResult        `protobuf:"bytes,2,rep,name=results,proto3" json:"results,omitempty"`
+	// Artifacts are the files of the run, downloadable from the REST API
+	// of the server.
+	Artifacts     []string `protobuf:"bytes,3,rep,name=artifacts,proto3" json:"artifacts,omitempty"`
+	Error         string   `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RunResult) Reset() {
+	*x = RunResult{}
+	mi := &file_build_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunResult) ProtoMessage() {}
+
+func (x *RunResult) ProtoReflect() protoreflect.Message {
+	mi := &file_build_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunResult.ProtoReflect.Descriptor instead.
+func (*RunResult) Descriptor() ([]byte, []int) {
+	return file_build_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *RunResult) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *RunResult) GetResults() []*TargetResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *RunResult) GetArtifacts() []string {
+	if x != nil {
+		return x.Artifacts
+	}
+	return nil
+}
+
+func (x *RunResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+var File_build_proto protoreflect.FileDescriptor
+
+const file_build_proto_rawDesc = "" +
+	"\n" +
+	"\vbuild.proto\x12\fgobuilder.v1\"X\n" +
+	"\fBuildRequest\x12\x18\n" +
+	"\aproject\x18\x01 \x01(\tR\aproject\x12\x18\n" +
+	"\atargets\x18\x02 \x03(\tR\atargets\x12\x14\n" +
+	"\x05flags\x18\x03 \x03(\tR\x05flags\"\xd2\x01\n" +
+	"\n" +
+	"BuildEvent\x124\n" +
+	"\astarted\x18\x01 \x01(\v2\x18.gobuilder.v1.JobStartedH\x00R\astarted\x12\x18\n" +
+	"\x06output\x18\x02 \x01(\tH\x00R\x06output\x124\n" +
+	"\x06target\x18\x03 \x01(\v2\x1a.gobuilder.v1.TargetResultH\x00R\x06target\x125\n" +
+	"\bfinished\x18\x04 \x01(\v2\x17.gobuilder.v1.RunResultH\x00R\bfinishedB\a\n" +
+	"\x05event\"\x1c\n" +
+	"\n" +
+	"JobStarted\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x9d\x01\n" +
+	"\fTargetResult\x12\x16\n" +
+	"\x06target\x18\x01 \x01(\tR\x06target\x12\x12\n" +
+	"\x04race\x18\x02 \x01(\bR\x04race\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x1f\n" +
+	"\vduration_ms\x18\x04 \x01(\x03R\n" +
+	"durationMs\x12\x12\n" +
+	"\x04size\x18\x05 \x01(\x03R\x04size\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error\"\x8f\x01\n" +
+	"\tRunResult\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x124\n" +
+	"\aresults\x18\x02 \x03(\v2\x1a.gobuilder.v1.TargetResultR\aresults\x12\x1c\n" +
+	"\tartifacts\x18\x03 \x03(\tR\tartifacts\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error2O\n" +
+	"\fBuildService\x12?\n" +
+	"\x05Build\x12\x1a.gobuilder.v1.BuildRequest\x1a\x18.gobuilder.v1.BuildEvent0\x01B,Z*github.com/jrstaple/go-builder/pkg/buildpbb\x06proto3"
+
+var (
+	file_build_proto_rawDescOnce sync.Once
+	file_build_proto_rawDescData []byte
+)
+
+func file_build_proto_rawDescGZIP() []byte {
+	file_build_proto_rawDescOnce.Do(func() {
+		file_build_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_build_proto_rawDesc), len(file_build_proto_rawDesc)))
+	})
+	return file_build_proto_rawDescData
+}
+
+var file_build_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_build_proto_goTypes = []any{
+	(*BuildRequest)(nil), // 0: gobuilder.v1.BuildRequest
+	(*BuildEvent)(nil),   // 1: gobuilder.v1.BuildEvent
+	(*JobStarted)(nil),   // 2: gobuilder.v1.JobStarted
+	(*TargetResult)(nil), // 3: gobuilder.v1.TargetResult
+	(*RunResult)(nil),    // 4: gobuilder.v1.RunResult
+}
+var file_build_proto_depIdxs = []int32{
+	2, // 0: gobuilder.v1.BuildEvent.started:type_name -> gobuilder.v1.JobStarted
+	3, // 1: gobuilder.v1.BuildEvent.target:type_name -> gobuilder.v1.TargetResult
+	4, // 2: gobuilder.v1.BuildEvent.finished:type_name -> gobuilder.v1.RunResult
+	3, // 3: gobuilder.v1.RunResult.results:type_name -> gobuilder.v1.TargetResult
+	0, // 4: gobuilder.v1.BuildService.Build:input_type -> gobuilder.v1.BuildRequest
+	1, // 5: gobuilder.v1.BuildService.Build:output_type -> gobuilder.v1.BuildEvent
+	5, // [5:6] is the sub-list for method output_type
+	4, // [4:5] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_build_proto_init() }
+func file_build_proto_init() {
+	if File_build_proto != nil {
+		return
+	}
+	file_build_proto_msgTypes[1].OneofWrappers = []any{
+		(*BuildEvent_Started)(nil),
+		(*BuildEvent_Output)(nil),
+		(*BuildEvent_Target)(nil),
+		(*BuildEvent_Finished)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_build_proto_rawDesc), len(file_build_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_build_proto_goTypes,
+		DependencyIndexes: file_build_proto_depIdxs,
+		MessageInfos:      file_build_proto_msgTypes,
+	}.Build()
+	File_build_proto = out.File
+	file_build_proto_goTypes = nil
+	file_build_proto_depIdxs = nil
+}
@@ -0,0 +1,5 @@
+// Package buildpb holds the gRPC build service served by go-builder serve
+// -grpc-addr and used by go-builder remote, generated from build.proto.
+package buildpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative build.proto
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// prefixColors are cycled through so neighbouring targets are told apart.
+var prefixColors = []string{"\x1b[36m", "\x1b[33m", "\x1b[32m", "\x1b[35m", "\x1b[34m", "\x1b[31m"}
+
+// prefixWriter writes each line with a tag naming its target, such as
+// "[linux/arm64] ", so the output of concurrent builds stays readable when
+// interleaved. Writers sharing mu write whole lines to w in turn.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+// newPrefixWriters returns a writer per name, with the tags padded to the
+// longest name and colored when colors is enabled.
+func newPrefixWriters(w io.Writer, names []string, colors palette) []*prefixWriter {
+	width := 0
+	for _, name := range names {
+		width = max(width, len(name))
+	}
+
+	mu := &sync.Mutex{}
+	writers := make([]*prefixWriter, len(names))
+	for i, name := range names {
+		tag := fmt.Sprintf("%-*s ", width+2, "["+name+"]")
+		writers[i] = &prefixWriter{
+			mu:     mu,
+			w:      w,
+			prefix: colors.paint(prefixColors[i%len(prefixColors)], tag),
+		}
+	}
+
+	return writers
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+
+	end := bytes.LastIndexByte(p.buf, '\n')
+	if end < 0 {
+		return len(b), nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, line := range bytes.SplitAfter(p.buf[:end+1], []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if _, err := io.WriteString(p.w, p.prefix); err != nil {
+			return 0, err
+		}
+		if _, err := p.w.Write(line); err != nil {
+			return 0, err
+		}
+	}
+
+	p.buf = append(p.buf[:0], p.buf[end+1:]...)
+	return len(b), nil
+}
+
+// Flush writes a final line that did not end in a newline.
+func (p *prefixWriter) Flush() error {
+	if len(p.buf) == 0 {
+		return nil
+	}
+
+	_, err := p.Write([]byte("\n"))
+	return err
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	out := strings.Builder{}
+	writers := newPrefixWriters(&out, []string{"linux/arm64", "js/wasm"}, palette{})
+
+	arm, wasm := writers[0], writers[1]
+	arm.Write([]byte("# app\n./main.go:3:9: "))
+	wasm.Write([]byte("hook output\n"))
+	arm.Write([]byte("undefined: foo\nno newline"))
+	arm.Flush()
+	wasm.Flush()
+
+	wants := "[linux/arm64] # app\n" +
+		"[js/wasm]     hook output\n" +
+		"[linux/arm64] ./main.go:3:9: undefined: foo\n" +
+		"[linux/arm64] no newline\n"
+
+	if out.String() != wants {
+		t.Logf("Incorrect output, wanted:\n%s\ngot:\n%s\n", wants, out.String())
+		t.Fail()
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var ErrUnknownProfile = errors.New("no profile with this name")
+
+// Profile is a named set of build settings selected with -profile, so one
+// config covers debug builds and release builds.
+type Profile struct {
+	// Ldflags are appended to the ldflags of every build.
+	Ldflags string `json:"ldflags"`
+	// RemoveLdflags are removed from the ldflags of every build, e.g. -s
+	// and -w so a debug build keeps its symbols.
+	RemoveLdflags []string `json:"remove_ldflags"`
+	Gcflags       string   `json:"gcflags"`
+	Trimpath      bool     `json:"trimpath"`
+	Tags          []string `json:"tags"`
+	// Flags are other go build flags, e.g. -race.
+	Flags []string `json:"flags"`
+	// UPX compresses the binaries of upxTargets with upx, given UPXFlags,
+	// e.g. --best. The others are left uncompressed.
+	UPX      bool     `json:"upx"`
+	UPXFlags []string `json:"upx_flags"`
+}
+
+// builtinProfiles are available without configuration. Profiles of the
+// same name in the config replace them.
+var builtinProfiles = map[string]Profile{
+	"debug":   {Gcflags: "all=-N -l", RemoveLdflags: []string{"-s", "-w"}},
+	"release": {Trimpath: true, Ldflags: "-s -w", UPX: true},
+}
+
+// lookupProfile returns the profile name, from the config's profiles or the
+// built-in ones.
+func lookupProfile(profiles map[string]Profile, name string) (Profile, error) {
+	all := maps.Clone(builtinProfiles)
+	maps.Copy(all, profiles)
+
+	if p, ok := all[name]; ok {
+		return p, nil
+	}
+
+	return Profile{}, fmt.Errorf("%w: %q, expected one of %s", ErrUnknownProfile, name, strings.Join(slices.Sorted(maps.Keys(all)), ", "))
+}
+
+// buildFlags returns the go build flags of the profile.
+func (p Profile) buildFlags() []string {
+	flags := []string{}
+
+	if p.Gcflags != "" {
+		flags = append(flags, "-gcflags="+p.Gcflags)
+	}
+
+	if p.Trimpath {
+		flags = append(flags, "-trimpath")
+	}
+
+	return append(flags, p.Flags...)
+}
+
+// profileBuilds applies the profile to every build. Flags given after --
+// come after the profile's, so they win.
+func profileBuilds(builds []builder.BuildConfig, p Profile) []builder.BuildConfig {
+	profiled := []builder.BuildConfig{}
+
+	for _, build := range builds {
+		if len(p.RemoveLdflags) > 0 {
+			ldflags := slices.DeleteFunc(strings.Fields(build.Ldflags), func(flag string) bool {
+				return slices.Contains(p.RemoveLdflags, flag)
+			})
+			build.Ldflags = strings.Join(ldflags, " ")
+		}
+		build.Ldflags = strings.TrimSpace(build.Ldflags + " " + p.Ldflags)
+		build.Tags = append(slices.Clone(build.Tags), p.Tags...)
+		build.BuildFlags = append(p.buildFlags(), build.BuildFlags...)
+		profiled = append(profiled, build)
+	}
+
+	return profiled
+}
+
+// upxTargets are the GOOS/GOARCH pairs whose executables upx can pack.
+// It cannot pack current macOS binaries, windows/arm64 or most of the
+// other ports, e.g. riscv64, loong64 or s390x.
+var upxTargets = map[string][]string{
+	"linux":   {"386", "amd64", "arm", "arm64", "mips", "mipsle", "ppc64le"},
+	"windows": {"386", "amd64"},
+}
+
+// upxSupported reports whether upx can compress the build of dist: it
+// packs the executables of upxTargets, not wasm modules or libraries.
+func upxSupported(config builder.BuildConfig, dist builder.GoDist) bool {
+	if !slices.Contains(upxTargets[dist.GOOS], dist.GOARCH) {
+		return false
+	}
+
+	switch config.BuildModeFor(dist) {
+	case "", "default", "exe", "pie":
+		return true
+	}
+
+	return false
+}
+
+// compressBinary runs upx on the binary of dist and returns its output.
+func compressBinary(ctx context.Context, config builder.BuildConfig, dist builder.GoDist, flags []string) (string, error) {
+	if !upxSupported(config, dist) {
+		return "", nil
+	}
+
+	cmd := exec.CommandContext(ctx, "upx", append(slices.Clone(flags), builder.OutputPath(config, dist))...)
+	cmd.Dir = config.ProjectDir
+
+	res := bytes.Buffer{}
+	cmd.Stdout = &res
+	if config.Output != nil {
+		cmd.Stdout = io.MultiWriter(&res, config.Output)
+	}
+	cmd.Stderr = cmd.Stdout
+
+	if err := cmd.Run(); err != nil {
+		return res.String(), fmt.Errorf("upx: %w", err)
+	}
+
+	return res.String(), nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestLookupProfile(t *testing.T) {
+	profiles := map[string]Profile{
+		"release": {Ldflags: "-s"},
+		"bench":   {Flags: []string{"-race"}},
+	}
+
+	testCases := []struct {
+		name  string
+		input string
+		wants Profile
+		err   error
+	}{
+		{name: "builtin", input: "debug", wants: builtinProfiles["debug"]},
+		{name: "config overrides builtin", input: "release", wants: Profile{Ldflags: "-s"}},
+		{name: "config", input: "bench", wants: profiles["bench"]},
+		{name: "unknown", input: "fast", err: ErrUnknownProfile},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := lookupProfile(profiles, tc.input)
+
+			if !errors.Is(err, tc.err) {
+				t.Logf("Incorrect error, wanted: %v got: %v\n", tc.err, err)
+				t.Fail()
+			}
+
+			if res.Ldflags != tc.wants.Ldflags || res.Gcflags != tc.wants.Gcflags || !slices.Equal(res.Flags, tc.wants.Flags) {
+				t.Logf("Incorrect profile, wanted: %+v got: %+v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestProfileBuilds(t *testing.T) {
+	app := builder.NewConfig()
+	app.BinaryName = "app"
+	app.Ldflags = "-X main.version=1.0"
+	app.BuildFlags = []string{"-gcflags=all=-B"}
+
+	profile := Profile{Ldflags: "-s -w", Gcflags: "all=-N -l", Trimpath: true, Tags: []string{"prod"}, Flags: []string{"-buildvcs=false"}}
+	res := profileBuilds([]builder.BuildConfig{app}, profile)
+
+	if len(res) != 1 {
+		t.Fatalf("Incorrect number of builds, wanted: 1 got: %d\n", len(res))
+	}
+
+	if res[0].Ldflags != "-X main.version=1.0 -s -w" {
+		t.Logf("Incorrect ldflags: %q\n", res[0].Ldflags)
+		t.Fail()
+	}
+
+	if !slices.Equal(res[0].Tags, []string{"prod"}) {
+		t.Logf("Incorrect tags: %v\n", res[0].Tags)
+		t.Fail()
+	}
+
+	wantsFlags := []string{"-gcflags=all=-N -l", "-trimpath", "-buildvcs=false", "-gcflags=all=-B"}
+	if !slices.Equal(res[0].BuildFlags, wantsFlags) {
+		t.Logf("Incorrect build flags, wanted: %q got: %q\n", wantsFlags, res[0].BuildFlags)
+		t.Fail()
+	}
+}
+
+func TestBuiltinProfiles(t *testing.T) {
+	app := builder.NewConfig()
+	app.Ldflags = "-s -w -X main.version=1.0"
+
+	testCases := []struct {
+		name     string
+		input    string
+		wants    string
+		wantsUPX bool
+	}{
+		{name: "debug keeps symbols", input: "debug", wants: "-X main.version=1.0"},
+		{name: "release strips and compresses", input: "release", wants: "-s -w -X main.version=1.0 -s -w", wantsUPX: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			profile, err := lookupProfile(nil, tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res := profileBuilds([]builder.BuildConfig{app}, profile)[0].Ldflags; res != tc.wants {
+				t.Logf("Incorrect ldflags, wanted: %q got: %q\n", tc.wants, res)
+				t.Fail()
+			}
+
+			if profile.UPX != tc.wantsUPX {
+				t.Logf("Incorrect upx, wanted: %t got: %t\n", tc.wantsUPX, profile.UPX)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestUPXSupported(t *testing.T) {
+	config := builder.NewConfig()
+	config.BuildModes = map[string]string{"windows/amd64": "c-shared"}
+
+	testCases := []struct {
+		name  string
+		input builder.GoDist
+		wants bool
+	}{
+		{name: "linux amd64", input: builder.GoDist{GOOS: "linux", GOARCH: "amd64"}, wants: true},
+		{name: "linux arm64", input: builder.GoDist{GOOS: "linux", GOARCH: "arm64"}, wants: true},
+		{name: "linux arm sub-arch", input: builder.GoDist{GOOS: "linux", GOARCH: "arm", SubArch: "6"}, wants: true},
+		{name: "linux ppc64le", input: builder.GoDist{GOOS: "linux", GOARCH: "ppc64le"}, wants: true},
+		{name: "windows 386", input: builder.GoDist{GOOS: "windows", GOARCH: "386"}, wants: true},
+		{name: "windows arm64", input: builder.GoDist{GOOS: "windows", GOARCH: "arm64"}, wants: false},
+		{name: "darwin amd64", input: builder.GoDist{GOOS: "darwin", GOARCH: "amd64"}, wants: false},
+		{name: "darwin arm64", input: builder.GoDist{GOOS: "darwin", GOARCH: "arm64"}, wants: false},
+		{name: "linux riscv64", input: builder.GoDist{GOOS: "linux", GOARCH: "riscv64"}, wants: false},
+		{name: "linux loong64", input: builder.GoDist{GOOS: "linux", GOARCH: "loong64"}, wants: false},
+		{name: "linux mips64", input: builder.GoDist{GOOS: "linux", GOARCH: "mips64"}, wants: false},
+		{name: "linux s390x", input: builder.GoDist{GOOS: "linux", GOARCH: "s390x"}, wants: false},
+		{name: "linux ppc64", input: builder.GoDist{GOOS: "linux", GOARCH: "ppc64"}, wants: false},
+		{name: "freebsd amd64", input: builder.GoDist{GOOS: "freebsd", GOARCH: "amd64"}, wants: false},
+		{name: "wasm", input: builder.GoDist{GOOS: "js", GOARCH: "wasm"}, wants: false},
+		{name: "library", input: builder.GoDist{GOOS: "windows", GOARCH: "amd64"}, wants: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if res := upxSupported(config, tc.input); res != tc.wants {
+				t.Logf("Incorrect result for %s, wanted: %v got: %v\n", tc.input, tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// isTerminal reports whether f is a character device such as a terminal
+// rather than a file or pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// progress reports on parallel builds. On a terminal it redraws one line per
+// in-flight target with a spinner and the elapsed time, below the targets
+// that finished. Otherwise it logs a line as each target finishes.
+type progress struct {
+	mu      sync.Mutex
+	w       io.Writer
+	tty     bool
+	colors  palette
+	total   int
+	done    int
+	frame   int
+	drawn   int
+	running []string
+	started map[string]time.Time
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+func newProgress(w io.Writer, tty bool, colors palette, total int) *progress {
+	return &progress{
+		w:       w,
+		tty:     tty,
+		colors:  colors,
+		total:   total,
+		started: map[string]time.Time{},
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Run redraws the in-flight targets until Stop is called.
+func (p *progress) Run() {
+	defer close(p.stopped)
+
+	if !p.tty {
+		<-p.stop
+		return
+	}
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			p.mu.Lock()
+			p.clear()
+			p.mu.Unlock()
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			p.frame++
+			p.clear()
+			p.draw()
+			p.mu.Unlock()
+		}
+	}
+}
+
+func (p *progress) Stop() {
+	close(p.stop)
+	<-p.stopped
+}
+
+func (p *progress) Start(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.running = append(p.running, name)
+	p.started[name] = time.Now()
+}
+
+func (p *progress) Finish(name string, status string, took time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if i := slices.Index(p.running, name); i >= 0 {
+		p.running = slices.Delete(p.running, i, i+1)
+	}
+	delete(p.started, name)
+	p.done++
+
+	p.clear()
+	fmt.Fprintf(p.w, "[%d/%d] %s %s %s\n", p.done, p.total, name, p.colors.status(status), took.Round(100*time.Millisecond))
+	p.draw()
+}
+
+// clear erases the lines drawn for the in-flight targets.
+func (p *progress) clear() {
+	if p.tty && p.drawn > 0 {
+		fmt.Fprintf(p.w, "\x1b[%dA\x1b[J", p.drawn)
+	}
+	p.drawn = 0
+}
+
+func (p *progress) draw() {
+	if !p.tty {
+		return
+	}
+
+	spinner := spinnerFrames[p.frame%len(spinnerFrames)]
+	for _, name := range p.running {
+		fmt.Fprintf(p.w, "%s %s %s\n", spinner, name, time.Since(p.started[name]).Round(time.Second))
+	}
+	fmt.Fprintf(p.w, "%d/%d done\n", p.done, p.total)
+
+	p.drawn = len(p.running) + 1
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgress(t *testing.T) {
+	testCases := []struct {
+		name  string
+		tty   bool
+		wants string
+	}{
+		{
+			name: "plain",
+			tty:  false,
+			wants: "[1/2] linux/amd64 ok 1.2s\n" +
+				"[2/2] windows/amd64 failed 300ms\n",
+		},
+		{
+			name: "terminal",
+			tty:  true,
+			wants: "[1/2] linux/amd64 ok 1.2s\n" +
+				"⠋ windows/amd64 0s\n" +
+				"1/2 done\n" +
+				"\x1b[2A\x1b[J" +
+				"[2/2] windows/amd64 failed 300ms\n" +
+				"2/2 done\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := strings.Builder{}
+			// without Run nothing is redrawn between the updates
+			p := newProgress(&out, tc.tty, palette{}, 2)
+
+			p.Start("linux/amd64")
+			p.Start("windows/amd64")
+			p.Finish("linux/amd64", "ok", 1240*time.Millisecond)
+			p.Finish("windows/amd64", "failed", 300*time.Millisecond)
+
+			if out.String() != tc.wants {
+				t.Logf("Incorrect progress, wanted:\n%q\ngot:\n%q\n", tc.wants, out.String())
+				t.Fail()
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+var ErrUnknownPublisher = errors.New("unsupported publish backend")
+
+// publishers lists the release backends that can be selected with -publish or
+// the publish config setting.
+var publishers = []string{"github", "gitlab", "gitea"}
+
+// Publisher creates or updates a release and attaches files to it, returning
+// a link to the published release.
+type Publisher interface {
+	Publish(ctx context.Context, release Release, files []string) (string, error)
+	// DownloadURL returns the URL a file published to the tag's release
+	// is downloaded from.
+	DownloadURL(tag string, file string) string
+}
+
+// newPublisher returns the backend named by name. Repositories that are not
+// set in the config default to the project's origin remote.
+func newPublisher(ctx context.Context, name string, config ConfigFile, projectDir string, binaryName string) (Publisher, error) {
+	remote := func(repo string) (string, error) {
+		if repo != "" {
+			return repo, nil
+		}
+
+		return gitRemoteRepo(ctx, projectDir)
+	}
+
+	var err error
+
+	switch name {
+	case "github":
+		config.GitHub.Repo, err = remote(config.GitHub.Repo)
+		return githubPublisher{config: config.GitHub}, err
+	case "gitlab":
+		config.GitLab.Project, err = remote(config.GitLab.Project)
+		return gitlabPublisher{config: config.GitLab, pkgName: binaryName}, err
+	case "gitea":
+		config.Gitea.Repo, err = remote(config.Gitea.Repo)
+		return giteaPublisher{config: config.Gitea}, err
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPublisher, name)
+	}
+}
+
+// tokenFromEnv returns the first non-empty variable of names.
+func tokenFromEnv(names ...string) (string, error) {
+	for _, name := range names {
+		if token := os.Getenv(name); token != "" {
+			return token, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w (%s)", ErrMissingToken, strings.Join(names, " or "))
+}
+
+// apiClient sends JSON requests to a forge's REST API with the headers
+// (authentication, API version) that forge requires.
+type apiClient struct {
+	headers map[string]string
+}
+
+func (c apiClient) do(ctx context.Context, method string, u string, contentType string, body io.Reader, out any) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return 0, err
+	}
+
+	for key, value := range c.headers {
+		req.Header.Set(key, value)
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, fmt.Errorf("%s %s: %s: %s", method, u, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	if out != nil {
+		return resp.StatusCode, json.NewDecoder(resp.Body).Decode(out)
+	}
+
+	return resp.StatusCode, nil
+}
+
+func jsonBody(v any) io.Reader {
+	raw, _ := json.Marshal(v)
+	return bytes.NewReader(raw)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"runtime"
+	"slices"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+// raceDetectorPlatforms are the GOOS/GOARCH pairs the race detector supports.
+var raceDetectorPlatforms = []string{
+	"darwin/amd64",
+	"darwin/arm64",
+	"freebsd/amd64",
+	"linux/amd64",
+	"linux/arm64",
+	"linux/loong64",
+	"linux/ppc64le",
+	"linux/s390x",
+	"netbsd/amd64",
+	"windows/amd64",
+}
+
+func raceSupported(dist builder.GoDist) bool {
+	return dist.CgoSupported && slices.Contains(raceDetectorPlatforms, dist.GOOS+"/"+dist.GOARCH)
+}
+
+// raceJobs returns the additional -race builds: the host platform, whether
+// or not it was selected, plus every selected target the race detector
+// supports.
+func raceJobs(config builder.BuildConfig, dists []builder.GoDist) []buildJob {
+	config.Race = true
+
+	host := builder.GoDist{GOOS: runtime.GOOS, GOARCH: runtime.GOARCH, CgoSupported: true}
+	jobs := []buildJob{}
+
+	if raceSupported(host) {
+		jobs = append(jobs, buildJob{Config: config, Dist: host})
+	}
+
+	for _, dist := range dists {
+		if raceSupported(dist) && dist.String() != host.String() {
+			jobs = append(jobs, buildJob{Config: config, Dist: dist})
+		}
+	}
+
+	return jobs
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestRaceJobs(t *testing.T) {
+	host := builder.GoDist{GOOS: runtime.GOOS, GOARCH: runtime.GOARCH, CgoSupported: true, FirstClass: true}
+	if !raceSupported(host) {
+		t.Skip("race detector not supported on host")
+	}
+
+	dists := []builder.GoDist{
+		host,
+		{GOOS: "linux", GOARCH: "arm64", CgoSupported: true},
+		{GOOS: "linux", GOARCH: "mips", CgoSupported: true},
+		{GOOS: "windows", GOARCH: "amd64", CgoSupported: true},
+		{GOOS: "js", GOARCH: "wasm", CgoSupported: false},
+	}
+
+	jobs := raceJobs(builder.NewConfig(), dists)
+
+	res := []string{}
+	for _, job := range jobs {
+		if !job.Config.Race {
+			t.Logf("Race job for %s does not have race enabled\n", job.Dist)
+			t.Fail()
+		}
+		res = append(res, job.Dist.GOOS+"/"+job.Dist.GOARCH)
+	}
+
+	wants := []string{host.GOOS + "/" + host.GOARCH}
+	for _, platform := range []string{"linux/arm64", "windows/amd64"} {
+		if !slices.Contains(wants, platform) {
+			wants = append(wants, platform)
+		}
+	}
+
+	if !slices.Equal(res, wants) {
+		t.Logf("Incorrect race jobs, wanted: %v got: %v\n", wants, res)
+		t.Fail()
+	}
+}
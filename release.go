@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var ErrNoReleaseTag = errors.New("no release tag given and HEAD is not tagged")
+
+// Release describes the release the artifacts are published to.
+type Release struct {
+	Tag        string
+	Name       string
+	Draft      bool
+	Prerelease bool
+}
+
+// releaseTag returns the tag pointing at HEAD of the project.
+func releaseTag(ctx context.Context, projectDir string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "describe", "--tags", "--exact-match")
+	cmd.Dir = projectDir
+
+	raw, err := cmd.Output()
+	if err != nil {
+		return "", ErrNoReleaseTag
+	}
+
+	return strings.TrimSpace(string(raw)), nil
+}
+
+// gitRemoteRepo returns the owner/repo path of the project's origin remote,
+// for either the scp-like (git@host:owner/repo.git) or URL form.
+func gitRemoteRepo(ctx context.Context, projectDir string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "remote", "get-url", "origin")
+	cmd.Dir = projectDir
+
+	raw, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git remote: %w", err)
+	}
+
+	return parseRemoteRepo(strings.TrimSpace(string(raw)))
+}
+
+func parseRemoteRepo(remote string) (string, error) {
+	repo := remote
+
+	if i := strings.Index(repo, "://"); i >= 0 {
+		repo = repo[i+3:]
+		repo = repo[strings.Index(repo, "/")+1:]
+	} else if i := strings.Index(repo, ":"); i >= 0 {
+		repo = repo[i+1:]
+	}
+
+	repo = strings.TrimSuffix(strings.Trim(repo, "/"), ".git")
+
+	if strings.Count(repo, "/") < 1 {
+		return "", fmt.Errorf("unable to find owner/repo in remote %q", remote)
+	}
+
+	return repo, nil
+}
+
+// releaseFiles returns the artifacts to attach to a release along with any
+// signatures next to them.
+func releaseFiles(artifacts []string) []string {
+	files := []string{}
+
+	for _, artifact := range artifacts {
+		files = append(files, artifact)
+
+		for _, suffix := range []string{".sig", ".pem"} {
+			if _, err := os.Stat(artifact + suffix); err == nil {
+				files = append(files, artifact+suffix)
+			}
+		}
+	}
+
+	return files
+}
+
+// expandReleaseURL fills in a release_url template, where {tag} is the
+// release tag, {version} the tag without its v prefix and {file} the file
+// name.
+func expandReleaseURL(template string, tag string, file string) string {
+	return strings.NewReplacer(
+		"{tag}", tag,
+		"{version}", strings.TrimPrefix(tag, "v"),
+		"{file}", file,
+	).Replace(template)
+}
+
+// pushToRepo clones the git repository, copies the files into dir within it,
+// and commits and pushes the change with the user's git credentials.
+func pushToRepo(ctx context.Context, repo string, dir string, message string, files ...string) error {
+	clone, err := os.MkdirTemp("", "gobuilder-repo")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(clone)
+
+	git := func(args ...string) error {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = clone
+
+		if res, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git %s: %w\n%s", args[0], err, res)
+		}
+
+		return nil
+	}
+
+	if err := git("clone", "--depth", "1", repo, "."); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Join(clone, dir), 0o755); err != nil {
+		return err
+	}
+
+	for _, fp := range files {
+		contents, err := os.ReadFile(fp)
+		if err != nil {
+			return err
+		}
+
+		dst := filepath.Join(dir, filepath.Base(fp))
+		if err := os.WriteFile(filepath.Join(clone, dst), contents, 0o644); err != nil {
+			return err
+		}
+
+		if err := git("add", dst); err != nil {
+			return err
+		}
+	}
+
+	// unchanged files leave nothing to commit
+	if err := git("diff", "--cached", "--quiet"); err == nil {
+		return nil
+	}
+
+	if err := git("commit", "-m", message); err != nil {
+		return err
+	}
+
+	return git("push")
+}
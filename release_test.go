@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestParseRemoteRepo(t *testing.T) {
+	testCases := []struct {
+		input    string
+		wants    string
+		wantsErr bool
+	}{
+		{input: "git@github.com:jrstapl/go-builder.git", wants: "jrstapl/go-builder"},
+		{input: "https://github.com/jrstapl/go-builder", wants: "jrstapl/go-builder"},
+		{input: "https://github.com/jrstapl/go-builder.git/", wants: "jrstapl/go-builder"},
+		{input: "ssh://git@gitlab.example.com:2222/group/sub/project.git", wants: "group/sub/project"},
+		{input: "https://github.com/", wantsErr: true},
+	}
+
+	for _, tc := range testCases {
+		res, err := parseRemoteRepo(tc.input)
+
+		if (err != nil) != tc.wantsErr {
+			t.Logf("Incorrect error for %s, wanted error: %v got: %v\n", tc.input, tc.wantsErr, err)
+			t.Fail()
+		} else if res != tc.wants {
+			t.Logf("Incorrect repo for %s, wanted: %s got: %s\n", tc.input, tc.wants, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestWriteChecksums(t *testing.T) {
+	dir := t.TempDir()
+
+	fp := filepath.Join(dir, "app-linux_amd64")
+	os.WriteFile(fp, []byte("hello\n"), 0o755)
+
+	checksums, err := writeChecksums(dir, []string{fp})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents, _ := os.ReadFile(checksums)
+	wants := "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03  app-linux_amd64\n"
+
+	if string(contents) != wants {
+		t.Logf("Incorrect checksums, wanted: %q got: %q\n", wants, contents)
+		t.Fail()
+	}
+}
+
+// testDownloadURL is the release URL of the package manager tests.
+func testDownloadURL(tag string, file string) string {
+	return expandReleaseURL("https://dl.example.com/{tag}/{file}", tag, file)
+}
+
+// writeTestArchives writes a fake archive of config's binary for each dist,
+// e.g. app-linux_amd64.tar.gz or app-windows_amd64.zip, to its output
+// directory and returns them by dist as buildArchives does. Each archive
+// holds its dist, so their checksums differ.
+func writeTestArchives(t *testing.T, config builder.BuildConfig, dists ...string) map[string]string {
+	t.Helper()
+
+	archives := map[string]string{}
+	for _, dist := range dists {
+		ext := ".tar.gz"
+		if strings.HasPrefix(dist, "windows/") {
+			ext = ".zip"
+		}
+
+		fp := filepath.Join(config.OutputDir, config.BinaryName+"-"+strings.ReplaceAll(dist, "/", "_")+ext)
+		if err := os.WriteFile(fp, []byte(dist), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		archives[dist] = fp
+	}
+
+	return archives
+}
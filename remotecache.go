@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrUnknownCacheCommand = errors.New("unknown cache command")
+
+// RemoteCacheConfig shares compiled packages between machines through
+// GOCACHEPROG. Prog is a GOCACHEPROG command used as is. URL is an HTTP
+// cache that stores PUT bodies and serves them back on GET, such as nginx
+// with WebDAV; this binary then acts as the cache program. ReadOnly only
+// fetches, for machines that should not populate the cache. A bearer token
+// is read from GOBUILDER_CACHE_TOKEN.
+type RemoteCacheConfig struct {
+	URL      string `json:"url"`
+	Prog     string `json:"prog"`
+	ReadOnly bool   `json:"read_only"`
+}
+
+func (r RemoteCacheConfig) IsEmpty() bool {
+	return r == RemoteCacheConfig{}
+}
+
+// goCacheProg returns the GOCACHEPROG for the config, running exe as the
+// cache program for an HTTP cache.
+func (r RemoteCacheConfig) goCacheProg(exe string) string {
+	if r.Prog != "" || r.URL == "" {
+		return r.Prog
+	}
+
+	prog := exe + " -cacheprog " + r.URL
+	if r.ReadOnly {
+		prog += " -cacheprog-readonly"
+	}
+
+	return prog
+}
+
+// cacheRequest and cacheResponse are the GOCACHEPROG protocol messages, see
+// cmd/go/internal/cacheprog.
+type cacheRequest struct {
+	ID       int64
+	Command  string
+	ActionID []byte `json:",omitempty"`
+	OutputID []byte `json:",omitempty"`
+	BodySize int64  `json:",omitempty"`
+}
+
+type cacheResponse struct {
+	ID            int64
+	Err           string     `json:",omitempty"`
+	KnownCommands []string   `json:",omitempty"`
+	Miss          bool       `json:",omitempty"`
+	OutputID      []byte     `json:",omitempty"`
+	Size          int64      `json:",omitempty"`
+	Time          *time.Time `json:",omitempty"`
+	DiskPath      string     `json:",omitempty"`
+}
+
+// httpCache keeps cache entries in dir and mirrors them to an HTTP server
+// as a-<action id>, holding the output id, size and time, and o-<output
+// id>, holding the body.
+type httpCache struct {
+	url      string
+	dir      string
+	readOnly bool
+	token    string
+	client   *http.Client
+
+	warnOnce sync.Once
+}
+
+func newHTTPCache(url string, dir string, readOnly bool) *httpCache {
+	return &httpCache{
+		url:      strings.TrimSuffix(url, "/"),
+		dir:      dir,
+		readOnly: readOnly,
+		token:    os.Getenv("GOBUILDER_CACHE_TOKEN"),
+		client:   &http.Client{Timeout: time.Minute},
+	}
+}
+
+// warn reports the first remote failure. The local cache keeps working, so
+// the build goes on without the remote.
+func (c *httpCache) warn(err error) {
+	c.warnOnce.Do(func() {
+		fmt.Fprintln(os.Stderr, "Warning: remote cache:", err)
+	})
+}
+
+func (c *httpCache) request(ctx context.Context, method string, name string, body []byte) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.url+"/"+name, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err == nil && res.StatusCode >= 300 && res.StatusCode != http.StatusNotFound {
+		err = fmt.Errorf("%s %s: %s", method, name, res.Status)
+	}
+
+	return data, res.StatusCode, err
+}
+
+// writeAtomic writes data to fp through a temporary file so concurrent
+// readers never see a partial entry.
+func writeAtomic(fp string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(fp), ".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), fp)
+	}
+
+	if err != nil {
+		os.Remove(f.Name())
+	}
+
+	return err
+}
+
+func parseCacheEntry(raw []byte) (outputID []byte, size int64, t time.Time, err error) {
+	fields := strings.Fields(string(raw))
+	if len(fields) != 3 {
+		return nil, 0, t, fmt.Errorf("invalid cache entry %q", raw)
+	}
+
+	if outputID, err = hex.DecodeString(fields[0]); err != nil {
+		return nil, 0, t, err
+	}
+
+	if size, err = strconv.ParseInt(fields[1], 10, 64); err != nil {
+		return nil, 0, t, err
+	}
+
+	nanos, err := strconv.ParseInt(fields[2], 10, 64)
+	return outputID, size, time.Unix(0, nanos), err
+}
+
+func (c *httpCache) get(ctx context.Context, actionID []byte) (cacheResponse, error) {
+	entryName := "a-" + hex.EncodeToString(actionID)
+	entryPath := filepath.Join(c.dir, entryName)
+
+	entry, err := os.ReadFile(entryPath)
+	remoteEntry := false
+
+	if err != nil {
+		data, status, err := c.request(ctx, http.MethodGet, entryName, nil)
+		if err != nil {
+			c.warn(err)
+			return cacheResponse{Miss: true}, nil
+		} else if status == http.StatusNotFound {
+			return cacheResponse{Miss: true}, nil
+		}
+
+		entry = data
+		remoteEntry = true
+	}
+
+	outputID, size, t, err := parseCacheEntry(entry)
+	if err != nil {
+		return cacheResponse{Miss: true}, nil
+	}
+
+	outputName := "o-" + hex.EncodeToString(outputID)
+	outputPath := filepath.Join(c.dir, outputName)
+
+	if info, err := os.Stat(outputPath); err != nil || info.Size() != size {
+		body, status, err := c.request(ctx, http.MethodGet, outputName, nil)
+		if err != nil {
+			c.warn(err)
+			return cacheResponse{Miss: true}, nil
+		} else if status == http.StatusNotFound || int64(len(body)) != size {
+			return cacheResponse{Miss: true}, nil
+		}
+
+		if err := writeAtomic(outputPath, body); err != nil {
+			return cacheResponse{}, err
+		}
+	}
+
+	if remoteEntry {
+		if err := writeAtomic(entryPath, entry); err != nil {
+			return cacheResponse{}, err
+		}
+	}
+
+	return cacheResponse{OutputID: outputID, Size: size, Time: &t, DiskPath: outputPath}, nil
+}
+
+func (c *httpCache) put(ctx context.Context, req cacheRequest, body []byte) (cacheResponse, error) {
+	if int64(len(body)) != req.BodySize {
+		return cacheResponse{}, fmt.Errorf("body of %d bytes, wanted %d", len(body), req.BodySize)
+	}
+
+	outputName := "o-" + hex.EncodeToString(req.OutputID)
+	outputPath := filepath.Join(c.dir, outputName)
+
+	if err := writeAtomic(outputPath, body); err != nil {
+		return cacheResponse{}, err
+	}
+
+	entryName := "a-" + hex.EncodeToString(req.ActionID)
+	entry := fmt.Appendf(nil, "%x %d %d\n", req.OutputID, len(body), time.Now().UnixNano())
+
+	if err := writeAtomic(filepath.Join(c.dir, entryName), entry); err != nil {
+		return cacheResponse{}, err
+	}
+
+	// the body goes first so a remote entry never points at a missing output
+	if !c.readOnly {
+		if _, _, err := c.request(ctx, http.MethodPut, outputName, body); err != nil {
+			c.warn(err)
+		} else if _, _, err := c.request(ctx, http.MethodPut, entryName, entry); err != nil {
+			c.warn(err)
+		}
+	}
+
+	return cacheResponse{DiskPath: outputPath}, nil
+}
+
+// serveCacheProg answers the go command's cache requests read from in until
+// it sends close. Requests are handled concurrently, as the protocol allows.
+func serveCacheProg(ctx context.Context, c *httpCache, in io.Reader, out io.Writer) error {
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return err
+	}
+
+	outMu := sync.Mutex{}
+	w := bufio.NewWriter(out)
+	enc := json.NewEncoder(w)
+
+	respond := func(res cacheResponse) {
+		outMu.Lock()
+		defer outMu.Unlock()
+
+		enc.Encode(res)
+		w.Flush()
+	}
+
+	respond(cacheResponse{KnownCommands: []string{"get", "put", "close"}})
+
+	dec := json.NewDecoder(bufio.NewReader(in))
+	wg := sync.WaitGroup{}
+	defer wg.Wait()
+
+	for {
+		req := cacheRequest{}
+		if err := dec.Decode(&req); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var body []byte
+		if req.Command == "put" && req.BodySize > 0 {
+			if err := dec.Decode(&body); err != nil {
+				return err
+			}
+		}
+
+		if req.Command == "close" {
+			wg.Wait()
+			respond(cacheResponse{ID: req.ID})
+			return nil
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var res cacheResponse
+			var err error
+
+			switch req.Command {
+			case "get":
+				res, err = c.get(ctx, req.ActionID)
+			case "put":
+				res, err = c.put(ctx, req, body)
+			default:
+				err = fmt.Errorf("%w: %s", ErrUnknownCacheCommand, req.Command)
+			}
+
+			res.ID = req.ID
+			if err != nil {
+				res.Err = err.Error()
+			}
+
+			respond(res)
+		}()
+	}
+}
+
+// remoteCacheDir is where the cache program keeps its local copies.
+func remoteCacheDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(cacheDir, "go-builder", "remote-cache"), nil
+}
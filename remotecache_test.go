@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestGoCacheProg(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input RemoteCacheConfig
+		wants string
+	}{
+		{name: "none"},
+		{name: "prog", input: RemoteCacheConfig{Prog: "gobuildcache -bucket b"}, wants: "gobuildcache -bucket b"},
+		{name: "http", input: RemoteCacheConfig{URL: "https://cache/go"}, wants: "/bin/gb -cacheprog https://cache/go"},
+		{name: "read only", input: RemoteCacheConfig{URL: "https://cache/go", ReadOnly: true}, wants: "/bin/gb -cacheprog https://cache/go -cacheprog-readonly"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.input.goCacheProg("/bin/gb"); got != tc.wants {
+				t.Logf("Incorrect GOCACHEPROG, wanted: %q got: %q\n", tc.wants, got)
+				t.Fail()
+			}
+		})
+	}
+}
+
+// cacheSession sends the requests, each followed by its body when it has
+// one, to a cache program and returns the responses by ID.
+func cacheSession(t *testing.T, c *httpCache, requests []cacheRequest, bodies map[int64][]byte) map[int64]cacheResponse {
+	t.Helper()
+
+	in := bytes.Buffer{}
+	enc := json.NewEncoder(&in)
+	for _, req := range requests {
+		enc.Encode(req)
+		if body, ok := bodies[req.ID]; ok {
+			enc.Encode(body)
+		}
+	}
+
+	out := bytes.Buffer{}
+	if err := serveCacheProg(context.Background(), c, &in, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	responses := map[int64]cacheResponse{}
+	dec := json.NewDecoder(&out)
+	for {
+		res := cacheResponse{}
+		if err := dec.Decode(&res); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		responses[res.ID] = res
+	}
+
+	return responses
+}
+
+func TestServeCacheProg(t *testing.T) {
+	storeMu := sync.Mutex{}
+	store := map[string][]byte{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		storeMu.Lock()
+		defer storeMu.Unlock()
+
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		name := strings.TrimPrefix(r.URL.Path, "/go/")
+		switch r.Method {
+		case http.MethodPut:
+			store[name], _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodGet:
+			if body, ok := store[name]; ok {
+				w.Write(body)
+			} else {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("GOBUILDER_CACHE_TOKEN", "secret")
+
+	actionID := []byte{0xaa, 0x01}
+	outputID := []byte{0xbb, 0x02}
+	body := []byte("compiled package")
+
+	writer := newHTTPCache(server.URL+"/go/", t.TempDir(), false)
+	responses := cacheSession(t, writer, []cacheRequest{
+		{ID: 1, Command: "get", ActionID: actionID},
+		{ID: 2, Command: "close"},
+	}, nil)
+
+	if !slices.Equal(responses[0].KnownCommands, []string{"get", "put", "close"}) {
+		t.Logf("Incorrect handshake: %+v\n", responses[0])
+		t.Fail()
+	}
+
+	if !responses[1].Miss {
+		t.Logf("Empty cache did not miss: %+v\n", responses[1])
+		t.Fail()
+	}
+
+	responses = cacheSession(t, writer, []cacheRequest{
+		{ID: 2, Command: "put", ActionID: actionID, OutputID: outputID, BodySize: int64(len(body))},
+		{ID: 3, Command: "close"},
+	}, map[int64][]byte{2: body})
+
+	if responses[2].Err != "" || responses[2].DiskPath == "" {
+		t.Logf("Incorrect put response: %+v\n", responses[2])
+		t.Fail()
+	}
+
+	if _, ok := store["o-bb02"]; !ok {
+		t.Logf("Body was not uploaded, store has: %v\n", store)
+		t.Fail()
+	}
+
+	// another machine with an empty local cache
+	reader := newHTTPCache(server.URL+"/go", t.TempDir(), true)
+	responses = cacheSession(t, reader, []cacheRequest{
+		{ID: 1, Command: "get", ActionID: actionID},
+		{ID: 2, Command: "close"},
+	}, nil)
+
+	res := responses[1]
+	if res.Miss || !bytes.Equal(res.OutputID, outputID) || res.Size != int64(len(body)) {
+		t.Fatalf("Incorrect get response: %+v\n", res)
+	}
+
+	if got, _ := os.ReadFile(res.DiskPath); !bytes.Equal(got, body) {
+		t.Logf("Incorrect body on disk, wanted: %q got: %q\n", body, got)
+		t.Fail()
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var ErrInvalidReportFormat = errors.New("unsupported report format")
+
+// reportFormats maps each report format to its file name in the output
+// directory.
+var reportFormats = map[string]string{
+	"json":  "report.json",
+	"junit": "junit.xml",
+}
+
+// reportSpec is a report requested with -report format[=path].
+type reportSpec struct {
+	Format string
+	Path   string
+}
+
+func parseReport(v string) (reportSpec, error) {
+	format, fp, _ := strings.Cut(v, "=")
+
+	if _, ok := reportFormats[format]; !ok {
+		return reportSpec{}, fmt.Errorf("%w: %q, expected one of %s", ErrInvalidReportFormat, format, strings.Join(slices.Sorted(maps.Keys(reportFormats)), ", "))
+	}
+
+	return reportSpec{Format: format, Path: fp}, nil
+}
+
+// buildReport is the machine-readable outcome of a run.
+type buildReport struct {
+	Project string `json:"project"`
+	Success bool   `json:"success"`
+	// Duration is the wall-clock time of the run, set by the caller.
+	Duration time.Duration `json:"duration_ns"`
+	// Slowest lists the targets that took longest to build, slowest first.
+	Slowest []string              `json:"slowest"`
+	Results []builder.BuildResult `json:"results"`
+}
+
+func newBuildReport(project string, results []builder.BuildResult) buildReport {
+	success := true
+	for _, result := range results {
+		success = success && result.Success
+	}
+
+	slowest := []string{}
+	for _, result := range slowestResults(results, 5) {
+		slowest = append(slowest, resultName(result))
+	}
+
+	return buildReport{Project: project, Success: success, Slowest: slowest, Results: results}
+}
+
+func writeJSONReport(fp string, report buildReport) error {
+	raw, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(fp, append(raw, '\n'), 0o644)
+}
+
+// writeReports writes every requested report, by default into outputDir, and
+// returns the files written.
+func writeReports(specs []reportSpec, outputDir string, report buildReport) ([]string, error) {
+	written := []string{}
+
+	for _, spec := range specs {
+		fp := spec.Path
+		if fp == "" {
+			fp = filepath.Join(outputDir, reportFormats[spec.Format])
+		}
+
+		if err := os.MkdirAll(filepath.Dir(fp), 0o755); err != nil {
+			return written, err
+		}
+
+		var err error
+		switch spec.Format {
+		case "json":
+			err = writeJSONReport(fp, report)
+		case "junit":
+			err = writeJUnitReport(fp, report)
+		}
+
+		if err != nil {
+			return written, fmt.Errorf("%s report: %w", spec.Format, err)
+		}
+
+		written = append(written, fp)
+	}
+
+	return written, nil
+}
+
+// JUnit XML as read by Jenkins, GitLab and Buildkite. Each target is a test
+// case; cancelled targets are skipped.
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure"`
+	Skipped   *junitMessage `xml:"skipped"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func junitReport(report buildReport) junitTestSuites {
+	suite := junitTestSuite{Name: report.Project, Tests: len(report.Results)}
+	total := time.Duration(0)
+
+	for _, result := range report.Results {
+		tc := junitTestCase{
+			Name:      resultName(result),
+			ClassName: report.Project,
+			Time:      fmt.Sprintf("%.3f", result.Duration.Seconds()),
+		}
+
+		switch {
+		case result.Cancelled:
+			suite.Skipped++
+			tc.Skipped = &junitMessage{Message: "cancelled"}
+		case !result.Success:
+			suite.Failures++
+			tc.Failure = &junitMessage{Message: result.Error, Text: result.Stderr}
+		case result.UpToDate:
+			tc.SystemOut = result.Path + " up to date"
+		default:
+			tc.SystemOut = fmt.Sprintf("%s (%d bytes)", result.Path, result.Size)
+		}
+
+		total += result.Duration
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	suite.Time = fmt.Sprintf("%.3f", total.Seconds())
+
+	return junitTestSuites{Suites: []junitTestSuite{suite}}
+}
+
+func writeJUnitReport(fp string, report buildReport) error {
+	raw, err := xml.MarshalIndent(junitReport(report), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(fp, append([]byte(xml.Header), append(raw, '\n')...), 0o644)
+}
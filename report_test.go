@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestParseReport(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		wants    reportSpec
+		wantsErr error
+	}{
+		{name: "format", input: "json", wants: reportSpec{Format: "json"}},
+		{name: "path", input: "json=ci/report.json", wants: reportSpec{Format: "json", Path: "ci/report.json"}},
+		{name: "unsupported", input: "yaml", wantsErr: ErrInvalidReportFormat},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := parseReport(tc.input)
+
+			if !errors.Is(err, tc.wantsErr) {
+				t.Logf("Incorrect error, wanted: %v got: %v\n", tc.wantsErr, err)
+				t.Fail()
+			}
+
+			if res != tc.wants {
+				t.Logf("Incorrect report, wanted: %+v got: %+v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestWriteReports(t *testing.T) {
+	dir := t.TempDir()
+
+	results := []builder.BuildResult{
+		{Target: "linux/amd64", Path: "build/app-linux_amd64", Size: 1024, Duration: time.Second, Success: true, Attempts: 1},
+		{
+			Target: "windows/arm64", Path: "build/app-windows_arm64.exe", Stderr: "undefined: foo", Error: "exit status 1", Attempts: 2,
+			AttemptResults: []builder.Attempt{{Duration: time.Second, Error: "exit status 1"}, {Duration: time.Second, Error: "exit status 1"}},
+		},
+	}
+
+	written, err := writeReports([]reportSpec{{Format: "json"}}, dir, newBuildReport("app", results))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wants := []string{filepath.Join(dir, "report.json")}
+	if !slices.Equal(written, wants) {
+		t.Logf("Incorrect files, wanted: %v got: %v\n", wants, written)
+		t.Fail()
+	}
+
+	raw, _ := os.ReadFile(wants[0])
+	report := buildReport{}
+	if err := json.Unmarshal(raw, &report); err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Project != "app" || report.Success || !reflect.DeepEqual(report.Results, results) {
+		t.Logf("Incorrect report: %+v\n", report)
+		t.Fail()
+	}
+}
+
+func TestJUnitReport(t *testing.T) {
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "junit.xml")
+
+	results := []builder.BuildResult{
+		{Target: "linux/amd64", Path: "build/app-linux_amd64", Size: 1024, Duration: 1500 * time.Millisecond, Success: true},
+		{Target: "linux/amd64", Race: true, Path: "build/app-linux_amd64-race", Success: true, UpToDate: true},
+		{Target: "windows/arm64", Duration: 250 * time.Millisecond, Stderr: "undefined: foo", Error: "exit status 1"},
+		{Target: "darwin/arm64", Error: "signal: interrupt", Cancelled: true},
+	}
+
+	if _, err := writeReports([]reportSpec{{Format: "junit", Path: fp}}, dir, newBuildReport("app", results)); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, _ := os.ReadFile(fp)
+	report := junitTestSuites{}
+	if err := xml.Unmarshal(raw, &report); err != nil {
+		t.Fatal(err)
+	}
+
+	suite := report.Suites[0]
+	if suite.Tests != 4 || suite.Failures != 1 || suite.Skipped != 1 || suite.Time != "1.750" {
+		t.Logf("Incorrect suite: %+v\n", suite)
+		t.Fail()
+	}
+
+	names := []string{}
+	for _, tc := range suite.Cases {
+		names = append(names, tc.Name)
+	}
+
+	wants := []string{"linux/amd64", "linux/amd64 (race)", "windows/arm64", "darwin/arm64"}
+	if !slices.Equal(names, wants) {
+		t.Logf("Incorrect test cases, wanted: %v got: %v\n", wants, names)
+		t.Fail()
+	}
+
+	if failure := suite.Cases[2].Failure; failure == nil || failure.Message != "exit status 1" || failure.Text != "undefined: foo" {
+		t.Logf("Incorrect failure: %+v\n", failure)
+		t.Fail()
+	}
+
+	if suite.Cases[3].Skipped == nil {
+		t.Logf("Cancelled build was not skipped\n")
+		t.Fail()
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+// buildAttempt records one try at building a target.
+type buildAttempt struct {
+	Err      error
+	Duration time.Duration
+}
+
+// attemptResults describes the attempts for the report. A build that
+// succeeded or failed at once has none, as its result says it all.
+func attemptResults(attempts []buildAttempt) []builder.Attempt {
+	if len(attempts) < 2 {
+		return nil
+	}
+
+	results := []builder.Attempt{}
+	for _, a := range attempts {
+		result := builder.Attempt{Duration: a.Duration}
+		if a.Err != nil {
+			result.Error = a.Err.Error()
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// retryPolicy retries failed builds, which helps with transient failures such
+// as module proxy errors. The wait before each retry doubles, starting at
+// Backoff.
+type retryPolicy struct {
+	Retries int
+	Backoff time.Duration
+}
+
+// run calls build, numbering attempts from 1, until it succeeds, the retries
+// are used up or ctx is done, and returns every attempt made.
+func (p retryPolicy) run(ctx context.Context, build func(attempt int) error) []buildAttempt {
+	attempts := []buildAttempt{}
+	wait := p.Backoff
+
+	for n := 1; ; n++ {
+		start := time.Now()
+		err := build(n)
+		attempts = append(attempts, buildAttempt{Err: err, Duration: time.Since(start)})
+
+		if err == nil || n > p.Retries || ctx.Err() != nil {
+			return attempts
+		}
+
+		select {
+		case <-ctx.Done():
+			return attempts
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
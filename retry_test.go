@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	errProxy := errors.New("proxy.golang.org: 502 Bad Gateway")
+
+	testCases := []struct {
+		name     string
+		retries  int
+		failures int
+		wants    int
+		wantsErr error
+	}{
+		{name: "success", retries: 2, failures: 0, wants: 1},
+		{name: "transient", retries: 2, failures: 1, wants: 2},
+		{name: "exhausted", retries: 2, failures: 5, wants: 3, wantsErr: errProxy},
+		{name: "no retries", retries: 0, failures: 1, wants: 1, wantsErr: errProxy},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy := retryPolicy{Retries: tc.retries, Backoff: time.Millisecond}
+			attempts := policy.run(context.Background(), func(attempt int) error {
+				if attempt <= tc.failures {
+					return errProxy
+				}
+				return nil
+			})
+
+			if len(attempts) != tc.wants {
+				t.Logf("Incorrect attempts, wanted: %v got: %v\n", tc.wants, len(attempts))
+				t.Fail()
+			}
+
+			if err := attempts[len(attempts)-1].Err; err != tc.wantsErr {
+				t.Logf("Incorrect error, wanted: %v got: %v\n", tc.wantsErr, err)
+				t.Fail()
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := retryPolicy{Retries: 5, Backoff: time.Hour}
+	attempts := policy.run(ctx, func(attempt int) error {
+		cancel()
+		return context.Canceled
+	})
+
+	if len(attempts) != 1 {
+		t.Logf("Retried a cancelled run, attempts: %v\n", len(attempts))
+		t.Fail()
+	}
+}
+
+func TestAttemptResults(t *testing.T) {
+	errProxy := errors.New("proxy.golang.org: 502 Bad Gateway")
+
+	testCases := []struct {
+		name  string
+		input []buildAttempt
+		wants []builder.Attempt
+	}{
+		{name: "single attempt", input: []buildAttempt{{Err: errProxy, Duration: time.Second}}, wants: nil},
+		{
+			name:  "retried",
+			input: []buildAttempt{{Err: errProxy, Duration: time.Second}, {Duration: 2 * time.Second}},
+			wants: []builder.Attempt{{Duration: time.Second, Error: errProxy.Error()}, {Duration: 2 * time.Second}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if res := attemptResults(tc.input); !slices.Equal(res, tc.wants) {
+				t.Logf("Incorrect attempts, wanted: %v got: %v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+// ScoopConfig describes the Scoop manifest generated for the windows
+// archives and the bucket it is pushed to.
+type ScoopConfig struct {
+	// Name is the manifest name, defaulting to the binary name.
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Homepage    string `json:"homepage"`
+	License     string `json:"license"`
+	// Bucket is the git URL of the bucket repository the manifest is
+	// committed to under bucket/. The manifest is only written locally
+	// when empty.
+	Bucket string `json:"bucket"`
+	// Checkver is copied into the manifest so `scoop update` can find new
+	// versions, e.g. {"github": "https://github.com/acme/app"}.
+	Checkver any `json:"checkver"`
+}
+
+func (s ScoopConfig) IsEmpty() bool {
+	return s.Name == "" && s.Description == "" && s.Homepage == "" && s.License == "" &&
+		s.Bucket == "" && s.Checkver == nil
+}
+
+// scoopArch maps the windows architectures to Scoop's architecture keys.
+var scoopArch = map[string]string{
+	"amd64": "64bit",
+	"386":   "32bit",
+	"arm64": "arm64",
+}
+
+type scoopInstaller struct {
+	URL  string `json:"url"`
+	Hash string `json:"hash,omitempty"`
+}
+
+type scoopManifest struct {
+	Version      string                    `json:"version"`
+	Description  string                    `json:"description,omitempty"`
+	Homepage     string                    `json:"homepage,omitempty"`
+	License      string                    `json:"license,omitempty"`
+	Architecture map[string]scoopInstaller `json:"architecture"`
+	Bin          string                    `json:"bin"`
+	Checkver     any                       `json:"checkver,omitempty"`
+	Autoupdate   struct {
+		Architecture map[string]scoopInstaller `json:"architecture"`
+	} `json:"autoupdate"`
+}
+
+// writeScoopManifest writes the manifest for the windows archives, keyed by
+// dist, to the output directory and returns its path. The autoupdate URLs
+// are the release URLs with the version replaced by Scoop's $version.
+func writeScoopManifest(config builder.BuildConfig, scoop ScoopConfig, archives map[string]string, tag string, downloadURL func(tag, file string) string) (string, error) {
+	name := scoop.Name
+	if name == "" {
+		name = config.BinaryName
+	}
+
+	version := strings.TrimPrefix(tag, "v")
+
+	manifest := scoopManifest{
+		Version:      version,
+		Description:  scoop.Description,
+		Homepage:     scoop.Homepage,
+		License:      scoop.License,
+		Architecture: map[string]scoopInstaller{},
+		Bin:          config.BinaryName + ".exe",
+		Checkver:     scoop.Checkver,
+	}
+	manifest.Autoupdate.Architecture = map[string]scoopInstaller{}
+
+	for goarch, arch := range scoopArch {
+		fp, ok := archives["windows/"+goarch]
+		if !ok {
+			continue
+		}
+
+		sum, err := sha256File(fp)
+		if err != nil {
+			return "", err
+		}
+
+		url := downloadURL(tag, filepath.Base(fp))
+		manifest.Architecture[arch] = scoopInstaller{URL: url, Hash: sum}
+		manifest.Autoupdate.Architecture[arch] = scoopInstaller{URL: strings.ReplaceAll(url, version, "$version")}
+	}
+
+	if len(manifest.Architecture) == 0 {
+		return "", fmt.Errorf("%w: expected windows amd64, 386 or arm64", ErrNoPackageArchives)
+	}
+
+	raw, err := json.MarshalIndent(manifest, "", "    ")
+	if err != nil {
+		return "", err
+	}
+
+	fp := filepath.Join(config.OutputDir, name+".json")
+	return fp, os.WriteFile(fp, append(raw, '\n'), 0o644)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestWriteScoopManifest(t *testing.T) {
+	testCases := []struct {
+		name      string
+		scoop     ScoopConfig
+		dists     []string
+		wantsFile string
+		wantsArch map[string]string
+		err       error
+	}{
+		{
+			name:      "amd64",
+			dists:     []string{"windows/amd64"},
+			wantsFile: "app.json",
+			wantsArch: map[string]string{"64bit": "windows/amd64"},
+		},
+		{
+			name:      "all architectures",
+			dists:     []string{"windows/amd64", "windows/386", "windows/arm64", "linux/amd64"},
+			wantsFile: "app.json",
+			wantsArch: map[string]string{"64bit": "windows/amd64", "32bit": "windows/386", "arm64": "windows/arm64"},
+		},
+		{
+			name:      "named",
+			scoop:     ScoopConfig{Name: "my-app"},
+			dists:     []string{"windows/386"},
+			wantsFile: "my-app.json",
+			wantsArch: map[string]string{"32bit": "windows/386"},
+		},
+		{
+			name:  "no windows archives",
+			dists: []string{"linux/amd64", "darwin/arm64"},
+			err:   ErrNoPackageArchives,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := builder.NewConfig()
+			config.OutputDir = t.TempDir()
+			config.BinaryName = "app"
+			archives := writeTestArchives(t, config, tc.dists...)
+
+			fp, err := writeScoopManifest(config, tc.scoop, archives, "v1.2.3", testDownloadURL)
+
+			if !errors.Is(err, tc.err) {
+				t.Logf("Incorrect error returned, wanted: %v got: %v\n", tc.err, err)
+				t.FailNow()
+			} else if err != nil {
+				return
+			}
+
+			raw, err := os.ReadFile(fp)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var manifest scoopManifest
+			if err := json.Unmarshal(raw, &manifest); err != nil {
+				t.Fatal(err)
+			}
+
+			if filepath.Base(fp) != tc.wantsFile || manifest.Version != "1.2.3" || manifest.Bin != "app.exe" {
+				t.Logf("Incorrect manifest %s: %+v\n", fp, manifest)
+				t.Fail()
+			}
+
+			if res := slices.Sorted(maps.Keys(manifest.Architecture)); !slices.Equal(res, slices.Sorted(maps.Keys(tc.wantsArch))) {
+				t.Logf("Incorrect architectures, wanted: %v got: %v\n", slices.Sorted(maps.Keys(tc.wantsArch)), res)
+				t.Fail()
+			}
+
+			for arch, dist := range tc.wantsArch {
+				archive := archives[dist]
+				wantsHash, err := sha256File(archive)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				wantsURL := testDownloadURL("v1.2.3", filepath.Base(archive))
+				if res := manifest.Architecture[arch]; res.URL != wantsURL || res.Hash != wantsHash {
+					t.Logf("Incorrect %s installer, wanted: %s %s got: %+v\n", arch, wantsURL, wantsHash, res)
+					t.Fail()
+				}
+
+				wantsURL = testDownloadURL("v$version", filepath.Base(archive))
+				if res := manifest.Autoupdate.Architecture[arch]; res.URL != wantsURL {
+					t.Logf("Incorrect %s autoupdate url, wanted: %s got: %s\n", arch, wantsURL, res.URL)
+					t.Fail()
+				}
+			}
+		})
+	}
+}
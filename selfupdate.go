@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var (
+	ErrNoReleaseAsset    = errors.New("release has no binary for this platform")
+	ErrChecksumMismatch  = errors.New("checksum mismatch")
+	ErrSignatureMismatch = errors.New("checksums signature is invalid")
+	ErrInvalidUpdateKey  = errors.New("invalid update public key")
+	ErrMissingChecksum   = errors.New("no checksum for file")
+)
+
+const selfUpdateRepo = "jrstaple/go-builder"
+
+// updatePublicKey is the base64 ed25519 key release checksums are signed
+// with, set by release builds with -ldflags "-X main.updatePublicKey=...".
+// When set, self-update requires checksums.txt.sig to verify against it.
+var updatePublicKey string
+
+// selfUpdateRelease is the part of a GitHub release self-update reads.
+type selfUpdateRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+func (r selfUpdateRelease) assetURL(name string) (string, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset.URL, true
+		}
+	}
+
+	return "", false
+}
+
+// selfUpdateAsset is the release binary for goos and goarch, named the way
+// go-builder names its own builds.
+func selfUpdateAsset(goos string, goarch string) string {
+	config := builder.NewConfig()
+	config.BinaryName = "go-builder"
+	config.OutputDir = ""
+
+	return builder.OutputPath(config, builder.GoDist{GOOS: goos, GOARCH: goarch})
+}
+
+// checksumFor finds the sha256 of name in a sha256sum style checksums file.
+func checksumFor(checksums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		sum, file, ok := strings.Cut(scanner.Text(), "  ")
+		if ok && strings.TrimPrefix(file, "*") == name {
+			return sum, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrMissingChecksum, name)
+}
+
+// verifyChecksumsSignature checks sig, a base64 ed25519 signature, of the
+// checksums file against the base64 public key.
+func verifyChecksumsSignature(publicKey string, checksums []byte, sig []byte) error {
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return ErrInvalidUpdateKey
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil || !ed25519.Verify(key, checksums, raw) {
+		return ErrSignatureMismatch
+	}
+
+	return nil
+}
+
+// download returns the body at u, which must be fetched successfully.
+func download(ctx context.Context, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// replaceExecutable atomically replaces the executable at exe with binary.
+func replaceExecutable(exe string, binary []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".go-builder-update-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(binary)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return err
+	}
+
+	// windows cannot replace a running executable, but can rename it
+	if runtime.GOOS == "windows" {
+		old := exe + ".old"
+		os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return err
+		}
+	}
+
+	return os.Rename(tmp.Name(), exe)
+}
+
+// installUpdate downloads the binary name of release, verifies it against
+// the release checksums and replaces exe with it.
+func installUpdate(ctx context.Context, release selfUpdateRelease, name string, exe string) error {
+	binaryURL, ok := release.assetURL(name)
+	if !ok {
+		return fmt.Errorf("%w: %s %s", ErrNoReleaseAsset, release.TagName, name)
+	}
+
+	checksumsURL, ok := release.assetURL(checksumsFile)
+	if !ok {
+		return fmt.Errorf("%w: %s %s", ErrNoReleaseAsset, release.TagName, checksumsFile)
+	}
+
+	checksums, err := download(ctx, checksumsURL)
+	if err != nil {
+		return err
+	}
+
+	if updatePublicKey != "" {
+		sigURL, ok := release.assetURL(checksumsFile + ".sig")
+		if !ok {
+			return fmt.Errorf("%w: %s %s", ErrNoReleaseAsset, release.TagName, checksumsFile+".sig")
+		}
+
+		sig, err := download(ctx, sigURL)
+		if err != nil {
+			return err
+		}
+
+		if err := verifyChecksumsSignature(updatePublicKey, checksums, sig); err != nil {
+			return err
+		}
+	}
+
+	want, err := checksumFor(checksums, name)
+	if err != nil {
+		return err
+	}
+
+	binary, err := download(ctx, binaryURL)
+	if err != nil {
+		return err
+	}
+
+	if sum := sha256.Sum256(binary); hex.EncodeToString(sum[:]) != want {
+		return fmt.Errorf("%w: %s", ErrChecksumMismatch, name)
+	}
+
+	return replaceExecutable(exe, binary)
+}
+
+// selfUpdate runs the self-update subcommand, which replaces the running
+// executable with the binary of the latest, or the given, release.
+func selfUpdate(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("self-update", flag.ExitOnError)
+	check := flags.Bool("check", false, "Only report whether an update is available.")
+	tag := flags.String("version", "", "Install the release with this tag instead of the latest, e.g. v1.4.0.")
+	repo := flags.String("repo", selfUpdateRepo, "Specify the GitHub repository releases are downloaded from.")
+	apiURL := flags.String("api-url", defaultGitHubAPI, "Specify the GitHub API URL, for GitHub Enterprise.")
+	flags.Parse(args)
+
+	headers := map[string]string{"Accept": "application/vnd.github+json"}
+	// a token is optional, it only raises the API rate limit
+	if token, err := tokenFromEnv("GITHUB_TOKEN", "GH_TOKEN"); err == nil {
+		headers["Authorization"] = "Bearer " + token
+	}
+
+	releaseURL := fmt.Sprintf("%s/repos/%s/releases/latest", strings.TrimSuffix(*apiURL, "/"), *repo)
+	if *tag != "" {
+		releaseURL = fmt.Sprintf("%s/repos/%s/releases/tags/%s", strings.TrimSuffix(*apiURL, "/"), *repo, url.PathEscape(*tag))
+	}
+
+	release := selfUpdateRelease{}
+	if _, err := (apiClient{headers: headers}).do(ctx, http.MethodGet, releaseURL, "", nil, &release); err != nil {
+		return err
+	}
+
+	current := readVersionInfo(debug.ReadBuildInfo()).Version
+	if release.TagName == current {
+		fmt.Println("go-builder", current, "is up to date")
+		return nil
+	}
+
+	if *check {
+		fmt.Println("go-builder", release.TagName, "is available, this is", current)
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	exe, err = filepath.EvalSymlinks(exe)
+	if err != nil {
+		return err
+	}
+
+	if err := installUpdate(ctx, release, selfUpdateAsset(runtime.GOOS, runtime.GOARCH), exe); err != nil {
+		return err
+	}
+
+	fmt.Println("Updated go-builder", current, "to", release.TagName)
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSelfUpdateAsset(t *testing.T) {
+	testCases := []struct {
+		goos   string
+		goarch string
+		wants  string
+	}{
+		{goos: "linux", goarch: "amd64", wants: "go-builder-linux_amd64"},
+		{goos: "windows", goarch: "arm64", wants: "go-builder-windows_arm64.exe"},
+	}
+
+	for _, tc := range testCases {
+		if res := selfUpdateAsset(tc.goos, tc.goarch); res != tc.wants {
+			t.Logf("Incorrect asset, wanted: %s got: %s\n", tc.wants, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestInstallUpdate(t *testing.T) {
+	binary := []byte("new binary")
+	sum := sha256.Sum256(binary)
+	checksums := fmt.Sprintf("%s  go-builder-linux_amd64\n%s  other\n", hex.EncodeToString(sum[:]), hex.EncodeToString(make([]byte, 32)))
+
+	public, private, _ := ed25519.GenerateKey(nil)
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(private, []byte(checksums)))
+
+	files := map[string]string{
+		"go-builder-linux_amd64": string(binary),
+		"other":                  "tampered",
+		"checksums.txt":          checksums,
+		"checksums.txt.sig":      sig,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(files[filepath.Base(r.URL.Path)]))
+	}))
+	defer server.Close()
+
+	release := selfUpdateRelease{TagName: "v1.2.3"}
+	for name := range files {
+		release.Assets = append(release.Assets, struct {
+			Name string `json:"name"`
+			URL  string `json:"browser_download_url"`
+		}{Name: name, URL: server.URL + "/" + name})
+	}
+
+	testCases := []struct {
+		name     string
+		asset    string
+		key      string
+		wantsErr error
+	}{
+		{name: "checksum", asset: "go-builder-linux_amd64"},
+		{name: "signed", asset: "go-builder-linux_amd64", key: base64.StdEncoding.EncodeToString(public)},
+		{name: "wrong key", asset: "go-builder-linux_amd64", key: base64.StdEncoding.EncodeToString(make([]byte, 32)), wantsErr: ErrSignatureMismatch},
+		{name: "checksum mismatch", asset: "other", wantsErr: ErrChecksumMismatch},
+		{name: "missing asset", asset: "go-builder-plan9_386", wantsErr: ErrNoReleaseAsset},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			updatePublicKey = tc.key
+			defer func() { updatePublicKey = "" }()
+
+			exe := filepath.Join(t.TempDir(), "go-builder")
+			os.WriteFile(exe, []byte("old binary"), 0o755)
+
+			err := installUpdate(context.Background(), release, tc.asset, exe)
+			if !errors.Is(err, tc.wantsErr) {
+				t.Logf("Incorrect error, wanted: %v got: %v\n", tc.wantsErr, err)
+				t.Fail()
+			}
+
+			wants := "new binary"
+			if tc.wantsErr != nil {
+				wants = "old binary"
+			}
+
+			if b, _ := os.ReadFile(exe); string(b) != wants {
+				t.Logf("Incorrect executable, wanted: %q got: %q\n", wants, b)
+				t.Fail()
+			}
+
+			if entries, _ := os.ReadDir(filepath.Dir(exe)); len(entries) != 1 {
+				t.Logf("Temporary files were left behind: %v\n", entries)
+				t.Fail()
+			}
+		})
+	}
+}
@@ -0,0 +1,546 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidJob    = errors.New("invalid build job")
+	ErrJobNotFound   = errors.New("build job not found")
+	ErrServeToken    = errors.New("GOBUILDER_SERVE_TOKEN must be set to serve on a non-loopback address")
+	ErrNoProjectsDir = errors.New("the server accepts no jobs without a -projects directory")
+)
+
+// serveJobFlags are the flags a submitted job can set, mapped to whether
+// they take a value. The others are refused: the server picks the output
+// directory, report, config and env file, a job has to finish on its own and
+// it cannot run commands, read files or publish anything on the server's
+// behalf. The empty config leaves out the project's hooks, lint, publish,
+// upload and notify settings.
+var serveJobFlags = map[string]bool{
+	"target": true, "arch": true, "exclude": true, "all": false, "yes": false,
+	"first-class": false, "cgo-only": false, "no-cgo": false, "mod": true,
+	"wasm-exec": false, "universal": false, "buildmode": true, "race": false,
+	"compiler": true, "zig": false, "force": false, "deadline": true,
+	"timeout-per-target": true, "max-size": true, "fail-fast": false,
+	"retries": true, "retry-backoff": true, "tag-set": true, "profile": true,
+	"variant": true, "n": true, "no-color": false, "q": false, "quiet": false,
+	"log-level": true, "log-format": true, "v": false, "nproc": true,
+}
+
+// jobRequest is the body of POST /jobs.
+type jobRequest struct {
+	// Project is the project directory on the server, which has to be in
+	// the server's -projects directory. A relative one is relative to it.
+	Project string   `json:"project"`
+	Targets []string `json:"targets"`
+	// Flags are more command line flags, e.g. ["-ldflags", "-s -w"].
+	Flags []string `json:"flags"`
+}
+
+// projectDir returns the project directory of the request in root.
+func (r jobRequest) projectDir(root string) string {
+	if filepath.IsAbs(r.Project) {
+		return r.Project
+	}
+
+	return filepath.Join(root, r.Project)
+}
+
+// isWithin reports whether fp is dir or inside it, once symlinks are
+// resolved.
+func isWithin(fp string, dir string) bool {
+	fp, err := filepath.EvalSymlinks(fp)
+	if err != nil {
+		return false
+	}
+
+	dir, err = filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false
+	}
+
+	rel, err := filepath.Rel(dir, fp)
+	return err == nil && (rel == "." || filepath.IsLocal(rel))
+}
+
+// validate checks the job can be built: its project is a directory in root,
+// not in the server's own jobsDir, and it only sets the serveJobFlags.
+func (r jobRequest) validate(root string, jobsDir string) error {
+	if root == "" {
+		return fmt.Errorf("%w: %w", ErrInvalidJob, ErrNoProjectsDir)
+	}
+
+	if r.Project == "" {
+		return fmt.Errorf("%w: missing project", ErrInvalidJob)
+	}
+
+	project := r.projectDir(root)
+	if info, err := os.Stat(project); err != nil || !info.IsDir() {
+		return fmt.Errorf("%w: project %s is not a directory", ErrInvalidJob, r.Project)
+	}
+
+	// uploaded sources and job output are not projects
+	if !isWithin(project, root) || isWithin(project, jobsDir) {
+		return fmt.Errorf("%w: project %s is not in the projects directory", ErrInvalidJob, r.Project)
+	}
+
+	for i := 0; i < len(r.Flags); i++ {
+		arg := r.Flags[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			return fmt.Errorf("%w: argument %q is not a flag", ErrInvalidJob, arg)
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		takesValue, ok := serveJobFlags[name]
+		if !ok {
+			return fmt.Errorf("%w: flag %s cannot be set", ErrInvalidJob, arg)
+		}
+
+		if takesValue && !hasValue {
+			if i++; i == len(r.Flags) {
+				return fmt.Errorf("%w: flag %s needs a value", ErrInvalidJob, arg)
+			}
+			value = r.Flags[i]
+		}
+
+		// the binary name is joined to the job's output directory
+		if name == "n" && (value != filepath.Base(value) || value == "." || value == ".." || strings.ContainsAny(value, `/\`)) {
+			return fmt.Errorf("%w: binary name %q is not a file name", ErrInvalidJob, value)
+		}
+	}
+
+	return nil
+}
+
+// isJSONRequest reports whether the body of r is declared as JSON.
+func isJSONRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
+
+// isLoopbackAddr reports whether addr only listens on a loopback interface.
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// serveJob is a build submitted to the server and its outcome.
+type serveJob struct {
+	ID       string     `json:"id"`
+	Request  jobRequest `json:"request"`
+	Status   string     `json:"status"`
+	Created  time.Time  `json:"created"`
+	Started  time.Time  `json:"started,omitzero"`
+	Finished time.Time  `json:"finished,omitzero"`
+	Error    string     `json:"error,omitempty"`
+	// Report and Output are only included for a single job.
+	Report    *buildReport `json:"report,omitempty"`
+	Output    string       `json:"output,omitempty"`
+	Artifacts []string     `json:"artifacts,omitempty"`
+
+	cancel context.CancelFunc
+}
+
+// buildServer runs the jobs submitted over its HTTP API by running exe, this
+// binary, for each, with its output directory under dir.
+type buildServer struct {
+	exe string
+	dir string
+	// projects is the directory submitted projects have to be in, no
+	// jobs are accepted when it is empty
+	projects string
+	token    string
+	slots    chan struct{}
+
+	mu   sync.Mutex
+	jobs map[string]*serveJob
+	// output holds the combined output of each job
+	output map[string]*bytes.Buffer
+}
+
+func newBuildServer(exe string, dir string, projects string, token string, concurrency int) *buildServer {
+	return &buildServer{
+		exe:      exe,
+		dir:      dir,
+		projects: projects,
+		token:    token,
+		slots:    make(chan struct{}, max(concurrency, 1)),
+		jobs:     map[string]*serveJob{},
+		output:   map[string]*bytes.Buffer{},
+	}
+}
+
+func (s *buildServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", s.submit)
+	mux.HandleFunc("GET /jobs", s.list)
+	mux.HandleFunc("GET /jobs/{id}", s.status)
+	mux.HandleFunc("DELETE /jobs/{id}", s.cancel)
+	mux.HandleFunc("GET /jobs/{id}/artifacts/{name}", s.artifact)
+
+	// the agent API used by builds with -workers
+	mux.HandleFunc("PUT /sources/{id}", s.putSource)
+	mux.HandleFunc("POST /builds", s.build)
+	mux.HandleFunc("GET /builds/{id}/{name}", s.buildArtifact)
+	mux.HandleFunc("GET /load", s.load)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := []byte(r.Header.Get("Authorization"))
+		if s.token != "" && subtle.ConstantTimeCompare(auth, []byte("Bearer "+s.token)) != 1 {
+			writeJSONError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func (s *buildServer) submit(w http.ResponseWriter, r *http.Request) {
+	if !isJSONRequest(r) {
+		writeJSONError(w, http.StatusUnsupportedMediaType, fmt.Errorf("%w: expected Content-Type application/json", ErrInvalidJob))
+		return
+	}
+
+	req := jobRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("%w: %w", ErrInvalidJob, err))
+		return
+	}
+
+	if err := req.validate(s.projects, s.dir); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	job, _ := s.start(req, io.Discard)
+
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// start queues a job for req and returns a copy of it along with a channel
+// closed once it finished. The output of the build is also written to out.
+func (s *buildServer) start(req jobRequest, out io.Writer) (serveJob, <-chan struct{}) {
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &serveJob{ID: hex.EncodeToString(id), Request: req, Status: "queued", Created: time.Now(), cancel: cancel}
+
+	s.mu.Lock()
+	s.jobs[job.ID] = job
+	s.output[job.ID] = &bytes.Buffer{}
+	snapshot := *job
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.run(ctx, job, out)
+	}()
+
+	return snapshot, done
+}
+
+// run waits for a free slot and builds the job.
+func (s *buildServer) run(ctx context.Context, job *serveJob, out io.Writer) {
+	defer job.cancel()
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		s.finish(job, ctx.Err())
+		return
+	}
+
+	s.mu.Lock()
+	job.Status, job.Started = "running", time.Now()
+	buf := s.output[job.ID]
+	s.mu.Unlock()
+
+	// jobs are built without the project's config and env file, which
+	// could run commands on the server
+	config := filepath.Join(s.dir, "job-config.json")
+	os.MkdirAll(s.dir, 0o755)
+	if err := os.WriteFile(config, []byte("{}\n"), 0o644); err != nil {
+		s.finish(job, err)
+		return
+	}
+
+	outputDir := filepath.Join(s.dir, job.ID)
+	args := slices.Clone(job.Request.Flags)
+	for _, target := range job.Request.Targets {
+		args = append(args, "-target", target)
+	}
+	args = append(args, "-o", outputDir, "-report", "json="+filepath.Join(outputDir, "report.json"),
+		"-config", config, "-env-file", os.DevNull, job.Request.projectDir(s.projects))
+
+	// a single writer for both streams keeps their lines in order
+	cmd := exec.CommandContext(ctx, s.exe, args...)
+	cmd.Stdout = io.MultiWriter(&lockedWriter{mu: &s.mu, w: buf}, out)
+	cmd.Stderr = cmd.Stdout
+	cmd.Env = append(os.Environ(), "NO_COLOR=1")
+
+	s.finish(job, cmd.Run())
+}
+
+func (s *buildServer) finish(job *serveJob, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job.Finished = time.Now()
+
+	switch {
+	case err == nil:
+		job.Status = "succeeded"
+	case errors.Is(err, context.Canceled) || job.Status == "cancelling":
+		job.Status = "cancelled"
+	default:
+		job.Status, job.Error = "failed", err.Error()
+	}
+}
+
+// lockedWriter serializes writes to w, which is read while jobs run.
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  *bytes.Buffer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.w.Write(p)
+}
+
+func (s *buildServer) list(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	jobs := []serveJob{}
+	for _, job := range s.jobs {
+		jobs = append(jobs, *job)
+	}
+	s.mu.Unlock()
+
+	slices.SortFunc(jobs, func(a, b serveJob) int { return a.Created.Compare(b.Created) })
+
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+// lookup returns a copy of the job with its output so far.
+func (s *buildServer) lookup(id string) (serveJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return serveJob{}, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+
+	snapshot := *job
+	snapshot.Output = s.output[id].String()
+
+	return snapshot, nil
+}
+
+// addResults reads the report and lists the artifacts the job wrote so far.
+func (s *buildServer) addResults(job *serveJob) {
+	outputDir := filepath.Join(s.dir, job.ID)
+
+	if raw, err := os.ReadFile(filepath.Join(outputDir, "report.json")); err == nil {
+		report := buildReport{}
+		if json.Unmarshal(raw, &report) == nil {
+			job.Report = &report
+		}
+	}
+
+	entries, _ := os.ReadDir(outputDir)
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			job.Artifacts = append(job.Artifacts, "/jobs/"+job.ID+"/artifacts/"+entry.Name())
+		}
+	}
+}
+
+func (s *buildServer) status(w http.ResponseWriter, r *http.Request) {
+	job, err := s.lookup(r.PathValue("id"))
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, err)
+		return
+	}
+
+	s.addResults(&job)
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+// cancelJob stops the job if it has not finished and reports whether it
+// exists.
+func (s *buildServer) cancelJob(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if ok && (job.Status == "queued" || job.Status == "running") {
+		job.Status = "cancelling"
+		job.cancel()
+	}
+
+	return ok
+}
+
+func (s *buildServer) cancel(w http.ResponseWriter, r *http.Request) {
+	if !s.cancelJob(r.PathValue("id")) {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("%w: %s", ErrJobNotFound, r.PathValue("id")))
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *buildServer) artifact(w http.ResponseWriter, r *http.Request) {
+	job, err := s.lookup(r.PathValue("id"))
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, err)
+		return
+	}
+
+	// the name is a single path element, so it cannot leave the job's
+	// output directory
+	name := r.PathValue("name")
+	if name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		writeJSONError(w, http.StatusNotFound, os.ErrNotExist)
+		return
+	}
+
+	fp := filepath.Join(s.dir, job.ID, name)
+	if info, err := os.Stat(fp); err != nil || !info.Mode().IsRegular() {
+		writeJSONError(w, http.StatusNotFound, os.ErrNotExist)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeFile(w, r, fp)
+}
+
+// serve runs the build server of the serve subcommand until ctx is done.
+func serve(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", "localhost:8080", "Specify the address to listen on.")
+	dir := flags.String("dir", "serve-jobs", "Specify the directory the output of each job is written to.")
+	projects := flags.String("projects", "", "Specify the directory submitted projects have to be in. Jobs are refused without it; -workers builds do not need it.")
+	concurrency := flags.Int("jobs", 1, "Specify how many jobs are built at once.")
+	grpcAddr := flags.String("grpc-addr", "", "Also serve the gRPC build service, used by go-builder remote, on the address. It is plaintext, so keep it on a trusted network.")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: go-builder serve [flags]")
+		fmt.Fprintln(flags.Output(), "Serves an API to submit builds: POST /jobs, GET /jobs, GET and DELETE /jobs/{id},")
+		fmt.Fprintln(flags.Output(), "GET /jobs/{id}/artifacts/{name}. Requests need a bearer token when GOBUILDER_SERVE_TOKEN is set,")
+		fmt.Fprintln(flags.Output(), "which it has to be unless the server only listens on a loopback address.")
+		fmt.Fprintln(flags.Output(), "Jobs build projects in the -projects directory, without their config and env files.")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	dirPath, err := filepath.Abs(*dir)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dirPath, 0o755); err != nil {
+		return err
+	}
+
+	token := os.Getenv("GOBUILDER_SERVE_TOKEN")
+	if token == "" && (!isLoopbackAddr(*addr) || (*grpcAddr != "" && !isLoopbackAddr(*grpcAddr))) {
+		return ErrServeToken
+	}
+
+	projectsPath := ""
+	if *projects != "" {
+		projectsPath, err = filepath.Abs(*projects)
+		if err != nil {
+			return err
+		}
+	}
+
+	builds := newBuildServer(exe, dirPath, projectsPath, token, *concurrency)
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           builds.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	if *grpcAddr != "" {
+		listener, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			return err
+		}
+
+		grpcServer := newGRPCServer(builds)
+		go grpcServer.Serve(listener)
+		defer grpcServer.Stop()
+
+		log.Println("Serving gRPC builds on", *grpcAddr)
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Println("Serving builds on", *addr)
+
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJobRequestValidate(t *testing.T) {
+	root := t.TempDir()
+	jobsDir := filepath.Join(root, "jobs")
+	outside := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"app/go.mod":                "module example.com/app\n",
+		"jobs/sources/0123/go.mod":  "module example.com/uploaded\n",
+		"jobs/0123/app-linux_amd64": "binary\n",
+		"other/gobuilder.json":      "{}\n",
+	})
+	os.Symlink(outside, filepath.Join(root, "escape"))
+
+	project := filepath.Join(root, "app")
+
+	testCases := []struct {
+		name  string
+		root  string
+		input jobRequest
+		err   error
+	}{
+		{name: "valid", root: root, input: jobRequest{Project: project, Targets: []string{"linux/amd64"}, Flags: []string{"-profile", "release", "-race", "-n=app"}}},
+		{name: "relative to root", root: root, input: jobRequest{Project: "app"}},
+		{name: "no projects directory", root: "", input: jobRequest{Project: project}, err: ErrNoProjectsDir},
+		{name: "missing project", root: root, input: jobRequest{}, err: ErrInvalidJob},
+		{name: "project not found", root: root, input: jobRequest{Project: filepath.Join(root, "missing")}, err: ErrInvalidJob},
+		{name: "outside root", root: root, input: jobRequest{Project: outside}, err: ErrInvalidJob},
+		{name: "parent of root", root: root, input: jobRequest{Project: ".."}, err: ErrInvalidJob},
+		{name: "symlink out of root", root: root, input: jobRequest{Project: "escape"}, err: ErrInvalidJob},
+		{name: "uploaded source", root: root, input: jobRequest{Project: filepath.Join(jobsDir, "sources", "0123")}, err: ErrInvalidJob},
+		{name: "job output", root: root, input: jobRequest{Project: filepath.Join(jobsDir, "0123")}, err: ErrInvalidJob},
+		{name: "output flag", root: root, input: jobRequest{Project: project, Flags: []string{"-o=/etc"}}, err: ErrInvalidJob},
+		{name: "config flag", root: root, input: jobRequest{Project: project, Flags: []string{"-config", "other/gobuilder.json"}}, err: ErrInvalidJob},
+		{name: "watch flag", root: root, input: jobRequest{Project: project, Flags: []string{"--watch"}}, err: ErrInvalidJob},
+		{name: "unknown flag", root: root, input: jobRequest{Project: project, Flags: []string{"-go", "/tmp/go"}}, err: ErrInvalidJob},
+		{name: "build flags", root: root, input: jobRequest{Project: project, Flags: []string{"--", "-toolexec=/bin/sh"}}, err: ErrInvalidJob},
+		{name: "positional", root: root, input: jobRequest{Project: project, Flags: []string{"-race", "/etc"}}, err: ErrInvalidJob},
+		{name: "missing value", root: root, input: jobRequest{Project: project, Flags: []string{"-profile"}}, err: ErrInvalidJob},
+		{name: "binary name path", root: root, input: jobRequest{Project: project, Flags: []string{"-n", "../../escape"}}, err: ErrInvalidJob},
+		{name: "binary name path with equals", root: root, input: jobRequest{Project: project, Flags: []string{"-n=sub/app"}}, err: ErrInvalidJob},
+		{name: "binary name dot dot", root: root, input: jobRequest{Project: project, Flags: []string{"-n", ".."}}, err: ErrInvalidJob},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.input.validate(tc.root, jobsDir); !errors.Is(err, tc.err) {
+				t.Logf("Incorrect error returned, wanted: %v got: %v\n", tc.err, err)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestBuildServer(t *testing.T) {
+	dir := t.TempDir()
+
+	// the fake build writes its arguments as the artifact in the -o
+	// directory
+	exe := filepath.Join(dir, "fake-builder")
+	script := "#!/bin/sh\n" +
+		"args=\"$*\"\n" +
+		"while [ $# -gt 0 ]; do [ \"$1\" = -o ] && out=$2; shift; done\n" +
+		"mkdir -p \"$out\" && echo \"$args\" > \"$out/app-linux_amd64\" && echo built\n"
+	os.WriteFile(exe, []byte(script), 0o755)
+
+	server := httptest.NewServer(newBuildServer(exe, filepath.Join(dir, "jobs"), dir, "secret", 1).Handler())
+	defer server.Close()
+
+	request := func(method string, path string, body string, out any) int {
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if out != nil {
+			json.NewDecoder(resp.Body).Decode(out)
+		}
+
+		return resp.StatusCode
+	}
+
+	if resp, err := http.Get(server.URL + "/jobs"); err != nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Logf("Request without token was not rejected: %v\n", err)
+		t.Fail()
+	}
+
+	if status := request("POST", "/jobs", `{"project": "/no/such/dir"}`, nil); status != http.StatusBadRequest {
+		t.Logf("Incorrect status for an invalid job: %d\n", status)
+		t.Fail()
+	}
+
+	req, _ := http.NewRequest("POST", server.URL+"/jobs", strings.NewReader(`{"project": "`+dir+`"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Logf("Job without a JSON content type was not rejected: %v\n", err)
+		t.Fail()
+	}
+
+	job := serveJob{}
+	status := request("POST", "/jobs", `{"project": "`+dir+`", "targets": ["linux/amd64"], "flags": ["-race"]}`, &job)
+	if status != http.StatusAccepted || job.ID == "" {
+		t.Fatalf("Incorrect submit, status: %d job: %+v\n", status, job)
+	}
+
+	for deadline := time.Now().Add(10 * time.Second); job.Status != "succeeded" && time.Now().Before(deadline); {
+		time.Sleep(50 * time.Millisecond)
+		request("GET", "/jobs/"+job.ID, "", &job)
+
+		if job.Status == "failed" {
+			t.Fatalf("Job failed: %s\n%s\n", job.Error, job.Output)
+		}
+	}
+
+	if job.Status != "succeeded" || job.Output != "built\n" || len(job.Artifacts) != 1 {
+		t.Fatalf("Incorrect job: %+v\n", job)
+	}
+
+	req, _ = http.NewRequest("GET", server.URL+job.Artifacts[0], nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	artifact, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if !strings.HasPrefix(string(artifact), "-race -target linux/amd64 -o ") {
+		t.Logf("Incorrect artifact: %q\n", artifact)
+		t.Fail()
+	}
+
+	// the project's own config and env file are not read
+	wants := "-config " + filepath.Join(dir, "jobs", "job-config.json") + " -env-file " + os.DevNull + " " + dir + "\n"
+	if !strings.HasSuffix(string(artifact), wants) {
+		t.Logf("Incorrect config arguments, wanted suffix: %q got: %q\n", wants, artifact)
+		t.Fail()
+	}
+
+	if status := request("GET", "/jobs/"+job.ID+"/artifacts/..%2Freport.json", "", nil); status != http.StatusNotFound {
+		t.Logf("Incorrect status for an artifact outside the job: %d\n", status)
+		t.Fail()
+	}
+
+	jobs := []serveJob{}
+	if request("GET", "/jobs", "", &jobs); len(jobs) != 1 || jobs[0].ID != job.ID {
+		t.Logf("Incorrect jobs: %+v\n", jobs)
+		t.Fail()
+	}
+}
+
+func TestIsLoopbackAddr(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		wants bool
+	}{
+		{name: "localhost", input: "localhost:8080", wants: true},
+		{name: "ipv4 loopback", input: "127.0.0.1:8080", wants: true},
+		{name: "ipv6 loopback", input: "[::1]:8080", wants: true},
+		{name: "all interfaces", input: ":8080", wants: false},
+		{name: "any address", input: "0.0.0.0:8080", wants: false},
+		{name: "host name", input: "builder.example.com:8080", wants: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if res := isLoopbackAddr(tc.input); res != tc.wants {
+				t.Logf("Incorrect loopback, wanted: %t got: %t\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
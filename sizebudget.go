@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var (
+	ErrInvalidSize        = errors.New("invalid size")
+	ErrSizeBudgetExceeded = errors.New("size budget exceeded")
+)
+
+// sizeUnits are the suffixes parseSize accepts, longest first so "MiB" is
+// not read as "B".
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"kib", 1 << 10}, {"mib", 1 << 20}, {"gib", 1 << 30},
+	{"kb", 1000}, {"mb", 1000 * 1000}, {"gb", 1000 * 1000 * 1000},
+	{"k", 1 << 10}, {"m", 1 << 20}, {"g", 1 << 30},
+	{"b", 1},
+}
+
+// parseSize parses a size such as 4096, 512KiB, 8MB or 1.5M. The single
+// letter units are binary, like those of ls -h.
+func parseSize(s string) (int64, error) {
+	value := strings.ToLower(strings.TrimSpace(s))
+	multiplier := int64(1)
+
+	for _, unit := range sizeUnits {
+		if rest, ok := strings.CutSuffix(value, unit.suffix); ok {
+			value, multiplier = strings.TrimSpace(rest), unit.bytes
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidSize, s)
+	}
+
+	return int64(n * float64(multiplier)), nil
+}
+
+// parseSizeBudgets parses the budgets of the config, keyed by target pattern,
+// with "*" applying to every target.
+func parseSizeBudgets(budgets map[string]string) (map[string]int64, error) {
+	parsed := map[string]int64{}
+
+	for target, size := range budgets {
+		n, err := parseSize(size)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", target, err)
+		}
+
+		parsed[target] = n
+	}
+
+	return parsed, nil
+}
+
+// checkSizeBudget returns the budget for the binary of dist and an error
+// when size exceeds it. A zero budget means there is none.
+func checkSizeBudget(budgets map[string]int64, dist builder.GoDist, size int64) (int64, error) {
+	budget, ok := builder.TargetSetting(budgets, dist)
+	if !ok || budget <= 0 || size <= budget {
+		return budget, nil
+	}
+
+	return budget, fmt.Errorf("%w: %s is %s over its %s budget",
+		ErrSizeBudgetExceeded, formatSize(size), formatSize(size-budget), formatSize(budget))
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestParseSize(t *testing.T) {
+	testCases := []struct {
+		input    string
+		wants    int64
+		wantsErr error
+	}{
+		{input: "4096", wants: 4096},
+		{input: "512KiB", wants: 512 << 10},
+		{input: "8MB", wants: 8000000},
+		{input: "1.5M", wants: 3 << 19},
+		{input: "20 mib", wants: 20 << 20},
+		{input: "2GiB", wants: 2 << 30},
+		{input: "100B", wants: 100},
+		{input: "big", wantsErr: ErrInvalidSize},
+		{input: "-1MB", wantsErr: ErrInvalidSize},
+	}
+
+	for _, tc := range testCases {
+		res, err := parseSize(tc.input)
+
+		if !errors.Is(err, tc.wantsErr) {
+			t.Logf("Incorrect error for %q, wanted: %v got: %v\n", tc.input, tc.wantsErr, err)
+			t.Fail()
+		}
+
+		if res != tc.wants {
+			t.Logf("Incorrect size for %q, wanted: %v got: %v\n", tc.input, tc.wants, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestCheckSizeBudget(t *testing.T) {
+	budgets, err := parseSizeBudgets(map[string]string{"*": "10MiB", "linux/arm": "4MiB"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name        string
+		dist        builder.GoDist
+		size        int64
+		wantsBudget int64
+		wantsErr    error
+	}{
+		{name: "under global", dist: builder.GoDist{GOOS: "linux", GOARCH: "amd64"}, size: 8 << 20, wantsBudget: 10 << 20},
+		{name: "over global", dist: builder.GoDist{GOOS: "windows", GOARCH: "amd64"}, size: 12 << 20, wantsBudget: 10 << 20, wantsErr: ErrSizeBudgetExceeded},
+		{name: "over target", dist: builder.GoDist{GOOS: "linux", GOARCH: "arm", SubArch: "7"}, size: 5 << 20, wantsBudget: 4 << 20, wantsErr: ErrSizeBudgetExceeded},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			budget, err := checkSizeBudget(budgets, tc.dist, tc.size)
+
+			if !errors.Is(err, tc.wantsErr) {
+				t.Logf("Incorrect error, wanted: %v got: %v\n", tc.wantsErr, err)
+				t.Fail()
+			}
+
+			if budget != tc.wantsBudget {
+				t.Logf("Incorrect budget, wanted: %v got: %v\n", tc.wantsBudget, budget)
+				t.Fail()
+			}
+		})
+	}
+
+	if _, err := checkSizeBudget(map[string]int64{}, builder.GoDist{GOOS: "linux", GOARCH: "amd64"}, 1<<30); err != nil {
+		t.Logf("Failed without a budget: %v\n", err)
+		t.Fail()
+	}
+
+	if _, err := parseSizeBudgets(map[string]string{"linux": "lots"}); !errors.Is(err, ErrInvalidSize) {
+		t.Logf("Incorrect error, wanted: %v got: %v\n", ErrInvalidSize, err)
+		t.Fail()
+	}
+}
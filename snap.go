@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+// SnapConfig describes the snaps packed from the linux binaries. Template is
+// an optional snap.yaml template, relative to the project directory,
+// rendered with the fields of snapData.
+type SnapConfig struct {
+	// Name is the snap name, defaulting to the binary name.
+	Name        string   `json:"name"`
+	Summary     string   `json:"summary"`
+	Description string   `json:"description"`
+	Base        string   `json:"base"`
+	Grade       string   `json:"grade"`
+	Confinement string   `json:"confinement"`
+	Plugs       []string `json:"plugs"`
+	Template    string   `json:"template"`
+}
+
+func (s SnapConfig) IsEmpty() bool {
+	return s.Name == "" && s.Summary == "" && s.Description == "" && s.Template == ""
+}
+
+// snapArch maps the linux architectures to snap architectures.
+var snapArch = map[string]string{
+	"amd64":   "amd64",
+	"arm64":   "arm64",
+	"arm":     "armhf",
+	"386":     "i386",
+	"ppc64le": "ppc64el",
+	"s390x":   "s390x",
+	"riscv64": "riscv64",
+}
+
+type snapData struct {
+	SnapConfig
+	Version string
+	Arch    string
+	Binary  string
+}
+
+const defaultSnapTemplate = `name: {{ .Name }}
+version: "{{ .Version }}"
+summary: "{{ .Summary }}"
+description: "{{ .Description }}"
+base: {{ .Base }}
+grade: {{ .Grade }}
+confinement: {{ .Confinement }}
+architectures: [{{ .Arch }}]
+apps:
+  {{ .Binary }}:
+    command: {{ .Binary }}
+{{- if .Plugs }}
+    plugs: [{{ range $i, $plug := .Plugs }}{{ if $i }}, {{ end }}{{ $plug }}{{ end }}]
+{{- end }}
+`
+
+// snapOutputPath is the binary's output path with a .snap extension.
+func snapOutputPath(config builder.BuildConfig, dist builder.GoDist) string {
+	return packageBasePath(config, dist) + ".snap"
+}
+
+// snapYAML renders the snap.yaml for the dist from the project's template or
+// the default one.
+func snapYAML(config builder.BuildConfig, snap SnapConfig, dist builder.GoDist, version string) ([]byte, error) {
+	text := defaultSnapTemplate
+	if snap.Template != "" {
+		raw, err := os.ReadFile(filepath.Join(config.ProjectDir, snap.Template))
+		if err != nil {
+			return nil, err
+		}
+		text = string(raw)
+	}
+
+	tmpl, err := template.New("snap.yaml").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	data := snapData{SnapConfig: snap, Version: strings.TrimPrefix(version, "v"), Arch: snapArch[dist.GOARCH], Binary: config.BinaryName}
+
+	if data.Name == "" {
+		data.Name = config.BinaryName
+	}
+	if data.Summary == "" {
+		data.Summary = data.Name
+	}
+	if data.Base == "" {
+		data.Base = "core22"
+	}
+	if data.Grade == "" {
+		data.Grade = "stable"
+	}
+	if data.Confinement == "" {
+		data.Confinement = "strict"
+	}
+
+	out := bytes.Buffer{}
+	err = tmpl.Execute(&out, data)
+	return out.Bytes(), err
+}
+
+// buildSnaps packs a snap for each linux binary of an architecture snaps
+// support and returns the snaps it wrote.
+func buildSnaps(ctx context.Context, config builder.BuildConfig, snap SnapConfig, jobs []buildJob, version string) ([]string, error) {
+	snaps := []string{}
+
+	for _, job := range jobs {
+		if _, ok := snapArch[job.Dist.GOARCH]; !ok || job.Dist.GOOS != "linux" || !job.Distributable() {
+			continue
+		}
+
+		fp, err := packSnap(ctx, job.Config, snap, job.Dist, version)
+		if err != nil {
+			return snaps, fmt.Errorf("%s: %w", job.Dist, err)
+		}
+
+		snaps = append(snaps, fp)
+	}
+
+	return snaps, nil
+}
+
+// packSnap lays out the binary and meta/snap.yaml in a prime directory and
+// packs it with snapcraft.
+func packSnap(ctx context.Context, config builder.BuildConfig, snap SnapConfig, dist builder.GoDist, version string) (string, error) {
+	prime, err := os.MkdirTemp("", "gobuilder-snap")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(prime)
+
+	meta, err := snapYAML(config, snap, dist, version)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Join(prime, "meta"), 0o755); err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(filepath.Join(prime, "meta", "snap.yaml"), meta, 0o644); err != nil {
+		return "", err
+	}
+
+	bin, err := os.ReadFile(builder.OutputPath(config, dist))
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(filepath.Join(prime, config.BinaryName), bin, 0o755); err != nil {
+		return "", err
+	}
+
+	fp := snapOutputPath(config, dist)
+
+	cmd := exec.CommandContext(ctx, "snapcraft", "pack", prime, "--output", fp)
+	if res, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("snapcraft pack: %w\n%s", err, res)
+	}
+
+	return fp, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestSnapYAML(t *testing.T) {
+	config := builder.NewConfig()
+	config.ProjectDir = t.TempDir()
+	config.BinaryName = "app"
+
+	got, err := snapYAML(config, SnapConfig{Summary: "An app", Plugs: []string{"network", "home"}}, builder.GoDist{GOOS: "linux", GOARCH: "arm"}, "v1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wants := []string{
+		"name: app\n",
+		`version: "1.2.3"`,
+		"base: core22\n",
+		"architectures: [armhf]\n",
+		"    command: app\n    plugs: [network, home]\n",
+	}
+
+	for _, want := range wants {
+		if !strings.Contains(string(got), want) {
+			t.Logf("snap.yaml is missing %q:\n%s\n", want, got)
+			t.Fail()
+		}
+	}
+
+	os.WriteFile(filepath.Join(config.ProjectDir, "snap.tmpl"), []byte("name: {{ .Name }}-{{ .Arch }}\n"), 0o644)
+
+	got, err = snapYAML(config, SnapConfig{Template: "snap.tmpl"}, builder.GoDist{GOOS: "linux", GOARCH: "amd64"}, "v1.2.3")
+	if err != nil || string(got) != "name: app-amd64\n" {
+		t.Logf("Incorrect templated snap.yaml, got: %q %v\n", got, err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func formatSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
+
+func resultStatus(result builder.BuildResult) string {
+	switch {
+	case result.Cancelled:
+		return "cancelled"
+	case !result.Success:
+		return "failed"
+	case result.UpToDate:
+		return "up to date"
+	default:
+		return "ok"
+	}
+}
+
+// writeBuildSummary prints a table of every target with its status, binary
+// size against its budget and build time, followed by the totals.
+func writeBuildSummary(w io.Writer, results []builder.BuildResult, took time.Duration, colors palette) {
+	table := strings.Builder{}
+	tw := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TARGET\tSTATUS\tSIZE\tTIME")
+
+	counts := map[string]int{}
+	for _, result := range results {
+		status := resultStatus(result)
+		counts[status]++
+
+		size := ""
+		if result.Size > 0 {
+			size = formatSize(result.Size)
+		}
+		if result.SizeBudget > 0 {
+			size += " / " + formatSize(result.SizeBudget)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", resultName(result), status, size, result.Duration.Round(100*time.Millisecond))
+	}
+
+	tw.Flush()
+
+	// the table is colored once aligned, as tabwriter would count the
+	// escape codes as text
+	lines := strings.SplitAfter(table.String(), "\n")
+	lines[0] = colors.bold(strings.TrimSuffix(lines[0], "\n")) + "\n"
+	for i, result := range results {
+		name, status := resultName(result), resultStatus(result)
+		line := lines[i+1]
+		at := len(name) + strings.Index(line[len(name):], status)
+		lines[i+1] = line[:at] + colors.status(status) + line[at+len(status):]
+	}
+	io.WriteString(w, strings.Join(lines, ""))
+
+	fmt.Fprintf(w, "%d built, %d up to date, %s in %s\n", counts["ok"], counts["up to date"], colors.paint(failedColor(counts["failed"]), fmt.Sprintf("%d failed", counts["failed"])), took.Round(100*time.Millisecond))
+
+	for _, result := range results {
+		switch {
+		case resultStatus(result) != "failed":
+		case result.Size > 0:
+			// the binary was built but failed a size check
+			fmt.Fprintf(w, "%s %s: %s\n", resultName(result), colors.status("failed"), result.Error)
+		case result.Log != "":
+			fmt.Fprintf(w, "%s %s, see %s\n", resultName(result), colors.status("failed"), result.Log)
+		}
+	}
+}
+
+// slowestResults returns up to n of the targets that were built, slowest
+// first. Up-to-date and cancelled targets say nothing of build times.
+func slowestResults(results []builder.BuildResult, n int) []builder.BuildResult {
+	built := slices.DeleteFunc(slices.Clone(results), func(r builder.BuildResult) bool {
+		return r.UpToDate || r.Cancelled || r.Duration == 0
+	})
+
+	slices.SortStableFunc(built, func(a, b builder.BuildResult) int {
+		return cmp.Compare(b.Duration, a.Duration)
+	})
+
+	return built[:min(n, len(built))]
+}
+
+// writeTimings prints the slowest targets and how much of the run they built
+// in parallel, which tells whether more -parallel or caching would help.
+func writeTimings(w io.Writer, results []builder.BuildResult, took time.Duration) {
+	slowest := slowestResults(results, len(results))
+	if len(slowest) < 2 || took <= 0 {
+		return
+	}
+
+	total := time.Duration(0)
+	for _, result := range slowest {
+		total += result.Duration
+	}
+
+	names := []string{}
+	for _, result := range slowest[:min(3, len(slowest))] {
+		names = append(names, fmt.Sprintf("%s %s", resultName(result), result.Duration.Round(100*time.Millisecond)))
+	}
+
+	fmt.Fprintf(w, "Slowest: %s\n", strings.Join(names, ", "))
+	fmt.Fprintf(w, "%s of builds in %s, %.1fx parallel\n", total.Round(100*time.Millisecond), took.Round(100*time.Millisecond), total.Seconds()/took.Seconds())
+}
+
+// failedColor is red when anything failed and green otherwise.
+func failedColor(failed int) string {
+	if failed > 0 {
+		return colorRed
+	}
+
+	return colorGreen
+}
+
+// writeFailures prints each failed target with its error and compiler
+// output, for quiet runs that have no summary table.
+func writeFailures(w io.Writer, results []builder.BuildResult, colors palette) {
+	for _, result := range results {
+		if resultStatus(result) != "failed" {
+			continue
+		}
+
+		fmt.Fprintf(w, "%s %s: %s\n", resultName(result), colors.status("failed"), result.Error)
+		for _, line := range strings.Split(strings.TrimRight(result.Stderr, "\n"), "\n") {
+			if line != "" {
+				fmt.Fprintf(w, "    %s\n", line)
+			}
+		}
+	}
+}
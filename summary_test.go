@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestFormatSize(t *testing.T) {
+	testCases := []struct {
+		input int64
+		wants string
+	}{
+		{input: 512, wants: "512 B"},
+		{input: 1536, wants: "1.5 KiB"},
+		{input: 5767168, wants: "5.5 MiB"},
+	}
+
+	for _, tc := range testCases {
+		if res := formatSize(tc.input); res != tc.wants {
+			t.Logf("Incorrect size, wanted: %v got: %v\n", tc.wants, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestWriteBuildSummary(t *testing.T) {
+	out := strings.Builder{}
+	writeBuildSummary(&out, []builder.BuildResult{
+		{Target: "linux/amd64", Size: 5 << 20, Duration: 1240 * time.Millisecond, Success: true},
+		{Target: "linux/amd64", Race: true, Size: 2048, Success: true, UpToDate: true},
+		{Target: "windows/arm64", Duration: 300 * time.Millisecond, Error: "exit status 1", Log: "build/logs/windows_arm64.log"},
+		{Target: "linux/arm", Size: 6 << 20, SizeBudget: 4 << 20, Duration: time.Second, Error: "size budget exceeded: 6.0 MiB is 2.0 MiB over its 4.0 MiB budget"},
+	}, 2*time.Second, palette{})
+
+	wants := "TARGET              STATUS      SIZE               TIME\n" +
+		"linux/amd64         ok          5.0 MiB            1.2s\n" +
+		"linux/amd64 (race)  up to date  2.0 KiB            0s\n" +
+		"windows/arm64       failed                         300ms\n" +
+		"linux/arm           failed      6.0 MiB / 4.0 MiB  1s\n" +
+		"1 built, 1 up to date, 2 failed in 2s\n" +
+		"windows/arm64 failed, see build/logs/windows_arm64.log\n" +
+		"linux/arm failed: size budget exceeded: 6.0 MiB is 2.0 MiB over its 4.0 MiB budget\n"
+
+	if out.String() != wants {
+		t.Logf("Incorrect summary, wanted:\n%s\ngot:\n%s\n", wants, out.String())
+		t.Fail()
+	}
+}
+
+func TestWriteFailures(t *testing.T) {
+	out := strings.Builder{}
+	writeFailures(&out, []builder.BuildResult{
+		{Target: "linux/amd64", Success: true},
+		{Target: "windows/arm64", Stderr: "# app\n./main.go:3:9: undefined: foo\n", Error: "exit status 1"},
+		{Target: "darwin/arm64", Error: "signal: interrupt", Cancelled: true},
+	}, palette{})
+
+	wants := "windows/arm64 failed: exit status 1\n" +
+		"    # app\n" +
+		"    ./main.go:3:9: undefined: foo\n"
+
+	if out.String() != wants {
+		t.Logf("Incorrect failures, wanted:\n%s\ngot:\n%s\n", wants, out.String())
+		t.Fail()
+	}
+}
+
+func TestWriteTimings(t *testing.T) {
+	results := []builder.BuildResult{
+		{Target: "linux/amd64", Duration: 2 * time.Second, Success: true},
+		{Target: "darwin/arm64", Duration: 5 * time.Second, Success: true},
+		{Target: "linux/arm64", Duration: 9 * time.Second, Success: true, UpToDate: true},
+		{Target: "windows/amd64", Duration: 3 * time.Second, Error: "exit status 1"},
+		{Target: "linux/386", Duration: 8 * time.Second, Cancelled: true},
+		{Target: "freebsd/amd64", Duration: time.Second, Success: true},
+	}
+
+	slowest := []string{}
+	for _, result := range slowestResults(results, 3) {
+		slowest = append(slowest, result.Target)
+	}
+
+	if strings.Join(slowest, " ") != "darwin/arm64 windows/amd64 linux/amd64" {
+		t.Logf("Incorrect slowest targets: %v\n", slowest)
+		t.Fail()
+	}
+
+	out := strings.Builder{}
+	writeTimings(&out, results, 5*time.Second)
+
+	wants := "Slowest: darwin/arm64 5s, windows/amd64 3s, linux/amd64 2s\n" +
+		"11s of builds in 5s, 2.2x parallel\n"
+
+	if out.String() != wants {
+		t.Logf("Incorrect timings, wanted:\n%s\ngot:\n%s\n", wants, out.String())
+		t.Fail()
+	}
+
+	out.Reset()
+	writeTimings(&out, results[:1], time.Second)
+	if out.String() != "" {
+		t.Logf("Incorrect timings for a single target: %q\n", out.String())
+		t.Fail()
+	}
+}
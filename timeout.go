@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrBuildTimeout = errors.New("build timed out")
+
+// targetContext bounds the build of a single target, hooks included, by
+// timeout. A timeout of zero leaves the build unbounded.
+func targetContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeoutCause(ctx, timeout, fmt.Errorf("%w after %s", ErrBuildTimeout, timeout))
+}
+
+// timeoutError reports err as ErrBuildTimeout when the target's own timeout,
+// rather than an interrupt or the deadline of the whole run, stopped the
+// build.
+func timeoutError(ctx context.Context, err error) error {
+	if cause := context.Cause(ctx); err != nil && errors.Is(cause, ErrBuildTimeout) {
+		return cause
+	}
+
+	return err
+}
+
+// failFastCause returns the cause the other builds are cancelled with once
+// the build of target finished with err, or nil when they go on: without
+// -fail-fast, after a success, or when the build was stopped by ctx, the
+// context of the builds, being cancelled already.
+func failFastCause(ctx context.Context, failFast bool, target string, err error) error {
+	if !failFast || err == nil || ctx.Err() != nil {
+		return nil
+	}
+
+	return fmt.Errorf("%s failed", target)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestTargetTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks use sh in this test")
+	}
+
+	config := builder.NewConfig()
+	config.ProjectDir = t.TempDir()
+	dist := builder.GoDist{GOOS: "linux", GOARCH: "amd64"}
+
+	timeout := 100 * time.Millisecond
+	ctx, cancel := targetContext(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	_, err := runHooks(ctx, []string{"exec sleep 30"}, config, dist)
+	err = timeoutError(ctx, err)
+
+	if !errors.Is(err, ErrBuildTimeout) {
+		t.Logf("Incorrect error, wanted: %v got: %v\n", ErrBuildTimeout, err)
+		t.Fail()
+	}
+
+	if took := time.Since(start); took > 10*time.Second {
+		t.Logf("Hung command was not stopped, took: %v\n", took)
+		t.Fail()
+	}
+
+	// the run's deadline passing is not a target timeout
+	run, cancelRun := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancelRun()
+	<-run.Done()
+
+	ctx, cancel = targetContext(run, time.Hour)
+	defer cancel()
+
+	_, err = runHooks(ctx, []string{"true"}, config, dist)
+	if err == nil || errors.Is(timeoutError(ctx, err), ErrBuildTimeout) {
+		t.Logf("Incorrect error for a cancelled run: %v\n", timeoutError(ctx, err))
+		t.Fail()
+	}
+}
+
+func TestFailFastCause(t *testing.T) {
+	errBuild := errors.New("exit status 1")
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testCases := []struct {
+		name     string
+		ctx      context.Context
+		failFast bool
+		input    error
+		wants    string
+	}{
+		{name: "failure", ctx: context.Background(), failFast: true, input: errBuild, wants: "linux/amd64 failed"},
+		{name: "success", ctx: context.Background(), failFast: true, input: nil, wants: ""},
+		{name: "without fail-fast", ctx: context.Background(), failFast: false, input: errBuild, wants: ""},
+		{name: "already cancelled", ctx: cancelled, failFast: true, input: context.Canceled, wants: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := ""
+			if cause := failFastCause(tc.ctx, tc.failFast, "linux/amd64", tc.input); cause != nil {
+				res = cause.Error()
+			}
+
+			if res != tc.wants {
+				t.Logf("Incorrect cause, wanted: %q got: %q\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
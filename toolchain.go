@@ -0,0 +1,237 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var (
+	ErrInvalidGoVersion  = errors.New("invalid go version")
+	ErrToolchainNotFound = errors.New("no go download for this version and platform")
+	ErrGoVersionsBuilder = errors.New("go_versions only applies to the local builder without -workers")
+	ErrConflictingGo     = errors.New("go and go_version cannot be used together")
+)
+
+const goDownloadURL = "https://go.dev/dl/"
+
+// goVersionPattern matches released go versions, e.g. 1.22.3 or go1.23rc1.
+var goVersionPattern = regexp.MustCompile(`^(go)?1\.\d+(\.\d+|rc\d+|beta\d+)?$`)
+
+// parseGoVersion returns v as a go version such as go1.22.3.
+func parseGoVersion(v string) (string, error) {
+	if !goVersionPattern.MatchString(v) {
+		return "", fmt.Errorf("%w: %q, expected e.g. 1.22.3", ErrInvalidGoVersion, v)
+	}
+
+	return "go" + strings.TrimPrefix(v, "go"), nil
+}
+
+// goDownload is a release on the go.dev/dl JSON listing.
+type goDownload struct {
+	Version string `json:"version"`
+	Files   []struct {
+		Filename string `json:"filename"`
+		OS       string `json:"os"`
+		Arch     string `json:"arch"`
+		SHA256   string `json:"sha256"`
+		Kind     string `json:"kind"`
+	} `json:"files"`
+}
+
+// toolchainCacheDir is where downloaded toolchains are kept.
+func toolchainCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "go-builder", "toolchains"), nil
+}
+
+// installToolchain downloads the go toolchain v for this platform from
+// baseURL into cacheDir/v, checking it against the listed sha256, and
+// returns its GOROOT. A toolchain already in the cache is used as is.
+func installToolchain(ctx context.Context, baseURL string, cacheDir string, v string) (string, error) {
+	goroot := filepath.Join(cacheDir, v)
+	if _, err := os.Stat(filepath.Join(goroot, "bin", goExe())); err == nil {
+		return goroot, nil
+	}
+
+	listing, err := download(ctx, baseURL+"?mode=json&include=all")
+	if err != nil {
+		return "", err
+	}
+
+	releases := []goDownload{}
+	if err := json.Unmarshal(listing, &releases); err != nil {
+		return "", fmt.Errorf("go downloads: %w", err)
+	}
+
+	filename, sum := "", ""
+	for _, release := range releases {
+		for _, file := range release.Files {
+			if release.Version == v && file.OS == runtime.GOOS && file.Arch == runtime.GOARCH && file.Kind == "archive" {
+				filename, sum = file.Filename, file.SHA256
+			}
+		}
+	}
+
+	if filename == "" {
+		return "", fmt.Errorf("%w: %s %s/%s", ErrToolchainNotFound, v, runtime.GOOS, runtime.GOARCH)
+	}
+
+	archive, err := download(ctx, baseURL+filename)
+	if err != nil {
+		return "", err
+	}
+
+	if got := sha256.Sum256(archive); hex.EncodeToString(got[:]) != sum {
+		return "", fmt.Errorf("%w: %s", ErrChecksumMismatch, filename)
+	}
+
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return "", err
+	}
+
+	// the archive is extracted beside the cache entry and renamed, so an
+	// interrupted download is never mistaken for a toolchain
+	tmp, err := os.MkdirTemp(cacheDir, ".download-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	if strings.HasSuffix(filename, ".zip") {
+		err = extractZip(archive, tmp)
+	} else {
+		err = extractTarGz(bytes.NewReader(archive), tmp)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", filename, err)
+	}
+
+	// go archives hold a single go directory
+	if err := os.Rename(filepath.Join(tmp, "go"), goroot); err != nil && !errors.Is(err, os.ErrExist) {
+		return "", err
+	}
+
+	return goroot, nil
+}
+
+// extractZip extracts the directories and regular files of a zip into dir.
+func extractZip(archive []byte, dir string) error {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range zr.File {
+		name := filepath.FromSlash(file.Name)
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("%w: %s", ErrUnsafeArchivePath, file.Name)
+		}
+		fp := filepath.Join(dir, name)
+
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(fp, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(fp), 0o755); err != nil {
+			return err
+		}
+
+		r, err := file.Open()
+		if err != nil {
+			return err
+		}
+
+		f, err := os.OpenFile(fp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, file.Mode().Perm()|0o600)
+		if err == nil {
+			_, err = io.Copy(f, r)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		r.Close()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func goExe() string {
+	if runtime.GOOS == "windows" {
+		return "go.exe"
+	}
+
+	return "go"
+}
+
+// lookGo finds the go command goPath, a path or a command on PATH such as
+// go1.22.3, and returns its absolute path and GOROOT.
+func lookGo(ctx context.Context, goPath string) (string, string, error) {
+	fp, err := exec.LookPath(goPath)
+	if err == nil {
+		fp, err = filepath.Abs(fp)
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	cmd := exec.CommandContext(ctx, fp, "env", "GOROOT")
+	cmd.Env = append(os.Environ(), "GOTOOLCHAIN=local")
+
+	res, err := cmd.Output()
+	if err != nil {
+		return "", "", fmt.Errorf("%s env GOROOT: %w", fp, err)
+	}
+
+	return fp, strings.TrimSpace(string(res)), nil
+}
+
+// useToolchain makes the go of goroot the one every go command of the run,
+// builds included, resolves to. GOTOOLCHAIN=local keeps it from switching
+// to the toolchain a go.mod asks for.
+func useToolchain(goroot string) {
+	os.Setenv("PATH", filepath.Join(goroot, "bin")+string(os.PathListSeparator)+os.Getenv("PATH"))
+	os.Setenv("GOTOOLCHAIN", "local")
+	os.Unsetenv("GOROOT")
+}
+
+// goVersionBuilds repeats every build with the go of each version in
+// goroots, appending the version to the binary names, e.g. app-go1.22.3.
+func goVersionBuilds(builds []builder.BuildConfig, versions []string, goroots map[string]string) []builder.BuildConfig {
+	matrix := []builder.BuildConfig{}
+
+	for _, build := range builds {
+		for _, v := range versions {
+			variant := build
+			variant.BinaryName += "-" + v
+			variant.Go = filepath.Join(goroots[v], "bin", goExe())
+			matrix = append(matrix, variant)
+		}
+	}
+
+	return matrix
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestParseGoVersion(t *testing.T) {
+	testCases := []struct {
+		input    string
+		wants    string
+		wantsErr error
+	}{
+		{input: "1.22.3", wants: "go1.22.3"},
+		{input: "go1.23.0", wants: "go1.23.0"},
+		{input: "1.24rc1", wants: "go1.24rc1"},
+		{input: "1.22", wants: "go1.22"},
+		{input: "latest", wantsErr: ErrInvalidGoVersion},
+		{input: "1.22.3/../x", wantsErr: ErrInvalidGoVersion},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			res, err := parseGoVersion(tc.input)
+
+			if !errors.Is(err, tc.wantsErr) {
+				t.Logf("Incorrect error, wanted: %v got: %v\n", tc.wantsErr, err)
+				t.Fail()
+			}
+
+			if res != tc.wants {
+				t.Logf("Incorrect version, wanted: %s got: %s\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestInstallToolchain(t *testing.T) {
+	buf := bytes.Buffer{}
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: "go/bin/", Typeflag: tar.TypeDir, Mode: 0o755})
+	tw.WriteHeader(&tar.Header{Name: "go/bin/" + goExe(), Typeflag: tar.TypeReg, Mode: 0o755, Size: 4})
+	tw.Write([]byte("fake"))
+	tw.Close()
+	gz.Close()
+	archive := buf.Bytes()
+
+	sum := sha256.Sum256(archive)
+	filename := fmt.Sprintf("go1.22.3.%s-%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	listing := fmt.Sprintf(`[{"version": "go1.22.3", "files": [
+		{"filename": "go1.22.3.src.tar.gz", "os": "", "arch": "", "sha256": "00", "kind": "source"},
+		{"filename": %q, "os": %q, "arch": %q, "sha256": %q, "kind": "archive"}
+	]}]`, filename, runtime.GOOS, runtime.GOARCH, hex.EncodeToString(sum[:]))
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/dl/"+filename {
+			w.Write(archive)
+			return
+		}
+		w.Write([]byte(listing))
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+
+	goroot, err := installToolchain(context.Background(), server.URL+"/dl/", cacheDir, "go1.22.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b, err := os.ReadFile(filepath.Join(goroot, "bin", goExe())); err != nil || string(b) != "fake" {
+		t.Logf("Toolchain was not extracted: %v\n", err)
+		t.Fail()
+	}
+
+	// a cached toolchain is not downloaded again
+	if _, err := installToolchain(context.Background(), server.URL+"/dl/", cacheDir, "go1.22.3"); err != nil || requests != 2 {
+		t.Logf("Cached toolchain was downloaded again, requests: %d err: %v\n", requests, err)
+		t.Fail()
+	}
+
+	if _, err := installToolchain(context.Background(), server.URL+"/dl/", cacheDir, "go1.9.9"); !errors.Is(err, ErrToolchainNotFound) {
+		t.Logf("Incorrect error, wanted: %v got: %v\n", ErrToolchainNotFound, err)
+		t.Fail()
+	}
+}
+
+func TestGoVersionBuilds(t *testing.T) {
+	app := builder.NewConfig()
+	app.BinaryName = "app"
+	tool := builder.NewConfig()
+	tool.BinaryName = "tool"
+
+	goroots := map[string]string{"go1.22.3": "/cache/go1.22.3", "go1.23.0": "/cache/go1.23.0"}
+	versions := []string{"go1.22.3", "go1.23.0"}
+	res := goVersionBuilds([]builder.BuildConfig{app, tool}, versions, goroots)
+
+	wants := []string{"app-go1.22.3", "app-go1.23.0", "tool-go1.22.3", "tool-go1.23.0"}
+	if len(res) != len(wants) {
+		t.Fatalf("Incorrect number of builds, wanted: %d got: %d\n", len(wants), len(res))
+	}
+
+	dist := builder.GoDist{GOOS: "linux", GOARCH: "amd64"}
+	for i, build := range res {
+		if build.BinaryName != wants[i] {
+			t.Logf("Incorrect binary name, wanted: %s got: %s\n", wants[i], build.BinaryName)
+			t.Fail()
+		}
+
+		goroot := goroots[versions[i%len(versions)]]
+		if name, _ := build.BuildCommand(dist, "out"); name != filepath.Join(goroot, "bin", goExe()) {
+			t.Logf("Incorrect go command: %s\n", name)
+			t.Fail()
+		}
+
+		if !slices.Contains(build.BuildEnv(dist), "GOTOOLCHAIN=local") {
+			t.Logf("Build may switch toolchains: %v\n", build.BuildEnv(dist))
+			t.Fail()
+		}
+	}
+}
+
+func TestGoVersionBuildsPackaging(t *testing.T) {
+	app := builder.NewConfig()
+	app.OutputDir = t.TempDir()
+	app.BinaryName = "app"
+
+	goroots := map[string]string{"go1.22.3": "/cache/go1.22.3", "go1.23.0": "/cache/go1.23.0"}
+	builds := goVersionBuilds([]builder.BuildConfig{app}, []string{"go1.22.3", "go1.23.0"}, goroots)
+
+	jobs := []buildJob{}
+	for _, build := range builds {
+		for _, dist := range []builder.GoDist{{GOOS: "linux", GOARCH: "amd64"}, {GOOS: "windows", GOARCH: "amd64"}} {
+			os.WriteFile(builder.OutputPath(build, dist), []byte(build.BinaryName), 0o755)
+			jobs = append(jobs, buildJob{Config: build, Dist: dist})
+		}
+	}
+
+	archives, written, err := buildArchives(app, ArchiveConfig{}, jobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wants := []string{
+		"app-go1.22.3-linux_amd64.tar.gz", "app-go1.22.3-windows_amd64.zip",
+		"app-go1.23.0-linux_amd64.tar.gz", "app-go1.23.0-windows_amd64.zip",
+	}
+	got := []string{}
+	for _, fp := range written {
+		got = append(got, filepath.Base(fp))
+	}
+
+	if !slices.Equal(got, wants) {
+		t.Logf("Incorrect archives, wanted: %v got: %v\n", wants, got)
+		t.Fail()
+	}
+
+	if res := filepath.Base(archives["linux/amd64"]); res != wants[0] {
+		t.Logf("Incorrect archive of linux/amd64, wanted: %s got: %s\n", wants[0], res)
+		t.Fail()
+	}
+
+	if res := filepath.Base(packageOutputPath(builds[1], builder.GoDist{GOOS: "linux", GOARCH: "amd64"}, "deb")); res != "app-go1.23.0-linux_amd64.deb" {
+		t.Logf("Incorrect package name: %s\n", res)
+		t.Fail()
+	}
+}
+
+func TestLookGo(t *testing.T) {
+	fp, goroot, err := lookGo(context.Background(), "go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !filepath.IsAbs(fp) {
+		t.Logf("Go command path is not absolute: %s\n", fp)
+		t.Fail()
+	}
+
+	if _, err := os.Stat(filepath.Join(goroot, "bin", goExe())); err != nil {
+		t.Logf("Incorrect GOROOT %s: %v\n", goroot, err)
+		t.Fail()
+	}
+
+	if _, _, err := lookGo(context.Background(), filepath.Join(t.TempDir(), "go")); err == nil {
+		t.Logf("Missing go command was found\n")
+		t.Fail()
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnsupportedOTLPProtocol = errors.New("unsupported OTLP protocol, only http/json is supported")
+	ErrTraceExport             = errors.New("unable to export traces")
+)
+
+// tracer records the spans of a run and exports them to an OpenTelemetry
+// collector with OTLP over HTTP, using the standard OTEL_* variables. A nil
+// tracer records nothing, so tracing costs nothing unless configured.
+type tracer struct {
+	endpoint string
+	headers  map[string]string
+	service  string
+	client   *http.Client
+
+	traceID string
+	// parentID is the span of the process that started the run, as given
+	// by TRACEPARENT, if any.
+	parentID string
+
+	mu    sync.Mutex
+	spans []*span
+}
+
+// span is a timed phase of the run. A nil span ignores every call.
+type span struct {
+	tracer   *tracer
+	name     string
+	id       string
+	parentID string
+	start    time.Time
+	end      time.Time
+	attrs    []any
+	err      error
+}
+
+type spanKey struct{}
+
+// newTracer configures tracing from the environment read with getenv. It
+// returns nil when no OTLP endpoint is set or the SDK is disabled.
+func newTracer(getenv func(string) string) (*tracer, error) {
+	if strings.EqualFold(getenv("OTEL_SDK_DISABLED"), "true") {
+		return nil, nil
+	}
+
+	endpoint := getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" && getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" {
+		endpoint = strings.TrimSuffix(getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "/") + "/v1/traces"
+	}
+	if endpoint == "" {
+		return nil, nil
+	}
+
+	protocol := getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL")
+	if protocol == "" {
+		protocol = getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+	}
+	if protocol != "" && protocol != "http/json" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedOTLPProtocol, protocol)
+	}
+
+	headers := map[string]string{}
+	for _, list := range []string{getenv("OTEL_EXPORTER_OTLP_HEADERS"), getenv("OTEL_EXPORTER_OTLP_TRACES_HEADERS")} {
+		for _, pair := range strings.Split(list, ",") {
+			key, value, ok := strings.Cut(pair, "=")
+			if !ok {
+				continue
+			}
+
+			if unescaped, err := url.QueryUnescape(strings.TrimSpace(value)); err == nil {
+				value = unescaped
+			}
+			headers[strings.TrimSpace(key)] = value
+		}
+	}
+
+	service := getenv("OTEL_SERVICE_NAME")
+	if service == "" {
+		service = "go-builder"
+	}
+
+	t := &tracer{
+		endpoint: endpoint,
+		headers:  headers,
+		service:  service,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		traceID:  randomID(16),
+	}
+
+	// a run started by a traced CI job joins its trace
+	if parts := strings.Split(getenv("TRACEPARENT"), "-"); len(parts) == 4 && len(parts[1]) == 32 && len(parts[2]) == 16 {
+		t.traceID, t.parentID = parts[1], parts[2]
+	}
+
+	return t, nil
+}
+
+func randomID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Start begins a span named name as a child of the span in ctx, with
+// attributes given as key-value pairs like those of log/slog.
+func (t *tracer) Start(ctx context.Context, name string, attrs ...any) (context.Context, *span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	s := &span{tracer: t, name: name, id: randomID(8), parentID: t.parentID, start: time.Now(), attrs: attrs}
+	if parent, ok := ctx.Value(spanKey{}).(*span); ok {
+		s.parentID = parent.id
+	}
+
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// Set adds an attribute to the span.
+func (s *span) Set(key string, value any) {
+	if s == nil {
+		return
+	}
+
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+
+	s.attrs = append(s.attrs, key, value)
+}
+
+// End ends the span, marking it failed when err is not nil.
+func (s *span) End(err error) {
+	if s == nil {
+		return
+	}
+
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+
+	s.end, s.err = time.Now(), err
+	s.tracer.spans = append(s.tracer.spans, s)
+}
+
+// otlpAttributes converts key-value pairs to OTLP attributes.
+func otlpAttributes(attrs []any) []map[string]any {
+	converted := []map[string]any{}
+
+	for i := 0; i+1 < len(attrs); i += 2 {
+		key, ok := attrs[i].(string)
+		if !ok {
+			continue
+		}
+
+		var value map[string]any
+		switch v := attrs[i+1].(type) {
+		case bool:
+			value = map[string]any{"boolValue": v}
+		case int:
+			value = map[string]any{"intValue": strconv.Itoa(v)}
+		case int64:
+			value = map[string]any{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			value = map[string]any{"doubleValue": v}
+		default:
+			value = map[string]any{"stringValue": fmt.Sprint(v)}
+		}
+
+		converted = append(converted, map[string]any{"key": key, "value": value})
+	}
+
+	return converted
+}
+
+// otlpRequest is the OTLP/JSON export request of the ended spans.
+func (t *tracer) otlpRequest() map[string]any {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	spans := []map[string]any{}
+	for _, s := range t.spans {
+		status := map[string]any{"code": 1}
+		if s.err != nil {
+			status = map[string]any{"code": 2, "message": s.err.Error()}
+		}
+
+		spans = append(spans, map[string]any{
+			"traceId":           t.traceID,
+			"spanId":            s.id,
+			"parentSpanId":      s.parentID,
+			"name":              s.name,
+			"kind":              1,
+			"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+			"attributes":        otlpAttributes(s.attrs),
+			"status":            status,
+		})
+	}
+
+	return map[string]any{
+		"resourceSpans": []map[string]any{{
+			"resource": map[string]any{
+				"attributes": otlpAttributes([]any{"service.name", t.service}),
+			},
+			"scopeSpans": []map[string]any{{
+				"scope": map[string]any{"name": "github.com/jrstaple/go-builder"},
+				"spans": spans,
+			}},
+		}},
+	}
+}
+
+// Flush exports the spans ended so far.
+func (t *tracer) Flush(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+
+	body, err := json.Marshal(t.otlpRequest())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%w: %s: %s", ErrTraceExport, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	t.mu.Lock()
+	t.spans = nil
+	t.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewTracer(t *testing.T) {
+	testCases := []struct {
+		name          string
+		env           map[string]string
+		wantsEndpoint string
+		wantsErr      error
+	}{
+		{name: "unset", env: map[string]string{}},
+		{name: "endpoint", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318/"}, wantsEndpoint: "http://collector:4318/v1/traces"},
+		{name: "traces endpoint", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "http://traces/otlp"}, wantsEndpoint: "http://traces/otlp"},
+		{name: "disabled", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318", "OTEL_SDK_DISABLED": "true"}},
+		{name: "grpc", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4317", "OTEL_EXPORTER_OTLP_PROTOCOL": "grpc"}, wantsErr: ErrUnsupportedOTLPProtocol},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := newTracer(func(key string) string { return tc.env[key] })
+
+			if !errors.Is(err, tc.wantsErr) {
+				t.Logf("Incorrect error, wanted: %v got: %v\n", tc.wantsErr, err)
+				t.Fail()
+			}
+
+			endpoint := ""
+			if res != nil {
+				endpoint = res.endpoint
+			}
+
+			if endpoint != tc.wantsEndpoint {
+				t.Logf("Incorrect endpoint, wanted: %q got: %q\n", tc.wantsEndpoint, endpoint)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestTracerFlush(t *testing.T) {
+	var body []byte
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		auth = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	env := map[string]string{
+		"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": server.URL,
+		"OTEL_EXPORTER_OTLP_HEADERS":         "Authorization=Bearer%20secret",
+		"TRACEPARENT":                        "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+	}
+	tracer, err := newTracer(func(key string) string { return env[key] })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, run := tracer.Start(context.Background(), "run", "targets", 2)
+	_, target := tracer.Start(ctx, "target", "target", "linux/amd64")
+	target.End(errors.New("exit status 1"))
+	run.End(nil)
+
+	if err := tracer.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if auth != "Bearer secret" {
+		t.Logf("Incorrect authorization header: %q\n", auth)
+		t.Fail()
+	}
+
+	type otlpSpan struct {
+		TraceID      string `json:"traceId"`
+		SpanID       string `json:"spanId"`
+		ParentSpanID string `json:"parentSpanId"`
+		Name         string `json:"name"`
+		Status       struct {
+			Code int `json:"code"`
+		} `json:"status"`
+	}
+	var req struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []otlpSpan `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("Incorrect spans: %s\n", body)
+	}
+
+	targetSpan, runSpan := spans[0], spans[1]
+	if runSpan.TraceID != "0af7651916cd43dd8448eb211c80319c" || runSpan.ParentSpanID != "b7ad6b7169203331" || runSpan.Status.Code != 1 {
+		t.Logf("Incorrect run span: %+v\n", runSpan)
+		t.Fail()
+	}
+
+	if targetSpan.Name != "target" || targetSpan.ParentSpanID != runSpan.SpanID || targetSpan.Status.Code != 2 {
+		t.Logf("Incorrect target span: %+v\n", targetSpan)
+		t.Fail()
+	}
+
+	tracer = nil
+	_, s := tracer.Start(ctx, "noop")
+	s.End(nil)
+	if err := tracer.Flush(ctx); err != nil {
+		t.Logf("Flushing a nil tracer failed: %v\n", err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"debug/macho"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+// universalOutputPath returns where the merged darwin binary is written,
+// e.g. build/myapp-darwin_universal.
+func universalOutputPath(config builder.BuildConfig) string {
+	return filepath.Join(config.OutputDir, fmt.Sprintf("%s-darwin_universal", config.BinaryName))
+}
+
+// universalBuilds returns the darwin builds to merge, by binary name and
+// GOARCH. Only distributable executables are merged: libraries and race
+// builds are left as they are.
+func universalBuilds(built []buildJob) map[string]map[string]buildJob {
+	darwinBuilds := map[string]map[string]buildJob{}
+
+	for _, job := range built {
+		if job.Dist.GOOS != "darwin" || job.Dist.SubArch != "" || !job.Distributable() {
+			continue
+		}
+
+		if darwinBuilds[job.Config.BinaryName] == nil {
+			darwinBuilds[job.Config.BinaryName] = map[string]buildJob{}
+		}
+		darwinBuilds[job.Config.BinaryName][job.Dist.GOARCH] = job
+	}
+
+	return darwinBuilds
+}
+
+// mergeMachO writes a universal (fat) Mach-O file to out containing each of
+// the thin inputs, the same layout lipo -create produces. Each slice is
+// aligned to 16KiB for arm64 and 4KiB otherwise.
+func mergeMachO(out string, inputs ...string) error {
+	type slice struct {
+		cpu    macho.Cpu
+		subCpu uint32
+		align  uint32
+		data   []byte
+	}
+
+	thin := []slice{}
+
+	for _, fp := range inputs {
+		f, err := macho.Open(fp)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(fp), err)
+		}
+
+		s := slice{cpu: f.Cpu, subCpu: f.SubCpu, align: 12}
+		f.Close()
+
+		if s.cpu == macho.CpuArm64 {
+			s.align = 14
+		}
+
+		if s.data, err = os.ReadFile(fp); err != nil {
+			return err
+		}
+
+		thin = append(thin, s)
+	}
+
+	const fatHeaderSize = 8
+	const fatArchSize = 20
+
+	header := []byte{}
+	header = binary.BigEndian.AppendUint32(header, macho.MagicFat)
+	header = binary.BigEndian.AppendUint32(header, uint32(len(thin)))
+
+	offset := uint32(fatHeaderSize + fatArchSize*len(thin))
+	offsets := []uint32{}
+
+	for _, s := range thin {
+		alignment := uint32(1) << s.align
+		offset = (offset + alignment - 1) &^ (alignment - 1)
+		offsets = append(offsets, offset)
+
+		header = binary.BigEndian.AppendUint32(header, uint32(s.cpu))
+		header = binary.BigEndian.AppendUint32(header, s.subCpu)
+		header = binary.BigEndian.AppendUint32(header, offset)
+		header = binary.BigEndian.AppendUint32(header, uint32(len(s.data)))
+		header = binary.BigEndian.AppendUint32(header, s.align)
+
+		offset += uint32(len(s.data))
+	}
+
+	contents := make([]byte, offset)
+	copy(contents, header)
+
+	for i, s := range thin {
+		copy(contents[offsets[i]:], s.data)
+	}
+
+	return os.WriteFile(out, contents, 0o755)
+}
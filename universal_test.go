@@ -0,0 +1,151 @@
+package main
+
+import (
+	"debug/macho"
+	"encoding/binary"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+// writeThinMachO writes a header-only 64-bit Mach-O executable for cpu.
+func writeThinMachO(t *testing.T, fp string, cpu macho.Cpu) {
+	contents := []byte{}
+	for _, v := range []uint32{macho.Magic64, uint32(cpu), 0, uint32(macho.TypeExec), 0, 0, 0, 0} {
+		contents = binary.LittleEndian.AppendUint32(contents, v)
+	}
+
+	if err := os.WriteFile(fp, contents, 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMergeMachO(t *testing.T) {
+	dir := t.TempDir()
+
+	amd64Fp := filepath.Join(dir, "app-darwin_amd64")
+	arm64Fp := filepath.Join(dir, "app-darwin_arm64")
+	outFp := filepath.Join(dir, "app-darwin_universal")
+
+	writeThinMachO(t, amd64Fp, macho.CpuAmd64)
+	writeThinMachO(t, arm64Fp, macho.CpuArm64)
+
+	if err := mergeMachO(outFp, amd64Fp, arm64Fp); err != nil {
+		t.Fatal(err)
+	}
+
+	fat, err := macho.OpenFat(outFp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fat.Close()
+
+	wants := []struct {
+		cpu    macho.Cpu
+		offset uint32
+		align  uint32
+	}{
+		{cpu: macho.CpuAmd64, offset: 1 << 12, align: 12},
+		{cpu: macho.CpuArm64, offset: 1 << 14, align: 14},
+	}
+
+	if len(fat.Arches) != len(wants) {
+		t.Fatalf("Incorrect number of slices, wanted: %d got: %d\n", len(wants), len(fat.Arches))
+	}
+
+	for i, want := range wants {
+		arch := fat.Arches[i]
+		if arch.Cpu != want.cpu || arch.Offset != want.offset || arch.Align != want.align {
+			t.Logf("Incorrect slice %d, wanted: %+v got: cpu=%v offset=%d align=%d\n",
+				i, want, arch.Cpu, arch.Offset, arch.Align)
+			t.Fail()
+		}
+	}
+
+	notMachO := filepath.Join(dir, "app-linux_amd64")
+	os.WriteFile(notMachO, []byte("\x7fELF"), 0o755)
+
+	if err := mergeMachO(filepath.Join(dir, "bad"), notMachO); err == nil {
+		t.Log("Expected an error merging a non Mach-O file")
+		t.Fail()
+	}
+}
+
+func TestUniversalBuilds(t *testing.T) {
+	app := builder.NewConfig()
+	app.BinaryName = "app"
+
+	race := app
+	race.Race = true
+
+	archive := app
+	archive.BuildMode = "c-archive"
+
+	shared := app
+	shared.BinaryName = "lib"
+	shared.BuildModes = map[string]string{"darwin": "c-shared"}
+
+	darwinAmd64 := builder.GoDist{GOOS: "darwin", GOARCH: "amd64"}
+	darwinArm64 := builder.GoDist{GOOS: "darwin", GOARCH: "arm64"}
+
+	testCases := []struct {
+		name  string
+		built []buildJob
+		wants map[string][]string
+	}{
+		{
+			name: "executables",
+			built: []buildJob{
+				{Config: app, Dist: darwinAmd64},
+				{Config: app, Dist: darwinArm64},
+				{Config: app, Dist: builder.GoDist{GOOS: "linux", GOARCH: "amd64"}},
+				{Config: app, Dist: builder.GoDist{GOOS: "darwin", GOARCH: "amd64", SubArch: "v3"}},
+			},
+			wants: map[string][]string{"app": {"amd64", "arm64"}},
+		},
+		{
+			name: "race builds",
+			built: []buildJob{
+				{Config: race, Dist: darwinAmd64},
+				{Config: race, Dist: darwinArm64},
+			},
+			wants: map[string][]string{},
+		},
+		{
+			name: "c-archive",
+			built: []buildJob{
+				{Config: archive, Dist: darwinAmd64},
+				{Config: archive, Dist: darwinArm64},
+			},
+			wants: map[string][]string{},
+		},
+		{
+			name: "c-shared beside an executable",
+			built: []buildJob{
+				{Config: shared, Dist: darwinAmd64},
+				{Config: shared, Dist: darwinArm64},
+				{Config: app, Dist: darwinAmd64},
+				{Config: app, Dist: darwinArm64},
+			},
+			wants: map[string][]string{"app": {"amd64", "arm64"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := map[string][]string{}
+			for name, builds := range universalBuilds(tc.built) {
+				res[name] = slices.Sorted(maps.Keys(builds))
+			}
+
+			if !maps.EqualFunc(res, tc.wants, slices.Equal) {
+				t.Logf("Incorrect universal builds, wanted: %v got: %v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
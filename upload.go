@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var ErrInvalidUploadURL = errors.New("invalid upload url")
+
+const defaultUploadConcurrency = 4
+
+// UploadConfig describes the object storage location the output directory is
+// copied to. URL is s3://bucket/prefix/, gs://bucket/prefix/ or
+// az://account/container/prefix/ and may contain {tag}, replaced with the
+// release tag.
+type UploadConfig struct {
+	URL string `json:"url"`
+	// Concurrency is the number of files uploaded at once. It also sets the
+	// parallel block uploads of az; aws and gcloud use their own settings.
+	Concurrency int `json:"concurrency"`
+}
+
+// blobLocation is a parsed upload URL. Account is only set for Azure.
+type blobLocation struct {
+	Scheme  string
+	Account string
+	Bucket  string
+	Prefix  string
+}
+
+func parseBlobURL(raw string) (blobLocation, error) {
+	scheme, rest, ok := strings.Cut(raw, "://")
+	if !ok {
+		return blobLocation{}, fmt.Errorf("%w: %q: missing scheme", ErrInvalidUploadURL, raw)
+	}
+
+	loc := blobLocation{Scheme: scheme}
+
+	switch scheme {
+	case "s3", "gs":
+	case "az":
+		loc.Account, rest, _ = strings.Cut(rest, "/")
+	default:
+		return blobLocation{}, fmt.Errorf("%w: %q: unsupported scheme %s, expected s3, gs or az", ErrInvalidUploadURL, raw, scheme)
+	}
+
+	loc.Bucket, loc.Prefix, _ = strings.Cut(rest, "/")
+	loc.Prefix = strings.Trim(loc.Prefix, "/")
+
+	if loc.Bucket == "" || (scheme == "az" && loc.Account == "") {
+		return blobLocation{}, fmt.Errorf("%w: %q: missing bucket", ErrInvalidUploadURL, raw)
+	}
+
+	return loc, nil
+}
+
+// uploadCommand returns the CLI command that copies the local file to key
+// under the location's prefix.
+func (loc blobLocation) uploadCommand(fp string, key string, concurrency int) (string, []string) {
+	key = path.Join(loc.Prefix, key)
+
+	switch loc.Scheme {
+	case "gs":
+		return "gcloud", []string{"storage", "cp", fp, "gs://" + loc.Bucket + "/" + key}
+	case "az":
+		return "az", []string{"storage", "blob", "upload",
+			"--account-name", loc.Account,
+			"--container-name", loc.Bucket,
+			"--name", key,
+			"--file", fp,
+			"--overwrite",
+			"--max-connections", strconv.Itoa(concurrency),
+			"--only-show-errors",
+		}
+	default:
+		return "aws", []string{"s3", "cp", "--only-show-errors", fp, "s3://" + loc.Bucket + "/" + key}
+	}
+}
+
+// uploadOutputDir copies every file in outputDir to the upload location,
+// keeping the directory layout, and returns the uploaded keys. Large files are
+// split into parallel multipart uploads by the storage CLIs.
+func uploadOutputDir(ctx context.Context, upload UploadConfig, outputDir string) ([]string, error) {
+	loc, err := parseBlobURL(upload.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency := upload.Concurrency
+	if concurrency < 1 {
+		concurrency = defaultUploadConcurrency
+	}
+
+	files := []string{}
+	err = filepath.WalkDir(outputDir, func(fp string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files = append(files, fp)
+		}
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, len(files))
+	errs := make([]error, len(files))
+	sem := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+
+	for i, fp := range files {
+		rel, _ := filepath.Rel(outputDir, fp)
+		keys[i] = path.Join(loc.Prefix, filepath.ToSlash(rel))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			name, args := loc.uploadCommand(fp, filepath.ToSlash(rel), concurrency)
+			cmd := exec.CommandContext(ctx, name, args...)
+
+			if res, err := cmd.CombinedOutput(); err != nil {
+				errs[i] = fmt.Errorf("%s: %w\n%s", rel, err, res)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return keys, errors.Join(errs...)
+}
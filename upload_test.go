@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseBlobURL(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		wants blobLocation
+		err   error
+	}{
+		{
+			name:  "s3",
+			input: "s3://bucket/myapp/v1.2.3/",
+			wants: blobLocation{Scheme: "s3", Bucket: "bucket", Prefix: "myapp/v1.2.3"},
+		},
+		{
+			name:  "gs no prefix",
+			input: "gs://bucket",
+			wants: blobLocation{Scheme: "gs", Bucket: "bucket"},
+		},
+		{
+			name:  "azure",
+			input: "az://acct/releases/myapp",
+			wants: blobLocation{Scheme: "az", Account: "acct", Bucket: "releases", Prefix: "myapp"},
+		},
+		{
+			name:  "azure no container",
+			input: "az://acct",
+			err:   ErrInvalidUploadURL,
+		},
+		{
+			name:  "no scheme",
+			input: "bucket/myapp",
+			err:   ErrInvalidUploadURL,
+		},
+		{
+			name:  "bad scheme",
+			input: "ftp://bucket/myapp",
+			err:   ErrInvalidUploadURL,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := parseBlobURL(tc.input)
+
+			if res != tc.wants {
+				t.Logf("Incorrect location, wanted: %+v got: %+v\n", tc.wants, res)
+				t.Fail()
+			} else if !errors.Is(err, tc.err) {
+				t.Logf("Incorrect error returned, wanted: %v got: %v\n", tc.err, err)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestUploadCommand(t *testing.T) {
+	testCases := []struct {
+		name  string
+		loc   blobLocation
+		wants []string
+	}{
+		{
+			name:  "s3",
+			loc:   blobLocation{Scheme: "s3", Bucket: "b", Prefix: "app/v1"},
+			wants: []string{"aws", "s3", "cp", "--only-show-errors", "out/app.exe", "s3://b/app/v1/app.exe"},
+		},
+		{
+			name:  "gs",
+			loc:   blobLocation{Scheme: "gs", Bucket: "b"},
+			wants: []string{"gcloud", "storage", "cp", "out/app.exe", "gs://b/app.exe"},
+		},
+		{
+			name: "az",
+			loc:  blobLocation{Scheme: "az", Account: "acct", Bucket: "c", Prefix: "app"},
+			wants: []string{"az", "storage", "blob", "upload", "--account-name", "acct", "--container-name", "c",
+				"--name", "app/app.exe", "--file", "out/app.exe", "--overwrite", "--max-connections", "4", "--only-show-errors"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd, args := tc.loc.uploadCommand("out/app.exe", "app.exe", 4)
+			res := append([]string{cmd}, args...)
+
+			if !slices.Equal(res, tc.wants) {
+				t.Logf("Incorrect command, wanted: %v got: %v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
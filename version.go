@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+)
+
+// toolVersion and toolCommit are set by release builds, e.g. with -ldflags
+// "-X main.toolVersion=v1.2.3 -X main.toolCommit=abc1234". Other builds
+// fall back to the build info of the binary.
+var (
+	toolVersion string
+	toolCommit  string
+)
+
+// versionInfo identifies the build of go-builder itself.
+type versionInfo struct {
+	Version   string
+	Commit    string
+	Modified  bool
+	GoVersion string
+	Platform  string
+}
+
+// readVersionInfo combines the ldflags values with the build info embedded
+// by the go command: the module version for go install builds and the vcs
+// revision for builds from a checkout.
+func readVersionInfo(info *debug.BuildInfo, ok bool) versionInfo {
+	v := versionInfo{
+		Version:   toolVersion,
+		Commit:    toolCommit,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if ok {
+		if v.Version == "" && info.Main.Version != "(devel)" {
+			v.Version = info.Main.Version
+		}
+
+		// the modified flag is only known for the vcs revision
+		if v.Commit == "" {
+			for _, setting := range info.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					v.Commit = setting.Value
+				case "vcs.modified":
+					v.Modified = setting.Value == "true"
+				}
+			}
+		}
+	}
+
+	if v.Version == "" {
+		v.Version = "devel"
+	}
+
+	return v
+}
+
+func (v versionInfo) write(w io.Writer) {
+	fmt.Fprintln(w, "go-builder", v.Version)
+
+	if v.Commit != "" {
+		commit := v.Commit
+		if v.Modified {
+			commit += " (modified)"
+		}
+		fmt.Fprintln(w, "commit:", commit)
+	}
+
+	fmt.Fprintln(w, "go:", v.GoVersion)
+	fmt.Fprintln(w, "platform:", v.Platform)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+func TestReadVersionInfo(t *testing.T) {
+	checkout := &debug.BuildInfo{
+		Main:     debug.Module{Version: "(devel)"},
+		Settings: []debug.BuildSetting{{Key: "vcs.revision", Value: "abc1234"}, {Key: "vcs.modified", Value: "true"}},
+	}
+
+	testCases := []struct {
+		name         string
+		info         *debug.BuildInfo
+		ok           bool
+		ldVersion    string
+		ldCommit     string
+		wantsVersion string
+		wantsCommit  string
+		wantsDirty   bool
+	}{
+		{name: "no build info", wantsVersion: "devel"},
+		{name: "go install", info: &debug.BuildInfo{Main: debug.Module{Version: "v1.2.3"}}, ok: true, wantsVersion: "v1.2.3"},
+		{name: "checkout", info: checkout, ok: true, wantsVersion: "devel", wantsCommit: "abc1234", wantsDirty: true},
+		{name: "ldflags", info: checkout, ok: true, ldVersion: "v2.0.0", ldCommit: "def5678", wantsVersion: "v2.0.0", wantsCommit: "def5678"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			toolVersion, toolCommit = tc.ldVersion, tc.ldCommit
+			defer func() { toolVersion, toolCommit = "", "" }()
+
+			res := readVersionInfo(tc.info, tc.ok)
+
+			if res.Version != tc.wantsVersion || res.Commit != tc.wantsCommit || res.Modified != tc.wantsDirty {
+				t.Logf("Incorrect version info, wanted: %s %s %t got: %+v\n", tc.wantsVersion, tc.wantsCommit, tc.wantsDirty, res)
+				t.Fail()
+			}
+
+			if res.GoVersion != runtime.Version() || res.Platform != runtime.GOOS+"/"+runtime.GOARCH {
+				t.Logf("Incorrect runtime info: %+v\n", res)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestVersionInfoWrite(t *testing.T) {
+	buf := bytes.Buffer{}
+	versionInfo{Version: "v1.2.3", Commit: "abc1234", Modified: true, GoVersion: "go1.24.6", Platform: "linux/amd64"}.write(&buf)
+
+	wants := "go-builder v1.2.3\ncommit: abc1234 (modified)\ngo: go1.24.6\nplatform: linux/amd64\n"
+	if buf.String() != wants {
+		t.Logf("Incorrect output, wanted: %q got: %q\n", wants, buf.String())
+		t.Fail()
+	}
+}
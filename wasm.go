@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// copyWasmExec copies the wasm_exec.js support file shipped with the Go
+// toolchain into outputDir so js/wasm builds can be loaded in a browser.
+// Go 1.24 moved the file from misc/wasm to lib/wasm; both are checked.
+func copyWasmExec(ctx context.Context, outputDir string) (string, error) {
+	rawGoroot, err := exec.CommandContext(ctx, "go", "env", "GOROOT").Output()
+	if err != nil {
+		return "", fmt.Errorf("goroot: %w", err)
+	}
+
+	goroot := strings.TrimSpace(string(rawGoroot))
+
+	var contents []byte
+	for _, dir := range []string{"lib", "misc"} {
+		contents, err = os.ReadFile(filepath.Join(goroot, dir, "wasm", "wasm_exec.js"))
+		if !errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	fp := filepath.Join(outputDir, "wasm_exec.js")
+	if err := os.WriteFile(fp, contents, 0o644); err != nil {
+		return "", err
+	}
+
+	return fp, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCopyWasmExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("go is faked with sh in this test")
+	}
+
+	testCases := []struct {
+		name  string
+		files map[string]string
+		wants string
+		err   error
+	}{
+		{
+			name:  "lib",
+			files: map[string]string{"lib/wasm/wasm_exec.js": "lib\n"},
+			wants: "lib\n",
+		},
+		{
+			name:  "misc",
+			files: map[string]string{"misc/wasm/wasm_exec.js": "misc\n"},
+			wants: "misc\n",
+		},
+		{
+			name:  "lib and misc",
+			files: map[string]string{"lib/wasm/wasm_exec.js": "lib\n", "misc/wasm/wasm_exec.js": "misc\n"},
+			wants: "lib\n",
+		},
+		{
+			name:  "neither",
+			files: map[string]string{"src/go.mod": "module std\n"},
+			err:   fs.ErrNotExist,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// the fake go prints the fake GOROOT for go env GOROOT
+			goroot := t.TempDir()
+			writeFiles(t, goroot, tc.files)
+
+			bin := t.TempDir()
+			if err := os.WriteFile(filepath.Join(bin, "go"), []byte("#!/bin/sh\necho "+goroot+"\n"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+			outputDir := t.TempDir()
+			fp, err := copyWasmExec(context.Background(), outputDir)
+
+			if !errors.Is(err, tc.err) {
+				t.Logf("Incorrect error returned, wanted: %v got: %v\n", tc.err, err)
+				t.FailNow()
+			} else if err != nil {
+				return
+			}
+
+			res, err := os.ReadFile(fp)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if fp != filepath.Join(outputDir, "wasm_exec.js") || string(res) != tc.wants {
+				t.Logf("Incorrect wasm_exec.js %s, wanted: %q got: %q\n", fp, tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+var ErrWatchProjects = errors.New("watch mode builds a single project")
+
+// watchExtensions are the files whose changes trigger a rebuild.
+var watchExtensions = []string{".go", ".mod", ".sum", ".work", ".s", ".c", ".h", ".cc", ".cpp", ".m"}
+
+func watchRelevant(fp string) bool {
+	name := filepath.Base(fp)
+	return !strings.HasPrefix(name, ".") && slices.Contains(watchExtensions, filepath.Ext(name))
+}
+
+// watchSkipped reports whether the directory at fp is left unwatched: hidden
+// and underscore directories, testdata and the output directory.
+func watchSkipped(fp string, outputDir string) bool {
+	name := filepath.Base(fp)
+	abs, _ := filepath.Abs(fp)
+	outputDir, _ = filepath.Abs(outputDir)
+
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || abs == outputDir
+}
+
+// addWatchDirs watches dir and its subdirectories that are not skipped.
+func addWatchDirs(w *fsnotify.Watcher, dir string, outputDir string) error {
+	return filepath.WalkDir(dir, func(fp string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+
+		if fp != dir && watchSkipped(fp, outputDir) {
+			return filepath.SkipDir
+		}
+
+		return w.Add(fp)
+	})
+}
+
+// watchFlags returns the flags for a rebuild: args without -watch and,
+// when no targets were selected, only the host target.
+func watchFlags(args []string, hostOnly bool) []string {
+	flags := []string{}
+
+	for _, arg := range args {
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if strings.HasPrefix(arg, "-") && name == "watch" {
+			continue
+		}
+
+		flags = append(flags, arg)
+	}
+
+	if hostOnly {
+		flags = append(flags, "-target", runtime.GOOS+"/"+runtime.GOARCH)
+	}
+
+	return flags
+}
+
+// rebuildSummary describes a rebuild triggered by the changed files.
+func rebuildSummary(changed []string, took time.Duration, err error) string {
+	cause := "initial build"
+	if len(changed) > 3 {
+		cause = fmt.Sprintf("%s and %d more changed", strings.Join(changed[:3], ", "), len(changed)-3)
+	} else if len(changed) > 0 {
+		cause = strings.Join(changed, ", ") + " changed"
+	}
+
+	status := "ok"
+	if err != nil {
+		status = "failed: " + err.Error()
+	}
+
+	return fmt.Sprintf("%s: %s in %s", cause, status, took.Round(100*time.Millisecond))
+}
+
+// watch calls build once and then after each burst of source changes under
+// dir, once no change arrived for debounce, until ctx is done. build gets
+// the changed paths relative to dir. Changes made while build runs are
+// dropped so files written by the build itself do not retrigger it.
+func watch(ctx context.Context, dir string, outputDir string, debounce time.Duration, build func(changed []string)) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	if err := addWatchDirs(w, dir, outputDir); err != nil {
+		return err
+	}
+
+	rebuild := func(changed []string) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			build(changed)
+		}()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-w.Events:
+			}
+		}
+	}
+
+	rebuild(nil)
+
+	changed := []string{}
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case err := <-w.Errors:
+			return err
+
+		case event := <-w.Events:
+			if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+				if event.Has(fsnotify.Create) && !watchSkipped(event.Name, outputDir) {
+					addWatchDirs(w, event.Name, outputDir)
+				}
+				continue
+			}
+
+			if !watchRelevant(event.Name) || event.Op == fsnotify.Chmod {
+				continue
+			}
+
+			rel, err := filepath.Rel(dir, event.Name)
+			if err != nil {
+				rel = event.Name
+			}
+
+			if !slices.Contains(changed, rel) {
+				changed = append(changed, rel)
+			}
+			timer.Reset(debounce)
+
+		case <-timer.C:
+			rebuild(changed)
+			changed = []string{}
+		}
+	}
+}
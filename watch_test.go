@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWatchFlags(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    []string
+		hostOnly bool
+		wants    []string
+	}{
+		{
+			name:     "host target",
+			input:    []string{"-watch", "-v"},
+			hostOnly: true,
+			wants:    []string{"-v", "-target", runtime.GOOS + "/" + runtime.GOARCH},
+		},
+		{
+			name:  "selected targets",
+			input: []string{"--watch=true", "-target", "linux/arm64", "-watchdog"},
+			wants: []string{"-target", "linux/arm64", "-watchdog"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := watchFlags(tc.input, tc.hostOnly)
+
+			if !slices.Equal(got, tc.wants) {
+				t.Logf("Incorrect flags, wanted: %v got: %v\n", tc.wants, got)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestRebuildSummary(t *testing.T) {
+	testCases := []struct {
+		name    string
+		changed []string
+		err     error
+		wants   string
+	}{
+		{name: "initial", wants: "initial build: ok in 1.2s"},
+		{name: "changed", changed: []string{"main.go"}, wants: "main.go changed: ok in 1.2s"},
+		{
+			name:    "many failed",
+			changed: []string{"a.go", "b.go", "c.go", "d.go", "e.go"},
+			err:     errors.New("exit status 1"),
+			wants:   "a.go, b.go, c.go and 2 more changed: failed: exit status 1 in 1.2s",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := rebuildSummary(tc.changed, 1234*time.Millisecond, tc.err)
+
+			if got != tc.wants {
+				t.Logf("Incorrect summary, wanted: %q got: %q\n", tc.wants, got)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.go":       "package main\n",
+		"pkg/util.go":   "package pkg\n",
+		"build/app":     "",
+		".git/HEAD":     "",
+		"pkg/notes.txt": "",
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	builds := make(chan []string, 10)
+	done := make(chan error)
+
+	go func() {
+		done <- watch(ctx, dir, filepath.Join(dir, "build"), 200*time.Millisecond, func(changed []string) {
+			builds <- changed
+		})
+	}()
+
+	if changed := <-builds; changed != nil {
+		t.Logf("Initial build had changes: %v\n", changed)
+		t.Fail()
+	}
+
+	os.WriteFile(filepath.Join(dir, "build", "app"), []byte("binary"), 0o644)
+	os.WriteFile(filepath.Join(dir, "pkg", "notes.txt"), []byte("notes"), 0o644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "pkg", "util.go"), []byte("package pkg\n\nvar X = 1\n"), 0o644)
+
+	changed := <-builds
+	slices.Sort(changed)
+
+	wants := []string{"main.go", filepath.Join("pkg", "util.go")}
+	if !slices.Equal(changed, wants) {
+		t.Logf("Incorrect changes, wanted: %v got: %v\n", wants, changed)
+		t.Fail()
+	}
+
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Logf("Incorrect error, wanted: %v got: %v\n", context.Canceled, err)
+		t.Fail()
+	}
+}
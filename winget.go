@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var ErrIncompleteWingetManifest = errors.New("winget manifests need a publisher, license and short_description")
+
+const wingetManifestVersion = "1.6.0"
+
+// WingetConfig describes the winget manifests generated for the windows
+// archives, ready to be copied into a winget-pkgs pull request.
+type WingetConfig struct {
+	Publisher string `json:"publisher"`
+	// Name is the package name, defaulting to the binary name.
+	Name string `json:"name"`
+	// PackageIdentifier defaults to Publisher.Name.
+	PackageIdentifier string `json:"package_identifier"`
+	License           string `json:"license"`
+	ShortDescription  string `json:"short_description"`
+	PackageURL        string `json:"package_url"`
+}
+
+func (w WingetConfig) IsEmpty() bool {
+	return w == WingetConfig{}
+}
+
+// wingetArch maps the windows architectures to winget's.
+var wingetArch = map[string]string{
+	"amd64": "x64",
+	"386":   "x86",
+	"arm64": "arm64",
+}
+
+// yamlDoc renders ordered top-level fields as YAML, skipping empty ones.
+// Values starting with a newline are nested blocks written as is; others are
+// quoted with yamlString.
+func yamlDoc(schema string, fields [][2]string) string {
+	doc := strings.Builder{}
+	fmt.Fprintf(&doc, "# yaml-language-server: $schema=%s\n", schema)
+
+	for _, field := range fields {
+		if field[1] == "" {
+			continue
+		}
+
+		if strings.HasPrefix(field[1], "\n") {
+			fmt.Fprintf(&doc, "%s:%s\n", field[0], field[1])
+			continue
+		}
+
+		fmt.Fprintf(&doc, "%s: %s\n", field[0], yamlString(field[1]))
+	}
+
+	return doc.String()
+}
+
+// yamlString quotes s as a JSON string, which is also a valid YAML scalar.
+func yamlString(s string) string {
+	value, _ := json.Marshal(s)
+	return string(value)
+}
+
+// writeWingetManifests writes the version, installer and defaultLocale
+// manifests for the windows archives, keyed by dist, under the winget-pkgs
+// layout in the output directory and returns their directory.
+func writeWingetManifests(config builder.BuildConfig, winget WingetConfig, archives map[string]string, tag string, downloadURL func(tag, file string) string) (string, error) {
+	if winget.Publisher == "" || winget.License == "" || winget.ShortDescription == "" {
+		return "", ErrIncompleteWingetManifest
+	}
+
+	name := winget.Name
+	if name == "" {
+		name = config.BinaryName
+	}
+
+	id := winget.PackageIdentifier
+	if id == "" {
+		id = strings.ReplaceAll(winget.Publisher, " ", "") + "." + strings.ReplaceAll(name, " ", "")
+	}
+
+	version := strings.TrimPrefix(tag, "v")
+
+	installers := strings.Builder{}
+	for _, goarch := range []string{"386", "amd64", "arm64"} {
+		fp, ok := archives["windows/"+goarch]
+		if !ok {
+			continue
+		}
+
+		sum, err := sha256File(fp)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(&installers, "\n  - Architecture: %s\n    InstallerUrl: %s\n    InstallerSha256: %s",
+			wingetArch[goarch], yamlString(downloadURL(tag, filepath.Base(fp))), strings.ToUpper(sum))
+	}
+
+	if installers.Len() == 0 {
+		return "", fmt.Errorf("%w: expected windows 386, amd64 or arm64", ErrNoPackageArchives)
+	}
+
+	schema := func(manifestType string) string {
+		return fmt.Sprintf("https://aka.ms/winget-manifest.%s.%s.schema.json", manifestType, wingetManifestVersion)
+	}
+
+	nested := fmt.Sprintf("\n  - RelativeFilePath: %s\n    PortableCommandAlias: %s",
+		yamlString(config.BinaryName+".exe"), yamlString(config.BinaryName))
+
+	manifests := map[string]string{
+		id + ".yaml": yamlDoc(schema("version"), [][2]string{
+			{"PackageIdentifier", id},
+			{"PackageVersion", version},
+			{"DefaultLocale", "en-US"},
+			{"ManifestType", "version"},
+			{"ManifestVersion", wingetManifestVersion},
+		}),
+		id + ".installer.yaml": yamlDoc(schema("installer"), [][2]string{
+			{"PackageIdentifier", id},
+			{"PackageVersion", version},
+			{"InstallerType", "zip"},
+			{"NestedInstallerType", "portable"},
+			{"NestedInstallerFiles", nested},
+			{"Installers", installers.String()},
+			{"ManifestType", "installer"},
+			{"ManifestVersion", wingetManifestVersion},
+		}),
+		id + ".locale.en-US.yaml": yamlDoc(schema("defaultLocale"), [][2]string{
+			{"PackageIdentifier", id},
+			{"PackageVersion", version},
+			{"PackageLocale", "en-US"},
+			{"Publisher", winget.Publisher},
+			{"PackageName", name},
+			{"PackageUrl", winget.PackageURL},
+			{"License", winget.License},
+			{"ShortDescription", winget.ShortDescription},
+			{"ManifestType", "defaultLocale"},
+			{"ManifestVersion", wingetManifestVersion},
+		}),
+	}
+
+	// winget-pkgs shards by the lowercased first letter of the identifier
+	// and nests the identifier's dotted parts
+	dir := filepath.Join(append([]string{config.OutputDir, "winget", "manifests", strings.ToLower(id[:1])},
+		append(strings.Split(id, "."), version)...)...)
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	for file, contents := range manifests {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(contents), 0o644); err != nil {
+			return "", err
+		}
+	}
+
+	return dir, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestWriteWingetManifests(t *testing.T) {
+	testCases := []struct {
+		name   string
+		winget WingetConfig
+		dists  []string
+		// wantsDir is relative to the output directory and wantsArch lists
+		// the installers by winget architecture and dist, in order
+		wantsDir    string
+		wantsID     string
+		wantsArch   [][2]string
+		wantsLocale string
+		err         error
+	}{
+		{
+			name:        "amd64",
+			winget:      WingetConfig{Publisher: "Acme Corp", License: "MIT", ShortDescription: `Builds "Go"`},
+			dists:       []string{"windows/amd64"},
+			wantsDir:    "winget/manifests/a/AcmeCorp/app/1.2.3",
+			wantsID:     "AcmeCorp.app",
+			wantsArch:   [][2]string{{"x64", "windows/amd64"}},
+			wantsLocale: "PackageName: \"app\"\nLicense: \"MIT\"\nShortDescription: \"Builds \\\"Go\\\"\"\n",
+		},
+		{
+			name: "all architectures",
+			winget: WingetConfig{
+				Publisher:         "Acme",
+				Name:              "My App",
+				PackageIdentifier: "Acme.Tools.App",
+				License:           "MIT",
+				ShortDescription:  "An app",
+				PackageURL:        "https://acme.example.com",
+			},
+			dists:       []string{"windows/arm64", "windows/amd64", "windows/386", "linux/amd64"},
+			wantsDir:    "winget/manifests/a/Acme/Tools/App/1.2.3",
+			wantsID:     "Acme.Tools.App",
+			wantsArch:   [][2]string{{"x86", "windows/386"}, {"x64", "windows/amd64"}, {"arm64", "windows/arm64"}},
+			wantsLocale: "PackageName: \"My App\"\nPackageUrl: \"https://acme.example.com\"\n",
+		},
+		{
+			name:   "incomplete",
+			winget: WingetConfig{Publisher: "Acme"},
+			dists:  []string{"windows/amd64"},
+			err:    ErrIncompleteWingetManifest,
+		},
+		{
+			name:   "no windows archives",
+			winget: WingetConfig{Publisher: "Acme", License: "MIT", ShortDescription: "An app"},
+			dists:  []string{"linux/amd64", "darwin/arm64"},
+			err:    ErrNoPackageArchives,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := builder.NewConfig()
+			config.OutputDir = t.TempDir()
+			config.BinaryName = "app"
+			archives := writeTestArchives(t, config, tc.dists...)
+
+			res, err := writeWingetManifests(config, tc.winget, archives, "v1.2.3", testDownloadURL)
+
+			if !errors.Is(err, tc.err) {
+				t.Logf("Incorrect error returned, wanted: %v got: %v\n", tc.err, err)
+				t.FailNow()
+			} else if err != nil {
+				return
+			}
+
+			if wantsDir := filepath.Join(config.OutputDir, filepath.FromSlash(tc.wantsDir)); res != wantsDir {
+				t.Logf("Incorrect manifest dir, wanted: %s got: %s\n", wantsDir, res)
+				t.Fail()
+			}
+
+			installer, err := os.ReadFile(filepath.Join(res, tc.wantsID+".installer.yaml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			locale, err := os.ReadFile(filepath.Join(res, tc.wantsID+".locale.en-US.yaml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			wantsInstaller := "Installers:\n"
+			for _, arch := range tc.wantsArch {
+				sum, err := sha256File(archives[arch[1]])
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				wantsInstaller += "  - Architecture: " + arch[0] + "\n" +
+					"    InstallerUrl: \"" + testDownloadURL("v1.2.3", filepath.Base(archives[arch[1]])) + "\"\n" +
+					"    InstallerSha256: " + strings.ToUpper(sum) + "\n"
+			}
+
+			if !strings.Contains(string(installer), wantsInstaller+"ManifestType: \"installer\"\n") {
+				t.Logf("Incorrect installer manifest, wanted:\n%s\ngot:\n%s\n", wantsInstaller, installer)
+				t.Fail()
+			}
+
+			if !strings.Contains(string(locale), tc.wantsLocale) {
+				t.Logf("Incorrect locale manifest, wanted:\n%s\ngot:\n%s\n", tc.wantsLocale, locale)
+				t.Fail()
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var (
+	ErrSysoExists         = errors.New(".syso file already exists, remove it or the windows_resources config")
+	ErrResourcePackageDir = errors.New("main package is not a directory of the project")
+)
+
+// WindowsResourceConfig describes the icon, manifest and version info that
+// are compiled into a .syso with goversioninfo before windows builds.
+type WindowsResourceConfig struct {
+	Icon             string `json:"icon"`
+	Manifest         string `json:"manifest"`
+	CompanyName      string `json:"company_name"`
+	FileDescription  string `json:"file_description"`
+	ProductName      string `json:"product_name"`
+	Copyright        string `json:"copyright"`
+	OriginalFilename string `json:"original_filename"`
+	// FileVersion and ProductVersion use the dotted form 1.2.3.4; missing
+	// components are zero.
+	FileVersion    string `json:"file_version"`
+	ProductVersion string `json:"product_version"`
+}
+
+func (w WindowsResourceConfig) IsEmpty() bool {
+	return w == WindowsResourceConfig{}
+}
+
+type versionInfoVersion struct {
+	Major int
+	Minor int
+	Patch int
+	Build int
+}
+
+// parseVersionInfoVersion converts "1.2.3" (an optional leading v is
+// ignored) to the four part version used by Windows resources.
+func parseVersionInfoVersion(v string) (versionInfoVersion, error) {
+	parts := [4]int{}
+
+	if v != "" {
+		split := strings.Split(strings.TrimPrefix(v, "v"), ".")
+		if len(split) > 4 {
+			return versionInfoVersion{}, fmt.Errorf("version %q has more than four parts", v)
+		}
+
+		for i, part := range split {
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return versionInfoVersion{}, fmt.Errorf("version %q: %w", v, err)
+			}
+			parts[i] = n
+		}
+	}
+
+	return versionInfoVersion{parts[0], parts[1], parts[2], parts[3]}, nil
+}
+
+// versionInfoJSON renders the versioninfo.json read by goversioninfo.
+func (w WindowsResourceConfig) versionInfoJSON(config builder.BuildConfig) ([]byte, error) {
+	fileVersion, err := parseVersionInfoVersion(w.FileVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	productVersion, err := parseVersionInfoVersion(w.ProductVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	originalFilename := w.OriginalFilename
+	if originalFilename == "" {
+		originalFilename = config.BinaryName + ".exe"
+	}
+
+	productName := w.ProductName
+	if productName == "" {
+		productName = config.BinaryName
+	}
+
+	absPath := func(fp string) string {
+		if fp == "" || filepath.IsAbs(fp) {
+			return fp
+		}
+		return filepath.Join(config.ProjectDir, fp)
+	}
+
+	info := map[string]any{
+		"FixedFileInfo": map[string]any{
+			"FileVersion":    fileVersion,
+			"ProductVersion": productVersion,
+			"FileFlagsMask":  "3f",
+			"FileFlags":      "00",
+			"FileOS":         "040004",
+			"FileType":       "01",
+			"FileSubType":    "00",
+		},
+		"StringFileInfo": map[string]string{
+			"CompanyName":      w.CompanyName,
+			"FileDescription":  w.FileDescription,
+			"FileVersion":      w.FileVersion,
+			"InternalName":     config.BinaryName,
+			"LegalCopyright":   w.Copyright,
+			"OriginalFilename": originalFilename,
+			"ProductName":      productName,
+			"ProductVersion":   w.ProductVersion,
+		},
+		"VarFileInfo": map[string]any{
+			"Translation": map[string]string{
+				"LangID":    "0409",
+				"CharsetID": "04B0",
+			},
+		},
+		"IconPath":     absPath(w.Icon),
+		"ManifestPath": absPath(w.Manifest),
+	}
+
+	return json.MarshalIndent(info, "", "\t")
+}
+
+// resourcePackageDir returns the directory of the build's main package, where
+// the go tool links the .syso files it finds.
+func resourcePackageDir(config builder.BuildConfig) (string, error) {
+	dir := filepath.Join(config.ProjectDir, config.Package)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("%w: %s", ErrResourcePackageDir, config.MainPackage())
+	}
+
+	return dir, nil
+}
+
+// writeWindowsResources generates one rsrc_windows_<arch>.syso per windows
+// architecture in dists inside the main package directory of every build,
+// where the go tool links it automatically. Existing .syso files are not
+// overwritten. The returned files should be removed once the builds finish.
+func writeWindowsResources(ctx context.Context, builds []builder.BuildConfig, w WindowsResourceConfig, dists []builder.GoDist) ([]string, error) {
+	tmpDir, err := os.MkdirTemp("", "gobuilder-winres")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	archFlags := map[string][]string{
+		"386":   {},
+		"amd64": {"-64"},
+		"arm":   {"-arm"},
+		"arm64": {"-arm", "-64"},
+	}
+
+	sysoFiles := []string{}
+
+	for i, config := range builds {
+		dir, err := resourcePackageDir(config)
+		if err != nil {
+			removeFiles(sysoFiles)
+			return nil, err
+		}
+
+		contents, err := w.versionInfoJSON(config)
+		if err != nil {
+			removeFiles(sysoFiles)
+			return nil, err
+		}
+
+		infoFp := filepath.Join(tmpDir, fmt.Sprintf("versioninfo-%d.json", i))
+		if err := os.WriteFile(infoFp, contents, 0o644); err != nil {
+			removeFiles(sysoFiles)
+			return nil, err
+		}
+
+		for _, dist := range dists {
+			flags, ok := archFlags[dist.GOARCH]
+			fp := filepath.Join(dir, fmt.Sprintf("rsrc_windows_%s.syso", dist.GOARCH))
+
+			// builds of the same package, e.g. variants, share its resources
+			if dist.GOOS != "windows" || !ok || slices.Contains(sysoFiles, fp) {
+				continue
+			}
+
+			if _, err := os.Stat(fp); err == nil {
+				removeFiles(sysoFiles)
+				return nil, fmt.Errorf("%w: %s", ErrSysoExists, fp)
+			}
+
+			args := slices.Concat(flags, []string{"-o", fp, infoFp})
+			cmd := exec.CommandContext(ctx, "goversioninfo", args...)
+			cmd.Dir = dir
+
+			if res, err := cmd.CombinedOutput(); err != nil {
+				removeFiles(sysoFiles)
+				return nil, fmt.Errorf("goversioninfo %s: %w\n%s", dist.GOARCH, err, res)
+			}
+
+			sysoFiles = append(sysoFiles, fp)
+		}
+	}
+
+	return sysoFiles, nil
+}
+
+func removeFiles(fps []string) {
+	for _, fp := range fps {
+		os.Remove(fp)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestParseVersionInfoVersion(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		wants    versionInfoVersion
+		wantsErr bool
+	}{
+		{name: "empty", input: "", wants: versionInfoVersion{}},
+		{name: "full", input: "1.2.3.4", wants: versionInfoVersion{1, 2, 3, 4}},
+		{name: "semver", input: "v1.12.0", wants: versionInfoVersion{1, 12, 0, 0}},
+		{name: "too many parts", input: "1.2.3.4.5", wantsErr: true},
+		{name: "not a number", input: "1.2.x", wantsErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := parseVersionInfoVersion(tc.input)
+
+			if (err != nil) != tc.wantsErr {
+				t.Logf("Incorrect error returned, wanted error: %v got: %v\n", tc.wantsErr, err)
+				t.Fail()
+			} else if res != tc.wants {
+				t.Logf("Incorrect version parsed, wanted: %v got: %v\n", tc.wants, res)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestVersionInfoJSON(t *testing.T) {
+	config := builder.NewConfig()
+	config.ProjectDir = "/src/app"
+	config.BinaryName = "app"
+
+	testCases := []struct {
+		name  string
+		input WindowsResourceConfig
+		wants map[string]string
+	}{
+		{
+			name:  "defaults",
+			input: WindowsResourceConfig{FileVersion: "1.2.3"},
+			wants: map[string]string{"InternalName": "app", "OriginalFilename": "app.exe", "ProductName": "app", "FileVersion": "1.2.3"},
+		},
+		{
+			name:  "configured",
+			input: WindowsResourceConfig{ProductName: "App", OriginalFilename: "app-setup.exe", CompanyName: "Acme", Icon: "assets/app.ico"},
+			wants: map[string]string{"InternalName": "app", "OriginalFilename": "app-setup.exe", "ProductName": "App", "CompanyName": "Acme"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			raw, err := tc.input.versionInfoJSON(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res := struct {
+				StringFileInfo map[string]string
+				IconPath       string
+			}{}
+			if err := json.Unmarshal(raw, &res); err != nil {
+				t.Fatal(err)
+			}
+
+			for key, wants := range tc.wants {
+				if res.StringFileInfo[key] != wants {
+					t.Logf("Incorrect %s, wanted: %s got: %s\n", key, wants, res.StringFileInfo[key])
+					t.Fail()
+				}
+			}
+
+			if tc.input.Icon != "" && res.IconPath != filepath.Join(config.ProjectDir, tc.input.Icon) {
+				t.Logf("Icon path is not in the project, got: %s\n", res.IconPath)
+				t.Fail()
+			}
+		})
+	}
+
+	if _, err := (WindowsResourceConfig{FileVersion: "1.x"}).versionInfoJSON(config); err == nil {
+		t.Logf("Invalid file version was accepted\n")
+		t.Fail()
+	}
+}
+
+func TestWriteWindowsResources(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("goversioninfo is faked with sh in this test")
+	}
+
+	// the fake goversioninfo writes the -o file
+	bin := t.TempDir()
+	script := "#!/bin/sh\nwhile [ $# -gt 0 ]; do [ \"$1\" = -o ] && out=$2; shift; done\necho syso > \"$out\"\n"
+	os.WriteFile(filepath.Join(bin, "goversioninfo"), []byte(script), 0o755)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	dists := []builder.GoDist{{GOOS: "windows", GOARCH: "amd64"}, {GOOS: "windows", GOARCH: "arm64"}, {GOOS: "linux", GOARCH: "amd64"}}
+
+	testCases := []struct {
+		name     string
+		files    map[string]string
+		packages []string
+		wants    []string
+		wantsErr error
+	}{
+		{
+			name:     "project",
+			packages: []string{""},
+			wants:    []string{"rsrc_windows_amd64.syso", "rsrc_windows_arm64.syso"},
+		},
+		{
+			name:     "main packages",
+			files:    map[string]string{"cmd/app/main.go": "package main\n", "cmd/tool/main.go": "package main\n"},
+			packages: []string{"./cmd/app", "./cmd/app", "./cmd/tool"},
+			wants: []string{
+				"cmd/app/rsrc_windows_amd64.syso", "cmd/app/rsrc_windows_arm64.syso",
+				"cmd/tool/rsrc_windows_amd64.syso", "cmd/tool/rsrc_windows_arm64.syso",
+			},
+		},
+		{
+			name:     "existing syso",
+			files:    map[string]string{"cmd/app/main.go": "package main\n", "cmd/tool/rsrc_windows_arm64.syso": "mine\n"},
+			packages: []string{"./cmd/app", "./cmd/tool"},
+			wantsErr: ErrSysoExists,
+		},
+		{
+			name:     "missing package",
+			packages: []string{"./cmd/missing"},
+			wantsErr: ErrResourcePackageDir,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tc.files)
+
+			builds := []builder.BuildConfig{}
+			for _, pkg := range tc.packages {
+				build := builder.NewConfig()
+				build.ProjectDir = dir
+				build.Package = pkg
+				builds = append(builds, build)
+			}
+
+			res, err := writeWindowsResources(t.Context(), builds, WindowsResourceConfig{ProductName: "App"}, dists)
+			if !errors.Is(err, tc.wantsErr) {
+				t.Logf("Incorrect error, wanted: %v got: %v\n", tc.wantsErr, err)
+				t.Fail()
+			}
+
+			got := []string{}
+			for _, fp := range res {
+				rel, _ := filepath.Rel(dir, fp)
+				got = append(got, filepath.ToSlash(rel))
+			}
+
+			if !slices.Equal(got, tc.wants) && len(got)+len(tc.wants) > 0 {
+				t.Logf("Incorrect resources, wanted: %v got: %v\n", tc.wants, got)
+				t.Fail()
+			}
+
+			// a failure leaves no resources of its own behind and keeps
+			// those of the project
+			if err != nil {
+				if _, err := os.Stat(filepath.Join(dir, "cmd", "app", "rsrc_windows_amd64.syso")); err == nil {
+					t.Logf("Resources were left behind\n")
+					t.Fail()
+				}
+
+				if b, _ := os.ReadFile(filepath.Join(dir, "cmd", "tool", "rsrc_windows_arm64.syso")); tc.files["cmd/tool/rsrc_windows_arm64.syso"] != string(b) {
+					t.Logf("Existing syso was overwritten: %q\n", b)
+					t.Fail()
+				}
+			}
+		})
+	}
+}
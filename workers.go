@@ -0,0 +1,344 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var (
+	ErrNoWorkers    = errors.New("no build workers available")
+	ErrWorkerFailed = errors.New("build worker failed")
+)
+
+// isPrivateFile reports whether fp is one of the private files, such as env
+// files, that are never sent to a remote builder. A bare name such as .env
+// matches in every directory, a path only matches that file.
+func isPrivateFile(fp string, private []string) bool {
+	for _, name := range private {
+		if filepath.IsAbs(name) && fp == name {
+			return true
+		} else if !filepath.IsAbs(name) && filepath.Base(fp) == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// packSource writes a tar.gz of the project directory, without version
+// control metadata, the output directory and the private files (see
+// isPrivateFile), and returns its content hash.
+func packSource(w io.Writer, projectDir string, outputDir string, private []string) (string, error) {
+	hash := sha256.New()
+	gz := gzip.NewWriter(io.MultiWriter(w, hash))
+	tw := tar.NewWriter(gz)
+
+	outputDir, _ = filepath.Abs(outputDir)
+
+	err := filepath.WalkDir(projectDir, func(fp string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(projectDir, fp)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		abs, _ := filepath.Abs(fp)
+		if d.IsDir() && (d.Name() == ".git" || abs == outputDir) {
+			return filepath.SkipDir
+		} else if !d.IsDir() && isPrivateFile(abs, private) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil || !(info.IsDir() || info.Mode().IsRegular()) {
+			return err
+		}
+
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		// the hash only changes with the contents
+		hdr.ModTime = time.Time{}
+
+		if err := tw.WriteHeader(hdr); err != nil || info.IsDir() {
+			return err
+		}
+
+		f, err := os.Open(fp)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := tw.Close(); err != nil {
+		return "", err
+	}
+
+	if err := gz.Close(); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// worker is a go-builder serve agent and the builds it runs for this run.
+type worker struct {
+	url     string
+	slots   int
+	running int
+}
+
+// workerPool hands out targets to agents, each built on the agent with the
+// lowest share of its slots in use, and copies the binaries back.
+type workerPool struct {
+	token  string
+	source string
+	// projectDir is the local project directory, which paths of the
+	// config are made relative to
+	projectDir string
+	// privateEnv are the ExtraEnv variables left out of the configs sent
+	// to the agents, those of the env file unless -workers-env is set
+	privateEnv []string
+	client     *http.Client
+
+	mu      sync.Mutex
+	workers []*worker
+	free    chan struct{}
+}
+
+// newWorkerPool uploads the project, without its private files, to every
+// agent that answers and sizes the pool to their slots.
+func newWorkerPool(ctx context.Context, urls []string, token string, projectDir string, outputDir string, private []string) (*workerPool, error) {
+	source := bytes.Buffer{}
+	id, err := packSource(&source, projectDir, outputDir, private)
+	if err != nil {
+		return nil, err
+	}
+
+	projectDir, err = filepath.Abs(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &workerPool{token: token, source: id, projectDir: projectDir, client: &http.Client{}}
+	errs := []error{}
+
+	for _, u := range urls {
+		w := &worker{url: strings.TrimSuffix(u, "/")}
+
+		load := agentLoad{}
+		if err := p.do(ctx, http.MethodGet, w.url+"/load", nil, &load); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", u, err))
+			continue
+		}
+
+		if err := p.do(ctx, http.MethodPut, w.url+"/sources/"+id, bytes.NewReader(source.Bytes()), nil); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", u, err))
+			continue
+		}
+
+		w.slots = max(load.Slots, 1)
+		p.workers = append(p.workers, w)
+	}
+
+	if len(p.workers) == 0 {
+		return nil, errors.Join(append([]error{ErrNoWorkers}, errs...)...)
+	}
+
+	total := 0
+	for _, w := range p.workers {
+		total += w.slots
+	}
+	p.free = make(chan struct{}, total)
+
+	return p, errors.Join(errs...)
+}
+
+func (p *workerPool) do(ctx context.Context, method string, u string, body io.Reader, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%w: %s %s: %s", ErrWorkerFailed, method, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+
+	return nil
+}
+
+// acquire waits for a free slot and takes it on the least loaded worker.
+func (p *workerPool) acquire(ctx context.Context) (*worker, error) {
+	select {
+	case p.free <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var least *worker
+	for _, w := range p.workers {
+		if w.running < w.slots && (least == nil || w.running*least.slots < least.running*w.slots) {
+			least = w
+		}
+	}
+	least.running++
+
+	return least, nil
+}
+
+func (p *workerPool) release(w *worker) {
+	p.mu.Lock()
+	w.running--
+	p.mu.Unlock()
+
+	<-p.free
+}
+
+// Build builds dist on a worker and copies the binary to where a local build
+// would have written it.
+func (p *workerPool) Build(ctx context.Context, config builder.BuildConfig, dist builder.GoDist) (builder.BuildResult, error) {
+	w, err := p.acquire(ctx)
+	if err != nil {
+		return builder.BuildResult{Target: dist.String(), Race: config.Race, Path: builder.OutputPath(config, dist)}, err
+	}
+	defer p.release(w)
+
+	remote := config
+	remote.Output = nil
+	remote.Go = ""
+	remote.ExtraEnv = slices.DeleteFunc(slices.Clone(remote.ExtraEnv), func(v string) bool {
+		return slices.Contains(p.privateEnv, v)
+	})
+	if remote.GoWork != "" {
+		if rel, err := filepath.Rel(p.projectDir, remote.GoWork); err == nil && filepath.IsLocal(rel) {
+			remote.GoWork = rel
+		} else {
+			remote.GoWork = ""
+		}
+	}
+
+	body, err := json.Marshal(agentBuild{Source: p.source, Config: remote, Dist: dist, SubArch: dist.SubArch})
+	if err != nil {
+		return builder.BuildResult{}, err
+	}
+
+	res := agentResult{}
+	if err := p.do(ctx, http.MethodPost, w.url+"/builds", bytes.NewReader(body), &res); err != nil {
+		return builder.BuildResult{Target: dist.String(), Race: config.Race, Path: builder.OutputPath(config, dist)}, fmt.Errorf("%s: %w", w.url, err)
+	}
+
+	result := res.Result
+	result.Path = builder.OutputPath(config, dist)
+
+	if config.Output != nil {
+		io.WriteString(config.Output, result.Stderr)
+	}
+
+	if result.Error != "" {
+		return result, fmt.Errorf("%w: %s: %s", ErrWorkerFailed, w.url, result.Error)
+	}
+
+	if err := p.download(ctx, w.url+res.Artifact, result.Path); err != nil {
+		result.Success, result.Error = false, err.Error()
+		return result, fmt.Errorf("%s: %w", w.url, err)
+	}
+
+	return result, nil
+}
+
+// download copies the file at u to fp through a temporary file.
+func (p *workerPool) download(ctx context.Context, u string, fp string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: download: %s", ErrWorkerFailed, resp.Status)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fp), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fp), ".download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, resp.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), fp)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func TestPackSource(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":          "module example.com/app\n",
+		"main.go":         "package main\n",
+		".git/HEAD":       "ref\n",
+		"build/app-linux": "binary\n",
+		".env":            "API_TOKEN=hunter2\n",
+		"cmd/tool/.env":   "API_TOKEN=hunter2\n",
+		"secrets.env":     "API_TOKEN=hunter2\n",
+	})
+
+	private := []string{DefaultEnvFile, filepath.Join(dir, "secrets.env")}
+
+	buf := bytes.Buffer{}
+	id, err := packSource(&buf, dir, filepath.Join(dir, "build"), private)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := t.TempDir()
+	if err := extractTarGz(&buf, out); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, wants := range map[string]bool{
+		"go.mod":        true,
+		"main.go":       true,
+		"cmd/tool":      true,
+		".git":          false,
+		"build":         false,
+		".env":          false,
+		"cmd/tool/.env": false,
+		"secrets.env":   false,
+	} {
+		if _, err := os.Stat(filepath.Join(out, name)); (err == nil) != wants {
+			t.Logf("Incorrect archive content %s, wanted: %t\n", name, wants)
+			t.Fail()
+		}
+	}
+
+	// touching a file keeps the id, so agents reuse the upload
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(dir, "main.go"), later, later)
+	if again, _ := packSource(&bytes.Buffer{}, dir, filepath.Join(dir, "build"), private); again != id {
+		t.Logf("Incorrect source id, wanted: %s got: %s\n", id, again)
+		t.Fail()
+	}
+}
+
+func TestWorkerPool(t *testing.T) {
+	t.Setenv("GOFLAGS", "")
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":  "module example.com/remote\n\ngo 1.21\n",
+		"main.go": "package main\n\nfunc main() {}\n",
+	})
+
+	agents := []*httptest.Server{}
+	for i := range 2 {
+		agent := httptest.NewServer(newBuildServer("", filepath.Join(dir, "agents", string(rune('a'+i))), "", "secret", 1).Handler())
+		defer agent.Close()
+		agents = append(agents, agent)
+	}
+
+	ctx := context.Background()
+	if _, err := newWorkerPool(ctx, []string{agents[0].URL}, "wrong", dir, filepath.Join(dir, "build"), nil); err == nil {
+		t.Logf("Pool was created with a rejected token\n")
+		t.Fail()
+	}
+
+	pool, err := newWorkerPool(ctx, []string{agents[0].URL, agents[1].URL + "/"}, "secret", dir, filepath.Join(dir, "build"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pool.workers) != 2 || cap(pool.free) != 2 {
+		t.Logf("Incorrect pool size, workers: %d slots: %d\n", len(pool.workers), cap(pool.free))
+		t.Fail()
+	}
+
+	config := builder.NewConfig()
+	config.ProjectDir = dir
+	config.OutputDir = filepath.Join(dir, "build")
+	config.BinaryName = "remote"
+
+	dist := builder.GoDist{GOOS: "linux", GOARCH: "amd64"}
+	result, err := pool.Build(ctx, config, dist)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Path != builder.OutputPath(config, dist) || !result.Success {
+		t.Logf("Incorrect result: %+v\n", result)
+		t.Fail()
+	}
+
+	if info, err := os.Stat(result.Path); err != nil || info.Size() == 0 {
+		t.Logf("Binary was not downloaded: %v\n", err)
+		t.Fail()
+	}
+
+	// the slot is free again once the build is done
+	if len(pool.free) != 0 {
+		t.Logf("Slot was not released\n")
+		t.Fail()
+	}
+
+	// the sub-architecture reaches the agent although GoDist leaves it out
+	// of its JSON
+	armDist := builder.GoDist{GOOS: "linux", GOARCH: "arm", SubArch: "6"}
+	result, err = pool.Build(ctx, config, armDist)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Target != "linux/arm/6" || result.Path != builder.OutputPath(config, armDist) {
+		t.Logf("Incorrect sub-arch result, wanted: %s got: %+v\n", armDist, result)
+		t.Fail()
+	}
+
+	if info, err := os.Stat(result.Path); err != nil || info.Size() == 0 {
+		t.Logf("Sub-arch binary was not downloaded: %v\n", err)
+		t.Fail()
+	}
+}
+
+func TestWorkerPoolPrivateEnv(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"go.mod": "module example.com/remote\n"})
+
+	sent := agentBuild{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /load", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, agentLoad{Slots: 1})
+	})
+	mux.HandleFunc("PUT /sources/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /builds", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&sent)
+		writeJSON(w, http.StatusOK, agentResult{Result: builder.BuildResult{Error: "not built"}})
+	})
+
+	agent := httptest.NewServer(mux)
+	defer agent.Close()
+
+	pool, err := newWorkerPool(context.Background(), []string{agent.URL}, "", dir, filepath.Join(dir, "build"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.privateEnv = []string{"API_TOKEN=hunter2"}
+
+	config := builder.NewConfig()
+	config.ProjectDir = dir
+	config.ExtraEnv = []string{"API_TOKEN=hunter2", "GOPROXY=off"}
+
+	pool.Build(context.Background(), config, builder.GoDist{GOOS: "linux", GOARCH: "amd64"})
+
+	wants := []string{"GOPROXY=off"}
+	if !slices.Equal(sent.Config.ExtraEnv, wants) {
+		t.Logf("Incorrect env sent, wanted: %v got: %v\n", wants, sent.Config.ExtraEnv)
+		t.Fail()
+	}
+
+	if !slices.Equal(config.ExtraEnv, []string{"API_TOKEN=hunter2", "GOPROXY=off"}) {
+		t.Logf("Local config env was changed: %v\n", config.ExtraEnv)
+		t.Fail()
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+var (
+	ErrNoWorkspaceMain        = errors.New("no module of the workspace has a main package at its root")
+	ErrAmbiguousWorkspaceMain = errors.New("several workspace modules have a main package, select one with a builds entry")
+)
+
+// findGoWork returns the go.work file governing dir with the go command's
+// lookup: GOWORK when set, or the nearest go.work in dir or a parent. It
+// returns "" when workspaces are off or there is none.
+func findGoWork(dir string) (string, error) {
+	if gowork, ok := os.LookupEnv("GOWORK"); ok && gowork != "" {
+		if gowork == "off" {
+			return "", nil
+		}
+		return filepath.Abs(gowork)
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		fp := filepath.Join(dir, "go.work")
+		if _, err := os.Stat(fp); err == nil {
+			return fp, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// goWorkUses returns the module directories of the use directives in the
+// go.work at fp, as written in the file.
+func goWorkUses(fp string) ([]string, error) {
+	f, err := os.Open(fp)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	uses := []string{}
+	inBlock := false
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+		line = strings.TrimSpace(line)
+
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+		case inBlock && line != "":
+			uses = append(uses, strings.Trim(line, "\"`"))
+		case line == "use (":
+			inBlock = true
+		case strings.HasPrefix(line, "use "):
+			uses = append(uses, strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "use ")), "\"`"))
+		}
+	}
+
+	return uses, scanner.Err()
+}
+
+// resolveWorkspace sets the workspace file for the build and, when the
+// project directory is a workspace root that is not a module itself, points
+// the build at the one workspace module with a main package at its root.
+func resolveWorkspace(ctx context.Context, config *builder.BuildConfig) error {
+	gowork, err := findGoWork(config.ProjectDir)
+	if err != nil || gowork == "" {
+		return err
+	}
+
+	config.GoWork = gowork
+
+	if _, err := os.Stat(filepath.Join(config.ProjectDir, "go.mod")); err == nil || config.Package != "" {
+		return nil
+	}
+
+	uses, err := goWorkUses(gowork)
+	if err != nil {
+		return err
+	}
+
+	projectDir, err := filepath.Abs(config.ProjectDir)
+	if err != nil {
+		return err
+	}
+
+	pkgs := []string{}
+	for _, use := range uses {
+		dir := filepath.Join(filepath.Dir(gowork), use)
+
+		rel, err := filepath.Rel(projectDir, dir)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+
+		pkgs = append(pkgs, "./"+filepath.ToSlash(rel))
+	}
+
+	cmd := exec.CommandContext(ctx, "go", append([]string{"list", "-e", "-f", "{{.Name}}"}, pkgs...)...)
+	cmd.Dir = config.ProjectDir
+	cmd.Env = append(os.Environ(), "GOWORK="+gowork)
+
+	res, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("go list: %w", err)
+	}
+
+	mains := []string{}
+	for i, name := range strings.Split(strings.TrimSpace(string(res)), "\n") {
+		if name == "main" && i < len(pkgs) {
+			mains = append(mains, pkgs[i])
+		}
+	}
+
+	switch len(mains) {
+	case 0:
+		return ErrNoWorkspaceMain
+	case 1:
+		config.Package = mains[0]
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrAmbiguousWorkspaceMain, strings.Join(mains, ", "))
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/jrstaple/go-builder/pkg/builder"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, contents := range files {
+		fp := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(fp), 0o755)
+		if err := os.WriteFile(fp, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGoWorkUses(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.work": "go 1.21\n\nuse ./tool // the cli\n\nuse (\n\t./app\n\t\"./lib\"\n\t// ./old\n)\n",
+	})
+
+	uses, err := goWorkUses(filepath.Join(dir, "go.work"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wants := []string{"./tool", "./app", "./lib"}
+	if !slices.Equal(uses, wants) {
+		t.Logf("Incorrect uses, wanted: %v got: %v\n", wants, uses)
+		t.Fail()
+	}
+}
+
+func TestResolveWorkspace(t *testing.T) {
+	t.Setenv("GOWORK", "")
+	t.Setenv("GOFLAGS", "")
+
+	testCases := []struct {
+		name        string
+		files       map[string]string
+		wantsPkg    string
+		wantsErr    error
+		wantsGoWork bool
+	}{
+		{
+			name: "single main module",
+			files: map[string]string{
+				"go.work":     "go 1.21\n\nuse (\n\t./app\n\t./lib\n)\n",
+				"app/go.mod":  "module example.com/app\n\ngo 1.21\n",
+				"app/main.go": "package main\n\nfunc main() {}\n",
+				"lib/go.mod":  "module example.com/lib\n\ngo 1.21\n",
+				"lib/lib.go":  "package lib\n",
+			},
+			wantsPkg:    "./app",
+			wantsGoWork: true,
+		},
+		{
+			name: "several main modules",
+			files: map[string]string{
+				"go.work":   "go 1.21\n\nuse ./a\nuse ./b\n",
+				"a/go.mod":  "module example.com/a\n\ngo 1.21\n",
+				"a/main.go": "package main\n\nfunc main() {}\n",
+				"b/go.mod":  "module example.com/b\n\ngo 1.21\n",
+				"b/main.go": "package main\n\nfunc main() {}\n",
+			},
+			wantsErr:    ErrAmbiguousWorkspaceMain,
+			wantsGoWork: true,
+		},
+		{
+			name: "project is a module",
+			files: map[string]string{
+				"go.work": "go 1.21\n\nuse .\n",
+				"go.mod":  "module example.com/root\n\ngo 1.21\n",
+				"main.go": "package main\n\nfunc main() {}\n",
+			},
+			wantsGoWork: true,
+		},
+		{
+			name: "no workspace",
+			files: map[string]string{
+				"go.mod":  "module example.com/root\n\ngo 1.21\n",
+				"main.go": "package main\n\nfunc main() {}\n",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tc.files)
+
+			config := builder.NewConfig()
+			config.ProjectDir = dir
+
+			err := resolveWorkspace(context.Background(), &config)
+			if !errors.Is(err, tc.wantsErr) {
+				t.Fatalf("Incorrect error, wanted: %v got: %v\n", tc.wantsErr, err)
+			}
+
+			if config.Package != tc.wantsPkg {
+				t.Logf("Incorrect package, wanted: %q got: %q\n", tc.wantsPkg, config.Package)
+				t.Fail()
+			}
+
+			if (config.GoWork != "") != tc.wantsGoWork {
+				t.Logf("Incorrect GoWork: %q\n", config.GoWork)
+				t.Fail()
+			}
+		})
+	}
+}